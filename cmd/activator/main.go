@@ -35,25 +35,25 @@ import (
 	"github.com/knative/serving/pkg/activator"
 	activatorhandler "github.com/knative/serving/pkg/activator/handler"
 	activatorutil "github.com/knative/serving/pkg/activator/util"
+	"github.com/knative/serving/pkg/apis/serving/v1alpha1"
 	clientset "github.com/knative/serving/pkg/client/clientset/versioned"
+	informers "github.com/knative/serving/pkg/client/informers/externalversions"
 	"github.com/knative/serving/pkg/http/h2c"
 	"github.com/knative/serving/pkg/logging"
 	"github.com/knative/serving/pkg/metrics"
 	"github.com/knative/serving/pkg/system"
+	"github.com/knative/serving/pkg/tracing"
 	"go.uber.org/zap"
+	kubeinformers "k8s.io/client-go/informers"
 	"k8s.io/client-go/kubernetes"
 	"k8s.io/client-go/rest"
+	"k8s.io/client-go/tools/cache"
 
 	"github.com/knative/serving/pkg/websocket"
 )
 
 const (
-	maxUploadBytes = 32e6 // 32MB - same as app engine
-	component      = "activator"
-
-	maxRetries             = 18 // the sum of all retries would add up to 1 minute
-	minRetryInterval       = 100 * time.Millisecond
-	exponentialBackoffBase = 1.3
+	component = "activator"
 
 	// Add a little buffer space between request handling and stat
 	// reporting so that latency in the stat pipeline doesn't
@@ -62,6 +62,13 @@ const (
 
 	// Add enough buffer to not block request serving on stats collection
 	requestCountingQueueLength = 100
+
+	// The requests to a single revision that may be queued in the
+	// activator, and proxied to it at once.
+	breakerQueueDepth     = 10000
+	breakerMaxConcurrency = 1000
+
+	resyncPeriod = 10 * time.Hour
 )
 
 var (
@@ -124,22 +131,89 @@ func main() {
 		logger.Fatal("Failed to create stats reporter", zap.Error(err))
 	}
 
-	a := activator.NewRevisionActivator(kubeClient, servingClient, logger)
+	// set up signals so we handle the first shutdown signal gracefully
+	stopCh := signals.SetupSignalHandler()
+
+	// Revisions, services and endpoints are read from the informer caches
+	// rather than from the API server on every activation.
+	kubeInformerFactory := kubeinformers.NewSharedInformerFactory(kubeClient, resyncPeriod)
+	servingInformerFactory := informers.NewSharedInformerFactory(servingClient, resyncPeriod)
+	revisionInformer := servingInformerFactory.Serving().V1alpha1().Revisions()
+	serviceInformer := kubeInformerFactory.Core().V1().Services()
+	endpointsInformer := kubeInformerFactory.Core().V1().Endpoints()
+	// Routes and their ClusterIngresses resolve the revision of requests
+	// that come without the revision headers.
+	routeInformer := servingInformerFactory.Serving().V1alpha1().Routes()
+	clusterIngressInformer := servingInformerFactory.Networking().V1alpha1().ClusterIngresses()
+
+	// The activator config starts with the defaults, and follows the
+	// config map once the watcher below is started.
+	defaultConfig, err := activator.NewConfigFromMap(nil)
+	if err != nil {
+		logger.Fatal("Error creating the default activator config", zap.Error(err))
+	}
+	activatorConfig := activator.NewDynamicConfig(defaultConfig, logger)
+
+	a := activator.NewRevisionActivator(revisionInformer, serviceInformer, activatorConfig, reporter, logger)
 	a = activator.NewDedupingActivator(a)
 
-	// Retry on 503's for up to 60 seconds. The reason is there is
-	// a small delay for k8s to include the ready IP in service.
+	// Retry on 503's, for up to 60 seconds by default. The reason is there
+	// is a small delay for k8s to include the ready IP in service.
 	// https://github.com/knative/serving/issues/660#issuecomment-384062553
 	shouldRetry := activatorutil.RetryStatus(http.StatusServiceUnavailable)
-	backoffSettings := wait.Backoff{
-		Duration: minRetryInterval,
-		Factor:   exponentialBackoffBase,
-		Steps:    maxRetries,
+	backoffSettings := func() wait.Backoff {
+		return activatorConfig.Current().Backoff()
 	}
-	rt := activatorutil.NewRetryRoundTripper(activatorutil.AutoTransport, logger, backoffSettings, shouldRetry)
+	rt := activatorutil.NewDynamicRetryRoundTripper(tracing.NewTransport(activatorutil.AutoTransport), logger, backoffSettings, shouldRetry)
 
-	// set up signals so we handle the first shutdown signal gracefully
-	stopCh := signals.SetupSignalHandler()
+	tracer := tracing.NewTracer(component, logger)
+
+	// Keep the capacity and the pods of each revision in line with its
+	// ready endpoints.
+	throttler := activator.NewThrottler(activator.ThrottlerParams{
+		QueueDepth:     breakerQueueDepth,
+		MaxConcurrency: breakerMaxConcurrency,
+		GetRevision: func(namespace, name string) (*v1alpha1.Revision, error) {
+			return revisionInformer.Lister().Revisions(namespace).Get(name)
+		},
+		GetEndpoints: activator.ReadyEndpointsGetter(endpointsInformer.Lister()),
+		ActivationTimeout: func(rev *v1alpha1.Revision) time.Duration {
+			return activatorConfig.Current().ActivationTimeoutFor(rev)
+		},
+		Logger: logger,
+	})
+	endpointsInformer.Informer().AddEventHandler(cache.ResourceEventHandlerFuncs{
+		AddFunc: throttler.EndpointsUpdated,
+		UpdateFunc: func(oldObj, newObj interface{}) {
+			throttler.EndpointsUpdated(newObj)
+		},
+		DeleteFunc: throttler.EndpointsDeleted,
+	})
+
+	// Informers only run if they are set up before the factories start.
+	if err := clusterIngressInformer.Informer().AddIndexers(cache.Indexers{
+		activator.HostIndex: activator.IndexIngressHosts,
+	}); err != nil {
+		logger.Fatal("Error indexing the ClusterIngresses by host", zap.Error(err))
+	}
+	hostResolver := activator.NewHostResolver(clusterIngressInformer.Informer().GetIndexer(), routeInformer.Lister(), revisionInformer.Lister())
+
+	// These are non-blocking.
+	kubeInformerFactory.Start(stopCh)
+	servingInformerFactory.Start(stopCh)
+
+	logger.Info("Waiting for informer caches to sync")
+	for i, synced := range []cache.InformerSynced{
+		revisionInformer.Informer().HasSynced,
+		serviceInformer.Informer().HasSynced,
+		endpointsInformer.Informer().HasSynced,
+		routeInformer.Informer().HasSynced,
+		clusterIngressInformer.Informer().HasSynced,
+	} {
+		if ok := cache.WaitForCacheSync(stopCh, synced); !ok {
+			logger.Fatalf("failed to wait for cache at index %v to sync", i)
+		}
+	}
 
 	// Open a websocket connection to the autoscaler
 	autoscalerEndpoint := fmt.Sprintf("ws://%s.%s.svc.cluster.local:%s", "autoscaler", system.Namespace, "8080")
@@ -154,25 +228,46 @@ func main() {
 		ReportChan: time.NewTicker(time.Second).C,
 	})
 
-	ah := &activatorhandler.FilteringHandler{
-		NextHandler: activatorhandler.NewRequestEventHandler(reqChan,
-			&activatorhandler.EnforceMaxContentLengthHandler{
-				MaxContentLengthBytes: maxUploadBytes,
-				NextHandler: &activatorhandler.ActivationHandler{
-					Activator: a,
-					Transport: rt,
-					Logger:    logger,
-					Reporter:  reporter,
+	activationHandler := &activatorhandler.ActivationHandler{
+		Activator: a,
+		Transport: rt,
+		Logger:    logger,
+		Reporter:  reporter,
+		Throttler: throttler,
+		Config:    activatorConfig,
+	}
+	// Drop the proxies of revisions and pods that go away.
+	revisionInformer.Informer().AddEventHandler(cache.ResourceEventHandlerFuncs{
+		DeleteFunc: activationHandler.RevisionDeleted,
+	})
+	endpointsInformer.Informer().AddEventHandler(cache.ResourceEventHandlerFuncs{
+		UpdateFunc: func(oldObj, newObj interface{}) {
+			activationHandler.EndpointsUpdated(newObj)
+		},
+	})
+
+	var ah http.Handler = &activatorhandler.FilteringHandler{
+		NextHandler: &activatorhandler.HostRoutingHandler{
+			ResolveRevision: hostResolver.Resolve,
+			NextHandler: activatorhandler.NewRequestEventHandler(reqChan,
+				&activatorhandler.EnforceMaxContentLengthHandler{
+					GetMaxContentLengthBytes: activator.MaxUploadBytesGetter(activatorConfig, revisionInformer.Lister()),
+					NextHandler:              activationHandler,
 				},
-			},
-		),
+			),
+		},
 	}
+	ah = tracing.NewHandler(component, ah)
 
 	// Watch the logging config map and dynamically update logging levels.
 	configMapWatcher := configmap.NewInformedWatcher(kubeClient, system.Namespace)
 	configMapWatcher.Watch(logging.ConfigName, logging.UpdateLevelFromConfigMap(logger, atomicLevel, component))
 	// Watch the observability config map and dynamically update metrics exporter.
 	configMapWatcher.Watch(metrics.ObservabilityConfigName, metrics.UpdateExporterFromConfigMap(component, logger))
+	// Watch the tracing config map and dynamically update the trace exporter.
+	configMapWatcher.Watch(tracing.ConfigName, tracing.UpdateTracerFromConfigMap(tracer, logger))
+	// Watch the activator config map and dynamically update the activator config.
+	configMapWatcher.Watch(activator.ConfigName, activatorConfig.Update)
 	if err = configMapWatcher.Start(stopCh); err != nil {
 		logger.Fatalf("failed to start configuration manager: %v", err)
 	}
@@ -187,4 +282,5 @@ func main() {
 	<-stopCh
 	a.Shutdown()
 	srv.Shutdown(context.TODO())
+	tracer.Shutdown()
 }
@@ -142,6 +142,7 @@ func main() {
 	endpointsInformer := kubeInformerFactory.Core().V1().Endpoints()
 	configMapInformer := kubeInformerFactory.Core().V1().ConfigMaps()
 	virtualServiceInformer := sharedInformerFactory.Networking().V1alpha3().VirtualServices()
+	destinationRuleInformer := sharedInformerFactory.Networking().V1alpha3().DestinationRules()
 	imageInformer := cachingInformerFactory.Caching().V1alpha1().Images()
 
 	// Build all of our controllers, with the clients constructed above.
@@ -161,6 +162,7 @@ func main() {
 			coreServiceInformer,
 			endpointsInformer,
 			configMapInformer,
+			destinationRuleInformer,
 			buildInformerFactory,
 		),
 		route.NewController(
@@ -217,6 +219,7 @@ func main() {
 		endpointsInformer.Informer().HasSynced,
 		configMapInformer.Informer().HasSynced,
 		virtualServiceInformer.Informer().HasSynced,
+		destinationRuleInformer.Informer().HasSynced,
 	} {
 		if ok := cache.WaitForCacheSync(stopCh, synced); !ok {
 			logger.Fatalf("failed to wait for cache at index %v to sync", i)
@@ -18,6 +18,7 @@ package main
 
 import (
 	"context"
+	"encoding/json"
 	"flag"
 	"fmt"
 	"io"
@@ -26,6 +27,7 @@ import (
 	"net/url"
 	"os"
 	"os/signal"
+	"strconv"
 	"strings"
 	"sync"
 	"syscall"
@@ -35,14 +37,18 @@ import (
 	"github.com/knative/serving/cmd/util"
 	activatorutil "github.com/knative/serving/pkg/activator/util"
 	"github.com/knative/serving/pkg/autoscaler"
+	pkghttp "github.com/knative/serving/pkg/http"
 	"github.com/knative/serving/pkg/http/h2c"
 	"github.com/knative/serving/pkg/logging"
 	"github.com/knative/serving/pkg/queue"
 	"github.com/knative/serving/pkg/system"
+	"github.com/knative/serving/pkg/tracing"
 	"github.com/knative/serving/pkg/websocket"
 	"go.opencensus.io/exporter/prometheus"
 	"go.opencensus.io/stats/view"
+	"go.opencensus.io/trace"
 	"go.uber.org/zap"
+	corev1 "k8s.io/api/core/v1"
 )
 
 const (
@@ -52,11 +58,6 @@ const (
 	statReportingQueueLength = 10
 	// Add enough buffer to not block request serving on stats collection
 	requestCountingQueueLength = 100
-	// Duration the /quitquitquit handler should wait before returning.
-	// This is to give Istio a little bit more time to remove the pod
-	// from its configuration and propagate that to all istio-proxies
-	// in the mesh.
-	quitSleepDuration = 20 * time.Second
 )
 
 var (
@@ -69,18 +70,27 @@ var (
 	servingAutoscalerPort  string
 	containerConcurrency   int
 	revisionTimeoutSeconds int
+	userPort               int
+	drainTimeout           time.Duration
+	upgradedWeight         float64
+	requestLogTemplate     string
 	statChan               = make(chan *autoscaler.Stat, statReportingQueueLength)
 	reqChan                = make(chan queue.ReqEvent, requestCountingQueueLength)
 	statSink               *websocket.ManagedConnection
 	logger                 *zap.SugaredLogger
 	breaker                *queue.Breaker
+	drainer                *queue.Drainer
+	readinessProber        *queue.ReadinessProber
+	upgraded               *queue.UpgradedConnections
 
-	h2cProxy  *httputil.ReverseProxy
-	httpProxy *httputil.ReverseProxy
+	h2cProxy     *httputil.ReverseProxy
+	httpProxy    *httputil.ReverseProxy
+	upgradeProxy *httputil.ReverseProxy
 
 	server   *http.Server
 	health   *healthServer
 	reporter *queue.Reporter // Prometheus stats reporter.
+	tracer   *tracing.Tracer
 )
 
 func initEnv() {
@@ -92,6 +102,45 @@ func initEnv() {
 	servingAutoscalerPort = util.GetRequiredEnvOrFatal("SERVING_AUTOSCALER_PORT", logger)
 	containerConcurrency = util.MustParseIntEnvOrFatal("CONTAINER_CONCURRENCY", logger)
 	revisionTimeoutSeconds = util.MustParseIntEnvOrFatal("REVISION_TIMEOUT_SECONDS", logger)
+	userPort = util.MustParseIntEnvOrFatal("USER_PORT", logger)
+	requestLogTemplate = os.Getenv("SERVING_REQUEST_LOG_TEMPLATE")
+
+	drainTimeout = queue.DefaultDrainTimeout
+	if dt := os.Getenv("SERVING_DRAIN_TIMEOUT"); dt != "" {
+		d, err := time.ParseDuration(dt)
+		if err != nil {
+			logger.Fatalw("Failed to parse SERVING_DRAIN_TIMEOUT", zap.Error(err))
+		}
+		drainTimeout = d
+	}
+	settlePeriod := queue.DefaultDrainSettlePeriod
+	if sp := os.Getenv("SERVING_DRAIN_SETTLE_PERIOD"); sp != "" {
+		d, err := time.ParseDuration(sp)
+		if err != nil {
+			logger.Fatalw("Failed to parse SERVING_DRAIN_SETTLE_PERIOD", zap.Error(err))
+		}
+		settlePeriod = d
+	}
+	drainer = queue.NewDrainer(settlePeriod)
+
+	upgraded = queue.NewUpgradedConnections(util.MustParseIntEnvOrFatal("SERVING_MAX_UPGRADED_CONNECTIONS", logger))
+	upgradedWeight = queue.DefaultUpgradedConnectionWeight
+	if uw := os.Getenv("SERVING_UPGRADED_CONNECTION_WEIGHT"); uw != "" {
+		w, err := strconv.ParseFloat(uw, 64)
+		if err != nil {
+			logger.Fatalw("Failed to parse SERVING_UPGRADED_CONNECTION_WEIGHT", zap.Error(err))
+		}
+		upgradedWeight = w
+	}
+
+	var readinessProbe *corev1.Probe
+	if rp := os.Getenv("SERVING_READINESS_PROBE"); rp != "" {
+		readinessProbe = &corev1.Probe{}
+		if err := json.Unmarshal([]byte(rp), readinessProbe); err != nil {
+			logger.Fatalw("Failed to parse SERVING_READINESS_PROBE", zap.Error(err))
+		}
+	}
+	readinessProber = queue.NewReadinessProber(readinessProbe, userPort, logger)
 
 	// TODO(mattmoor): Move this key to be in terms of the KPA.
 	servingRevisionKey = autoscaler.NewKpaKey(servingNamespace, servingRevision)
@@ -101,6 +150,18 @@ func initEnv() {
 		logger.Fatal("Failed to create stats reporter", zap.Error(err))
 	}
 	reporter = _reporter
+
+	tracingConfig, err := tracing.NewTracingConfigFromMap(map[string]string{
+		tracing.EnableKey:         os.Getenv("TRACING_CONFIG_ENABLE"),
+		tracing.ZipkinEndpointKey: os.Getenv("TRACING_CONFIG_ZIPKIN_ENDPOINT"),
+		tracing.DebugKey:          os.Getenv("TRACING_CONFIG_DEBUG"),
+		tracing.SampleRateKey:     os.Getenv("TRACING_CONFIG_SAMPLE_RATE"),
+	})
+	if err != nil {
+		logger.Fatal("Failed to parse the tracing config", zap.Error(err))
+	}
+	tracer = tracing.NewTracer("queue-proxy", logger)
+	tracer.ApplyConfig(tracingConfig)
 }
 
 func statReporter() {
@@ -146,7 +207,41 @@ func isProbe(r *http.Request) bool {
 	return strings.HasPrefix(r.Header.Get("User-Agent"), "kube-probe/")
 }
 
+// proxyWithSpan proxies r to the user container within a "queue_proxy_proxy" span.
+func proxyWithSpan(proxy *httputil.ReverseProxy, w http.ResponseWriter, r *http.Request) {
+	ctx, span := trace.StartSpan(r.Context(), "queue_proxy_proxy")
+	defer span.End()
+	proxy.ServeHTTP(w, r.WithContext(ctx))
+}
+
+// upgradeHandler proxies an upgrade request, e.g. for a WebSocket, to the
+// user container. Upgraded connections are long-lived, so they aren't
+// queued by the breaker, and count towards the reported concurrency with
+// their own weight. A drain waits for them like for requests, and those
+// still open when it times out are closed.
+func upgradeHandler(w http.ResponseWriter, r *http.Request) {
+	r, release, ok := upgraded.Track(r)
+	if !ok {
+		http.Error(w, "too many upgraded connections", http.StatusServiceUnavailable)
+		return
+	}
+	defer release()
+	drainer.RequestStarted()
+	defer drainer.RequestFinished()
+
+	reqChan <- queue.ReqEvent{Time: time.Now(), EventType: queue.ReqUpgradedIn}
+	defer func() {
+		reqChan <- queue.ReqEvent{Time: time.Now(), EventType: queue.ReqUpgradedOut}
+	}()
+	proxyWithSpan(upgradeProxy, w, r)
+}
+
 func handler(w http.ResponseWriter, r *http.Request) {
+	if queue.IsUpgradeRequest(r) && !isProbe(r) {
+		upgradeHandler(w, r)
+		return
+	}
+
 	proxy := proxyForRequest(r)
 
 	if isProbe(r) {
@@ -156,21 +251,62 @@ func handler(w http.ResponseWriter, r *http.Request) {
 	}
 
 	// Metrics for autoscaling
-	reqChan <- queue.ReqEvent{Time: time.Now(), EventType: queue.ReqIn}
-	defer func() {
-		reqChan <- queue.ReqEvent{Time: time.Now(), EventType: queue.ReqOut}
-	}()
+	w, served := queue.TrackRequest(reqChan, w, r)
+	defer served()
+	// Let a drain know that traffic is still coming in.
+	drainer.RequestStarted()
+	defer drainer.RequestFinished()
 	// Enforce queuing and concurrency limits
 	if breaker != nil {
+		_, queueSpan := trace.StartSpan(r.Context(), "queue_proxy_queue")
 		ok := breaker.Maybe(func() {
-			proxy.ServeHTTP(w, r)
+			queueSpan.End()
+			proxyWithSpan(proxy, w, r)
 		})
 		if !ok {
+			queueSpan.SetStatus(trace.Status{Code: trace.StatusCodeUnavailable, Message: "overload"})
+			queueSpan.End()
 			http.Error(w, "overload", http.StatusServiceUnavailable)
 		}
 	} else {
-		proxy.ServeHTTP(w, r)
+		proxyWithSpan(proxy, w, r)
+	}
+}
+
+// timeoutHandler wraps h so that requests time out after timeout. gRPC
+// calls aren't run through http.TimeoutHandler, which buffers responses
+// and would hold back the messages of streaming calls; their context
+// gets a deadline instead. Upgrade requests can't go through it either,
+// since it doesn't let connections be hijacked, and aren't bounded by
+// timeout at all.
+func timeoutHandler(h http.Handler, timeout time.Duration) http.Handler {
+	th := http.TimeoutHandler(h, timeout, "request timeout")
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if queue.IsUpgradeRequest(r) {
+			h.ServeHTTP(w, r)
+			return
+		}
+		if !pkghttp.IsGRPCRequest(r) {
+			th.ServeHTTP(w, r)
+			return
+		}
+		ctx, cancel := context.WithTimeout(r.Context(), timeout)
+		defer cancel()
+		h.ServeHTTP(w, r.WithContext(ctx))
+	})
+}
+
+// pushRequestLogHandler wraps h so that every request is written to the
+// access log using requestLogTemplate.
+func pushRequestLogHandler(h http.Handler) http.Handler {
+	rlh, err := pkghttp.NewRequestLogHandler(h, logger.Named("requestlog"), requestLogTemplate)
+	if err != nil {
+		// The template is validated by the controller, so this should
+		// never happen. Keep serving without access logs if it does.
+		logger.Errorw("Error setting up request logger. Request logs will be unavailable.", zap.Error(err))
+		return h
 	}
+	return rlh
 }
 
 // healthServer registers whether a PreStop hook has been called.
@@ -194,19 +330,25 @@ func (h *healthServer) kill() {
 }
 
 // healthHandler is used for readinessProbe/livenessCheck of
-// queue-proxy.
+// queue-proxy. It only reports healthy once the user container
+// has been seen ready.
 func (h *healthServer) healthHandler(w http.ResponseWriter, r *http.Request) {
-	if h.isAlive() {
-		w.WriteHeader(http.StatusOK)
-		io.WriteString(w, "alive: true")
-	} else {
+	switch {
+	case !h.isAlive():
 		w.WriteHeader(http.StatusBadRequest)
 		io.WriteString(w, "alive: false")
+	case !readinessProber.IsReady():
+		w.WriteHeader(http.StatusServiceUnavailable)
+		io.WriteString(w, "ready: false")
+	default:
+		w.WriteHeader(http.StatusOK)
+		io.WriteString(w, "alive: true")
 	}
 }
 
-// quitHandler() is used for preStop hook of queue-proxy. It shuts down its main
-// server and blocks until it gets successfully shut down.
+// quitHandler() is used for preStop hook of queue-proxy. It waits for traffic
+// to go away, then shuts down its main server and blocks until it gets
+// successfully shut down.
 // This endpoint is also called by the user-container to block its shutdown until
 // the queue-proxy's proxy server is shutdown successfully.
 func (h *healthServer) quitHandler(w http.ResponseWriter, r *http.Request) {
@@ -225,7 +367,18 @@ func (h *healthServer) quitHandler(w http.ResponseWriter, r *http.Request) {
 		logger.Error("Error while sending stat", zap.Error(err))
 	}
 
-	time.Sleep(quitSleepDuration)
+	// Give Istio time to remove the pod from its configuration and propagate
+	// that to all istio-proxies in the mesh, but no longer than it takes for
+	// traffic to go away.
+	logger.Infof("Draining for at most %v", drainTimeout)
+	drainer.Drain(drainTimeout)
+	logger.Infow("Drain ended", zap.Any("status", drainer.Status()))
+
+	// Shutdown doesn't wait for nor close upgraded connections, close the
+	// ones the drain timed out on before the user container goes away
+	// under them.
+	logger.Infof("Closing %d upgraded connections", upgraded.Len())
+	upgraded.CloseAll()
 
 	// Shutdown the server.
 	currentServer := server
@@ -241,11 +394,20 @@ func (h *healthServer) quitHandler(w http.ResponseWriter, r *http.Request) {
 	io.WriteString(w, "alive: false")
 }
 
-// Sets up /health and /quitquitquit endpoints.
+// drainHandler reports the progress of the drain started by quitHandler.
+func drainHandler(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(drainer.Status()); err != nil {
+		logger.Errorw("Failed to write the drain status", zap.Error(err))
+	}
+}
+
+// Sets up /health, /quitquitquit and /drain endpoints.
 func setupAdminHandlers(server *http.Server) {
 	mux := http.NewServeMux()
 	mux.HandleFunc(fmt.Sprintf("/%s", queue.RequestQueueHealthPath), health.healthHandler)
 	mux.HandleFunc(fmt.Sprintf("/%s", queue.RequestQueueQuitPath), health.quitHandler)
+	mux.HandleFunc(fmt.Sprintf("/%s", queue.RequestQueueDrainPath), drainHandler)
 	server.Handler = mux
 	server.ListenAndServe()
 }
@@ -261,17 +423,22 @@ func main() {
 		zap.String(logkey.Key, servingRevisionKey),
 		zap.String(logkey.Pod, podName))
 
-	target, err := url.Parse("http://localhost:8080")
+	target, err := url.Parse(fmt.Sprintf("http://localhost:%d", userPort))
 	if err != nil {
-		logger.Fatal("Failed to parse localhost url", zap.Error(err))
+		logger.Fatal("Failed to parse the user container url", zap.Error(err))
 	}
 
 	httpProxy = httputil.NewSingleHostReverseProxy(target)
+	httpProxy.Transport = tracing.NewTransport(http.DefaultTransport)
 	h2cProxy = httputil.NewSingleHostReverseProxy(target)
-	h2cProxy.Transport = h2c.DefaultTransport
+	h2cProxy.Transport = tracing.NewTransport(h2c.DefaultTransport)
+	// The tracing transport wraps response bodies in a way that doesn't
+	// let ReverseProxy upgrade connections, so upgrades don't use it.
+	upgradeProxy = httputil.NewSingleHostReverseProxy(target)
 
 	activatorutil.SetupHeaderPruning(httpProxy)
 	activatorutil.SetupHeaderPruning(h2cProxy)
+	activatorutil.SetupHeaderPruning(upgradeProxy)
 
 	// If containerConcurrency == 0 then concurrency is unlimited.
 	if containerConcurrency > 0 {
@@ -295,7 +462,7 @@ func main() {
 	go func() {
 		mux := http.NewServeMux()
 		mux.Handle("/metrics", promExporter)
-		http.ListenAndServe(":9090", mux)
+		http.ListenAndServe(fmt.Sprintf(":%d", queue.RequestQueueMetricsPort), mux)
 	}()
 
 	// Open a websocket connection to the autoscaler
@@ -309,25 +476,39 @@ func main() {
 		ReqChan:    reqChan,
 		ReportChan: reportTicker,
 		StatChan:   statChan,
-	}, time.Now())
+	}, time.Now(), upgradedWeight)
 
 	adminServer := &http.Server{
 		Addr:    fmt.Sprintf(":%d", queue.RequestQueueAdminPort),
 		Handler: nil,
 	}
 
-	server = h2c.NewServer(
-		fmt.Sprintf(":%d", queue.RequestQueuePort),
-		http.TimeoutHandler(http.HandlerFunc(handler), time.Duration(revisionTimeoutSeconds)*time.Second, "request timeout"))
+	h := timeoutHandler(http.HandlerFunc(handler), time.Duration(revisionTimeoutSeconds)*time.Second)
+	if requestLogTemplate != "" {
+		h = pushRequestLogHandler(h)
+	}
+	h = tracing.NewHandler("queue-proxy", h)
+	server = h2c.NewServer(fmt.Sprintf(":%d", queue.RequestQueuePort), h)
 
 	go server.ListenAndServe()
 	go setupAdminHandlers(adminServer)
 
+	// Probe the user container much more often than the kubelet would,
+	// so that the Pod becomes ready as soon as it can serve.
+	probeStopCh := make(chan struct{})
+	go func() {
+		start := time.Now()
+		if readinessProber.Run(queue.ReadinessProbeInterval, probeStopCh) {
+			logger.Infof("User container became ready after %v", time.Since(start))
+		}
+	}()
+
 	// Shutdown logic and signal handling
 	sigTermChan := make(chan os.Signal)
 	signal.Notify(sigTermChan, syscall.SIGTERM)
 	// Blocks until we actually receive a TERM signal.
 	<-sigTermChan
+	close(probeStopCh)
 	// Calling server.Shutdown() allows pending requests to
 	// complete, while no new work is accepted.
 	logger.Debug("Received TERM signal, attempting to gracefully shutdown servers.")
@@ -340,4 +521,5 @@ func main() {
 			logger.Error("Failed to shutdown websocket connection", zap.Error(err))
 		}
 	}
+	tracer.Shutdown()
 }
@@ -52,7 +52,8 @@ ${GOPATH}/bin/deepcopy-gen \
   -i github.com/knative/serving/pkg/reconciler/v1alpha1/route/config \
   -i github.com/knative/serving/pkg/autoscaler \
   -i github.com/knative/serving/pkg/gc \
-  -i github.com/knative/serving/pkg/logging
+  -i github.com/knative/serving/pkg/logging \
+  -i github.com/knative/serving/pkg/tracing
 
 # Make sure our dependencies are up-to-date
 ${REPO_ROOT_DIR}/hack/update-deps.sh
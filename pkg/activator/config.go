@@ -0,0 +1,243 @@
+/*
+Copyright 2018 The Knative Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package activator
+
+import (
+	"fmt"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/knative/serving/pkg/apis/serving"
+	"github.com/knative/serving/pkg/apis/serving/v1alpha1"
+	servinglisters "github.com/knative/serving/pkg/client/listers/serving/v1alpha1"
+	"go.uber.org/zap"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/util/wait"
+)
+
+const (
+	// ConfigName is the name of the config map of the activator.
+	ConfigName = "config-activator"
+
+	defaultMaxUploadBytes         = 32e6 // 32MB - same as app engine
+	defaultMaxRetries             = 18   // the sum of all retries would add up to 1 minute
+	defaultMinRetryInterval       = 100 * time.Millisecond
+	defaultExponentialBackoffBase = 1.3
+	defaultActivationTimeout      = 60 * time.Second
+)
+
+// Config defines the tunable activator parameters.
+type Config struct {
+	// MaxUploadBytes is the largest request body the activator accepts.
+	MaxUploadBytes int64
+
+	// StreamingThresholdBytes is the largest request body the activator
+	// buffers in memory to retry the request. Larger bodies are streamed to
+	// a ready pod and sent only once. Zero disables streaming, every body
+	// is buffered.
+	StreamingThresholdBytes int64
+
+	// Retries of the requests that the revision answers with a 503.
+	MaxRetries             int
+	MinRetryInterval       time.Duration
+	ExponentialBackoffBase float64
+
+	// ActivationTimeout is for how long the activator waits for a revision
+	// to become ready.
+	ActivationTimeout time.Duration
+}
+
+// Backoff returns the backoff of the retries of a request.
+func (c *Config) Backoff() wait.Backoff {
+	return wait.Backoff{
+		Duration: c.MinRetryInterval,
+		Factor:   c.ExponentialBackoffBase,
+		Steps:    c.MaxRetries,
+	}
+}
+
+// ActivationTimeoutFor returns the activation timeout of rev, which its
+// annotations may override.
+func (c *Config) ActivationTimeoutFor(rev *v1alpha1.Revision) time.Duration {
+	if v, ok := rev.Annotations[serving.ActivationTimeoutAnnotationKey]; ok {
+		if d, err := time.ParseDuration(v); err == nil && d > 0 {
+			return d
+		}
+	}
+	return c.ActivationTimeout
+}
+
+// MaxUploadBytesFor returns the largest request body accepted for rev,
+// which its annotations may override.
+func (c *Config) MaxUploadBytesFor(rev *v1alpha1.Revision) int64 {
+	if v, ok := rev.Annotations[serving.MaxUploadBytesAnnotationKey]; ok {
+		if i, err := strconv.ParseInt(v, 10, 64); err == nil && i > 0 {
+			return i
+		}
+	}
+	return c.MaxUploadBytes
+}
+
+// NewConfigFromMap creates a Config from the supplied map. Missing keys
+// are set to their defaults.
+func NewConfigFromMap(data map[string]string) (*Config, error) {
+	c := &Config{
+		MaxUploadBytes:         defaultMaxUploadBytes,
+		MaxRetries:             defaultMaxRetries,
+		MinRetryInterval:       defaultMinRetryInterval,
+		ExponentialBackoffBase: defaultExponentialBackoffBase,
+		ActivationTimeout:      defaultActivationTimeout,
+	}
+
+	// Process Int64 fields
+	for _, i64 := range []struct {
+		key   string
+		field *int64
+	}{{
+		key:   "max-upload-bytes",
+		field: &c.MaxUploadBytes,
+	}, {
+		key:   "streaming-threshold-bytes",
+		field: &c.StreamingThresholdBytes,
+	}} {
+		if raw, ok := data[i64.key]; ok {
+			val, err := strconv.ParseInt(raw, 10, 64)
+			if err != nil {
+				return nil, err
+			}
+			*i64.field = val
+		}
+	}
+
+	if raw, ok := data["max-retries"]; ok {
+		val, err := strconv.Atoi(raw)
+		if err != nil {
+			return nil, err
+		}
+		c.MaxRetries = val
+	}
+
+	if raw, ok := data["exponential-backoff-base"]; ok {
+		val, err := strconv.ParseFloat(raw, 64)
+		if err != nil {
+			return nil, err
+		}
+		c.ExponentialBackoffBase = val
+	}
+
+	// Process Duration fields
+	for _, dur := range []struct {
+		key   string
+		field *time.Duration
+	}{{
+		key:   "min-retry-interval",
+		field: &c.MinRetryInterval,
+	}, {
+		key:   "activation-timeout",
+		field: &c.ActivationTimeout,
+	}} {
+		if raw, ok := data[dur.key]; ok {
+			val, err := time.ParseDuration(raw)
+			if err != nil {
+				return nil, err
+			}
+			*dur.field = val
+		}
+	}
+
+	if c.MaxUploadBytes <= 0 {
+		return nil, fmt.Errorf("max-upload-bytes must be greater than 0, got %d", c.MaxUploadBytes)
+	}
+	if c.StreamingThresholdBytes < 0 {
+		return nil, fmt.Errorf("streaming-threshold-bytes must be at least 0, got %d", c.StreamingThresholdBytes)
+	}
+	if c.MaxRetries < 1 {
+		return nil, fmt.Errorf("max-retries must be at least 1, got %d", c.MaxRetries)
+	}
+	if c.MinRetryInterval <= 0 {
+		return nil, fmt.Errorf("min-retry-interval must be greater than 0s, got %v", c.MinRetryInterval)
+	}
+	if c.ExponentialBackoffBase < 1 {
+		return nil, fmt.Errorf("exponential-backoff-base must be at least 1, got %v", c.ExponentialBackoffBase)
+	}
+	if c.ActivationTimeout <= 0 {
+		return nil, fmt.Errorf("activation-timeout must be greater than 0s, got %v", c.ActivationTimeout)
+	}
+
+	return c, nil
+}
+
+// NewConfigFromConfigMap creates a Config from the supplied ConfigMap
+func NewConfigFromConfigMap(configMap *corev1.ConfigMap) (*Config, error) {
+	return NewConfigFromMap(configMap.Data)
+}
+
+// DynamicConfig holds the activator Config, which is replaced every time
+// the config map changes.
+type DynamicConfig struct {
+	mutex  sync.RWMutex
+	config *Config
+
+	logger *zap.SugaredLogger
+}
+
+// NewDynamicConfig creates a DynamicConfig starting with config.
+func NewDynamicConfig(config *Config, logger *zap.SugaredLogger) *DynamicConfig {
+	c := *config
+	return &DynamicConfig{
+		config: &c,
+		logger: logger,
+	}
+}
+
+// Current returns a copy of the current Config.
+func (dc *DynamicConfig) Current() *Config {
+	dc.mutex.RLock()
+	defer dc.mutex.RUnlock()
+	c := *dc.config
+	return &c
+}
+
+// Update replaces the Config with the one of configMap. Invalid configs
+// are logged and ignored.
+func (dc *DynamicConfig) Update(configMap *corev1.ConfigMap) {
+	config, err := NewConfigFromConfigMap(configMap)
+	if err != nil {
+		dc.logger.Errorf("Error updating activator config: %v", err)
+		return
+	}
+	dc.mutex.Lock()
+	defer dc.mutex.Unlock()
+	dc.config = config
+	dc.logger.Infof("Activator configuration updated: %v", configMap)
+}
+
+// MaxUploadBytesGetter returns a func that looks up the largest request
+// body accepted for a revision, with the current config and the revision
+// in lister. Revisions that aren't known get the max-upload-bytes of the
+// config.
+func MaxUploadBytesGetter(config *DynamicConfig, lister servinglisters.RevisionLister) func(namespace, name string) int64 {
+	return func(namespace, name string) int64 {
+		c := config.Current()
+		rev, err := lister.Revisions(namespace).Get(name)
+		if err != nil {
+			return c.MaxUploadBytes
+		}
+		return c.MaxUploadBytesFor(rev)
+	}
+}
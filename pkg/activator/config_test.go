@@ -0,0 +1,205 @@
+/*
+Copyright 2018 The Knative Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package activator
+
+import (
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+	. "github.com/knative/pkg/logging/testing"
+	"github.com/knative/serving/pkg/apis/serving"
+	"github.com/knative/serving/pkg/apis/serving/v1alpha1"
+	servinglisters "github.com/knative/serving/pkg/client/listers/serving/v1alpha1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/tools/cache"
+)
+
+var defaultConfig = Config{
+	MaxUploadBytes:         32e6,
+	MaxRetries:             18,
+	MinRetryInterval:       100 * time.Millisecond,
+	ExponentialBackoffBase: 1.3,
+	ActivationTimeout:      60 * time.Second,
+}
+
+func TestNewConfig(t *testing.T) {
+	tests := []struct {
+		name    string
+		input   map[string]string
+		want    Config
+		wantErr bool
+	}{{
+		name:  "defaults",
+		input: map[string]string{},
+		want:  defaultConfig,
+	}, {
+		name: "all keys",
+		input: map[string]string{
+			"max-upload-bytes":          "1000",
+			"streaming-threshold-bytes": "100",
+			"max-retries":               "5",
+			"min-retry-interval":        "1s",
+			"exponential-backoff-base":  "2",
+			"activation-timeout":        "2m",
+		},
+		want: Config{
+			MaxUploadBytes:          1000,
+			StreamingThresholdBytes: 100,
+			MaxRetries:              5,
+			MinRetryInterval:        time.Second,
+			ExponentialBackoffBase:  2,
+			ActivationTimeout:       2 * time.Minute,
+		},
+	}, {
+		name:    "malformed max upload",
+		input:   map[string]string{"max-upload-bytes": "32MB"},
+		wantErr: true,
+	}, {
+		name:    "zero max upload",
+		input:   map[string]string{"max-upload-bytes": "0"},
+		wantErr: true,
+	}, {
+		name:    "malformed streaming threshold",
+		input:   map[string]string{"streaming-threshold-bytes": "1MB"},
+		wantErr: true,
+	}, {
+		name:    "negative streaming threshold",
+		input:   map[string]string{"streaming-threshold-bytes": "-1"},
+		wantErr: true,
+	}, {
+		name:    "malformed max retries",
+		input:   map[string]string{"max-retries": "many"},
+		wantErr: true,
+	}, {
+		name:    "no retries",
+		input:   map[string]string{"max-retries": "0"},
+		wantErr: true,
+	}, {
+		name:    "malformed retry interval",
+		input:   map[string]string{"min-retry-interval": "100"},
+		wantErr: true,
+	}, {
+		name:    "backoff base below 1",
+		input:   map[string]string{"exponential-backoff-base": "0.5"},
+		wantErr: true,
+	}, {
+		name:    "zero activation timeout",
+		input:   map[string]string{"activation-timeout": "0s"},
+		wantErr: true,
+	}}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			got, err := NewConfigFromConfigMap(&corev1.ConfigMap{Data: test.input})
+			if (err != nil) != test.wantErr {
+				t.Fatalf("NewConfigFromConfigMap() = %v, want error %v", err, test.wantErr)
+			}
+			if err == nil {
+				if diff := cmp.Diff(test.want, *got); diff != "" {
+					t.Errorf("NewConfigFromConfigMap() (-want, +got) = %v", diff)
+				}
+			}
+		})
+	}
+}
+
+func TestConfigRevisionOverrides(t *testing.T) {
+	tests := []struct {
+		name           string
+		annotations    map[string]string
+		wantTimeout    time.Duration
+		wantMaxUploads int64
+	}{{
+		name:           "no annotations",
+		wantTimeout:    60 * time.Second,
+		wantMaxUploads: 32e6,
+	}, {
+		name: "overridden",
+		annotations: map[string]string{
+			serving.ActivationTimeoutAnnotationKey: "2m",
+			serving.MaxUploadBytesAnnotationKey:    "1000",
+		},
+		wantTimeout:    2 * time.Minute,
+		wantMaxUploads: 1000,
+	}, {
+		name: "invalid annotations",
+		annotations: map[string]string{
+			serving.ActivationTimeoutAnnotationKey: "2",
+			serving.MaxUploadBytesAnnotationKey:    "-1",
+		},
+		wantTimeout:    60 * time.Second,
+		wantMaxUploads: 32e6,
+	}}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			rev := &v1alpha1.Revision{
+				ObjectMeta: metav1.ObjectMeta{Annotations: test.annotations},
+			}
+			c := defaultConfig
+			if got := c.ActivationTimeoutFor(rev); got != test.wantTimeout {
+				t.Errorf("ActivationTimeoutFor() = %v, want %v", got, test.wantTimeout)
+			}
+			if got := c.MaxUploadBytesFor(rev); got != test.wantMaxUploads {
+				t.Errorf("MaxUploadBytesFor() = %d, want %d", got, test.wantMaxUploads)
+			}
+		})
+	}
+}
+
+func TestUpdateDynamicConfig(t *testing.T) {
+	dc := NewDynamicConfig(&defaultConfig, TestLogger(t))
+
+	dc.Update(&corev1.ConfigMap{Data: map[string]string{"activation-timeout": "2m"}})
+	if got := dc.Current().ActivationTimeout; got != 2*time.Minute {
+		t.Errorf("ActivationTimeout = %v, want 2m", got)
+	}
+
+	// Invalid configs keep the previous one.
+	dc.Update(&corev1.ConfigMap{Data: map[string]string{"activation-timeout": "2"}})
+	if got := dc.Current().ActivationTimeout; got != 2*time.Minute {
+		t.Errorf("ActivationTimeout = %v, want 2m", got)
+	}
+}
+
+func TestMaxUploadBytesGetter(t *testing.T) {
+	indexer := cache.NewIndexer(cache.MetaNamespaceKeyFunc, cache.Indexers{})
+	dc := NewDynamicConfig(&defaultConfig, TestLogger(t))
+	getMaxUploadBytes := MaxUploadBytesGetter(dc, servinglisters.NewRevisionLister(indexer))
+
+	if got, want := getMaxUploadBytes(testNamespace, testRevision), int64(32e6); got != want {
+		t.Errorf("Unknown revision = %d, want %d", got, want)
+	}
+
+	indexer.Add(&v1alpha1.Revision{
+		ObjectMeta: metav1.ObjectMeta{
+			Namespace:   testNamespace,
+			Name:        testRevision,
+			Annotations: map[string]string{serving.MaxUploadBytesAnnotationKey: "1000"},
+		},
+	})
+	if got, want := getMaxUploadBytes(testNamespace, testRevision), int64(1000); got != want {
+		t.Errorf("Annotated revision = %d, want %d", got, want)
+	}
+
+	dc.Update(&corev1.ConfigMap{Data: map[string]string{"max-upload-bytes": "5000"}})
+	if got, want := getMaxUploadBytes(testNamespace, "other-rev"), int64(5000); got != want {
+		t.Errorf("Unknown revision after update = %d, want %d", got, want)
+	}
+}
@@ -15,16 +15,22 @@ package handler
 
 import (
 	"net/http"
+
+	"github.com/knative/serving/pkg/activator"
+	pkghttp "github.com/knative/serving/pkg/http"
 )
 
-// EnforceMaxContentLengthHandler prevents uploads larger than `MaxContentLengthBytes`
+// EnforceMaxContentLengthHandler prevents uploads larger than what
+// `GetMaxContentLengthBytes` returns for the revision of the request
 type EnforceMaxContentLengthHandler struct {
-	NextHandler           http.Handler
-	MaxContentLengthBytes int64
+	NextHandler              http.Handler
+	GetMaxContentLengthBytes func(namespace, name string) int64
 }
 
 func (h *EnforceMaxContentLengthHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
-	if r.ContentLength > h.MaxContentLengthBytes {
+	namespace := pkghttp.LastHeaderValue(r.Header, activator.RevisionHeaderNamespace)
+	name := pkghttp.LastHeaderValue(r.Header, activator.RevisionHeaderName)
+	if r.ContentLength > h.GetMaxContentLengthBytes(namespace, name) {
 		w.WriteHeader(http.StatusRequestEntityTooLarge)
 		return
 	}
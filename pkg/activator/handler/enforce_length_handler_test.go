@@ -17,6 +17,8 @@ import (
 	"net/http"
 	"net/http/httptest"
 	"testing"
+
+	"github.com/knative/serving/pkg/activator"
 )
 
 func TestEnforceMaxContentLengthHandler(t *testing.T) {
@@ -49,10 +51,20 @@ func TestEnforceMaxContentLengthHandler(t *testing.T) {
 			baseHandler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 				w.WriteHeader(http.StatusOK)
 			})
-			handler := EnforceMaxContentLengthHandler{NextHandler: baseHandler, MaxContentLengthBytes: int64(e.maxUpload)}
+			handler := EnforceMaxContentLengthHandler{
+				NextHandler: baseHandler,
+				GetMaxContentLengthBytes: func(namespace, name string) int64 {
+					if namespace != "real-namespace" || name != "real-name" {
+						t.Errorf("GetMaxContentLengthBytes(%q, %q), want the revision of the request", namespace, name)
+					}
+					return int64(e.maxUpload)
+				},
+			}
 
 			resp := httptest.NewRecorder()
 			req := httptest.NewRequest("POST", "http://example.com", bytes.NewBufferString(payload))
+			req.Header.Set(activator.RevisionHeaderNamespace, "real-namespace")
+			req.Header.Set(activator.RevisionHeaderName, "real-name")
 
 			handler.ServeHTTP(resp, req)
 
@@ -14,6 +14,7 @@ limitations under the License.
 package handler
 
 import (
+	"context"
 	"fmt"
 	"net/http"
 	"net/http/httputil"
@@ -23,19 +24,41 @@ import (
 
 	"github.com/knative/serving/pkg/activator"
 	"github.com/knative/serving/pkg/activator/util"
+	"github.com/knative/serving/pkg/apis/serving"
 	pkghttp "github.com/knative/serving/pkg/http"
+	"go.opencensus.io/trace"
 	"go.uber.org/zap"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/meta"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/tools/cache"
 )
 
 // ActivationHandler will wait for an active endpoint for a revision
-// to be available before proxing the request
+// to be available before proxing the request, and for the revision
+// to have capacity for it. Requests go to the least loaded pod of the
+// revision, or the pod of their session affinity key, or through its
+// service if no pod is known.
 type ActivationHandler struct {
 	Activator activator.Activator
 	Logger    *zap.SugaredLogger
 	Transport http.RoundTripper
 	Reporter  activator.StatsReporter
+	Throttler *activator.Throttler
+	// Config sets the largest request body that is buffered to be retried.
+	// Without it, every body is buffered.
+	Config *activator.DynamicConfig
+
+	proxies proxyCache
 }
 
+// attemptsKey is the key of the request context value that holds the
+// number of attempts the activator made to proxy a request.
+type attemptsKey struct{}
+
+// buffers is shared by the proxies of all the ActivationHandlers.
+var buffers = newBufferPool()
+
 func (a *ActivationHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 	namespace := pkghttp.LastHeaderValue(r.Header, activator.RevisionHeaderNamespace)
 	name := pkghttp.LastHeaderValue(r.Header, activator.RevisionHeaderName)
@@ -46,7 +69,12 @@ func (a *ActivationHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 		statusCode:     http.StatusOK,
 	}
 
+	_, activateSpan := trace.StartSpan(r.Context(), "activator_activate")
+	activateSpan.AddAttributes(
+		trace.StringAttribute("namespace", namespace),
+		trace.StringAttribute("revision", name))
 	ar := a.Activator.ActiveEndpoint(namespace, name)
+	activateSpan.End()
 	if ar.Error != nil {
 		msg := fmt.Sprintf("Error getting active endpoint: %v", ar.Error)
 		a.Logger.Errorf(msg)
@@ -54,40 +82,194 @@ func (a *ActivationHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	target := &url.URL{
-		Scheme: "http",
-		Host:   fmt.Sprintf("%s:%d", ar.Endpoint.FQDN, ar.Endpoint.Port),
+	streamed, err := a.streamBody(r)
+	if err != nil {
+		msg := fmt.Sprintf("Error reading the request body: %v", err)
+		a.Logger.Errorf(msg)
+		http.Error(w, msg, http.StatusBadRequest)
+		return
 	}
-	proxy := httputil.NewSingleHostReverseProxy(target)
-	proxy.Transport = a.Transport
 
+	rev := types.NamespacedName{Namespace: namespace, Name: name}
 	attempts := int(1) // one attempt is always needed
-	proxy.ModifyResponse = func(r *http.Response) error {
-		if numTries := r.Header.Get(activator.RequestCountHTTPHeader); numTries != "" {
-			if count, err := strconv.Atoi(numTries); err == nil {
-				a.Logger.Infof("got %d attempts", count)
-				attempts = count
-			} else {
-				a.Logger.Errorf("Value in %v header is not a valid integer. Error: %v", activator.RequestCountHTTPHeader, err)
+	ctx := context.WithValue(r.Context(), attemptsKey{}, &attempts)
+	rtStats := &util.RoundTripStats{}
+	ctx = util.WithRoundTripStats(ctx, rtStats)
+
+	_, queueSpan := trace.StartSpan(r.Context(), "activator_queue")
+	var httpStatus int
+	proxied := false
+	// A streamed body can only be sent once, so it must go to a pod that
+	// was probed ready. The service may pick any of its endpoints, so the
+	// request waits for a pod with spare capacity rather than go through it.
+	try := a.Throttler.Try
+	if streamed {
+		try = a.Throttler.TryPod
+	}
+	err = try(namespace, name, r, func(dest string) {
+		queueSpan.End()
+		if streamed && !a.probe(r.Context(), dest) {
+			httpStatus = http.StatusServiceUnavailable
+			http.Error(capture, "no pod ready to stream the request to", httpStatus)
+			return
+		}
+		// Send the request straight to a pod with spare capacity if there
+		// is one, rather than through the service of the revision.
+		host := dest
+		if host == "" {
+			host = fmt.Sprintf("%s:%d", ar.Endpoint.FQDN, ar.Endpoint.Port)
+		}
+		proxy := a.proxies.get(rev, dest, func() *httputil.ReverseProxy { return a.newProxy(host) })
+		proxyCtx, proxySpan := trace.StartSpan(ctx, "activator_proxy")
+		if streamed {
+			proxyCtx = util.WithNoRetries(proxyCtx)
+		}
+		proxy.ServeHTTP(capture, r.WithContext(proxyCtx))
+		proxied = true
+		httpStatus = capture.statusCode
+		proxySpan.AddAttributes(trace.Int64Attribute("http.status_code", int64(httpStatus)))
+		if pkghttp.IsGRPCRequest(r) {
+			// gRPC calls are answered with 200 even when they fail, their
+			// result is in the grpc-status trailer.
+			if code, ok := pkghttp.GRPCStatus(capture.Header()); ok {
+				proxySpan.AddAttributes(trace.Int64Attribute("grpc.status_code", int64(code)))
+				httpStatus = pkghttp.HTTPStatusFromGRPCCode(code)
 			}
 		}
-
-		// We don't return this header to the user. It's only used to transport
-		// state in the activator.
-		r.Header.Del(activator.RequestCountHTTPHeader)
-
-		return nil
+		proxySpan.End()
+	})
+	if err != nil {
+		queueSpan.SetStatus(trace.Status{Code: trace.StatusCodeUnavailable, Message: err.Error()})
+		queueSpan.End()
+		// Requests that were never proxied are reported too, so that
+		// throttling shows up in the request metrics.
+		switch err {
+		case activator.ErrActivatorOverload:
+			a.Logger.Errorf("Too many requests queued for revision %s/%s", namespace, name)
+			httpStatus = http.StatusServiceUnavailable
+			http.Error(w, activator.ErrActivatorOverload.Error(), httpStatus)
+		case activator.ErrActivationTimeout:
+			a.Logger.Errorf("Timed out waiting for the capacity of revision %s/%s", namespace, name)
+			httpStatus = http.StatusGatewayTimeout
+			http.Error(w, activator.ErrActivationTimeout.Error(), httpStatus)
+		case context.Canceled:
+			// The client went away while the request was queued.
+			httpStatus = http.StatusServiceUnavailable
+			http.Error(w, err.Error(), httpStatus)
+		default:
+			msg := fmt.Sprintf("Error getting the capacity of the revision: %v", err)
+			a.Logger.Errorf(msg)
+			httpStatus = http.StatusInternalServerError
+			http.Error(w, msg, httpStatus)
+		}
 	}
-	util.SetupHeaderPruning(proxy)
-
-	proxy.ServeHTTP(capture, r)
 
 	// Report the metrics
-	httpStatus := capture.statusCode
 	duration := time.Now().Sub(start)
 
 	a.Reporter.ReportRequestCount(namespace, ar.ServiceName, ar.ConfigurationName, name, httpStatus, attempts, 1.0)
 	a.Reporter.ReportResponseTime(namespace, ar.ServiceName, ar.ConfigurationName, name, httpStatus, duration)
+	if proxied {
+		a.Reporter.ReportRetries(namespace, ar.ServiceName, ar.ConfigurationName, name, rtStats.Retries, rtStats.Backoff)
+		a.Reporter.ReportTimeToFirstByte(namespace, ar.ServiceName, ar.ConfigurationName, name, rtStats.TimeToFirstByte)
+	}
+}
+
+// streamBody returns whether the body of r is too large to be buffered,
+// and must be streamed. Bodies of unknown length are buffered up to the
+// streaming threshold to find out.
+func (a *ActivationHandler) streamBody(r *http.Request) (bool, error) {
+	if a.Config == nil || r.Body == nil || r.Body == http.NoBody {
+		return false, nil
+	}
+	threshold := a.Config.Current().StreamingThresholdBytes
+	if threshold == 0 {
+		return false, nil
+	}
+	if r.ContentLength >= 0 {
+		return r.ContentLength > threshold, nil
+	}
+	body, buffered, err := util.BufferBody(r.Body, threshold)
+	if err != nil {
+		return false, err
+	}
+	r.Body = body
+	return !buffered, nil
+}
+
+// probe returns whether the pod at dest is ready, retrying for as long as
+// the transport retries requests.
+func (a *ActivationHandler) probe(ctx context.Context, dest string) bool {
+	ctx, span := trace.StartSpan(ctx, "activator_probe")
+	defer span.End()
+	ready, err := util.ProbeQueueProxy(ctx, a.Transport, dest)
+	if err != nil {
+		a.Logger.Errorf("Error probing pod %s: %v", dest, err)
+		span.SetStatus(trace.Status{Code: trace.StatusCodeUnknown, Message: err.Error()})
+		return false
+	}
+	if !ready {
+		a.Logger.Errorf("Pod %s is not ready", dest)
+		span.SetStatus(trace.Status{Code: trace.StatusCodeUnavailable, Message: "not ready"})
+	}
+	return ready
+}
+
+// RevisionDeleted is an informer handler that drops the proxies of the
+// given revision.
+func (a *ActivationHandler) RevisionDeleted(obj interface{}) {
+	if tombstone, ok := obj.(cache.DeletedFinalStateUnknown); ok {
+		obj = tombstone.Obj
+	}
+	if accessor, err := meta.Accessor(obj); err == nil {
+		a.proxies.evict(types.NamespacedName{Namespace: accessor.GetNamespace(), Name: accessor.GetName()})
+	}
+}
+
+// EndpointsUpdated is an informer handler that drops the proxies to the
+// pods of the revision of the given Endpoints that are no longer ready.
+func (a *ActivationHandler) EndpointsUpdated(obj interface{}) {
+	ep := obj.(*corev1.Endpoints)
+	if name, ok := ep.Labels[serving.RevisionLabelKey]; ok {
+		a.proxies.retain(types.NamespacedName{Namespace: ep.Namespace, Name: name}, activator.ReadyDests(ep))
+	}
+}
+
+// newProxy creates a reverse proxy to host. The state of each request is
+// carried in its context, so that the proxy can be reused.
+func (a *ActivationHandler) newProxy(host string) *httputil.ReverseProxy {
+	proxy := httputil.NewSingleHostReverseProxy(&url.URL{
+		Scheme: "http",
+		Host:   host,
+	})
+	proxy.Transport = a.Transport
+	proxy.BufferPool = buffers
+	proxy.ModifyResponse = a.modifyResponse
+	util.SetupHeaderPruning(proxy)
+	return proxy
+}
+
+// modifyResponse records the number of attempts the transport made to
+// proxy the request.
+func (a *ActivationHandler) modifyResponse(r *http.Response) error {
+	if numTries := r.Header.Get(activator.RequestCountHTTPHeader); numTries != "" {
+		if count, err := strconv.Atoi(numTries); err == nil {
+			a.Logger.Infof("got %d attempts", count)
+			if r.Request != nil {
+				if attempts, ok := r.Request.Context().Value(attemptsKey{}).(*int); ok {
+					*attempts = count
+				}
+			}
+		} else {
+			a.Logger.Errorf("Value in %v header is not a valid integer. Error: %v", activator.RequestCountHTTPHeader, err)
+		}
+	}
+
+	// We don't return this header to the user. It's only used to transport
+	// state in the activator.
+	r.Header.Del(activator.RequestCountHTTPHeader)
+
+	return nil
 }
 
 type statusCapture struct {
@@ -99,3 +281,10 @@ func (s *statusCapture) WriteHeader(statusCode int) {
 	s.statusCode = statusCode
 	s.ResponseWriter.WriteHeader(statusCode)
 }
+
+// Flush lets streamed responses, e.g. of gRPC calls, through as they come.
+func (s *statusCapture) Flush() {
+	if f, ok := s.ResponseWriter.(http.Flusher); ok {
+		f.Flush()
+	}
+}
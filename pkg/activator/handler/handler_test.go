@@ -3,7 +3,9 @@ Copyright 2018 The Knative Authors
 Licensed under the Apache License, Version 2.0 (the "License");
 you may not use this file except in compliance with the License.
 You may obtain a copy of the License at
-    http://www.apache.org/licenses/LICENSE-2.0
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
 Unless required by applicable law or agreed to in writing, software
 distributed under the License is distributed on an "AS IS" BASIS,
 WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
@@ -13,14 +15,19 @@ limitations under the License.
 package handler
 
 import (
+	"context"
 	"errors"
 	"fmt"
 	"io"
 	"io/ioutil"
+	"net"
 	"net/http"
 	"net/http/httptest"
+	"net/http/httputil"
 	"net/url"
 	"strconv"
+	"strings"
+	"sync"
 	"testing"
 	"time"
 
@@ -29,6 +36,19 @@ import (
 	. "github.com/knative/pkg/logging/testing"
 	"github.com/knative/serving/pkg/activator"
 	"github.com/knative/serving/pkg/activator/util"
+	"github.com/knative/serving/pkg/apis/serving"
+	"github.com/knative/serving/pkg/apis/serving/v1alpha1"
+	"github.com/knative/serving/pkg/http/grpctest"
+	"github.com/knative/serving/pkg/http/h2c"
+	"go.opencensus.io/trace"
+	"go.uber.org/zap"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/apimachinery/pkg/util/wait"
+	"k8s.io/client-go/tools/cache"
 )
 
 type stubActivator struct {
@@ -67,6 +87,22 @@ func (fa *stubActivator) ActiveEndpoint(namespace, name string) activator.Activa
 func (fa *stubActivator) Shutdown() {
 }
 
+// newTestThrottler returns a Throttler for revisions that don't limit
+// their concurrency and have ready endpoints at dests.
+func newTestThrottler(logger *zap.SugaredLogger, dests ...string) *activator.Throttler {
+	return activator.NewThrottler(activator.ThrottlerParams{
+		QueueDepth:     10,
+		MaxConcurrency: 10,
+		GetRevision: func(namespace, name string) (*v1alpha1.Revision, error) {
+			return &v1alpha1.Revision{}, nil
+		},
+		GetEndpoints: func(*v1alpha1.Revision) ([]string, error) {
+			return dests, nil
+		},
+		Logger: logger,
+	})
+}
+
 func TestActivationHandler(t *testing.T) {
 	errMsg := func(msg string) string {
 		return fmt.Sprintf("Error getting active endpoint: %v\n", msg)
@@ -118,6 +154,20 @@ func TestActivationHandler(t *testing.T) {
 					Config:     "config-real-name",
 					StatusCode: http.StatusOK,
 				},
+				{
+					Op:        "ReportRetries",
+					Namespace: "real-namespace",
+					Revision:  "real-name",
+					Service:   "service-real-name",
+					Config:    "config-real-name",
+				},
+				{
+					Op:        "ReportTimeToFirstByte",
+					Namespace: "real-namespace",
+					Revision:  "real-name",
+					Service:   "service-real-name",
+					Config:    "config-real-name",
+				},
 			},
 		},
 		{
@@ -146,6 +196,20 @@ func TestActivationHandler(t *testing.T) {
 					Config:     "config-real-name",
 					StatusCode: http.StatusOK,
 				},
+				{
+					Op:        "ReportRetries",
+					Namespace: "real-namespace",
+					Revision:  "real-name",
+					Service:   "service-real-name",
+					Config:    "config-real-name",
+				},
+				{
+					Op:        "ReportTimeToFirstByte",
+					Namespace: "real-namespace",
+					Revision:  "real-name",
+					Service:   "service-real-name",
+					Config:    "config-real-name",
+				},
 			},
 		},
 		{
@@ -183,6 +247,20 @@ func TestActivationHandler(t *testing.T) {
 					Config:     "config-real-name",
 					StatusCode: http.StatusBadGateway,
 				},
+				{
+					Op:        "ReportRetries",
+					Namespace: "real-namespace",
+					Revision:  "real-name",
+					Service:   "service-real-name",
+					Config:    "config-real-name",
+				},
+				{
+					Op:        "ReportTimeToFirstByte",
+					Namespace: "real-namespace",
+					Revision:  "real-name",
+					Service:   "service-real-name",
+					Config:    "config-real-name",
+				},
 			},
 		},
 		{
@@ -212,6 +290,20 @@ func TestActivationHandler(t *testing.T) {
 					Config:     "config-real-name",
 					StatusCode: http.StatusOK,
 				},
+				{
+					Op:        "ReportRetries",
+					Namespace: "real-namespace",
+					Revision:  "real-name",
+					Service:   "service-real-name",
+					Config:    "config-real-name",
+				},
+				{
+					Op:        "ReportTimeToFirstByte",
+					Namespace: "real-namespace",
+					Revision:  "real-name",
+					Service:   "service-real-name",
+					Config:    "config-real-name",
+				},
 			},
 		},
 	}
@@ -238,6 +330,7 @@ func TestActivationHandler(t *testing.T) {
 				Transport: rt,
 				Logger:    TestLogger(t),
 				Reporter:  reporter,
+				Throttler: newTestThrottler(TestLogger(t), server.Listener.Addr().String()),
 			}
 
 			resp := httptest.NewRecorder()
@@ -268,6 +361,595 @@ func TestActivationHandler(t *testing.T) {
 
 }
 
+func TestActivationHandlerOverload(t *testing.T) {
+	release := make(chan struct{})
+	server := httptest.NewServer(
+		http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			<-release
+		}),
+	)
+	defer server.Close()
+
+	// A single endpoint serving one request at a time, with room for one
+	// more request in the queue.
+	throttler := activator.NewThrottler(activator.ThrottlerParams{
+		QueueDepth:     1,
+		MaxConcurrency: 1,
+		GetRevision: func(namespace, name string) (*v1alpha1.Revision, error) {
+			return &v1alpha1.Revision{
+				Spec: v1alpha1.RevisionSpec{ContainerConcurrency: 1},
+			}, nil
+		},
+		GetEndpoints: func(*v1alpha1.Revision) ([]string, error) {
+			return []string{server.Listener.Addr().String()}, nil
+		},
+		Logger: TestLogger(t),
+	})
+	handler := ActivationHandler{
+		Activator: newStubActivator("real-namespace", "real-name", server),
+		Transport: http.DefaultTransport,
+		Logger:    TestLogger(t),
+		Reporter:  &fakeReporter{},
+		Throttler: throttler,
+	}
+
+	serve := func() *httptest.ResponseRecorder {
+		resp := httptest.NewRecorder()
+		req := httptest.NewRequest("POST", "http://example.com", nil)
+		req.Header.Set(activator.RevisionHeaderNamespace, "real-namespace")
+		req.Header.Set(activator.RevisionHeaderName, "real-name")
+		handler.ServeHTTP(resp, req)
+		return resp
+	}
+
+	// The first request is proxied, the second one waits behind it.
+	statuses := make(chan int, 2)
+	for i := 0; i < 2; i++ {
+		go func() {
+			statuses <- serve().Code
+		}()
+	}
+	// Let them reach the breaker.
+	time.Sleep(100 * time.Millisecond)
+
+	if got := serve().Code; got != http.StatusServiceUnavailable {
+		t.Errorf("Unexpected response status of an overload. Want %d, got %d", http.StatusServiceUnavailable, got)
+	}
+
+	close(release)
+	for i := 0; i < 2; i++ {
+		if got := <-statuses; got != http.StatusOK {
+			t.Errorf("Unexpected response status of a queued request. Want %d, got %d", http.StatusOK, got)
+		}
+	}
+}
+
+func TestActivationHandlerCapacityTimeout(t *testing.T) {
+	server := httptest.NewServer(
+		http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			t.Error("Unexpected request to the revision")
+		}),
+	)
+	defer server.Close()
+
+	// The revision reports ready, but its endpoints don't.
+	throttler := activator.NewThrottler(activator.ThrottlerParams{
+		QueueDepth:     10,
+		MaxConcurrency: 10,
+		GetRevision: func(namespace, name string) (*v1alpha1.Revision, error) {
+			return &v1alpha1.Revision{}, nil
+		},
+		GetEndpoints: func(*v1alpha1.Revision) ([]string, error) {
+			return nil, nil
+		},
+		ActivationTimeout: func(*v1alpha1.Revision) time.Duration {
+			return 10 * time.Millisecond
+		},
+		Logger: TestLogger(t),
+	})
+	reporter := &fakeReporter{}
+	handler := ActivationHandler{
+		Activator: newStubActivator("real-namespace", "real-name", server),
+		Transport: http.DefaultTransport,
+		Logger:    TestLogger(t),
+		Reporter:  reporter,
+		Throttler: throttler,
+	}
+
+	resp := httptest.NewRecorder()
+	req := httptest.NewRequest("POST", "http://example.com", nil)
+	req.Header.Set(activator.RevisionHeaderNamespace, "real-namespace")
+	req.Header.Set(activator.RevisionHeaderName, "real-name")
+	handler.ServeHTTP(resp, req)
+
+	if resp.Code != http.StatusGatewayTimeout {
+		t.Errorf("Unexpected response status. Want %d, got %d", http.StatusGatewayTimeout, resp.Code)
+	}
+
+	// The timed out request shows up in the request metrics.
+	want := []reporterCall{{
+		Op:         "ReportRequestCount",
+		Namespace:  "real-namespace",
+		Revision:   "real-name",
+		Service:    "service-real-name",
+		Config:     "config-real-name",
+		StatusCode: http.StatusGatewayTimeout,
+		Attempts:   1,
+		Value:      1.0,
+	}, {
+		Op:         "ReportResponseTime",
+		Namespace:  "real-namespace",
+		Revision:   "real-name",
+		Service:    "service-real-name",
+		Config:     "config-real-name",
+		StatusCode: http.StatusGatewayTimeout,
+	}}
+	if diff := cmp.Diff(want, reporter.calls, ignoreDurationOption); diff != "" {
+		t.Errorf("Reporting calls are different (-want, +got) = %v", diff)
+	}
+}
+
+func TestActivationHandlerCapacityError(t *testing.T) {
+	server := httptest.NewServer(
+		http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			t.Error("Unexpected request to the revision")
+		}),
+	)
+	defer server.Close()
+
+	handler := ActivationHandler{
+		Activator: newStubActivator("real-namespace", "real-name", server),
+		Transport: http.DefaultTransport,
+		Logger:    TestLogger(t),
+		Reporter:  &fakeReporter{},
+		Throttler: activator.NewThrottler(activator.ThrottlerParams{
+			QueueDepth:     10,
+			MaxConcurrency: 10,
+			GetRevision: func(namespace, name string) (*v1alpha1.Revision, error) {
+				return nil, errors.New("revision not found")
+			},
+			Logger: TestLogger(t),
+		}),
+	}
+
+	resp := httptest.NewRecorder()
+	req := httptest.NewRequest("POST", "http://example.com", nil)
+	req.Header.Set(activator.RevisionHeaderNamespace, "real-namespace")
+	req.Header.Set(activator.RevisionHeaderName, "real-name")
+	handler.ServeHTTP(resp, req)
+
+	if resp.Code != http.StatusInternalServerError {
+		t.Errorf("Unexpected response status. Want %d, got %d", http.StatusInternalServerError, resp.Code)
+	}
+	want := "Error getting the capacity of the revision: revision not found\n"
+	if got := resp.Body.String(); got != want {
+		t.Errorf("Unexpected response body. Want %q, got %q", want, got)
+	}
+}
+
+func TestActivationHandlerPodRouting(t *testing.T) {
+	service := httptest.NewServer(
+		http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			io.WriteString(w, "service")
+		}),
+	)
+	defer service.Close()
+	pod := httptest.NewServer(
+		http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			io.WriteString(w, "pod")
+		}),
+	)
+	defer pod.Close()
+
+	handler := ActivationHandler{
+		Activator: newStubActivator("real-namespace", "real-name", service),
+		Transport: http.DefaultTransport,
+		Logger:    TestLogger(t),
+		Reporter:  &fakeReporter{},
+		Throttler: newTestThrottler(TestLogger(t), pod.Listener.Addr().String()),
+	}
+
+	resp := httptest.NewRecorder()
+	req := httptest.NewRequest("POST", "http://example.com", nil)
+	req.Header.Set(activator.RevisionHeaderNamespace, "real-namespace")
+	req.Header.Set(activator.RevisionHeaderName, "real-name")
+	handler.ServeHTTP(resp, req)
+
+	if got, want := resp.Body.String(), "pod"; got != want {
+		t.Errorf("Unexpected response body. Want %q, got %q", want, got)
+	}
+}
+
+func TestActivationHandlerStreaming(t *testing.T) {
+	payload := "SAMPLE PAYLOAD"
+	// Requests go to the pod, never through the service.
+	service := httptest.NewServer(nil)
+	defer service.Close()
+
+	examples := []struct {
+		label         string
+		threshold     string
+		contentLength int64
+		podReady      bool
+		wantProbes    int
+		wantAttempts  int
+		wantStatus    int
+	}{{
+		label:         "buffered",
+		threshold:     "100",
+		contentLength: int64(len(payload)),
+		podReady:      true,
+		wantProbes:    0,
+		wantAttempts:  3,
+		wantStatus:    http.StatusServiceUnavailable,
+	}, {
+		label:         "streaming disabled",
+		threshold:     "0",
+		contentLength: int64(len(payload)),
+		podReady:      true,
+		wantProbes:    0,
+		wantAttempts:  3,
+		wantStatus:    http.StatusServiceUnavailable,
+	}, {
+		label:         "streamed",
+		threshold:     "4",
+		contentLength: int64(len(payload)),
+		podReady:      true,
+		wantProbes:    1,
+		wantAttempts:  1,
+		wantStatus:    http.StatusServiceUnavailable,
+	}, {
+		label:         "chunked and buffered",
+		threshold:     "100",
+		contentLength: -1,
+		podReady:      true,
+		wantProbes:    0,
+		wantAttempts:  3,
+		wantStatus:    http.StatusServiceUnavailable,
+	}, {
+		label:         "chunked and streamed",
+		threshold:     "4",
+		contentLength: -1,
+		podReady:      true,
+		wantProbes:    1,
+		wantAttempts:  1,
+		wantStatus:    http.StatusServiceUnavailable,
+	}, {
+		label:         "pod not ready",
+		threshold:     "4",
+		contentLength: int64(len(payload)),
+		podReady:      false,
+		wantProbes:    3,
+		wantAttempts:  0,
+		wantStatus:    http.StatusServiceUnavailable,
+	}}
+
+	for _, e := range examples {
+		t.Run(e.label, func(t *testing.T) {
+			var probes, attempts int
+			var bodies []string
+			// The pod answers 503, which is retried unless the body is
+			// streamed.
+			transport := util.RoundTripperFunc(func(r *http.Request) (*http.Response, error) {
+				if r.URL.Path == "/health" {
+					probes++
+					if r.URL.Host != "10.0.0.1:8022" {
+						t.Errorf("Probed %q, want the admin port of the pod", r.URL.Host)
+					}
+					status := http.StatusServiceUnavailable
+					if e.podReady {
+						status = http.StatusOK
+					}
+					return &http.Response{StatusCode: status, Body: ioutil.NopCloser(strings.NewReader(""))}, nil
+				}
+				attempts++
+				b, _ := ioutil.ReadAll(r.Body)
+				r.Body.Close()
+				bodies = append(bodies, string(b))
+				return &http.Response{StatusCode: http.StatusServiceUnavailable, Body: ioutil.NopCloser(strings.NewReader(""))}, nil
+			})
+			config, err := activator.NewConfigFromMap(map[string]string{"streaming-threshold-bytes": e.threshold})
+			if err != nil {
+				t.Fatalf("NewConfigFromMap() = %v", err)
+			}
+
+			handler := ActivationHandler{
+				Activator: newStubActivator("real-namespace", "real-name", service),
+				Transport: util.NewRetryRoundTripper(transport, TestLogger(t), wait.Backoff{Steps: 3},
+					util.RetryStatus(http.StatusServiceUnavailable)),
+				Logger:    TestLogger(t),
+				Reporter:  &fakeReporter{},
+				Throttler: newTestThrottler(TestLogger(t), "10.0.0.1:8012"),
+				Config:    activator.NewDynamicConfig(config, TestLogger(t)),
+			}
+
+			resp := httptest.NewRecorder()
+			req := httptest.NewRequest("POST", "http://example.com", strings.NewReader(payload))
+			req.ContentLength = e.contentLength
+			req.Header.Set(activator.RevisionHeaderNamespace, "real-namespace")
+			req.Header.Set(activator.RevisionHeaderName, "real-name")
+			handler.ServeHTTP(resp, req)
+
+			if resp.Code != e.wantStatus {
+				t.Errorf("Unexpected response status. Want %d, got %d", e.wantStatus, resp.Code)
+			}
+			if probes != e.wantProbes {
+				t.Errorf("Probes = %d, want %d", probes, e.wantProbes)
+			}
+			if attempts != e.wantAttempts {
+				t.Errorf("Attempts = %d, want %d", attempts, e.wantAttempts)
+			}
+			for _, b := range bodies {
+				if b != payload {
+					t.Errorf("Proxied body = %q, want %q", b, payload)
+				}
+			}
+		})
+	}
+}
+
+func TestActivationHandlerRetryStats(t *testing.T) {
+	service := httptest.NewServer(nil)
+	defer service.Close()
+
+	attempts := 0
+	transport := util.RoundTripperFunc(func(r *http.Request) (*http.Response, error) {
+		attempts++
+		status := http.StatusServiceUnavailable
+		if attempts == 3 {
+			status = http.StatusOK
+		}
+		return &http.Response{StatusCode: status, Body: ioutil.NopCloser(strings.NewReader(""))}, nil
+	})
+	reporter := &fakeReporter{}
+	handler := ActivationHandler{
+		Activator: newStubActivator("real-namespace", "real-name", service),
+		Transport: util.NewRetryRoundTripper(transport, TestLogger(t), wait.Backoff{Steps: 5},
+			util.RetryStatus(http.StatusServiceUnavailable)),
+		Logger:    TestLogger(t),
+		Reporter:  reporter,
+		Throttler: newTestThrottler(TestLogger(t), "10.0.0.1:8012"),
+	}
+
+	req := httptest.NewRequest("POST", "http://example.com", nil)
+	req.Header.Set(activator.RevisionHeaderNamespace, "real-namespace")
+	req.Header.Set(activator.RevisionHeaderName, "real-name")
+	handler.ServeHTTP(httptest.NewRecorder(), req)
+
+	var retries []int
+	for _, call := range reporter.calls {
+		if call.Op == "ReportRetries" {
+			retries = append(retries, call.Attempts)
+		}
+	}
+	if want := []int{2}; !cmp.Equal(retries, want) {
+		t.Errorf("Reported retries = %v, want %v", retries, want)
+	}
+}
+
+func TestActivationHandlerProxyCache(t *testing.T) {
+	server := httptest.NewServer(
+		http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			io.WriteString(w, "everything good!")
+		}),
+	)
+	defer server.Close()
+
+	handler := ActivationHandler{
+		Activator: newStubActivator("real-namespace", "real-name", server),
+		Transport: http.DefaultTransport,
+		Logger:    TestLogger(t),
+		Reporter:  &fakeReporter{},
+		Throttler: newTestThrottler(TestLogger(t), server.Listener.Addr().String()),
+	}
+	rev := types.NamespacedName{Namespace: "real-namespace", Name: "real-name"}
+
+	var proxies []*httputil.ReverseProxy
+	for i := 0; i < 2; i++ {
+		req := httptest.NewRequest("POST", "http://example.com", nil)
+		req.Header.Set(activator.RevisionHeaderNamespace, "real-namespace")
+		req.Header.Set(activator.RevisionHeaderName, "real-name")
+		handler.ServeHTTP(httptest.NewRecorder(), req)
+		proxies = append(proxies, handler.proxies.proxies[rev][server.Listener.Addr().String()])
+	}
+	if proxies[0] == nil || proxies[0] != proxies[1] {
+		t.Errorf("Expected the proxy to be reused, got %v", proxies)
+	}
+
+	// The pod is no longer ready.
+	handler.proxies.get(rev, "", func() *httputil.ReverseProxy { return &httputil.ReverseProxy{} })
+	handler.EndpointsUpdated(&corev1.Endpoints{
+		ObjectMeta: metav1.ObjectMeta{
+			Namespace: "real-namespace",
+			Name:      "real-name-service",
+			Labels:    map[string]string{serving.RevisionLabelKey: "real-name"},
+		},
+	})
+	if _, ok := handler.proxies.proxies[rev][server.Listener.Addr().String()]; ok {
+		t.Error("Expected the proxy of the pod that is no longer ready to be evicted")
+	}
+	if _, ok := handler.proxies.proxies[rev][""]; !ok {
+		t.Error("Expected the proxy of the service to be kept")
+	}
+
+	handler.RevisionDeleted(cache.DeletedFinalStateUnknown{
+		Key: "real-namespace/real-name",
+		Obj: &v1alpha1.Revision{
+			ObjectMeta: metav1.ObjectMeta{Namespace: "real-namespace", Name: "real-name"},
+		},
+	})
+	if _, ok := handler.proxies.proxies[rev]; ok {
+		t.Error("Expected the proxies of the deleted revision to be evicted")
+	}
+}
+
+func TestActivationHandlerTraceSpans(t *testing.T) {
+	server := httptest.NewServer(
+		http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			io.WriteString(w, "everything good!")
+		}),
+	)
+	defer server.Close()
+
+	exporter := &spanRecorder{}
+	trace.RegisterExporter(exporter)
+	defer trace.UnregisterExporter(exporter)
+
+	handler := ActivationHandler{
+		Activator: newStubActivator("real-namespace", "real-name", server),
+		Transport: http.DefaultTransport,
+		Logger:    TestLogger(t),
+		Reporter:  &fakeReporter{},
+		Throttler: newTestThrottler(TestLogger(t), server.Listener.Addr().String()),
+	}
+
+	ctx, span := trace.StartSpan(context.Background(), "parent", trace.WithSampler(trace.AlwaysSample()))
+	req := httptest.NewRequest("POST", "http://example.com", nil).WithContext(ctx)
+	req.Header.Set(activator.RevisionHeaderNamespace, "real-namespace")
+	req.Header.Set(activator.RevisionHeaderName, "real-name")
+	handler.ServeHTTP(httptest.NewRecorder(), req)
+	span.End()
+
+	got := map[string]bool{}
+	for _, sd := range exporter.spans {
+		if sd.ParentSpanID == span.SpanContext().SpanID {
+			got[sd.Name] = true
+		}
+	}
+	for _, name := range []string{"activator_activate", "activator_queue", "activator_proxy"} {
+		if !got[name] {
+			t.Errorf("Expected a %q child span, got %v", name, got)
+		}
+	}
+}
+
+func TestActivationHandlerGRPC(t *testing.T) {
+	backend, err := grpctest.NewServer()
+	if err != nil {
+		t.Fatalf("Failed to start gRPC server: %v", err)
+	}
+	defer backend.Close()
+	host, port, err := net.SplitHostPort(backend.Addr)
+	if err != nil {
+		t.Fatalf("Failed to parse %q: %v", backend.Addr, err)
+	}
+	p, _ := strconv.Atoi(port)
+
+	reporter := &fakeReporter{}
+	served := make(chan struct{}, 1)
+	handler := &ActivationHandler{
+		Activator: &stubActivator{
+			endpoint:  activator.Endpoint{FQDN: host, Port: int32(p)},
+			namespace: "real-namespace",
+			name:      "real-name",
+		},
+		Transport: util.AutoTransport,
+		Logger:    TestLogger(t),
+		Reporter:  reporter,
+		Throttler: newTestThrottler(TestLogger(t), backend.Addr),
+	}
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("Failed to listen: %v", err)
+	}
+	frontend := h2c.NewServer("", http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		r.Header.Set(activator.RevisionHeaderNamespace, "real-namespace")
+		r.Header.Set(activator.RevisionHeaderName, "real-name")
+		handler.ServeHTTP(w, r)
+		served <- struct{}{}
+	}))
+	go frontend.Serve(l)
+	defer frontend.Close()
+
+	conn, err := grpctest.Dial(l.Addr().String())
+	if err != nil {
+		t.Fatalf("Failed to dial the activator: %v", err)
+	}
+	defer conn.Close()
+
+	// reportedStatus waits for the call to be reported, and returns the
+	// status code it was reported with.
+	reportedStatus := func() int {
+		select {
+		case <-served:
+		case <-time.After(5 * time.Second):
+			t.Fatal("Timed out waiting for the call to be reported")
+		}
+		var status int
+		for _, call := range reporter.calls {
+			if call.Op == "ReportResponseTime" {
+				status = call.StatusCode
+			}
+		}
+		reporter.calls = nil
+		return status
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	for _, test := range []struct {
+		code       codes.Code
+		wantStatus int
+	}{{
+		code:       codes.OK,
+		wantStatus: http.StatusOK,
+	}, {
+		code:       codes.NotFound,
+		wantStatus: http.StatusNotFound,
+	}, {
+		code:       codes.Unavailable,
+		wantStatus: http.StatusServiceUnavailable,
+	}, {
+		code:       codes.Internal,
+		wantStatus: http.StatusInternalServerError,
+	}} {
+		t.Run("unary "+test.code.String(), func(t *testing.T) {
+			backend.Code = test.code
+			got, err := grpctest.Unary(ctx, conn, "hello")
+			if gotCode := status.Code(err); gotCode != test.code {
+				t.Errorf("Unary() = %v, want code %v", err, test.code)
+			}
+			if test.code == codes.OK && got != "hello" {
+				t.Errorf("Unary() = %q, want %q", got, "hello")
+			}
+			if got := reportedStatus(); got != test.wantStatus {
+				t.Errorf("Reported status code = %d, want %d", got, test.wantStatus)
+			}
+		})
+	}
+
+	t.Run("server stream", func(t *testing.T) {
+		backend.Code = codes.OK
+		recv, err := grpctest.ServerStream(ctx, conn, "hello")
+		if err != nil {
+			t.Fatalf("ServerStream() = %v", err)
+		}
+		// The first message comes through before the stream ends.
+		if got, err := recv(); err != nil || got != "hello" {
+			t.Fatalf("First message = (%q, %v), want %q", got, err, "hello")
+		}
+		close(backend.Release)
+		if got, err := recv(); err != nil || got != "hello" {
+			t.Fatalf("Second message = (%q, %v), want %q", got, err, "hello")
+		}
+		if _, err := recv(); err != io.EOF {
+			t.Fatalf("End of stream = %v, want %v", err, io.EOF)
+		}
+		if got := reportedStatus(); got != http.StatusOK {
+			t.Errorf("Reported status code = %d, want %d", got, http.StatusOK)
+		}
+	})
+}
+
+type spanRecorder struct {
+	spans []*trace.SpanData
+}
+
+func (r *spanRecorder) ExportSpan(sd *trace.SpanData) {
+	r.spans = append(r.spans, sd)
+}
+
 var ignoreDurationOption = cmpopts.IgnoreFields(reporterCall{}, "Duration")
 
 type reporterCall struct {
@@ -283,10 +965,13 @@ type reporterCall struct {
 }
 
 type fakeReporter struct {
+	mux   sync.Mutex
 	calls []reporterCall
 }
 
 func (f *fakeReporter) ReportRequestCount(ns, service, config, rev string, responseCode, numTries int, v float64) error {
+	f.mux.Lock()
+	defer f.mux.Unlock()
 	f.calls = append(f.calls, reporterCall{
 		Op:         "ReportRequestCount",
 		Namespace:  ns,
@@ -302,6 +987,8 @@ func (f *fakeReporter) ReportRequestCount(ns, service, config, rev string, respo
 }
 
 func (f *fakeReporter) ReportResponseTime(ns, service, config, rev string, responseCode int, d time.Duration) error {
+	f.mux.Lock()
+	defer f.mux.Unlock()
 	f.calls = append(f.calls, reporterCall{
 		Op:         "ReportResponseTime",
 		Namespace:  ns,
@@ -314,3 +1001,106 @@ func (f *fakeReporter) ReportResponseTime(ns, service, config, rev string, respo
 
 	return nil
 }
+
+func (f *fakeReporter) ReportRevisionReadyTime(ns, service, config, rev string, d time.Duration) error {
+	return nil
+}
+
+func (f *fakeReporter) ReportEndpointResolutionTime(ns, service, config, rev string, d time.Duration) error {
+	return nil
+}
+
+func (f *fakeReporter) ReportRetries(ns, service, config, rev string, retries int, backoff time.Duration) error {
+	f.mux.Lock()
+	defer f.mux.Unlock()
+	f.calls = append(f.calls, reporterCall{
+		Op:        "ReportRetries",
+		Namespace: ns,
+		Service:   service,
+		Config:    config,
+		Revision:  rev,
+		Attempts:  retries,
+		Duration:  backoff,
+	})
+
+	return nil
+}
+
+func (f *fakeReporter) ReportTimeToFirstByte(ns, service, config, rev string, d time.Duration) error {
+	f.mux.Lock()
+	defer f.mux.Unlock()
+	f.calls = append(f.calls, reporterCall{
+		Op:        "ReportTimeToFirstByte",
+		Namespace: ns,
+		Service:   service,
+		Config:    config,
+		Revision:  rev,
+		Duration:  d,
+	})
+
+	return nil
+}
+
+type nopReporter struct{}
+
+func (nopReporter) ReportRequestCount(ns, service, config, rev string, responseCode, numTries int, v float64) error {
+	return nil
+}
+
+func (nopReporter) ReportResponseTime(ns, service, config, rev string, responseCode int, d time.Duration) error {
+	return nil
+}
+
+func (nopReporter) ReportRevisionReadyTime(ns, service, config, rev string, d time.Duration) error {
+	return nil
+}
+
+func (nopReporter) ReportEndpointResolutionTime(ns, service, config, rev string, d time.Duration) error {
+	return nil
+}
+
+func (nopReporter) ReportRetries(ns, service, config, rev string, retries int, backoff time.Duration) error {
+	return nil
+}
+
+func (nopReporter) ReportTimeToFirstByte(ns, service, config, rev string, d time.Duration) error {
+	return nil
+}
+
+func BenchmarkActivationHandler(b *testing.B) {
+	// Answer without a round trip, to only measure the handler.
+	rt := util.RoundTripperFunc(func(r *http.Request) (*http.Response, error) {
+		return &http.Response{
+			StatusCode: http.StatusOK,
+			Header:     http.Header{activator.RequestCountHTTPHeader: {"2"}},
+			Body:       ioutil.NopCloser(strings.NewReader("everything good!")),
+			Request:    r,
+		}, nil
+	})
+	logger := zap.NewNop().Sugar()
+	handler := &ActivationHandler{
+		Activator: &stubActivator{
+			endpoint:  activator.Endpoint{FQDN: "real-name-service.real-namespace.svc.cluster.local", Port: 80},
+			namespace: "real-namespace",
+			name:      "real-name",
+		},
+		Transport: rt,
+		Logger:    logger,
+		Reporter:  nopReporter{},
+		Throttler: newTestThrottler(logger, "10.0.0.1:8012", "10.0.0.2:8012"),
+	}
+
+	req := httptest.NewRequest("POST", "http://example.com", nil)
+	req.Header.Set(activator.RevisionHeaderNamespace, "real-namespace")
+	req.Header.Set(activator.RevisionHeaderName, "real-name")
+
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		resp := httptest.NewRecorder()
+		handler.ServeHTTP(resp, req)
+		if resp.Code != http.StatusOK {
+			b.Fatalf("Unexpected response status. Want %d, got %d", http.StatusOK, resp.Code)
+		}
+	}
+}
@@ -0,0 +1,52 @@
+/*
+Copyright 2018 The Knative Authors
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package handler
+
+import (
+	"fmt"
+	"net/http"
+
+	"github.com/knative/serving/pkg/activator"
+	pkghttp "github.com/knative/serving/pkg/http"
+)
+
+// HostRoutingHandler sets the revision headers of requests that don't
+// carry them, e.g. because they didn't come through an ingress that sets
+// them, to the revision `ResolveRevision` finds for their host and path.
+type HostRoutingHandler struct {
+	NextHandler     http.Handler
+	ResolveRevision func(host, path string) (namespace, name string, err error)
+}
+
+func (h *HostRoutingHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if pkghttp.LastHeaderValue(r.Header, activator.RevisionHeaderNamespace) != "" &&
+		pkghttp.LastHeaderValue(r.Header, activator.RevisionHeaderName) != "" {
+		h.NextHandler.ServeHTTP(w, r)
+		return
+	}
+
+	// The Host of HTTP/2 requests is their :authority.
+	namespace, name, err := h.ResolveRevision(r.Host, r.URL.Path)
+	if err == activator.ErrHostNotFound {
+		http.Error(w, fmt.Sprintf("No revision found for host %q", r.Host), http.StatusNotFound)
+		return
+	} else if err != nil {
+		http.Error(w, fmt.Sprintf("Error finding the revision for host %q: %v", r.Host, err), http.StatusInternalServerError)
+		return
+	}
+	r.Header.Set(activator.RevisionHeaderNamespace, namespace)
+	r.Header.Set(activator.RevisionHeaderName, name)
+
+	h.NextHandler.ServeHTTP(w, r)
+}
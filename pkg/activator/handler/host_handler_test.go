@@ -0,0 +1,102 @@
+/*
+Copyright 2018 The Knative Authors
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package handler
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/knative/serving/pkg/activator"
+)
+
+func TestHostRoutingHandler(t *testing.T) {
+	resolve := func(host, path string) (string, string, error) {
+		switch host {
+		case "route.example.com":
+			return "resolved-namespace", "resolved-name", nil
+		case "broken.example.com":
+			return "", "", errors.New("lister error")
+		default:
+			return "", "", activator.ErrHostNotFound
+		}
+	}
+
+	examples := []struct {
+		label         string
+		host          string
+		namespace     string
+		name          string
+		wantStatus    int
+		wantNamespace string
+		wantName      string
+	}{{
+		label:         "revision headers",
+		host:          "route.example.com",
+		namespace:     "real-namespace",
+		name:          "real-name",
+		wantStatus:    http.StatusOK,
+		wantNamespace: "real-namespace",
+		wantName:      "real-name",
+	}, {
+		label:         "resolved from host",
+		host:          "route.example.com",
+		wantStatus:    http.StatusOK,
+		wantNamespace: "resolved-namespace",
+		wantName:      "resolved-name",
+	}, {
+		label:         "partial revision headers",
+		host:          "route.example.com",
+		name:          "real-name",
+		wantStatus:    http.StatusOK,
+		wantNamespace: "resolved-namespace",
+		wantName:      "resolved-name",
+	}, {
+		label:      "unknown host",
+		host:       "unknown.example.com",
+		wantStatus: http.StatusNotFound,
+	}, {
+		label:      "resolution error",
+		host:       "broken.example.com",
+		wantStatus: http.StatusInternalServerError,
+	}}
+
+	for _, e := range examples {
+		t.Run(e.label, func(t *testing.T) {
+			var gotNamespace, gotName string
+			baseHandler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				gotNamespace = r.Header.Get(activator.RevisionHeaderNamespace)
+				gotName = r.Header.Get(activator.RevisionHeaderName)
+			})
+			handler := HostRoutingHandler{NextHandler: baseHandler, ResolveRevision: resolve}
+
+			resp := httptest.NewRecorder()
+			req := httptest.NewRequest("POST", "http://"+e.host, nil)
+			if e.namespace != "" {
+				req.Header.Set(activator.RevisionHeaderNamespace, e.namespace)
+			}
+			if e.name != "" {
+				req.Header.Set(activator.RevisionHeaderName, e.name)
+			}
+			handler.ServeHTTP(resp, req)
+
+			if resp.Code != e.wantStatus {
+				t.Errorf("Unexpected response status. Want %d, got %d", e.wantStatus, resp.Code)
+			}
+			if gotNamespace != e.wantNamespace || gotName != e.wantName {
+				t.Errorf("Unexpected revision. Want %s/%s, got %s/%s", e.wantNamespace, e.wantName, gotNamespace, gotName)
+			}
+		})
+	}
+}
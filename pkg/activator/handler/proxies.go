@@ -0,0 +1,112 @@
+/*
+Copyright 2018 The Knative Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package handler
+
+import (
+	"net/http/httputil"
+	"sync"
+
+	"k8s.io/apimachinery/pkg/types"
+)
+
+// proxyCache keeps a reverse proxy per revision and destination pod, or
+// the service of the revision for the empty destination, so that they
+// aren't set up again for every request. The zero value is ready to use.
+type proxyCache struct {
+	mux     sync.RWMutex
+	proxies map[types.NamespacedName]map[string]*httputil.ReverseProxy
+}
+
+// get returns the proxy to dest for rev, creating it with create if
+// there is none yet.
+func (c *proxyCache) get(rev types.NamespacedName, dest string, create func() *httputil.ReverseProxy) *httputil.ReverseProxy {
+	c.mux.RLock()
+	proxy, ok := c.proxies[rev][dest]
+	c.mux.RUnlock()
+	if ok {
+		return proxy
+	}
+
+	c.mux.Lock()
+	defer c.mux.Unlock()
+	if proxy, ok := c.proxies[rev][dest]; ok {
+		return proxy
+	}
+	if c.proxies == nil {
+		c.proxies = make(map[types.NamespacedName]map[string]*httputil.ReverseProxy)
+	}
+	if c.proxies[rev] == nil {
+		c.proxies[rev] = make(map[string]*httputil.ReverseProxy)
+	}
+	proxy = create()
+	c.proxies[rev][dest] = proxy
+	return proxy
+}
+
+// retain drops the proxies of rev to the pods that aren't in dests, e.g.
+// once they are no longer ready. The proxy to the service is kept.
+func (c *proxyCache) retain(rev types.NamespacedName, dests []string) {
+	keep := make(map[string]struct{}, len(dests))
+	for _, dest := range dests {
+		keep[dest] = struct{}{}
+	}
+	c.mux.Lock()
+	defer c.mux.Unlock()
+	for dest := range c.proxies[rev] {
+		if _, ok := keep[dest]; !ok && dest != "" {
+			delete(c.proxies[rev], dest)
+		}
+	}
+}
+
+// evict drops the proxies of rev.
+func (c *proxyCache) evict(rev types.NamespacedName) {
+	c.mux.Lock()
+	defer c.mux.Unlock()
+	delete(c.proxies, rev)
+}
+
+// bufferPool is an httputil.BufferPool shared by all the proxies, so that
+// they don't each allocate a buffer to copy every response.
+type bufferPool struct {
+	pool sync.Pool
+}
+
+var _ httputil.BufferPool = (*bufferPool)(nil)
+
+func newBufferPool() *bufferPool {
+	return &bufferPool{
+		pool: sync.Pool{
+			New: func() interface{} {
+				// The size of the buffers httputil.ReverseProxy
+				// allocates without a pool.
+				b := make([]byte, 32*1024)
+				return &b
+			},
+		},
+	}
+}
+
+// Get implements httputil.BufferPool.
+func (b *bufferPool) Get() []byte {
+	return *b.pool.Get().(*[]byte)
+}
+
+// Put implements httputil.BufferPool.
+func (b *bufferPool) Put(buf []byte) {
+	b.pool.Put(&buf)
+}
@@ -0,0 +1,180 @@
+/*
+Copyright 2018 The Knative Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package activator
+
+import (
+	"errors"
+	"math/rand"
+	"net"
+	"regexp"
+
+	networkingv1alpha1 "github.com/knative/serving/pkg/apis/networking/v1alpha1"
+	"github.com/knative/serving/pkg/apis/serving"
+	"github.com/knative/serving/pkg/apis/serving/v1alpha1"
+	servinglisters "github.com/knative/serving/pkg/client/listers/serving/v1alpha1"
+	apierrs "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/client-go/tools/cache"
+)
+
+// ErrHostNotFound is returned by HostResolver.Resolve when no route
+// serves the host.
+var ErrHostNotFound = errors.New("no route found for host")
+
+// HostIndex is the name of the ClusterIngress index, by the hosts of their
+// rules, that a HostResolver looks hosts up in. Its index function is
+// IndexIngressHosts.
+const HostIndex = "host"
+
+// IndexIngressHosts is a cache.IndexFunc that indexes the ClusterIngresses
+// of routes by the hosts of their rules.
+func IndexIngressHosts(obj interface{}) ([]string, error) {
+	ci, ok := obj.(*networkingv1alpha1.ClusterIngress)
+	if !ok || ci.Labels[serving.RouteNamespaceLabelKey] == "" || ci.Labels[serving.RouteLabelKey] == "" {
+		return nil, nil
+	}
+	var hosts []string
+	for _, rule := range ci.Spec.Rules {
+		hosts = append(hosts, rule.Hosts...)
+	}
+	return hosts, nil
+}
+
+// HostResolver finds the revision a request should go to from its host,
+// for requests that don't carry the revision headers, e.g. because they
+// didn't come through an ingress that sets them.
+type HostResolver struct {
+	ingressIndexer cache.Indexer
+	routeLister    servinglisters.RouteLister
+	revisionLister servinglisters.RevisionLister
+
+	// intn picks a random number in [0, n), for testing.
+	intn func(n int) int
+}
+
+// NewHostResolver creates a HostResolver that looks up the ClusterIngress
+// of a host in the HostIndex of ingressIndexer, then its Route and the
+// Revisions the Route sends traffic to in the given listers.
+func NewHostResolver(ingressIndexer cache.Indexer, routeLister servinglisters.RouteLister, revisionLister servinglisters.RevisionLister) *HostResolver {
+	return &HostResolver{
+		ingressIndexer: ingressIndexer,
+		routeLister:    routeLister,
+		revisionLister: revisionLister,
+		intn:           rand.Intn,
+	}
+}
+
+// Resolve returns the namespace and name of the revision to send a
+// request for host and path to. Of the traffic targets of the route for
+// host and path, it picks an inactive revision according to the traffic
+// weights, since active revisions are reached without the activator. If
+// all of them are active, it picks among all of them.
+func (h *HostResolver) Resolve(host, path string) (string, string, error) {
+	if hostname, _, err := net.SplitHostPort(host); err == nil {
+		host = hostname
+	}
+	route, err := h.routeOf(host)
+	if err != nil {
+		return "", "", err
+	}
+
+	targets := targetsOf(route, host, path)
+	var inactive []v1alpha1.TrafficTarget
+	for _, t := range targets {
+		rev, err := h.revisionLister.Revisions(route.Namespace).Get(t.RevisionName)
+		if err == nil && rev.Status.IsActivationRequired() {
+			inactive = append(inactive, t)
+		}
+	}
+	if len(inactive) > 0 {
+		targets = inactive
+	}
+	if len(targets) == 0 {
+		return "", "", ErrHostNotFound
+	}
+	return route.Namespace, h.pick(targets).RevisionName, nil
+}
+
+// routeOf returns the route whose ClusterIngress has a rule for host.
+func (h *HostResolver) routeOf(host string) (*v1alpha1.Route, error) {
+	ingresses, err := h.ingressIndexer.ByIndex(HostIndex, host)
+	if err != nil {
+		return nil, err
+	}
+	if len(ingresses) == 0 {
+		return nil, ErrHostNotFound
+	}
+	ci := ingresses[0].(*networkingv1alpha1.ClusterIngress)
+	route, err := h.routeLister.Routes(ci.Labels[serving.RouteNamespaceLabelKey]).Get(ci.Labels[serving.RouteLabelKey])
+	if apierrs.IsNotFound(err) {
+		// The ClusterIngress outlived its route.
+		return nil, ErrHostNotFound
+	}
+	return route, err
+}
+
+// targetsOf returns the traffic targets of route that serve host and path:
+// the targets with the name host starts with, or if host is one of the
+// domains of the whole route, the targets of the first path-scoped traffic
+// block that matches path, or else all of them.
+func targetsOf(route *v1alpha1.Route, host, path string) []v1alpha1.TrafficTarget {
+	var named []v1alpha1.TrafficTarget
+	for _, t := range route.Status.Traffic {
+		if t.Name != "" && host == t.Name+"."+route.Status.Domain {
+			named = append(named, t)
+		}
+	}
+	if len(named) > 0 {
+		return named
+	}
+	for _, pt := range route.Status.Paths {
+		if matchPath(pt.Path, path) {
+			return pt.Traffic
+		}
+	}
+	return route.Status.Traffic
+}
+
+// matchPath returns whether the whole path matches the regexp of a
+// path-scoped traffic block, as the ingress matches it.
+func matchPath(pattern, path string) bool {
+	re, err := regexp.CompilePOSIX("^(" + pattern + ")$")
+	if err != nil {
+		// Invalid paths are rejected by the webhook.
+		return false
+	}
+	return re.MatchString(path)
+}
+
+// pick returns one of targets at random, according to their share of
+// traffic. Targets without traffic are picked evenly if no target has any.
+func (h *HostResolver) pick(targets []v1alpha1.TrafficTarget) v1alpha1.TrafficTarget {
+	total := 0
+	for _, t := range targets {
+		total += t.GetBasisPoints()
+	}
+	if total == 0 {
+		return targets[h.intn(len(targets))]
+	}
+	n := h.intn(total)
+	for _, t := range targets {
+		if n < t.GetBasisPoints() {
+			return t
+		}
+		n -= t.GetBasisPoints()
+	}
+	return targets[len(targets)-1]
+}
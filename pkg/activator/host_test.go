@@ -0,0 +1,213 @@
+/*
+Copyright 2018 The Knative Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package activator
+
+import (
+	"testing"
+
+	networkingv1alpha1 "github.com/knative/serving/pkg/apis/networking/v1alpha1"
+	"github.com/knative/serving/pkg/apis/serving"
+	"github.com/knative/serving/pkg/apis/serving/v1alpha1"
+	servinglisters "github.com/knative/serving/pkg/client/listers/serving/v1alpha1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/tools/cache"
+)
+
+const testDomain = "test-route.test-namespace.example.com"
+
+// newTestHostResolver returns a HostResolver for a route that sends 80%
+// of its traffic to rev-a and 20% to rev-b, also named "candidate", and
+// all of its traffic under /api/ to rev-b. The revisions in inactive
+// require activation.
+func newTestHostResolver(n int, inactive ...string) *HostResolver {
+	ingresses := cache.NewIndexer(cache.MetaNamespaceKeyFunc, cache.Indexers{HostIndex: IndexIngressHosts})
+	routes := cache.NewIndexer(cache.MetaNamespaceKeyFunc, cache.Indexers{})
+	revisions := cache.NewIndexer(cache.MetaNamespaceKeyFunc, cache.Indexers{})
+
+	ingresses.Add(&networkingv1alpha1.ClusterIngress{
+		ObjectMeta: metav1.ObjectMeta{
+			Name: "test-route-abcde",
+			Labels: map[string]string{
+				serving.RouteLabelKey:          "test-route",
+				serving.RouteNamespaceLabelKey: testNamespace,
+			},
+		},
+		Spec: networkingv1alpha1.IngressSpec{
+			Rules: []networkingv1alpha1.ClusterIngressRule{{
+				Hosts: []string{testDomain, "test-route.test-namespace.svc.cluster.local"},
+			}, {
+				Hosts: []string{"candidate." + testDomain},
+			}},
+		},
+	})
+	// The route of this ClusterIngress is gone.
+	ingresses.Add(&networkingv1alpha1.ClusterIngress{
+		ObjectMeta: metav1.ObjectMeta{
+			Name: "gone-route-abcde",
+			Labels: map[string]string{
+				serving.RouteLabelKey:          "gone-route",
+				serving.RouteNamespaceLabelKey: testNamespace,
+			},
+		},
+		Spec: networkingv1alpha1.IngressSpec{
+			Rules: []networkingv1alpha1.ClusterIngressRule{{
+				Hosts: []string{"gone.example.com"},
+			}},
+		},
+	})
+	// ClusterIngresses that aren't for a route are ignored.
+	ingresses.Add(&networkingv1alpha1.ClusterIngress{
+		ObjectMeta: metav1.ObjectMeta{Name: "other"},
+		Spec: networkingv1alpha1.IngressSpec{
+			Rules: []networkingv1alpha1.ClusterIngressRule{{
+				Hosts: []string{"other.example.com"},
+			}},
+		},
+	})
+	routes.Add(&v1alpha1.Route{
+		ObjectMeta: metav1.ObjectMeta{Namespace: testNamespace, Name: "test-route"},
+		Status: v1alpha1.RouteStatus{
+			Domain: testDomain,
+			Traffic: []v1alpha1.TrafficTarget{{
+				RevisionName: "rev-a",
+				Percent:      80,
+			}, {
+				Name:         "candidate",
+				RevisionName: "rev-b",
+				Percent:      20,
+			}},
+			Paths: []v1alpha1.PathTraffic{{
+				Path: "/api/.*",
+				Traffic: []v1alpha1.TrafficTarget{{
+					RevisionName: "rev-b",
+					Percent:      100,
+				}},
+			}},
+		},
+	})
+	for _, name := range []string{"rev-a", "rev-b"} {
+		rev := &v1alpha1.Revision{
+			ObjectMeta: metav1.ObjectMeta{Namespace: testNamespace, Name: name},
+		}
+		rev.Status.MarkActive()
+		for _, i := range inactive {
+			if i == name {
+				rev.Status.MarkInactive("Idle", "")
+			}
+		}
+		revisions.Add(rev)
+	}
+
+	h := NewHostResolver(ingresses, servinglisters.NewRouteLister(routes), servinglisters.NewRevisionLister(revisions))
+	h.intn = func(int) int { return n }
+	return h
+}
+
+func TestHostResolver(t *testing.T) {
+	tests := []struct {
+		name     string
+		host     string
+		path     string
+		n        int
+		inactive []string
+		want     string
+		wantErr  error
+	}{{
+		name:     "only inactive revision",
+		host:     testDomain,
+		n:        0,
+		inactive: []string{"rev-b"},
+		want:     "rev-b",
+	}, {
+		name:     "inactive revisions by weight",
+		host:     testDomain,
+		n:        7999,
+		inactive: []string{"rev-a", "rev-b"},
+		want:     "rev-a",
+	}, {
+		name:     "inactive revisions by weight, other side",
+		host:     testDomain,
+		n:        8000,
+		inactive: []string{"rev-a", "rev-b"},
+		want:     "rev-b",
+	}, {
+		name: "active revisions by weight",
+		host: testDomain,
+		n:    8500,
+		want: "rev-b",
+	}, {
+		name:     "host with port",
+		host:     testDomain + ":80",
+		n:        0,
+		inactive: []string{"rev-b"},
+		want:     "rev-b",
+	}, {
+		name:     "cluster local host",
+		host:     "test-route.test-namespace.svc.cluster.local",
+		n:        0,
+		inactive: []string{"rev-a"},
+		want:     "rev-a",
+	}, {
+		name:     "named target",
+		host:     "candidate." + testDomain,
+		n:        0,
+		inactive: []string{"rev-a"},
+		want:     "rev-b",
+	}, {
+		name:     "path-scoped traffic",
+		host:     testDomain,
+		path:     "/api/v1",
+		n:        0,
+		inactive: []string{"rev-a"},
+		want:     "rev-b",
+	}, {
+		name:     "path outside of the path-scoped traffic",
+		host:     testDomain,
+		path:     "/apiv1",
+		n:        0,
+		inactive: []string{"rev-a"},
+		want:     "rev-a",
+	}, {
+		name:    "unknown host",
+		host:    "unknown.example.com",
+		wantErr: ErrHostNotFound,
+	}, {
+		name:    "host without route",
+		host:    "other.example.com",
+		wantErr: ErrHostNotFound,
+	}, {
+		name:    "host of a deleted route",
+		host:    "gone.example.com",
+		wantErr: ErrHostNotFound,
+	}}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			h := newTestHostResolver(test.n, test.inactive...)
+			namespace, name, err := h.Resolve(test.host, test.path)
+			if err != test.wantErr {
+				t.Fatalf("Resolve() = %v, want %v", err, test.wantErr)
+			}
+			if err != nil {
+				return
+			}
+			if namespace != testNamespace || name != test.want {
+				t.Errorf("Resolve() = %s/%s, want %s/%s", namespace, name, testNamespace, test.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,180 @@
+/*
+Copyright 2018 The Knative Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package activator
+
+import (
+	"context"
+	"hash/fnv"
+	"sync"
+)
+
+// podTracker keeps the ready pods of a revision along with the number of
+// requests the activator is proxying to each of them, to pick the least
+// loaded pod for the next request, or the pod of its session affinity key.
+type podTracker struct {
+	// containerConcurrency is how many requests a pod takes at once, or 0
+	// if there is no limit.
+	containerConcurrency int32
+
+	mux  sync.Mutex
+	pods []*trackedPod
+	// next is where the search for the least loaded pod starts, so that
+	// requests are spread round-robin across pods that are equally loaded.
+	next int
+	// changed is closed, and replaced, whenever a pod may have gained
+	// spare capacity, to wake up the requests waiting for one.
+	changed chan struct{}
+}
+
+type trackedPod struct {
+	dest     string
+	inFlight int32
+}
+
+func newPodTracker(containerConcurrency int32, dests []string) *podTracker {
+	pt := &podTracker{
+		containerConcurrency: containerConcurrency,
+		changed:              make(chan struct{}),
+	}
+	pt.update(dests)
+	return pt
+}
+
+// update replaces the tracked pods with the pods at dests, keeping the
+// requests in flight to the pods that are still there.
+func (pt *podTracker) update(dests []string) {
+	pt.mux.Lock()
+	defer pt.mux.Unlock()
+
+	existing := make(map[string]*trackedPod, len(pt.pods))
+	for _, p := range pt.pods {
+		existing[p.dest] = p
+	}
+	pods := make([]*trackedPod, 0, len(dests))
+	for _, dest := range dests {
+		if p, ok := existing[dest]; ok {
+			pods = append(pods, p)
+		} else {
+			pods = append(pods, &trackedPod{dest: dest})
+		}
+	}
+	pt.pods = pods
+	pt.notify()
+}
+
+// notify wakes up the requests waiting for a pod. pt.mux must be held.
+func (pt *podTracker) notify() {
+	close(pt.changed)
+	pt.changed = make(chan struct{})
+}
+
+// acquire picks the pod of the given session affinity key, or the least
+// loaded pod that has spare capacity if there is no key or the pod of the
+// key is full, and returns its destination along with a func to call once
+// the request is done. If no pod has spare capacity it returns "".
+func (pt *podTracker) acquire(key string) (string, func()) {
+	pt.mux.Lock()
+	defer pt.mux.Unlock()
+	return pt.acquireLocked(key)
+}
+
+// acquireContext is like acquire, but waits for a pod to have spare
+// capacity rather than return "". It returns the error of ctx if it is
+// done first.
+func (pt *podTracker) acquireContext(ctx context.Context, key string) (string, func(), error) {
+	for {
+		pt.mux.Lock()
+		dest, release := pt.acquireLocked(key)
+		changed := pt.changed
+		pt.mux.Unlock()
+		if dest != "" {
+			return dest, release, nil
+		}
+		select {
+		case <-changed:
+		case <-ctx.Done():
+			return "", nil, ctx.Err()
+		}
+	}
+}
+
+// acquireLocked is acquire with pt.mux held.
+func (pt *podTracker) acquireLocked(key string) (string, func()) {
+	picked := pt.hashed(key)
+	if picked == nil {
+		picked = pt.leastLoaded()
+	}
+	if picked == nil {
+		return "", func() {}
+	}
+
+	picked.inFlight++
+	return picked.dest, func() {
+		pt.mux.Lock()
+		defer pt.mux.Unlock()
+		picked.inFlight--
+		pt.notify()
+	}
+}
+
+// hashed returns the pod that key hashes to if it has spare capacity.
+// Keys are spread with rendezvous hashing, so that only the keys of the
+// pods that go away move to other pods as the revision scales.
+func (pt *podTracker) hashed(key string) *trackedPod {
+	if key == "" {
+		return nil
+	}
+	var (
+		picked *trackedPod
+		max    uint64
+	)
+	for _, p := range pt.pods {
+		h := fnv.New64a()
+		h.Write([]byte(key))
+		h.Write([]byte(p.dest))
+		if w := h.Sum64(); picked == nil || w > max {
+			picked, max = p, w
+		}
+	}
+	if picked == nil || pt.full(picked) {
+		return nil
+	}
+	return picked
+}
+
+// leastLoaded returns the least loaded pod that has spare capacity.
+func (pt *podTracker) leastLoaded() *trackedPod {
+	var picked *trackedPod
+	for i := range pt.pods {
+		p := pt.pods[(pt.next+i)%len(pt.pods)]
+		if pt.full(p) {
+			continue
+		}
+		if picked == nil || p.inFlight < picked.inFlight {
+			picked = p
+		}
+	}
+	if picked != nil {
+		pt.next = (pt.next + 1) % len(pt.pods)
+	}
+	return picked
+}
+
+// full returns whether p has no spare capacity.
+func (pt *podTracker) full(p *trackedPod) bool {
+	return pt.containerConcurrency > 0 && p.inFlight >= pt.containerConcurrency
+}
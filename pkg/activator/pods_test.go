@@ -0,0 +1,167 @@
+/*
+Copyright 2018 The Knative Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package activator
+
+import (
+	"context"
+	"reflect"
+	"testing"
+	"time"
+)
+
+// podDests returns the destinations of the pods tracked by pt.
+func podDests(pt *podTracker) []string {
+	pt.mux.Lock()
+	defer pt.mux.Unlock()
+	var dests []string
+	for _, p := range pt.pods {
+		dests = append(dests, p.dest)
+	}
+	return dests
+}
+
+func TestPodTrackerRoundRobin(t *testing.T) {
+	pt := newPodTracker(0, []string{"a", "b", "c"})
+
+	// Without load, requests go round-robin.
+	var got []string
+	for i := 0; i < 6; i++ {
+		dest, release := pt.acquire("")
+		release()
+		got = append(got, dest)
+	}
+	if want := []string{"a", "b", "c", "a", "b", "c"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("Destinations = %v, want %v", got, want)
+	}
+}
+
+func TestPodTrackerLeastLoaded(t *testing.T) {
+	pt := newPodTracker(0, []string{"a", "b"})
+
+	a, releaseA := pt.acquire("")
+	pt.acquire("")
+	pt.acquire("")
+	if a != "a" {
+		t.Fatalf("First destination = %q, want %q", a, "a")
+	}
+	// a has 2 requests in flight and b 1, then b is the least loaded.
+	if dest, _ := pt.acquire(""); dest != "b" {
+		t.Errorf("Destination = %q, want %q", dest, "b")
+	}
+	releaseA()
+	releaseA()
+	if dest, _ := pt.acquire(""); dest != "a" {
+		t.Errorf("Destination = %q, want %q after a was released", dest, "a")
+	}
+}
+
+func TestPodTrackerConcurrencyLimit(t *testing.T) {
+	pt := newPodTracker(1, []string{"a", "b"})
+
+	first, _ := pt.acquire("")
+	second, release := pt.acquire("")
+	if first == second {
+		t.Fatalf("Both requests went to %q", first)
+	}
+	// All pods are at their limit.
+	if dest, _ := pt.acquire(""); dest != "" {
+		t.Errorf("Destination = %q, want none", dest)
+	}
+	release()
+	if dest, _ := pt.acquire(""); dest != second {
+		t.Errorf("Destination = %q, want %q", dest, second)
+	}
+}
+
+func TestPodTrackerUpdate(t *testing.T) {
+	pt := newPodTracker(1, []string{"a", "b"})
+	a, release := pt.acquire("")
+
+	// The requests in flight to a pod are kept across updates.
+	pt.update([]string{"c", a})
+	if got, want := podDests(pt), []string{"c", a}; !reflect.DeepEqual(got, want) {
+		t.Errorf("Pods = %v, want %v", got, want)
+	}
+	if dest, _ := pt.acquire(""); dest != "c" {
+		t.Errorf("Destination = %q, want %q", dest, "c")
+	}
+	if dest, _ := pt.acquire(""); dest != "" {
+		t.Errorf("Destination = %q, want none", dest)
+	}
+
+	// Releasing a request to a removed pod is harmless.
+	pt.update(nil)
+	if dest, _ := pt.acquire(""); dest != "" {
+		t.Errorf("Destination = %q, want none without pods", dest)
+	}
+	release()
+}
+
+func TestPodTrackerSessionAffinity(t *testing.T) {
+	pt := newPodTracker(1, []string{"a", "b", "c"})
+
+	// Requests with the same key go to the same pod, whatever the load of
+	// the other pods.
+	dest, release := pt.acquire("user-1")
+	release()
+	for i := 0; i < 3; i++ {
+		got, release := pt.acquire("user-1")
+		release()
+		if got != dest {
+			t.Fatalf("Destination = %q, want %q", got, dest)
+		}
+	}
+
+	// Once the pod of the key is full, requests go to the least loaded pod.
+	_, releaseFull := pt.acquire("user-1")
+	if got, _ := pt.acquire("user-1"); got == dest || got == "" {
+		t.Errorf("Destination = %q, want another pod than %q", got, dest)
+	}
+	releaseFull()
+
+	// The keys of the remaining pods don't move when a pod goes away.
+	var other string
+	for _, p := range []string{"a", "b", "c"} {
+		if p != dest {
+			other = p
+		}
+	}
+	pt.update([]string{dest, other})
+	if got, release := pt.acquire("user-1"); got != dest {
+		t.Errorf("Destination = %q, want %q after a pod went away", got, dest)
+	} else {
+		release()
+	}
+}
+
+func TestPodTrackerAcquireContext(t *testing.T) {
+	pt := newPodTracker(1, []string{"a"})
+	_, release := pt.acquire("")
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	if _, _, err := pt.acquireContext(ctx, ""); err != context.DeadlineExceeded {
+		t.Errorf("acquireContext() = %v with a full pod, want %v", err, context.DeadlineExceeded)
+	}
+
+	// Releasing the pod wakes up the waiting request.
+	time.AfterFunc(10*time.Millisecond, release)
+	dest, _, err := pt.acquireContext(context.Background(), "")
+	if err != nil || dest != "a" {
+		t.Errorf("acquireContext() = (%q, %v), want (%q, nil)", dest, err, "a")
+	}
+}
@@ -19,45 +19,107 @@ package activator
 import (
 	"fmt"
 	"net/http"
+	"sync"
 	"time"
 
 	"github.com/knative/pkg/logging/logkey"
 	"github.com/knative/serving/pkg/apis/serving"
 	"github.com/knative/serving/pkg/apis/serving/v1alpha1"
-	clientset "github.com/knative/serving/pkg/client/clientset/versioned"
+	servinginformers "github.com/knative/serving/pkg/client/informers/externalversions/serving/v1alpha1"
+	servinglisters "github.com/knative/serving/pkg/client/listers/serving/v1alpha1"
 	revisionresources "github.com/knative/serving/pkg/reconciler/v1alpha1/revision/resources"
 	revisionresourcenames "github.com/knative/serving/pkg/reconciler/v1alpha1/revision/resources/names"
 	"github.com/pkg/errors"
 	"go.uber.org/zap"
-	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
-	"k8s.io/client-go/kubernetes"
+	corev1informers "k8s.io/client-go/informers/core/v1"
+	corev1listers "k8s.io/client-go/listers/core/v1"
+	"k8s.io/client-go/tools/cache"
 )
 
 var _ Activator = (*revisionActivator)(nil)
 
 type revisionActivator struct {
-	readyTimout time.Duration // for testing
-	kubeClient  kubernetes.Interface
-	knaClient   clientset.Interface
-	logger      *zap.SugaredLogger
+	config         *DynamicConfig
+	reporter       StatsReporter
+	revisionLister servinglisters.RevisionLister
+	serviceLister  corev1listers.ServiceLister
+	logger         *zap.SugaredLogger
+
+	// waiters are notified when the revision they wait for is updated.
+	waitersMux sync.Mutex
+	waiters    map[revisionID]map[chan struct{}]struct{}
 }
 
 // NewRevisionActivator creates an Activator that changes revision
 // serving status to active if necessary, then returns the endpoint
-// once the revision is ready to serve traffic.
-func NewRevisionActivator(kubeClient kubernetes.Interface, servingClient clientset.Interface, logger *zap.SugaredLogger) Activator {
-	return &revisionActivator{
-		readyTimout: 60 * time.Second,
-		kubeClient:  kubeClient,
-		knaClient:   servingClient,
-		logger:      logger,
+// once the revision is ready to serve traffic. Revisions and services
+// are read from the caches of the given informers, and the time to wait
+// for a revision to become ready from config. The time spent activating
+// revisions is sent to reporter.
+func NewRevisionActivator(revisionInformer servinginformers.RevisionInformer, serviceInformer corev1informers.ServiceInformer, config *DynamicConfig, reporter StatsReporter, logger *zap.SugaredLogger) Activator {
+	r := &revisionActivator{
+		config:         config,
+		reporter:       reporter,
+		revisionLister: revisionInformer.Lister(),
+		serviceLister:  serviceInformer.Lister(),
+		logger:         logger,
+		waiters:        make(map[revisionID]map[chan struct{}]struct{}),
 	}
+	revisionInformer.Informer().AddEventHandler(cache.ResourceEventHandlerFuncs{
+		AddFunc: r.revisionUpdated,
+		UpdateFunc: func(oldObj, newObj interface{}) {
+			r.revisionUpdated(newObj)
+		},
+	})
+	return r
 }
 
 func (r *revisionActivator) Shutdown() {
 	// nothing to do
 }
 
+// revisionUpdated notifies the waiters of the given revision.
+func (r *revisionActivator) revisionUpdated(obj interface{}) {
+	revision, ok := obj.(*v1alpha1.Revision)
+	if !ok {
+		return
+	}
+	rev := revisionID{
+		namespace: revision.Namespace,
+		name:      revision.Name,
+	}
+
+	r.waitersMux.Lock()
+	defer r.waitersMux.Unlock()
+	for ch := range r.waiters[rev] {
+		select {
+		case ch <- struct{}{}:
+		default:
+			// The waiter hasn't consumed the previous notification yet.
+		}
+	}
+}
+
+// watch registers a waiter for updates of rev, and returns its channel
+// along with a func to unregister it.
+func (r *revisionActivator) watch(rev revisionID) (<-chan struct{}, func()) {
+	ch := make(chan struct{}, 1)
+	r.waitersMux.Lock()
+	defer r.waitersMux.Unlock()
+	if r.waiters[rev] == nil {
+		r.waiters[rev] = make(map[chan struct{}]struct{})
+	}
+	r.waiters[rev][ch] = struct{}{}
+	return ch, func() {
+		r.waitersMux.Lock()
+		defer r.waitersMux.Unlock()
+		delete(r.waiters[rev], ch)
+		if len(r.waiters[rev]) == 0 {
+			delete(r.waiters, rev)
+		}
+	}
+}
+
 func (r *revisionActivator) activateRevision(namespace, name string) (*v1alpha1.Revision, error) {
 	key := fmt.Sprintf("%s/%s", namespace, name)
 	logger := r.logger.With(zap.String(logkey.Key, key))
@@ -66,44 +128,32 @@ func (r *revisionActivator) activateRevision(namespace, name string) (*v1alpha1.
 		name:      name,
 	}
 
+	// Watch before looking the revision up, so that no update is missed
+	// between the two.
+	updated, stop := r.watch(rev)
+	defer stop()
+
 	// Get the current revision serving state
-	revisionClient := r.knaClient.ServingV1alpha1().Revisions(rev.namespace)
-	revision, err := revisionClient.Get(rev.name, metav1.GetOptions{})
+	revision, err := r.revisionLister.Revisions(rev.namespace).Get(rev.name)
 	if err != nil {
 		return nil, errors.Wrap(err, "Unable to get revision")
 	}
 
 	// Wait for the revision to not require activation.
-	if revision.Status.IsActivationRequired() {
-		wi, err := r.knaClient.ServingV1alpha1().Revisions(rev.namespace).Watch(metav1.ListOptions{
-			FieldSelector: fmt.Sprintf("metadata.name=%s", rev.name),
-		})
-		if err != nil {
-			return nil, fmt.Errorf("Failed to watch the revision")
-		}
-		defer wi.Stop()
-		ch := wi.ResultChan()
-	RevisionActive:
-		for {
-			select {
-			case <-time.After(r.readyTimout):
-				// last chance to check
-				if !revision.Status.IsActivationRequired() {
-					break RevisionActive
-				}
-				return nil, errors.New("Timeout waiting for revision to become ready")
-			case event := <-ch:
-				if revision, ok := event.Object.(*v1alpha1.Revision); ok {
-					if revision.Status.IsActivationRequired() {
-						logger.Info("Revision is not yet ready")
-						continue
-					} else {
-						logger.Info("Revision is ready")
-					}
-					break RevisionActive
-				} else {
-					return nil, fmt.Errorf("Unexpected result type for revision: %v", event)
-				}
+	timeout := time.After(r.config.Current().ActivationTimeoutFor(revision))
+	for revision.Status.IsActivationRequired() {
+		select {
+		case <-timeout:
+			return nil, errors.New("Timeout waiting for revision to become ready")
+		case <-updated:
+			revision, err = r.revisionLister.Revisions(rev.namespace).Get(rev.name)
+			if err != nil {
+				return nil, errors.Wrap(err, "Unable to get revision")
+			}
+			if revision.Status.IsActivationRequired() {
+				logger.Info("Revision is not yet ready")
+			} else {
+				logger.Info("Revision is ready")
 			}
 		}
 	}
@@ -112,9 +162,8 @@ func (r *revisionActivator) activateRevision(namespace, name string) (*v1alpha1.
 
 func (r *revisionActivator) getRevisionEndpoint(revision *v1alpha1.Revision) (end Endpoint, err error) {
 	// Get the revision endpoint
-	services := r.kubeClient.CoreV1().Services(revision.GetNamespace())
 	serviceName := revisionresourcenames.K8sService(revision)
-	svc, err := services.Get(serviceName, metav1.GetOptions{})
+	svc, err := r.serviceLister.Services(revision.GetNamespace()).Get(serviceName)
 	if err != nil {
 		return end, errors.Wrapf(err, "Unable to get service %s for revision", serviceName)
 	}
@@ -142,6 +191,7 @@ func (r *revisionActivator) getRevisionEndpoint(revision *v1alpha1.Revision) (en
 func (r *revisionActivator) ActiveEndpoint(namespace, name string) ActivationResult {
 	key := fmt.Sprintf("%s/%s", namespace, name)
 	logger := r.logger.With(zap.String(logkey.Key, key))
+	start := time.Now()
 	revision, err := r.activateRevision(namespace, name)
 	if err != nil {
 		logger.Error("Failed to activate the revision.", zap.Error(err))
@@ -152,7 +202,10 @@ func (r *revisionActivator) ActiveEndpoint(namespace, name string) ActivationRes
 	}
 
 	serviceName, configurationName := getServiceAndConfigurationLabels(revision)
+	r.reporter.ReportRevisionReadyTime(namespace, serviceName, configurationName, name, time.Since(start))
+	start = time.Now()
 	endpoint, err := r.getRevisionEndpoint(revision)
+	r.reporter.ReportEndpointResolutionTime(namespace, serviceName, configurationName, name, time.Since(start))
 	if err != nil {
 		logger.Error("Failed to get revision endpoint.", zap.Error(err))
 		return ActivationResult{
@@ -17,20 +17,26 @@ package activator
 
 import (
 	"net/http"
+	"sync"
 	"testing"
 	"time"
 
+	"github.com/google/go-cmp/cmp"
+	"github.com/google/go-cmp/cmp/cmpopts"
 	duckv1alpha1 "github.com/knative/pkg/apis/duck/v1alpha1"
 	. "github.com/knative/pkg/logging/testing"
 	"github.com/knative/serving/pkg/apis/serving"
 	"github.com/knative/serving/pkg/apis/serving/v1alpha1"
 	clientset "github.com/knative/serving/pkg/client/clientset/versioned"
 	fakeKna "github.com/knative/serving/pkg/client/clientset/versioned/fake"
+	informers "github.com/knative/serving/pkg/client/informers/externalversions"
 	revisionresources "github.com/knative/serving/pkg/reconciler/v1alpha1/revision/resources"
 	corev1 "k8s.io/api/core/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	kubeinformers "k8s.io/client-go/informers"
 	"k8s.io/client-go/kubernetes"
 	fakeK8s "k8s.io/client-go/kubernetes/fake"
+	"k8s.io/client-go/tools/cache"
 )
 
 const (
@@ -56,7 +62,7 @@ func TestActiveEndpoint_Reserve_WaitsForReady(t *testing.T) {
 			withReady(false).
 			build())
 	k8s.CoreV1().Services(testNamespace).Create(newServiceBuilder().build())
-	a := NewRevisionActivator(k8s, kna, TestLogger(t))
+	a := newTestActivator(t, k8s, kna)
 
 	ch := make(chan ActivationResult)
 	go func() {
@@ -98,6 +104,27 @@ func TestActiveEndpoint_Reserve_WaitsForReady(t *testing.T) {
 	default:
 		t.Errorf("Expected result after revision ready.")
 	}
+
+	reporter := a.(*revisionActivator).reporter.(*fakeReporter)
+	wantTimes := []reportedTime{{
+		Metric:   "revision_ready",
+		Service:  "test-service",
+		Config:   "test-config",
+		Revision: testRevision,
+	}, {
+		Metric:   "endpoint_resolution",
+		Service:  "test-service",
+		Config:   "test-config",
+		Revision: testRevision,
+	}}
+	ignoreDuration := cmpopts.IgnoreFields(reportedTime{}, "Duration")
+	if diff := cmp.Diff(wantTimes, reporter.reported(), ignoreDuration); diff != "" {
+		t.Errorf("Unexpected reported times (-want, +got): %v", diff)
+	}
+	// The revision became ready after at least 100ms.
+	if got := reporter.reported(); len(got) > 0 && got[0].Duration < 100*time.Millisecond {
+		t.Errorf("Unexpected ready time. Want at least 100ms. Got %v.", got[0].Duration)
+	}
 }
 
 func TestActiveEndpoint_Reserve_ReadyTimeoutWithError(t *testing.T) {
@@ -107,8 +134,10 @@ func TestActiveEndpoint_Reserve_ReadyTimeoutWithError(t *testing.T) {
 			withReady(false).
 			build())
 	k8s.CoreV1().Services(testNamespace).Create(newServiceBuilder().build())
-	a := NewRevisionActivator(k8s, kna, TestLogger(t))
-	a.(*revisionActivator).readyTimout = 200 * time.Millisecond
+	a := newTestActivator(t, k8s, kna)
+	a.(*revisionActivator).config.Update(&corev1.ConfigMap{
+		Data: map[string]string{"activation-timeout": "200ms"},
+	})
 
 	ch := make(chan ActivationResult)
 	go func() {
@@ -145,6 +174,169 @@ func TestActiveEndpoint_Reserve_ReadyTimeoutWithError(t *testing.T) {
 	}
 }
 
+func TestActiveEndpoint_Reserve_AnnotatedReadyTimeout(t *testing.T) {
+	k8s, kna := fakeClients()
+	kna.ServingV1alpha1().Revisions(testNamespace).Create(
+		newRevisionBuilder(defaultRevisionLabels).
+			withAnnotations(map[string]string{serving.ActivationTimeoutAnnotationKey: "200ms"}).
+			withReady(false).
+			build())
+	k8s.CoreV1().Services(testNamespace).Create(newServiceBuilder().build())
+	a := newTestActivator(t, k8s, kna)
+
+	ch := make(chan ActivationResult)
+	go func() {
+		ch <- a.ActiveEndpoint(testNamespace, testRevision)
+	}()
+
+	select {
+	case ar := <-ch:
+		if got, want := ar.Status, http.StatusInternalServerError; got != want {
+			t.Errorf("Unexpected error state. Want %v. Got %v.", want, got)
+		}
+	case <-time.After(3 * time.Second):
+		t.Errorf("Expected result after the timeout of the revision.")
+	}
+}
+
+func TestActiveEndpoint_Active_ReturnsImmediately(t *testing.T) {
+	k8s, kna := fakeClients()
+	kna.ServingV1alpha1().Revisions(testNamespace).Create(
+		newRevisionBuilder(defaultRevisionLabels).
+			withReady(true).
+			build())
+	k8s.CoreV1().Services(testNamespace).Create(newServiceBuilder().build())
+	a := newTestActivator(t, k8s, kna)
+
+	ar := a.ActiveEndpoint(testNamespace, testRevision)
+	if ar.Error != nil {
+		t.Fatalf("Unexpected error. Want nil. Got %v.", ar.Error)
+	}
+	if want := (Endpoint{testServiceFQDN, 8080}); ar.Endpoint != want {
+		t.Errorf("Unexpected endpoint. Want %+v. Got %+v.", want, ar.Endpoint)
+	}
+	if waiters := len(a.(*revisionActivator).waiters); waiters != 0 {
+		t.Errorf("Expected no waiters left. Got %d.", waiters)
+	}
+}
+
+func TestActiveEndpoint_Errors(t *testing.T) {
+	tests := []struct {
+		name     string
+		revision *v1alpha1.Revision
+		service  *corev1.Service
+	}{{
+		name:    "no revision",
+		service: newServiceBuilder().build(),
+	}, {
+		name:     "no service",
+		revision: newRevisionBuilder(defaultRevisionLabels).withReady(true).build(),
+	}, {
+		name:     "no service port",
+		revision: newRevisionBuilder(defaultRevisionLabels).withReady(true).build(),
+		service: &corev1.Service{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      testService,
+				Namespace: testNamespace,
+			},
+		},
+	}}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			k8s, kna := fakeClients()
+			if test.revision != nil {
+				kna.ServingV1alpha1().Revisions(testNamespace).Create(test.revision)
+			}
+			if test.service != nil {
+				k8s.CoreV1().Services(testNamespace).Create(test.service)
+			}
+			a := newTestActivator(t, k8s, kna)
+
+			ar := a.ActiveEndpoint(testNamespace, testRevision)
+			if ar.Error == nil {
+				t.Error("Expected error. Want error. Got nil.")
+			}
+			if got, want := ar.Status, http.StatusInternalServerError; got != want {
+				t.Errorf("Unexpected error state. Want %v. Got %v.", want, got)
+			}
+		})
+	}
+}
+
+// newTestActivator creates a revision Activator backed by informers on the
+// given clients, once their caches are synced.
+func newTestActivator(t *testing.T, k8s kubernetes.Interface, kna clientset.Interface) Activator {
+	stopCh := make(chan struct{})
+	// The informers are left running, like the handlers of the activator.
+	kubeInformerFactory := kubeinformers.NewSharedInformerFactory(k8s, 0)
+	servingInformerFactory := informers.NewSharedInformerFactory(kna, 0)
+	revisionInformer := servingInformerFactory.Serving().V1alpha1().Revisions()
+	serviceInformer := kubeInformerFactory.Core().V1().Services()
+
+	config := NewDynamicConfig(&defaultConfig, TestLogger(t))
+	a := NewRevisionActivator(revisionInformer, serviceInformer, config, &fakeReporter{}, TestLogger(t))
+	kubeInformerFactory.Start(stopCh)
+	servingInformerFactory.Start(stopCh)
+	if !cache.WaitForCacheSync(stopCh, revisionInformer.Informer().HasSynced, serviceInformer.Informer().HasSynced) {
+		t.Fatal("Failed to sync the informer caches")
+	}
+	return a
+}
+
+// reportedTime is a time reported by the revision activator.
+type reportedTime struct {
+	Metric   string
+	Service  string
+	Config   string
+	Revision string
+	Duration time.Duration
+}
+
+// fakeReporter records the times reported by the revision activator.
+type fakeReporter struct {
+	mux   sync.Mutex
+	times []reportedTime
+}
+
+func (f *fakeReporter) ReportRequestCount(ns, service, config, rev string, responseCode, numTries int, v float64) error {
+	return nil
+}
+
+func (f *fakeReporter) ReportResponseTime(ns, service, config, rev string, responseCode int, d time.Duration) error {
+	return nil
+}
+
+func (f *fakeReporter) ReportRevisionReadyTime(ns, service, config, rev string, d time.Duration) error {
+	f.record(reportedTime{Metric: "revision_ready", Service: service, Config: config, Revision: rev, Duration: d})
+	return nil
+}
+
+func (f *fakeReporter) ReportEndpointResolutionTime(ns, service, config, rev string, d time.Duration) error {
+	f.record(reportedTime{Metric: "endpoint_resolution", Service: service, Config: config, Revision: rev, Duration: d})
+	return nil
+}
+
+func (f *fakeReporter) ReportRetries(ns, service, config, rev string, retries int, backoff time.Duration) error {
+	return nil
+}
+
+func (f *fakeReporter) ReportTimeToFirstByte(ns, service, config, rev string, d time.Duration) error {
+	return nil
+}
+
+func (f *fakeReporter) record(t reportedTime) {
+	f.mux.Lock()
+	defer f.mux.Unlock()
+	f.times = append(f.times, t)
+}
+
+func (f *fakeReporter) reported() []reportedTime {
+	f.mux.Lock()
+	defer f.mux.Unlock()
+	return append([]reportedTime(nil), f.times...)
+}
+
 func fakeClients() (kubernetes.Interface, clientset.Interface) {
 	nsObj := &corev1.Namespace{
 		ObjectMeta: metav1.ObjectMeta{
@@ -191,6 +383,11 @@ func (b *revisionBuilder) withRevisionName(name string) *revisionBuilder {
 	return b
 }
 
+func (b *revisionBuilder) withAnnotations(annotations map[string]string) *revisionBuilder {
+	b.revision.ObjectMeta.Annotations = annotations
+	return b
+}
+
 func (b *revisionBuilder) withReady(ready bool) *revisionBuilder {
 	if ready {
 		b.revision.Status.MarkContainerHealthy()
@@ -34,6 +34,25 @@ const (
 
 	// ResponseTimeInMsecM is the response time in millisecond
 	ResponseTimeInMsecM
+
+	// RevisionReadyTimeInMsecM is the time waited for the revision to
+	// become ready in millisecond
+	RevisionReadyTimeInMsecM
+
+	// EndpointResolutionTimeInMsecM is the time taken to resolve the
+	// endpoint of the revision in millisecond
+	EndpointResolutionTimeInMsecM
+
+	// RetryCountM is the number of times a request was retried
+	RetryCountM
+
+	// RetryBackoffTimeInMsecM is the time spent backing off between the
+	// retries of a request in millisecond
+	RetryBackoffTimeInMsecM
+
+	// TimeToFirstByteInMsecM is the time until the first byte of the
+	// response of the pod in millisecond
+	TimeToFirstByteInMsecM
 )
 
 var (
@@ -46,13 +65,41 @@ var (
 			"request_latencies",
 			"The response time in millisecond",
 			stats.UnitNone),
+		RevisionReadyTimeInMsecM: stats.Float64(
+			"revision_ready_latencies",
+			"The time waited for the revision to become ready in millisecond",
+			stats.UnitNone),
+		EndpointResolutionTimeInMsecM: stats.Float64(
+			"endpoint_resolution_latencies",
+			"The time taken to resolve the endpoint of the revision in millisecond",
+			stats.UnitNone),
+		RetryCountM: stats.Float64(
+			"request_retries",
+			"The number of times a request was retried",
+			stats.UnitNone),
+		RetryBackoffTimeInMsecM: stats.Float64(
+			"retry_backoff_latencies",
+			"The time spent backing off between retries in millisecond",
+			stats.UnitNone),
+		TimeToFirstByteInMsecM: stats.Float64(
+			"time_to_first_byte_latencies",
+			"The time until the first byte of the response of the pod in millisecond",
+			stats.UnitNone),
 	}
+
+	// Cold starts can take a while, and are bounded by the activation
+	// timeout.
+	coldStartBuckets = view.Distribution(10, 50, 100, 250, 500, 1000, 2500, 5000, 10000, 20000, 30000, 60000, 120000)
 )
 
 // StatsReporter defines the interface for sending activator metrics
 type StatsReporter interface {
 	ReportRequestCount(ns, service, config, rev string, responseCode, numTries int, v float64) error
 	ReportResponseTime(ns, service, config, rev string, responseCode int, d time.Duration) error
+	ReportRevisionReadyTime(ns, service, config, rev string, d time.Duration) error
+	ReportEndpointResolutionTime(ns, service, config, rev string, d time.Duration) error
+	ReportRetries(ns, service, config, rev string, retries int, backoff time.Duration) error
+	ReportTimeToFirstByte(ns, service, config, rev string, d time.Duration) error
 }
 
 // Reporter holds cached metric objects to report autoscaler metrics
@@ -122,6 +169,36 @@ func NewStatsReporter() (*Reporter, error) {
 			Aggregation: view.Distribution(1000, 2000, 3000, 4000, 5000, 6000, 7000, 8000, 9000, 10000, 11000, 12000, 13000, 14000, 15000),
 			TagKeys:     []tag.Key{r.namespaceTagKey, r.serviceTagKey, r.configTagKey, r.revisionTagKey, r.responseCodeClassKey, r.responseCodeKey},
 		},
+		&view.View{
+			Description: "The time waited for the revision to become ready in millisecond",
+			Measure:     measurements[RevisionReadyTimeInMsecM],
+			Aggregation: coldStartBuckets,
+			TagKeys:     []tag.Key{r.namespaceTagKey, r.serviceTagKey, r.configTagKey, r.revisionTagKey},
+		},
+		&view.View{
+			Description: "The time taken to resolve the endpoint of the revision in millisecond",
+			Measure:     measurements[EndpointResolutionTimeInMsecM],
+			Aggregation: view.Distribution(1, 5, 10, 50, 100, 500, 1000, 5000),
+			TagKeys:     []tag.Key{r.namespaceTagKey, r.serviceTagKey, r.configTagKey, r.revisionTagKey},
+		},
+		&view.View{
+			Description: "The number of times a request was retried",
+			Measure:     measurements[RetryCountM],
+			Aggregation: view.Distribution(1, 2, 3, 4, 5, 6, 8, 10, 12, 15, 18, 25),
+			TagKeys:     []tag.Key{r.namespaceTagKey, r.serviceTagKey, r.configTagKey, r.revisionTagKey},
+		},
+		&view.View{
+			Description: "The time spent backing off between retries in millisecond",
+			Measure:     measurements[RetryBackoffTimeInMsecM],
+			Aggregation: coldStartBuckets,
+			TagKeys:     []tag.Key{r.namespaceTagKey, r.serviceTagKey, r.configTagKey, r.revisionTagKey},
+		},
+		&view.View{
+			Description: "The time until the first byte of the response of the pod in millisecond",
+			Measure:     measurements[TimeToFirstByteInMsecM],
+			Aggregation: coldStartBuckets,
+			TagKeys:     []tag.Key{r.namespaceTagKey, r.serviceTagKey, r.configTagKey, r.revisionTagKey},
+		},
 	)
 	if err != nil {
 		return nil, err
@@ -177,6 +254,64 @@ func (r *Reporter) ReportResponseTime(ns, service, config, rev string, responseC
 	return nil
 }
 
+// ReportRevisionReadyTime captures the time waited for a revision to
+// become ready
+func (r *Reporter) ReportRevisionReadyTime(ns, service, config, rev string, d time.Duration) error {
+	return r.recordRevisionTime(ns, service, config, rev, RevisionReadyTimeInMsecM, d)
+}
+
+// ReportEndpointResolutionTime captures the time taken to resolve the
+// endpoint of a revision
+func (r *Reporter) ReportEndpointResolutionTime(ns, service, config, rev string, d time.Duration) error {
+	return r.recordRevisionTime(ns, service, config, rev, EndpointResolutionTimeInMsecM, d)
+}
+
+// ReportRetries captures the number of retries of a request, and the time
+// spent backing off between them
+func (r *Reporter) ReportRetries(ns, service, config, rev string, retries int, backoff time.Duration) error {
+	ctx, err := r.revisionContext(ns, service, config, rev)
+	if err != nil {
+		return err
+	}
+
+	stats.Record(ctx,
+		measurements[RetryCountM].M(float64(retries)),
+		measurements[RetryBackoffTimeInMsecM].M(float64(backoff/time.Millisecond)))
+	return nil
+}
+
+// ReportTimeToFirstByte captures the time until the first byte of the
+// response of a pod
+func (r *Reporter) ReportTimeToFirstByte(ns, service, config, rev string, d time.Duration) error {
+	return r.recordRevisionTime(ns, service, config, rev, TimeToFirstByteInMsecM, d)
+}
+
+// recordRevisionTime records d in millisecond to the measurement m, tagged
+// with the revision.
+func (r *Reporter) recordRevisionTime(ns, service, config, rev string, m Measurement, d time.Duration) error {
+	ctx, err := r.revisionContext(ns, service, config, rev)
+	if err != nil {
+		return err
+	}
+
+	stats.Record(ctx, measurements[m].M(float64(d/time.Millisecond)))
+	return nil
+}
+
+// revisionContext returns a context with the tags of the revision.
+func (r *Reporter) revisionContext(ns, service, config, rev string) (context.Context, error) {
+	if !r.initialized {
+		return nil, errors.New("StatsReporter is not initialized yet")
+	}
+
+	return tag.New(
+		context.Background(),
+		tag.Insert(r.namespaceTagKey, ns),
+		tag.Insert(r.serviceTagKey, service),
+		tag.Insert(r.configTagKey, config),
+		tag.Insert(r.revisionTagKey, rev))
+}
+
 // getResponseCodeClass converts response code to a string of response code class.
 // e.g. The response code class is "5xx" for response code 503.
 func getResponseCodeClass(responseCode int) string {
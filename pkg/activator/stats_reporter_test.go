@@ -64,6 +64,56 @@ func TestActivatorReporter(t *testing.T) {
 		return r.ReportResponseTime("testns", "testsvc", "testconfig", "testrev", 200, 9100*time.Millisecond)
 	})
 	checkDistributionData(t, "request_latencies", wantTags3, 2, 1100, 9100)
+
+	// test the cold start breakdown
+	wantTags4 := map[string]string{
+		metricskey.LabelNamespaceName:     "testns",
+		metricskey.LabelServiceName:       "testsvc",
+		metricskey.LabelConfigurationName: "testconfig",
+		metricskey.LabelRevisionName:      "testrev",
+	}
+	expectSuccess(t, func() error {
+		return r.ReportRevisionReadyTime("testns", "testsvc", "testconfig", "testrev", 4200*time.Millisecond)
+	})
+	checkDistributionData(t, "revision_ready_latencies", wantTags4, 1, 4200, 4200)
+	expectSuccess(t, func() error {
+		return r.ReportEndpointResolutionTime("testns", "testsvc", "testconfig", "testrev", 3*time.Millisecond)
+	})
+	checkDistributionData(t, "endpoint_resolution_latencies", wantTags4, 1, 3, 3)
+	expectSuccess(t, func() error {
+		return r.ReportRetries("testns", "testsvc", "testconfig", "testrev", 2, 230*time.Millisecond)
+	})
+	expectSuccess(t, func() error {
+		return r.ReportRetries("testns", "testsvc", "testconfig", "testrev", 5, 1100*time.Millisecond)
+	})
+	checkDistributionData(t, "request_retries", wantTags4, 2, 2, 5)
+	checkDistributionData(t, "retry_backoff_latencies", wantTags4, 2, 230, 1100)
+	expectSuccess(t, func() error {
+		return r.ReportTimeToFirstByte("testns", "testsvc", "testconfig", "testrev", 120*time.Millisecond)
+	})
+	checkDistributionData(t, "time_to_first_byte_latencies", wantTags4, 1, 120, 120)
+}
+
+func TestActivatorReporterNotInitialized(t *testing.T) {
+	r := &Reporter{}
+	for name, report := range map[string]func() error{
+		"ReportRevisionReadyTime": func() error {
+			return r.ReportRevisionReadyTime("testns", "testsvc", "testconfig", "testrev", time.Second)
+		},
+		"ReportEndpointResolutionTime": func() error {
+			return r.ReportEndpointResolutionTime("testns", "testsvc", "testconfig", "testrev", time.Second)
+		},
+		"ReportRetries": func() error {
+			return r.ReportRetries("testns", "testsvc", "testconfig", "testrev", 1, time.Second)
+		},
+		"ReportTimeToFirstByte": func() error {
+			return r.ReportTimeToFirstByte("testns", "testsvc", "testconfig", "testrev", time.Second)
+		},
+	} {
+		if err := report(); err == nil {
+			t.Errorf("%s() expected an error before init. Got success.", name)
+		}
+	}
 }
 
 func expectSuccess(t *testing.T, f func() error) {
@@ -0,0 +1,341 @@
+/*
+Copyright 2018 The Knative Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package activator
+
+import (
+	"context"
+	"errors"
+	"net"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/knative/pkg/logging/logkey"
+	"github.com/knative/serving/pkg/apis/serving"
+	"github.com/knative/serving/pkg/apis/serving/v1alpha1"
+	"github.com/knative/serving/pkg/queue"
+	revisionresources "github.com/knative/serving/pkg/reconciler/v1alpha1/revision/resources"
+	revisionresourcenames "github.com/knative/serving/pkg/reconciler/v1alpha1/revision/resources/names"
+	"go.uber.org/zap"
+	corev1 "k8s.io/api/core/v1"
+	apierrs "k8s.io/apimachinery/pkg/api/errors"
+	corev1listers "k8s.io/client-go/listers/core/v1"
+	"k8s.io/client-go/tools/cache"
+)
+
+// ErrActivatorOverload is returned by Throttler.Try when the queue of the
+// revision is full.
+var ErrActivatorOverload = errors.New("activator overload")
+
+// ErrActivationTimeout is returned by Throttler.Try when the revision has
+// no capacity for the request within its activation timeout.
+var ErrActivationTimeout = errors.New("timeout waiting for the revision to have capacity")
+
+// ThrottlerParams defines the parameters of a Throttler.
+type ThrottlerParams struct {
+	// QueueDepth is how many requests to a revision may wait for capacity.
+	QueueDepth int32
+	// MaxConcurrency bounds the number of requests proxied to a revision at
+	// once. It is also the capacity of a revision that doesn't limit the
+	// concurrency of its containers, as soon as it has ready endpoints.
+	MaxConcurrency int32
+	// GetRevision returns the revision with the given namespace and name.
+	GetRevision func(namespace, name string) (*v1alpha1.Revision, error)
+	// GetEndpoints returns the host:port destinations of the ready
+	// endpoints of rev.
+	GetEndpoints func(rev *v1alpha1.Revision) ([]string, error)
+	// ActivationTimeout returns for how long at most a request to rev may
+	// wait for capacity. Without it, requests wait as long as their
+	// context allows.
+	ActivationTimeout func(rev *v1alpha1.Revision) time.Duration
+	Logger            *zap.SugaredLogger
+}
+
+// Throttler keeps a queue.Breaker per revision, whose capacity follows the
+// number of ready endpoints of the revision. Requests to a revision that
+// has no capacity left wait in the activator, rather than bouncing off
+// overloaded pods. Admitted requests are sent to the least loaded ready
+// pod of the revision, or to the pod of their key if the revision has a
+// session affinity.
+type Throttler struct {
+	params ThrottlerParams
+
+	mux      sync.Mutex
+	breakers map[revisionID]*revisionBreaker
+}
+
+type revisionBreaker struct {
+	*queue.Breaker
+	revision             *v1alpha1.Revision
+	containerConcurrency int32
+	pods                 *podTracker
+	// affinity is the session affinity of the revision, if any.
+	affinity *v1alpha1.SessionAffinity
+}
+
+// NewThrottler creates a Throttler with the given parameters.
+func NewThrottler(params ThrottlerParams) *Throttler {
+	return &Throttler{
+		params:   params,
+		breakers: make(map[revisionID]*revisionBreaker),
+	}
+}
+
+// Try runs thunk once the revision has capacity for one more request,
+// with the host:port of the pod to send it to. The destination is empty
+// if no pod is known to have spare capacity, in which case the request
+// should go through the service of the revision. If the revision has a
+// session affinity, requests with the same key in r go to the same pod
+// while it has spare capacity. Try returns ErrActivatorOverload without
+// running thunk if too many requests are already waiting, and
+// ErrActivationTimeout or the error of the context of r if the request
+// gives up waiting for capacity.
+func (t *Throttler) Try(namespace, name string, r *http.Request, thunk func(dest string)) error {
+	return t.try(namespace, name, r, false, thunk)
+}
+
+// TryPod is like Try, but the destination is always a pod: once admitted,
+// the request waits for a pod with spare capacity rather than go through
+// the service, within the same activation timeout.
+func (t *Throttler) TryPod(namespace, name string, r *http.Request, thunk func(dest string)) error {
+	return t.try(namespace, name, r, true, thunk)
+}
+
+func (t *Throttler) try(namespace, name string, r *http.Request, needPod bool, thunk func(dest string)) error {
+	b, err := t.breaker(revisionID{namespace: namespace, name: name})
+	if err != nil {
+		return err
+	}
+	ctx := context.Background()
+	if r != nil {
+		ctx = r.Context()
+	}
+	if t.params.ActivationTimeout != nil {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, t.params.ActivationTimeout(b.revision))
+		defer cancel()
+	}
+	key := sessionKey(b.affinity, r)
+	var podErr error
+	err = b.MaybeContext(ctx, func() {
+		var (
+			dest    string
+			release func()
+		)
+		if needPod {
+			if dest, release, podErr = b.pods.acquireContext(ctx, key); podErr != nil {
+				return
+			}
+		} else {
+			dest, release = b.pods.acquire(key)
+		}
+		defer release()
+		thunk(dest)
+	})
+	if err == nil {
+		err = podErr
+	}
+	switch err {
+	case queue.ErrRequestQueueFull:
+		return ErrActivatorOverload
+	case context.DeadlineExceeded:
+		return ErrActivationTimeout
+	}
+	return err
+}
+
+// UpdateEndpoints sets the ready pods of the revision to the ones at
+// dests, and its capacity to match. Revisions that haven't been requested
+// yet are ignored, their pods are looked up when they are.
+func (t *Throttler) UpdateEndpoints(namespace, name string, dests []string) error {
+	t.mux.Lock()
+	b, ok := t.breakers[revisionID{namespace: namespace, name: name}]
+	t.mux.Unlock()
+	if !ok {
+		return nil
+	}
+	b.pods.update(dests)
+	return b.UpdateConcurrency(t.capacity(b.containerConcurrency, int32(len(dests))))
+}
+
+// Remove forgets the revision. Requests waiting for it are left in the
+// queue of its breaker.
+func (t *Throttler) Remove(namespace, name string) {
+	t.mux.Lock()
+	defer t.mux.Unlock()
+	delete(t.breakers, revisionID{namespace: namespace, name: name})
+}
+
+// EndpointsUpdated is an informer handler that updates the ready pods of
+// the revision of the given Endpoints.
+func (t *Throttler) EndpointsUpdated(obj interface{}) {
+	ep := obj.(*corev1.Endpoints)
+	name, ok := ep.Labels[serving.RevisionLabelKey]
+	if !ok {
+		return
+	}
+	if err := t.UpdateEndpoints(ep.Namespace, name, ReadyDests(ep)); err != nil {
+		t.params.Logger.Errorw("Failed to update the capacity of the revision",
+			zap.String(logkey.Key, ep.Namespace+"/"+name), zap.Error(err))
+	}
+}
+
+// EndpointsDeleted is an informer handler that removes the revision of the
+// given Endpoints.
+func (t *Throttler) EndpointsDeleted(obj interface{}) {
+	if tombstone, ok := obj.(cache.DeletedFinalStateUnknown); ok {
+		obj = tombstone.Obj
+	}
+	ep, ok := obj.(*corev1.Endpoints)
+	if !ok {
+		return
+	}
+	if name, ok := ep.Labels[serving.RevisionLabelKey]; ok {
+		t.Remove(ep.Namespace, name)
+	}
+}
+
+// breaker returns the breaker of the revision, creating it with the
+// current capacity of the revision if needed.
+func (t *Throttler) breaker(rev revisionID) (*revisionBreaker, error) {
+	t.mux.Lock()
+	b, ok := t.breakers[rev]
+	t.mux.Unlock()
+	if ok {
+		return b, nil
+	}
+
+	// Look the revision up without holding the lock, so that the first
+	// requests to different revisions don't wait for each other.
+	revision, err := t.params.GetRevision(rev.namespace, rev.name)
+	if err != nil {
+		return nil, err
+	}
+	dests, err := t.params.GetEndpoints(revision)
+	if err != nil {
+		return nil, err
+	}
+	affinity, err := revision.GetSessionAffinity()
+	if err != nil {
+		// Invalid annotations are rejected by the webhook, so this should
+		// not happen. Fall back to the least loaded pod.
+		t.params.Logger.Errorw("Ignoring the session affinity of the revision",
+			zap.String(logkey.Key, rev.namespace+"/"+rev.name), zap.Error(err))
+	}
+	cc := int32(revision.Spec.ContainerConcurrency)
+	b = &revisionBreaker{
+		Breaker:              queue.NewBreaker(t.params.QueueDepth, t.params.MaxConcurrency, t.capacity(cc, int32(len(dests)))),
+		revision:             revision,
+		containerConcurrency: cc,
+		pods:                 newPodTracker(cc, dests),
+		affinity:             affinity,
+	}
+
+	t.mux.Lock()
+	if existing, ok := t.breakers[rev]; ok {
+		// Another request created the breaker in the meantime.
+		t.mux.Unlock()
+		return existing, nil
+	}
+	t.breakers[rev] = b
+	t.mux.Unlock()
+
+	// The endpoints may have changed before the breaker was registered,
+	// when their updates were still ignored.
+	if dests, err = t.params.GetEndpoints(revision); err != nil {
+		return nil, err
+	}
+	if err := t.UpdateEndpoints(rev.namespace, rev.name, dests); err != nil {
+		return nil, err
+	}
+	return b, nil
+}
+
+// capacity returns how many requests endpoints with the given container
+// concurrency may serve at once, bounded by the max concurrency.
+func (t *Throttler) capacity(containerConcurrency, endpoints int32) int32 {
+	if endpoints <= 0 {
+		return 0
+	}
+	if containerConcurrency == 0 {
+		return t.params.MaxConcurrency
+	}
+	if c := containerConcurrency * endpoints; c < t.params.MaxConcurrency {
+		return c
+	}
+	return t.params.MaxConcurrency
+}
+
+// sessionKey returns the key of r for the session affinity, or "" if there
+// is no affinity or r doesn't have the key. Unlike Envoy, the activator
+// doesn't generate missing cookies, such requests go to any pod.
+func sessionKey(affinity *v1alpha1.SessionAffinity, r *http.Request) string {
+	if affinity == nil || r == nil {
+		return ""
+	}
+	switch {
+	case affinity.Header != "":
+		return r.Header.Get(affinity.Header)
+	case affinity.Cookie != "":
+		if c, err := r.Cookie(affinity.Cookie); err == nil {
+			return c.Value
+		}
+	case affinity.SourceIP:
+		// The client is the first hop of the proxies that forwarded r.
+		if xff := r.Header.Get("X-Forwarded-For"); xff != "" {
+			return strings.TrimSpace(strings.Split(xff, ",")[0])
+		}
+		if host, _, err := net.SplitHostPort(r.RemoteAddr); err == nil {
+			return host
+		}
+	}
+	return ""
+}
+
+// ReadyEndpointsGetter returns a ThrottlerParams.GetEndpoints that looks
+// up the ready endpoints of the service of a revision in lister. A
+// revision whose endpoints aren't known yet has none.
+func ReadyEndpointsGetter(lister corev1listers.EndpointsLister) func(*v1alpha1.Revision) ([]string, error) {
+	return func(rev *v1alpha1.Revision) ([]string, error) {
+		ep, err := lister.Endpoints(rev.Namespace).Get(revisionresourcenames.K8sService(rev))
+		if apierrs.IsNotFound(err) {
+			return nil, nil
+		} else if err != nil {
+			return nil, err
+		}
+		return ReadyDests(ep), nil
+	}
+}
+
+// ReadyDests returns the host:port destinations of the ready addresses
+// of ep, on the port of the revision service.
+func ReadyDests(ep *corev1.Endpoints) []string {
+	var dests []string
+	for _, subset := range ep.Subsets {
+		for _, port := range subset.Ports {
+			if port.Name != revisionresources.ServicePortName {
+				continue
+			}
+			for _, addr := range subset.Addresses {
+				dests = append(dests, net.JoinHostPort(addr.IP, strconv.Itoa(int(port.Port))))
+			}
+		}
+	}
+	return dests
+}
@@ -0,0 +1,435 @@
+/*
+Copyright 2018 The Knative Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package activator
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+	"time"
+
+	. "github.com/knative/pkg/logging/testing"
+	"github.com/knative/serving/pkg/apis/serving"
+	"github.com/knative/serving/pkg/apis/serving/v1alpha1"
+	revisionresources "github.com/knative/serving/pkg/reconciler/v1alpha1/revision/resources"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	corev1listers "k8s.io/client-go/listers/core/v1"
+	"k8s.io/client-go/tools/cache"
+)
+
+func newTestThrottler(t *testing.T, containerConcurrency int, dests ...string) *Throttler {
+	return NewThrottler(ThrottlerParams{
+		QueueDepth:     10,
+		MaxConcurrency: 100,
+		GetRevision: func(namespace, name string) (*v1alpha1.Revision, error) {
+			return &v1alpha1.Revision{
+				ObjectMeta: metav1.ObjectMeta{Namespace: namespace, Name: name},
+				Spec: v1alpha1.RevisionSpec{
+					ContainerConcurrency: v1alpha1.RevisionContainerConcurrencyType(containerConcurrency),
+				},
+			}, nil
+		},
+		GetEndpoints: func(*v1alpha1.Revision) ([]string, error) {
+			return dests, nil
+		},
+		Logger: TestLogger(t),
+	})
+}
+
+// newEndpoints returns the Endpoints of the service of revision, with the
+// given number of ready addresses.
+func newEndpoints(revision string, addresses int) *corev1.Endpoints {
+	ep := &corev1.Endpoints{
+		ObjectMeta: metav1.ObjectMeta{
+			Namespace: testNamespace,
+			Name:      revision + "-service",
+			Labels:    map[string]string{serving.RevisionLabelKey: revision},
+		},
+	}
+	if addresses > 0 {
+		subset := corev1.EndpointSubset{
+			Ports: []corev1.EndpointPort{{
+				Name: revisionresources.ServicePortName,
+				Port: 8012,
+			}, {
+				Name: "other",
+				Port: 9090,
+			}},
+		}
+		for i := 0; i < addresses; i++ {
+			subset.Addresses = append(subset.Addresses, corev1.EndpointAddress{IP: fmt.Sprintf("10.0.0.%d", i+1)})
+		}
+		ep.Subsets = []corev1.EndpointSubset{subset}
+	}
+	return ep
+}
+
+// newDests returns n pod destinations.
+func newDests(n int) []string {
+	var dests []string
+	for i := 0; i < n; i++ {
+		dests = append(dests, fmt.Sprintf("10.0.0.%d:8012", i+1))
+	}
+	return dests
+}
+
+// breakerOf returns the breaker of the test revision.
+func breakerOf(t *testing.T, th *Throttler) *revisionBreaker {
+	t.Helper()
+	b, err := th.breaker(revisionID{namespace: testNamespace, name: testRevision})
+	if err != nil {
+		t.Fatalf("breaker() = %v", err)
+	}
+	return b
+}
+
+func TestThrottlerInitialCapacity(t *testing.T) {
+	tests := []struct {
+		name                 string
+		containerConcurrency int
+		endpoints            int
+		want                 int32
+	}{{
+		name:                 "no endpoints",
+		containerConcurrency: 10,
+		endpoints:            0,
+		want:                 0,
+	}, {
+		name:                 "concurrency times endpoints",
+		containerConcurrency: 10,
+		endpoints:            3,
+		want:                 30,
+	}, {
+		name:                 "bounded by max concurrency",
+		containerConcurrency: 10,
+		endpoints:            20,
+		want:                 100,
+	}, {
+		name:                 "unlimited concurrency",
+		containerConcurrency: 0,
+		endpoints:            1,
+		want:                 100,
+	}, {
+		name:                 "unlimited concurrency without endpoints",
+		containerConcurrency: 0,
+		endpoints:            0,
+		want:                 0,
+	}}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			th := newTestThrottler(t, test.containerConcurrency, newDests(test.endpoints)...)
+			if got := breakerOf(t, th).Capacity(); got != test.want {
+				t.Errorf("Capacity = %d, want %d", got, test.want)
+			}
+		})
+	}
+}
+
+func TestThrottlerTry(t *testing.T) {
+	th := newTestThrottler(t, 1, newDests(1)...)
+	var got *string
+	if err := th.Try(testNamespace, testRevision, nil, func(dest string) { got = &dest }); err != nil {
+		t.Fatalf("Try() = %v", err)
+	}
+	if got == nil {
+		t.Fatal("Try() didn't run the thunk")
+	}
+	if want := "10.0.0.1:8012"; *got != want {
+		t.Errorf("Destination = %q, want %q", *got, want)
+	}
+}
+
+func TestThrottlerTryServiceFallback(t *testing.T) {
+	th := newTestThrottler(t, 0, newDests(1)...)
+	// The pods are gone, but the capacity hasn't caught up yet.
+	breakerOf(t, th).pods.update(nil)
+
+	var got *string
+	if err := th.Try(testNamespace, testRevision, nil, func(dest string) { got = &dest }); err != nil {
+		t.Fatalf("Try() = %v", err)
+	}
+	if got == nil || *got != "" {
+		t.Errorf("Destination = %v, want the service", got)
+	}
+}
+
+func TestThrottlerTryPod(t *testing.T) {
+	th := newTestThrottler(t, 0, newDests(1)...)
+	th.params.ActivationTimeout = func(*v1alpha1.Revision) time.Duration {
+		return 50 * time.Millisecond
+	}
+	// The pods are gone, but the capacity hasn't caught up yet.
+	breakerOf(t, th).pods.update(nil)
+
+	// Without a pod, the request waits rather than go through the service.
+	if err := th.TryPod(testNamespace, testRevision, nil, func(string) {
+		t.Error("Unexpected call of the thunk")
+	}); err != ErrActivationTimeout {
+		t.Errorf("TryPod() = %v, want %v", err, ErrActivationTimeout)
+	}
+
+	got := make(chan string, 1)
+	errCh := make(chan error, 1)
+	go func() {
+		errCh <- th.TryPod(testNamespace, testRevision, nil, func(dest string) { got <- dest })
+	}()
+	time.Sleep(10 * time.Millisecond)
+	breakerOf(t, th).pods.update(newDests(1))
+	if err := <-errCh; err != nil {
+		t.Fatalf("TryPod() = %v", err)
+	}
+	if dest, want := <-got, "10.0.0.1:8012"; dest != want {
+		t.Errorf("Destination = %q, want %q", dest, want)
+	}
+}
+
+func TestThrottlerTrySessionAffinity(t *testing.T) {
+	th := NewThrottler(ThrottlerParams{
+		QueueDepth:     10,
+		MaxConcurrency: 100,
+		GetRevision: func(namespace, name string) (*v1alpha1.Revision, error) {
+			return &v1alpha1.Revision{
+				ObjectMeta: metav1.ObjectMeta{
+					Namespace: namespace,
+					Name:      name,
+					Annotations: map[string]string{
+						serving.SessionAffinityAnnotationKey: "header:X-User-Id",
+					},
+				},
+			}, nil
+		},
+		GetEndpoints: func(*v1alpha1.Revision) ([]string, error) {
+			return newDests(5), nil
+		},
+		Logger: TestLogger(t),
+	})
+
+	// Without load the requests of each key stick to the same pod, while
+	// round-robin would spread them.
+	dests := make(map[string]string)
+	for i := 0; i < 10; i++ {
+		user := fmt.Sprintf("user-%d", i%2)
+		r := httptest.NewRequest(http.MethodGet, "http://example.com", nil)
+		r.Header.Set("X-User-Id", user)
+		if err := th.Try(testNamespace, testRevision, r, func(dest string) {
+			if want, ok := dests[user]; ok && dest != want {
+				t.Errorf("Destination of %s = %q, want %q", user, dest, want)
+			}
+			dests[user] = dest
+		}); err != nil {
+			t.Fatalf("Try() = %v", err)
+		}
+	}
+}
+
+func TestSessionKey(t *testing.T) {
+	r := httptest.NewRequest(http.MethodGet, "http://example.com", nil)
+	r.RemoteAddr = "10.1.2.3:4567"
+	r.Header.Set("X-User-Id", "alice")
+	r.AddCookie(&http.Cookie{Name: "session", Value: "s3cr3t"})
+	forwarded := httptest.NewRequest(http.MethodGet, "http://example.com", nil)
+	forwarded.Header.Set("X-Forwarded-For", "1.2.3.4, 10.0.0.1")
+
+	tests := []struct {
+		name     string
+		affinity *v1alpha1.SessionAffinity
+		r        *http.Request
+		want     string
+	}{{
+		name: "no affinity",
+		r:    r,
+	}, {
+		name:     "no request",
+		affinity: &v1alpha1.SessionAffinity{Header: "X-User-Id"},
+	}, {
+		name:     "header",
+		affinity: &v1alpha1.SessionAffinity{Header: "X-User-Id"},
+		r:        r,
+		want:     "alice",
+	}, {
+		name:     "missing header",
+		affinity: &v1alpha1.SessionAffinity{Header: "X-Tenant"},
+		r:        r,
+	}, {
+		name:     "cookie",
+		affinity: &v1alpha1.SessionAffinity{Cookie: "session"},
+		r:        r,
+		want:     "s3cr3t",
+	}, {
+		name:     "missing cookie",
+		affinity: &v1alpha1.SessionAffinity{Cookie: "session"},
+		r:        forwarded,
+	}, {
+		name:     "remote address",
+		affinity: &v1alpha1.SessionAffinity{SourceIP: true},
+		r:        r,
+		want:     "10.1.2.3",
+	}, {
+		name:     "forwarded for",
+		affinity: &v1alpha1.SessionAffinity{SourceIP: true},
+		r:        forwarded,
+		want:     "1.2.3.4",
+	}}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if got := sessionKey(test.affinity, test.r); got != test.want {
+				t.Errorf("sessionKey() = %q, want %q", got, test.want)
+			}
+		})
+	}
+}
+
+func TestThrottlerTryError(t *testing.T) {
+	want := errors.New("no revision")
+	th := NewThrottler(ThrottlerParams{
+		QueueDepth:     10,
+		MaxConcurrency: 100,
+		GetRevision: func(namespace, name string) (*v1alpha1.Revision, error) {
+			return nil, want
+		},
+		Logger: TestLogger(t),
+	})
+	if err := th.Try(testNamespace, testRevision, nil, func(string) {
+		t.Error("Unexpected call of the thunk")
+	}); err != want {
+		t.Errorf("Try() = %v, want %v", err, want)
+	}
+}
+
+func TestThrottlerTryTimeout(t *testing.T) {
+	// Without endpoints, the revision has no capacity.
+	th := newTestThrottler(t, 10)
+	th.params.ActivationTimeout = func(*v1alpha1.Revision) time.Duration {
+		return 10 * time.Millisecond
+	}
+	if err := th.Try(testNamespace, testRevision, nil, func(string) {
+		t.Error("Unexpected call of the thunk")
+	}); err != ErrActivationTimeout {
+		t.Errorf("Try() = %v, want %v", err, ErrActivationTimeout)
+	}
+}
+
+func TestThrottlerTryCanceled(t *testing.T) {
+	th := newTestThrottler(t, 10)
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	r := httptest.NewRequest(http.MethodGet, "http://example.com", nil).WithContext(ctx)
+	if err := th.Try(testNamespace, testRevision, r, func(string) {
+		t.Error("Unexpected call of the thunk")
+	}); err != context.Canceled {
+		t.Errorf("Try() = %v, want %v", err, context.Canceled)
+	}
+}
+
+func TestThrottlerEndpointsUpdated(t *testing.T) {
+	th := newTestThrottler(t, 10)
+	b := breakerOf(t, th)
+	if got := b.Capacity(); got != 0 {
+		t.Fatalf("Capacity = %d, want 0", got)
+	}
+
+	th.EndpointsUpdated(newEndpoints(testRevision, 2))
+	if got := b.Capacity(); got != 20 {
+		t.Errorf("Capacity = %d, want 20", got)
+	}
+	if got, want := podDests(b.pods), newDests(2); !reflect.DeepEqual(got, want) {
+		t.Errorf("Pods = %v, want %v", got, want)
+	}
+
+	th.EndpointsUpdated(newEndpoints(testRevision, 1))
+	if got := b.Capacity(); got != 10 {
+		t.Errorf("Capacity = %d, want 10", got)
+	}
+	if got, want := podDests(b.pods), newDests(1); !reflect.DeepEqual(got, want) {
+		t.Errorf("Pods = %v, want %v", got, want)
+	}
+
+	// Endpoints of other revisions or without a revision are ignored.
+	th.EndpointsUpdated(newEndpoints("other-rev", 5))
+	unlabeled := newEndpoints(testRevision, 5)
+	unlabeled.Labels = nil
+	th.EndpointsUpdated(unlabeled)
+	if got := b.Capacity(); got != 10 {
+		t.Errorf("Capacity = %d, want 10", got)
+	}
+}
+
+func TestThrottlerEndpointsUnchangedAfterRequests(t *testing.T) {
+	th := newTestThrottler(t, 1, newDests(1)...)
+	th.params.ActivationTimeout = func(*v1alpha1.Revision) time.Duration {
+		return 100 * time.Millisecond
+	}
+	for i := 0; i < 3; i++ {
+		if err := th.Try(testNamespace, testRevision, nil, func(string) {}); err != nil {
+			t.Fatalf("Try() = %v", err)
+		}
+	}
+
+	// A resync with the same endpoints keeps the capacity.
+	if err := th.UpdateEndpoints(testNamespace, testRevision, newDests(1)); err != nil {
+		t.Fatalf("UpdateEndpoints() = %v", err)
+	}
+	if got := breakerOf(t, th).Capacity(); got != 1 {
+		t.Errorf("Capacity = %d, want 1", got)
+	}
+	if err := th.Try(testNamespace, testRevision, nil, func(string) {}); err != nil {
+		t.Errorf("Try() after the update = %v", err)
+	}
+}
+
+func TestThrottlerEndpointsDeleted(t *testing.T) {
+	th := newTestThrottler(t, 10, newDests(1)...)
+	breakerOf(t, th)
+
+	th.EndpointsDeleted(cache.DeletedFinalStateUnknown{
+		Key: testNamespace + "/" + testService,
+		Obj: newEndpoints(testRevision, 1),
+	})
+	if _, ok := th.breakers[revisionID{namespace: testNamespace, name: testRevision}]; ok {
+		t.Error("EndpointsDeleted() didn't remove the breaker")
+	}
+
+	// Updates of revisions without a breaker don't create one.
+	th.EndpointsUpdated(newEndpoints(testRevision, 1))
+	if _, ok := th.breakers[revisionID{namespace: testNamespace, name: testRevision}]; ok {
+		t.Error("EndpointsUpdated() created a breaker")
+	}
+}
+
+func TestReadyEndpointsGetter(t *testing.T) {
+	indexer := cache.NewIndexer(cache.MetaNamespaceKeyFunc, cache.Indexers{})
+	getEndpoints := ReadyEndpointsGetter(corev1listers.NewEndpointsLister(indexer))
+	rev := &v1alpha1.Revision{
+		ObjectMeta: metav1.ObjectMeta{Namespace: testNamespace, Name: testRevision},
+	}
+
+	if got, err := getEndpoints(rev); err != nil || len(got) != 0 {
+		t.Errorf("getEndpoints() = (%v, %v), want no endpoints", got, err)
+	}
+
+	indexer.Add(newEndpoints(testRevision, 3))
+	if got, err := getEndpoints(rev); err != nil || !reflect.DeepEqual(got, newDests(3)) {
+		t.Errorf("getEndpoints() = (%v, %v), want (%v, nil)", got, err, newDests(3))
+	}
+}
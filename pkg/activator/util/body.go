@@ -0,0 +1,43 @@
+/*
+Copyright 2018 The Knative Authors
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package util
+
+import (
+	"bytes"
+	"io"
+	"io/ioutil"
+)
+
+// BufferBody reads up to `limit` bytes of `rc`. If the whole body fits, it
+// returns it from memory along with true. Otherwise it returns a body that
+// streams the rest of `rc` after the bytes already read, along with false.
+func BufferBody(rc io.ReadCloser, limit int64) (io.ReadCloser, bool, error) {
+	buf, err := ioutil.ReadAll(io.LimitReader(rc, limit+1))
+	if err != nil {
+		return nil, false, err
+	}
+	if int64(len(buf)) <= limit {
+		rc.Close()
+		return ioutil.NopCloser(bytes.NewReader(buf)), true, nil
+	}
+	return &streamedBody{
+		Reader: io.MultiReader(bytes.NewReader(buf), rc),
+		Closer: rc,
+	}, false, nil
+}
+
+type streamedBody struct {
+	io.Reader
+	io.Closer
+}
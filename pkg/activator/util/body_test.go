@@ -0,0 +1,85 @@
+/*
+Copyright 2018 The Knative Authors
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package util
+
+import (
+	"errors"
+	"io/ioutil"
+	"strings"
+	"testing"
+)
+
+func TestBufferBody(t *testing.T) {
+	body := "SAMPLE PAYLOAD"
+
+	examples := []struct {
+		label        string
+		limit        int64
+		wantBuffered bool
+	}{{
+		label:        "under",
+		limit:        int64(len(body)) + 1,
+		wantBuffered: true,
+	}, {
+		label:        "equal",
+		limit:        int64(len(body)),
+		wantBuffered: true,
+	}, {
+		label:        "over",
+		limit:        int64(len(body)) - 1,
+		wantBuffered: false,
+	}, {
+		label:        "no buffering",
+		limit:        0,
+		wantBuffered: false,
+	}}
+
+	for _, e := range examples {
+		t.Run(e.label, func(t *testing.T) {
+			rc := &spyReadCloser{Reader: strings.NewReader(body)}
+			got, buffered, err := BufferBody(rc, e.limit)
+			if err != nil {
+				t.Fatalf("BufferBody() = %v", err)
+			}
+			if buffered != e.wantBuffered {
+				t.Errorf("Buffered = %v, want %v", buffered, e.wantBuffered)
+			}
+			if rc.Closed != e.wantBuffered {
+				t.Errorf("Closed = %v, want %v", rc.Closed, e.wantBuffered)
+			}
+			b, err := ioutil.ReadAll(got)
+			if err != nil {
+				t.Fatalf("Unexpected error reading the body: %v", err)
+			}
+			if string(b) != body {
+				t.Errorf("Body = %q, want %q", b, body)
+			}
+			got.Close()
+			if !rc.Closed {
+				t.Error("Expected the original body to be closed")
+			}
+		})
+	}
+}
+
+type errorReader struct{}
+
+func (errorReader) Read([]byte) (int, error) {
+	return 0, errors.New("read error")
+}
+
+func TestBufferBodyError(t *testing.T) {
+	if _, _, err := BufferBody(ioutil.NopCloser(errorReader{}), 10); err == nil {
+		t.Error("Expected an error reading the body")
+	}
+}
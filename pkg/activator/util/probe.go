@@ -0,0 +1,45 @@
+/*
+Copyright 2018 The Knative Authors
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package util
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"net/http"
+	"strconv"
+
+	"github.com/knative/serving/pkg/queue"
+)
+
+// ProbeQueueProxy checks whether the queue-proxy of the pod at `dest`, the
+// host:port of its serving port, reports ready on its admin port. Requests
+// made with the retry round tripper are retried while the pod answers 503.
+func ProbeQueueProxy(ctx context.Context, transport http.RoundTripper, dest string) (bool, error) {
+	host, _, err := net.SplitHostPort(dest)
+	if err != nil {
+		return false, err
+	}
+	url := fmt.Sprintf("http://%s/%s", net.JoinHostPort(host, strconv.Itoa(queue.RequestQueueAdminPort)), queue.RequestQueueHealthPath)
+	req, err := http.NewRequest(http.MethodGet, url, nil)
+	if err != nil {
+		return false, err
+	}
+	resp, err := transport.RoundTrip(req.WithContext(ctx))
+	if err != nil {
+		return false, err
+	}
+	resp.Body.Close()
+	return resp.StatusCode == http.StatusOK, nil
+}
@@ -0,0 +1,78 @@
+/*
+Copyright 2018 The Knative Authors
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package util
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"testing"
+)
+
+func TestProbeQueueProxy(t *testing.T) {
+	someErr := errors.New("some error")
+
+	examples := []struct {
+		label     string
+		dest      string
+		status    int
+		err       error
+		wantReady bool
+		wantErr   bool
+	}{{
+		label:     "ready",
+		dest:      "10.0.0.1:8012",
+		status:    http.StatusOK,
+		wantReady: true,
+	}, {
+		label:     "not ready",
+		dest:      "10.0.0.1:8012",
+		status:    http.StatusServiceUnavailable,
+		wantReady: false,
+	}, {
+		label:   "transport error",
+		dest:    "10.0.0.1:8012",
+		err:     someErr,
+		wantErr: true,
+	}, {
+		label:   "no port",
+		dest:    "10.0.0.1",
+		wantErr: true,
+	}}
+
+	for _, e := range examples {
+		t.Run(e.label, func(t *testing.T) {
+			var gotURL string
+			transport := RoundTripperFunc(func(r *http.Request) (*http.Response, error) {
+				gotURL = r.URL.String()
+				if e.err != nil {
+					return nil, e.err
+				}
+				return &http.Response{StatusCode: e.status, Body: &spyReadCloser{}}, nil
+			})
+
+			ready, err := ProbeQueueProxy(context.Background(), transport, e.dest)
+			if (err != nil) != e.wantErr {
+				t.Fatalf("ProbeQueueProxy() = %v, want error %v", err, e.wantErr)
+			}
+			if ready != e.wantReady {
+				t.Errorf("Ready = %v, want %v", ready, e.wantReady)
+			}
+			if !e.wantErr {
+				if want := "http://10.0.0.1:8022/health"; gotURL != want {
+					t.Errorf("Probed %q, want %q", gotURL, want)
+				}
+			}
+		})
+	}
+}
@@ -14,14 +14,17 @@ limitations under the License.
 package util
 
 import (
+	"context"
 	"net/http"
 	"strconv"
+	"time"
 
 	"k8s.io/apimachinery/pkg/util/wait"
 
 	"github.com/knative/serving/pkg/activator"
 
 	h2cutil "github.com/knative/serving/pkg/http/h2c"
+	"go.opencensus.io/trace"
 	"go.uber.org/zap"
 )
 
@@ -58,21 +61,78 @@ func RetryStatus(status int) RetryCond {
 type retryRoundTripper struct {
 	logger          *zap.SugaredLogger
 	transport       http.RoundTripper
-	backoffSettings wait.Backoff
+	backoffSettings func() wait.Backoff
 	retryConditions []RetryCond
 }
 
 // RetryRoundTripper retries a request on error or retry condition, using the given `retry` strategy
 func NewRetryRoundTripper(rt http.RoundTripper, l *zap.SugaredLogger, b wait.Backoff, conditions ...RetryCond) http.RoundTripper {
+	return NewDynamicRetryRoundTripper(rt, l, func() wait.Backoff { return b }, conditions...)
+}
+
+// NewDynamicRetryRoundTripper is like NewRetryRoundTripper, but looks the
+// retry strategy up with `backoff` for every request, so that it may change
+// over time.
+func NewDynamicRetryRoundTripper(rt http.RoundTripper, l *zap.SugaredLogger, backoff func() wait.Backoff, conditions ...RetryCond) http.RoundTripper {
 	return &retryRoundTripper{
 		logger:          l,
 		transport:       rt,
-		backoffSettings: b,
+		backoffSettings: backoff,
 		retryConditions: conditions,
 	}
 }
 
+// noRetriesKey is the key of the request context value that marks requests
+// that must be sent at most once.
+type noRetriesKey struct{}
+
+// WithNoRetries returns a copy of `ctx` that makes the retry round tripper
+// send a request only once, e.g. because its body can't be read again.
+func WithNoRetries(ctx context.Context) context.Context {
+	return context.WithValue(ctx, noRetriesKey{}, true)
+}
+
+// RoundTripStats records how the retry round tripper sent a request.
+type RoundTripStats struct {
+	// Retries is the number of attempts after the first one.
+	Retries int
+	// Backoff is the time spent waiting between the attempts.
+	Backoff time.Duration
+	// TimeToFirstByte is the time the last attempt took to get the
+	// response headers.
+	TimeToFirstByte time.Duration
+}
+
+// roundTripStatsKey is the key of the request context value that holds
+// the RoundTripStats of a request.
+type roundTripStatsKey struct{}
+
+// WithRoundTripStats returns a copy of `ctx` that makes the retry round
+// tripper record how it sent a request into `stats`.
+func WithRoundTripStats(ctx context.Context, stats *RoundTripStats) context.Context {
+	return context.WithValue(ctx, roundTripStatsKey{}, stats)
+}
+
 func (rrt *retryRoundTripper) RoundTrip(r *http.Request) (resp *http.Response, err error) {
+	stats, _ := r.Context().Value(roundTripStatsKey{}).(*RoundTripStats)
+	if stats == nil {
+		stats = &RoundTripStats{}
+	}
+
+	if noRetries, _ := r.Context().Value(noRetriesKey{}).(bool); noRetries {
+		r.Header.Add(activator.RequestCountHTTPHeader, "1")
+		start := time.Now()
+		resp, err = rrt.transport.RoundTrip(r)
+		stats.Retries, stats.Backoff, stats.TimeToFirstByte = 0, 0, time.Since(start)
+		if err == nil {
+			if resp.Header == nil {
+				resp.Header = make(http.Header)
+			}
+			resp.Header.Add(activator.RequestCountHTTPHeader, "1")
+		}
+		return
+	}
+
 	// The request body cannot be read multiple times for retries.
 	// The workaround is to clone the request body into a byte reader
 	// so the body can be read multiple times.
@@ -82,17 +142,29 @@ func (rrt *retryRoundTripper) RoundTrip(r *http.Request) (resp *http.Response, e
 	}
 
 	attempts := 0
-	wait.ExponentialBackoff(rrt.backoffSettings, func() (bool, error) {
+	// The time of the attempts, to tell it apart from the backoff.
+	var sent time.Duration
+	start := time.Now()
+	wait.ExponentialBackoff(rrt.backoffSettings(), func() (bool, error) {
 		rrt.logger.Debugf("Retrying")
 
 		attempts++
+		ctx, span := trace.StartSpan(r.Context(), "activator_retry")
+		defer span.End()
+		span.AddAttributes(trace.Int64Attribute("attempt", int64(attempts)))
+
 		r.Header.Add(activator.RequestCountHTTPHeader, strconv.Itoa(attempts))
-		resp, err = rrt.transport.RoundTrip(r)
+		attemptStart := time.Now()
+		resp, err = rrt.transport.RoundTrip(r.WithContext(ctx))
+		stats.TimeToFirstByte = time.Since(attemptStart)
+		sent += stats.TimeToFirstByte
 
 		if err != nil {
 			rrt.logger.Errorf("Error making a request: %s", err)
+			span.SetStatus(trace.Status{Code: trace.StatusCodeUnknown, Message: err.Error()})
 			return false, nil
 		}
+		span.AddAttributes(trace.Int64Attribute("http.status_code", int64(resp.StatusCode)))
 
 		for _, retryCond := range rrt.retryConditions {
 			if retryCond(resp) {
@@ -102,6 +174,8 @@ func (rrt *retryRoundTripper) RoundTrip(r *http.Request) (resp *http.Response, e
 		}
 		return true, nil
 	})
+	stats.Retries = attempts - 1
+	stats.Backoff = time.Since(start) - sent
 
 	if err == nil {
 		rrt.logger.Infof("Finished after %d attempt(s). Response code: %d", attempts, resp.StatusCode)
@@ -19,6 +19,7 @@ import (
 	"strconv"
 	"strings"
 	"testing"
+	"time"
 
 	. "github.com/knative/pkg/logging/testing"
 	"github.com/knative/serving/pkg/activator"
@@ -235,3 +236,99 @@ func TestRetryRoundTripperNilBody(t *testing.T) {
 
 	rt.RoundTrip(req)
 }
+
+func TestDynamicRetryRoundTripper(t *testing.T) {
+	attempts := 0
+	transport := RoundTripperFunc(func(r *http.Request) (*http.Response, error) {
+		attempts++
+		return &http.Response{StatusCode: http.StatusServiceUnavailable, Body: ioutil.NopCloser(strings.NewReader(""))}, nil
+	})
+
+	steps := 1
+	rt := NewDynamicRetryRoundTripper(
+		transport,
+		TestLogger(t),
+		func() wait.Backoff { return wait.Backoff{Steps: steps} },
+		RetryStatus(http.StatusServiceUnavailable),
+	)
+
+	for _, s := range []int{1, 3} {
+		steps, attempts = s, 0
+		req, _ := http.NewRequest("GET", "http://knative.dev/test/", nil)
+		rt.RoundTrip(req)
+		if attempts != s {
+			t.Errorf("Attempts = %d, want %d", attempts, s)
+		}
+	}
+}
+
+func TestRetryRoundTripperNoRetries(t *testing.T) {
+	attempts := 0
+	transport := RoundTripperFunc(func(r *http.Request) (*http.Response, error) {
+		attempts++
+		return &http.Response{StatusCode: http.StatusServiceUnavailable, Body: r.Body}, nil
+	})
+
+	rt := NewRetryRoundTripper(
+		transport,
+		TestLogger(t),
+		wait.Backoff{Steps: 3},
+		RetryStatus(http.StatusServiceUnavailable),
+	)
+
+	spy := &spyReadCloser{Reader: strings.NewReader("streamed")}
+	req, _ := http.NewRequest("POST", "http://knative.dev/test/", spy)
+	resp, err := rt.RoundTrip(req.WithContext(WithNoRetries(req.Context())))
+	if err != nil {
+		t.Fatalf("RoundTrip() = %v", err)
+	}
+
+	if attempts != 1 {
+		t.Errorf("Attempts = %d, want 1", attempts)
+	}
+	if got := resp.Header.Get(activator.RequestCountHTTPHeader); got != "1" {
+		t.Errorf("%s = %q, want 1", activator.RequestCountHTTPHeader, got)
+	}
+	// The body is passed through rather than buffered.
+	if resp.Body != spy {
+		t.Error("Expected the request body to be sent as is")
+	}
+}
+
+func TestRetryRoundTripperStats(t *testing.T) {
+	attempts := 0
+	transport := RoundTripperFunc(func(r *http.Request) (*http.Response, error) {
+		attempts++
+		time.Sleep(50 * time.Millisecond)
+		status := http.StatusServiceUnavailable
+		if attempts == 3 {
+			status = http.StatusOK
+		}
+		return &http.Response{StatusCode: status, Body: &spyReadCloser{}}, nil
+	})
+
+	rt := NewRetryRoundTripper(
+		transport,
+		TestLogger(t),
+		wait.Backoff{Duration: 20 * time.Millisecond, Factor: 1, Steps: 5},
+		RetryStatus(http.StatusServiceUnavailable),
+	)
+
+	stats := &RoundTripStats{}
+	req, _ := http.NewRequest("GET", "http://knative.dev/test/", nil)
+	if _, err := rt.RoundTrip(req.WithContext(WithRoundTripStats(req.Context(), stats))); err != nil {
+		t.Fatalf("RoundTrip() = %v", err)
+	}
+
+	if stats.Retries != 2 {
+		t.Errorf("Retries = %d, want 2", stats.Retries)
+	}
+	// Two backoffs of 20ms, not counting the 150ms of the attempts.
+	if stats.Backoff < 40*time.Millisecond || stats.Backoff >= 140*time.Millisecond {
+		t.Errorf("Backoff = %v, want about 40ms", stats.Backoff)
+	}
+	// The last attempt only.
+	if stats.TimeToFirstByte < 50*time.Millisecond || stats.TimeToFirstByte >= 100*time.Millisecond {
+		t.Errorf("TimeToFirstByte = %v, want about 50ms", stats.TimeToFirstByte)
+	}
+}
@@ -37,4 +37,16 @@ const (
 	// IngressLabelKey is the label key attached to underlying network programming
 	// resources to indicate which ClusterIngress triggered their creation.
 	IngressLabelKey = GroupName + "/clusteringress"
+
+	// QueueServingPort is the port the queue-proxy of a Revision's Pods
+	// serves requests on.
+	QueueServingPort = 8012
+
+	// QueueAdminPort is the port of the health checks and lifecycle hooks
+	// of the queue-proxy.
+	QueueAdminPort = 8022
+
+	// QueueMetricsPort is the port the queue-proxy exposes its Prometheus
+	// metrics on.
+	QueueMetricsPort = 9090
 )
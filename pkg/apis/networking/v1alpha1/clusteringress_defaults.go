@@ -65,7 +65,7 @@ func (r *HTTPClusterIngressRuleValue) SetDefaults() {
 
 func (p *HTTPClusterIngressPath) SetDefaults() {
 	// If only one split is specified, we default to 100.
-	if len(p.Splits) == 1 && p.Splits[0].Percent == 0 {
+	if len(p.Splits) == 1 && p.Splits[0].GetBasisPoints() == 0 {
 		p.Splits[0].Percent = 100
 	}
 
@@ -82,4 +82,9 @@ func (p *HTTPClusterIngressPath) SetDefaults() {
 	if p.Retries.PerTryTimeout == nil {
 		p.Retries.PerTryTimeout = &metav1.Duration{Duration: DefaultTimeout}
 	}
+
+	// If no mirror percentage is specified, we default to 100.
+	if p.Mirror != nil && p.Mirror.Percent == 0 {
+		p.Mirror.Percent = 100
+	}
 }
@@ -177,6 +177,13 @@ type HTTPClusterIngressPath struct {
 	// +optional
 	Path string `json:"path,omitempty"`
 
+	// Headers, if specified, restricts this path to requests whose headers
+	// match all of the given conditions, keyed by header name.
+	//
+	// NOTE: This differs from K8s Ingress which doesn't allow header matching.
+	// +optional
+	Headers map[string]HeaderMatch `json:"headers,omitempty"`
+
 	// Splits defines the referenced service endpoints to which the traffic
 	// will be forwarded to.
 	Splits []ClusterIngressBackendSplit `json:"splits"`
@@ -199,6 +206,29 @@ type HTTPClusterIngressPath struct {
 	// NOTE: This differs from K8s Ingress which doesn't allow retry settings.
 	// +optional
 	Retries *HTTPRetry `json:"retries,omitempty"`
+
+	// Mirror, if specified, sends a copy of a percentage of the requests to
+	// the given backend, and discards its responses.
+	//
+	// NOTE: This differs from K8s Ingress which doesn't allow mirroring.
+	// +optional
+	Mirror *ClusterIngressBackendMirror `json:"mirror,omitempty"`
+}
+
+// HeaderMatch describes how to match the value of an HTTP header. Exactly
+// one of its fields must be specified. Values are case-sensitive.
+type HeaderMatch struct {
+	// Exact matches header values equal to the given string.
+	// +optional
+	Exact string `json:"exact,omitempty"`
+
+	// Prefix matches header values starting with the given string.
+	// +optional
+	Prefix string `json:"prefix,omitempty"`
+
+	// Regex matches header values against an ECMAScript style regex.
+	// +optional
+	Regex string `json:"regex,omitempty"`
 }
 
 // ClusterIngressBackend describes all endpoints for a given service and port.
@@ -211,6 +241,23 @@ type ClusterIngressBackendSplit struct {
 	//
 	// NOTE: This differs from K8s Ingress to allow percentage split.
 	Percent int `json:"percent,omitempty"`
+
+	// Specifies the split in hundredths of a percent, a number between 0
+	// and 10000, for splits finer than Percent allows.  This is mutually
+	// exclusive with Percent.
+	BasisPoints int `json:"basisPoints,omitempty"`
+}
+
+// ClusterIngressBackendMirror describes a backend receiving copies of requests.
+type ClusterIngressBackendMirror struct {
+	// Specifies the backend receiving the mirrored requests.
+	ClusterIngressBackend `json:",inline"`
+
+	// Specifies the percentage of requests to mirror, a number between 0
+	// and 100.  If unspecified, we default to 100.  Requests are sampled by
+	// their random request id, so below 100 the percentage is rounded down
+	// to a multiple of 1/256, and at least that.
+	Percent int `json:"percent,omitempty"`
 }
 
 // ClusterIngressBackend describes all endpoints for a given service and port.
@@ -234,6 +281,12 @@ type HTTPRetry struct {
 
 	// Timeout per retry attempt for a given request. format: 1h/1m/1s/1ms. MUST BE >=1ms.
 	PerTryTimeout *metav1.Duration `json:"perTryTimeout"`
+
+	// RetryOn lists the conditions under which a request is retried, using
+	// Envoy's retry policy names, e.g. "5xx" or "connect-failure". If
+	// unspecified, the ingress implementation's default conditions apply.
+	// +optional
+	RetryOn []string `json:"retryOn,omitempty"`
 }
 
 // IngressStatus describe the current state of the ClusterIngress.
@@ -299,6 +352,22 @@ var clusterIngressCondSet = duckv1alpha1.NewLivingConditionSet(
 var _ apis.Validatable = (*ClusterIngress)(nil)
 var _ apis.Defaultable = (*ClusterIngress)(nil)
 
+// GetBasisPoints returns the share of the traffic of the split in basis
+// points, whether it is set as Percent or BasisPoints.
+func (s *ClusterIngressBackendSplit) GetBasisPoints() int {
+	return s.Percent*100 + s.BasisPoints
+}
+
+// SetBasisPoints sets the share of the traffic of the split, as Percent
+// when it is a whole percentage.
+func (s *ClusterIngressBackendSplit) SetBasisPoints(bp int) {
+	if bp%100 == 0 {
+		s.Percent, s.BasisPoints = bp/100, 0
+	} else {
+		s.Percent, s.BasisPoints = 0, bp
+	}
+}
+
 func (ci *ClusterIngress) GetGroupVersionKind() schema.GroupVersionKind {
 	return SchemeGroupVersion.WithKind("ClusterIngress")
 }
@@ -20,8 +20,10 @@ import (
 	"fmt"
 
 	"github.com/knative/pkg/apis"
+	"golang.org/x/net/http/httpguts"
 	"k8s.io/apimachinery/pkg/api/equality"
 	"k8s.io/apimachinery/pkg/util/intstr"
+	"k8s.io/apimachinery/pkg/util/sets"
 )
 
 func (ci *ClusterIngress) Validate() *apis.FieldError {
@@ -84,32 +86,64 @@ func (h HTTPClusterIngressPath) Validate() *apis.FieldError {
 	if len(h.Splits) == 0 {
 		all = all.Also(apis.ErrMissingField("splits"))
 	} else {
-		totalPct := 0
+		totalBP := 0
 		for idx, split := range h.Splits {
 			if err := split.Validate(); err != nil {
 				return err.ViaFieldIndex("splits", idx)
 			}
-			totalPct += split.Percent
+			totalBP += split.GetBasisPoints()
 		}
 		// If a single split is provided we allow missing Percent, and
 		// interpret as 100%.
-		if len(h.Splits) == 1 && totalPct == 0 {
-			totalPct = 100
+		if len(h.Splits) == 1 && totalBP == 0 {
+			totalBP = 100 * 100
 		}
 		// Total traffic split percentage must sum up to 100%.
-		if totalPct != 100 {
+		if totalBP != 100*100 {
 			all = all.Also(&apis.FieldError{
 				Message: "Traffic split percentage must total to 100",
 				Paths:   []string{"splits"},
 			})
 		}
 	}
+	for name, match := range h.Headers {
+		if !httpguts.ValidHeaderFieldName(name) {
+			all = all.Also(apis.ErrInvalidKeyName(name, "headers"))
+		}
+		all = all.Also(match.Validate().ViaFieldKey("headers", name))
+	}
 	if h.Retries != nil {
 		all = all.Also(h.Retries.Validate().ViaField("retries"))
 	}
+	if h.Mirror != nil {
+		all = all.Also(h.Mirror.Validate().ViaField("mirror"))
+	}
 	return all
 }
 
+// Validate inspects the fields of the type HeaderMatch
+// to determine if they are valid.
+func (m HeaderMatch) Validate() *apis.FieldError {
+	set := []string{}
+	if m.Exact != "" {
+		set = append(set, "exact")
+	}
+	if m.Prefix != "" {
+		set = append(set, "prefix")
+	}
+	if m.Regex != "" {
+		set = append(set, "regex")
+	}
+	switch len(set) {
+	case 0:
+		return apis.ErrMissingOneOf("exact", "prefix", "regex")
+	case 1:
+		return nil
+	default:
+		return apis.ErrMultipleOneOf(set...)
+	}
+}
+
 func (s ClusterIngressBackendSplit) Validate() *apis.FieldError {
 	// Must not be empty.
 	if equality.Semantic.DeepEqual(s, ClusterIngressBackendSplit{}) {
@@ -120,9 +154,27 @@ func (s ClusterIngressBackendSplit) Validate() *apis.FieldError {
 	if s.Percent < 0 || s.Percent > 100 {
 		all = all.Also(apis.ErrInvalidValue(fmt.Sprintf("%d", s.Percent), "percent"))
 	}
+	// BasisPoints must be between 0 and 10000, and not set along Percent.
+	if s.BasisPoints < 0 || s.BasisPoints > 100*100 {
+		all = all.Also(apis.ErrInvalidValue(fmt.Sprintf("%d", s.BasisPoints), "basisPoints"))
+	}
+	if s.Percent != 0 && s.BasisPoints != 0 {
+		all = all.Also(apis.ErrMultipleOneOf("percent", "basisPoints"))
+	}
 	return all.Also(s.ClusterIngressBackend.Validate())
 }
 
+// Validate inspects the fields of the type ClusterIngressBackendMirror
+// to determine if they are valid.
+func (m ClusterIngressBackendMirror) Validate() *apis.FieldError {
+	var all *apis.FieldError = nil
+	// Percent must be between 0 and 100.
+	if m.Percent < 0 || m.Percent > 100 {
+		all = all.Also(apis.ErrInvalidValue(fmt.Sprintf("%d", m.Percent), "percent"))
+	}
+	return all.Also(m.ClusterIngressBackend.Validate())
+}
+
 // Validate inspects the fields of the type ClusterIngressBackend
 // to determine if they are valid.
 func (b ClusterIngressBackend) Validate() *apis.FieldError {
@@ -144,11 +196,33 @@ func (b ClusterIngressBackend) Validate() *apis.FieldError {
 }
 
 func (r *HTTPRetry) Validate() *apis.FieldError {
+	var all *apis.FieldError
 	// Attempts must be greater than 0.
 	if r.Attempts < 0 {
-		return apis.ErrInvalidValue(fmt.Sprintf("%d", r.Attempts), "attempts")
+		all = apis.ErrInvalidValue(fmt.Sprintf("%d", r.Attempts), "attempts")
+	}
+	return all.Also(ValidateRetryOn(r.RetryOn))
+}
+
+// retryOnConditions are the supported conditions of HTTPRetry.RetryOn.
+var retryOnConditions = sets.NewString(
+	"5xx",
+	"gateway-error",
+	"connect-failure",
+	"retriable-4xx",
+	"refused-stream",
+	"reset",
+)
+
+// ValidateRetryOn verifies that the given retry conditions are supported.
+func ValidateRetryOn(conditions []string) *apis.FieldError {
+	var all *apis.FieldError
+	for i, c := range conditions {
+		if !retryOnConditions.Has(c) {
+			all = all.Also(apis.ErrInvalidValue(c, fmt.Sprintf("retryOn[%d]", i)))
+		}
 	}
-	return nil
+	return all
 }
 
 func (t *ClusterIngressTLS) Validate() *apis.FieldError {
@@ -247,6 +247,104 @@ func TestIngressSpecValidation(t *testing.T) {
 			Message: "Traffic split percentage must total to 100",
 			Paths:   []string{"rules[0].http.paths[0].splits"},
 		},
+	}, {
+		name: "split-basis-points",
+		cis: &IngressSpec{
+			Rules: []ClusterIngressRule{{
+				Hosts: []string{"example.com"},
+				HTTP: &HTTPClusterIngressRuleValue{
+					Paths: []HTTPClusterIngressPath{{
+						Splits: []ClusterIngressBackendSplit{{
+							ClusterIngressBackend: ClusterIngressBackend{
+								ServiceName:      "revision-000",
+								ServiceNamespace: "default",
+								ServicePort:      intstr.FromInt(8080),
+							},
+							BasisPoints: 9990,
+						}, {
+							ClusterIngressBackend: ClusterIngressBackend{
+								ServiceName:      "revision-001",
+								ServiceNamespace: "default",
+								ServicePort:      intstr.FromInt(8080),
+							},
+							BasisPoints: 10,
+						}},
+					}},
+				},
+			}},
+		},
+		want: nil,
+	}, {
+		name: "split-basis-points-sum-not-100",
+		cis: &IngressSpec{
+			Rules: []ClusterIngressRule{{
+				Hosts: []string{"example.com"},
+				HTTP: &HTTPClusterIngressRuleValue{
+					Paths: []HTTPClusterIngressPath{{
+						Splits: []ClusterIngressBackendSplit{{
+							ClusterIngressBackend: ClusterIngressBackend{
+								ServiceName:      "revision-000",
+								ServiceNamespace: "default",
+								ServicePort:      intstr.FromInt(8080),
+							},
+							Percent: 99,
+						}, {
+							ClusterIngressBackend: ClusterIngressBackend{
+								ServiceName:      "revision-001",
+								ServiceNamespace: "default",
+								ServicePort:      intstr.FromInt(8080),
+							},
+							BasisPoints: 10,
+						}},
+					}},
+				},
+			}},
+		},
+		want: &apis.FieldError{
+			Message: "Traffic split percentage must total to 100",
+			Paths:   []string{"rules[0].http.paths[0].splits"},
+		},
+	}, {
+		name: "split-percent-and-basis-points",
+		cis: &IngressSpec{
+			Rules: []ClusterIngressRule{{
+				Hosts: []string{"example.com"},
+				HTTP: &HTTPClusterIngressRuleValue{
+					Paths: []HTTPClusterIngressPath{{
+						Splits: []ClusterIngressBackendSplit{{
+							ClusterIngressBackend: ClusterIngressBackend{
+								ServiceName:      "revision-000",
+								ServiceNamespace: "default",
+								ServicePort:      intstr.FromInt(8080),
+							},
+							Percent:     50,
+							BasisPoints: 5000,
+						}},
+					}},
+				},
+			}},
+		},
+		want: apis.ErrMultipleOneOf("percent", "basisPoints").ViaField("rules[0].http.paths[0].splits[0]"),
+	}, {
+		name: "wrong-split-basis-points",
+		cis: &IngressSpec{
+			Rules: []ClusterIngressRule{{
+				Hosts: []string{"example.com"},
+				HTTP: &HTTPClusterIngressRuleValue{
+					Paths: []HTTPClusterIngressPath{{
+						Splits: []ClusterIngressBackendSplit{{
+							ClusterIngressBackend: ClusterIngressBackend{
+								ServiceName:      "revision-000",
+								ServiceNamespace: "default",
+								ServicePort:      intstr.FromInt(8080),
+							},
+							BasisPoints: 10001,
+						}},
+					}},
+				},
+			}},
+		},
+		want: apis.ErrInvalidValue("10001", "rules[0].http.paths[0].splits[0].basisPoints"),
 	}, {
 		name: "wrong-retry-attempts",
 		cis: &IngressSpec{
@@ -269,6 +367,178 @@ func TestIngressSpecValidation(t *testing.T) {
 			}},
 		},
 		want: apis.ErrInvalidValue("-1", "rules[0].http.paths[0].retries.attempts"),
+	}, {
+		name: "wrong-retry-on",
+		cis: &IngressSpec{
+			Rules: []ClusterIngressRule{{
+				Hosts: []string{"example.com"},
+				HTTP: &HTTPClusterIngressRuleValue{
+					Paths: []HTTPClusterIngressPath{{
+						Splits: []ClusterIngressBackendSplit{{
+							ClusterIngressBackend: ClusterIngressBackend{
+								ServiceName:      "revision-000",
+								ServiceNamespace: "default",
+								ServicePort:      intstr.FromInt(8080),
+							},
+						}},
+						Retries: &HTTPRetry{
+							Attempts: 3,
+							RetryOn:  []string{"5xx", "never"},
+						},
+					}},
+				},
+			}},
+		},
+		want: apis.ErrInvalidValue("never", "rules[0].http.paths[0].retries.retryOn[1]"),
+	}, {
+		name: "valid-mirror",
+		cis: &IngressSpec{
+			Rules: []ClusterIngressRule{{
+				Hosts: []string{"example.com"},
+				HTTP: &HTTPClusterIngressRuleValue{
+					Paths: []HTTPClusterIngressPath{{
+						Splits: []ClusterIngressBackendSplit{{
+							ClusterIngressBackend: ClusterIngressBackend{
+								ServiceName:      "revision-000",
+								ServiceNamespace: "default",
+								ServicePort:      intstr.FromInt(8080),
+							},
+						}},
+						Mirror: &ClusterIngressBackendMirror{
+							ClusterIngressBackend: ClusterIngressBackend{
+								ServiceName:      "revision-001",
+								ServiceNamespace: "default",
+								ServicePort:      intstr.FromInt(8080),
+							},
+							Percent: 10,
+						},
+					}},
+				},
+			}},
+		},
+		want: nil,
+	}, {
+		name: "wrong-mirror",
+		cis: &IngressSpec{
+			Rules: []ClusterIngressRule{{
+				Hosts: []string{"example.com"},
+				HTTP: &HTTPClusterIngressRuleValue{
+					Paths: []HTTPClusterIngressPath{{
+						Splits: []ClusterIngressBackendSplit{{
+							ClusterIngressBackend: ClusterIngressBackend{
+								ServiceName:      "revision-000",
+								ServiceNamespace: "default",
+								ServicePort:      intstr.FromInt(8080),
+							},
+						}},
+						Mirror: &ClusterIngressBackendMirror{
+							ClusterIngressBackend: ClusterIngressBackend{
+								ServiceName: "revision-001",
+								ServicePort: intstr.FromInt(8080),
+							},
+							Percent: 101,
+						},
+					}},
+				},
+			}},
+		},
+		want: apis.ErrInvalidValue("101", "rules[0].http.paths[0].mirror.percent").Also(
+			apis.ErrMissingField("rules[0].http.paths[0].mirror.serviceNamespace")),
+	}, {
+		name: "valid-headers",
+		cis: &IngressSpec{
+			Rules: []ClusterIngressRule{{
+				Hosts: []string{"example.com"},
+				HTTP: &HTTPClusterIngressRuleValue{
+					Paths: []HTTPClusterIngressPath{{
+						Headers: map[string]HeaderMatch{
+							"X-Canary": {
+								Exact: "true",
+							},
+						},
+						Splits: []ClusterIngressBackendSplit{{
+							ClusterIngressBackend: ClusterIngressBackend{
+								ServiceName:      "revision-000",
+								ServiceNamespace: "default",
+								ServicePort:      intstr.FromInt(8080),
+							},
+						}},
+					}},
+				},
+			}},
+		},
+		want: nil,
+	}, {
+		name: "missing-header-match",
+		cis: &IngressSpec{
+			Rules: []ClusterIngressRule{{
+				Hosts: []string{"example.com"},
+				HTTP: &HTTPClusterIngressRuleValue{
+					Paths: []HTTPClusterIngressPath{{
+						Headers: map[string]HeaderMatch{
+							"X-Canary": {},
+						},
+						Splits: []ClusterIngressBackendSplit{{
+							ClusterIngressBackend: ClusterIngressBackend{
+								ServiceName:      "revision-000",
+								ServiceNamespace: "default",
+								ServicePort:      intstr.FromInt(8080),
+							},
+						}},
+					}},
+				},
+			}},
+		},
+		want: apis.ErrMissingOneOf("exact", "prefix", "regex").ViaField("rules[0].http.paths[0].headers[X-Canary]"),
+	}, {
+		name: "multiple-header-matches",
+		cis: &IngressSpec{
+			Rules: []ClusterIngressRule{{
+				Hosts: []string{"example.com"},
+				HTTP: &HTTPClusterIngressRuleValue{
+					Paths: []HTTPClusterIngressPath{{
+						Headers: map[string]HeaderMatch{
+							"X-Canary": {
+								Exact:  "true",
+								Prefix: "t",
+							},
+						},
+						Splits: []ClusterIngressBackendSplit{{
+							ClusterIngressBackend: ClusterIngressBackend{
+								ServiceName:      "revision-000",
+								ServiceNamespace: "default",
+								ServicePort:      intstr.FromInt(8080),
+							},
+						}},
+					}},
+				},
+			}},
+		},
+		want: apis.ErrMultipleOneOf("exact", "prefix").ViaField("rules[0].http.paths[0].headers[X-Canary]"),
+	}, {
+		name: "invalid-header-name",
+		cis: &IngressSpec{
+			Rules: []ClusterIngressRule{{
+				Hosts: []string{"example.com"},
+				HTTP: &HTTPClusterIngressRuleValue{
+					Paths: []HTTPClusterIngressPath{{
+						Headers: map[string]HeaderMatch{
+							"X Canary": {
+								Exact: "true",
+							},
+						},
+						Splits: []ClusterIngressBackendSplit{{
+							ClusterIngressBackend: ClusterIngressBackend{
+								ServiceName:      "revision-000",
+								ServiceNamespace: "default",
+								ServicePort:      intstr.FromInt(8080),
+							},
+						}},
+					}},
+				},
+			}},
+		},
+		want: apis.ErrInvalidKeyName("X Canary", "rules[0].http.paths[0].headers"),
 	}, {
 		name: "empty-tls",
 		cis: &IngressSpec{
@@ -72,6 +72,23 @@ func (in *ClusterIngressBackend) DeepCopy() *ClusterIngressBackend {
 	return out
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ClusterIngressBackendMirror) DeepCopyInto(out *ClusterIngressBackendMirror) {
+	*out = *in
+	out.ClusterIngressBackend = in.ClusterIngressBackend
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ClusterIngressBackendMirror.
+func (in *ClusterIngressBackendMirror) DeepCopy() *ClusterIngressBackendMirror {
+	if in == nil {
+		return nil
+	}
+	out := new(ClusterIngressBackendMirror)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *ClusterIngressBackendSplit) DeepCopyInto(out *ClusterIngressBackendSplit) {
 	*out = *in
@@ -177,6 +194,13 @@ func (in *ClusterIngressTLS) DeepCopy() *ClusterIngressTLS {
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *HTTPClusterIngressPath) DeepCopyInto(out *HTTPClusterIngressPath) {
 	*out = *in
+	if in.Headers != nil {
+		in, out := &in.Headers, &out.Headers
+		*out = make(map[string]HeaderMatch, len(*in))
+		for key, val := range *in {
+			(*out)[key] = val
+		}
+	}
 	if in.Splits != nil {
 		in, out := &in.Splits, &out.Splits
 		*out = make([]ClusterIngressBackendSplit, len(*in))
@@ -207,6 +231,15 @@ func (in *HTTPClusterIngressPath) DeepCopyInto(out *HTTPClusterIngressPath) {
 			(*in).DeepCopyInto(*out)
 		}
 	}
+	if in.Mirror != nil {
+		in, out := &in.Mirror, &out.Mirror
+		if *in == nil {
+			*out = nil
+		} else {
+			*out = new(ClusterIngressBackendMirror)
+			**out = **in
+		}
+	}
 	return
 }
 
@@ -255,6 +288,11 @@ func (in *HTTPRetry) DeepCopyInto(out *HTTPRetry) {
 			**out = **in
 		}
 	}
+	if in.RetryOn != nil {
+		in, out := &in.RetryOn, &out.RetryOn
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
 	return
 }
 
@@ -268,6 +306,22 @@ func (in *HTTPRetry) DeepCopy() *HTTPRetry {
 	return out
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *HeaderMatch) DeepCopyInto(out *HeaderMatch) {
+	*out = *in
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new HeaderMatch.
+func (in *HeaderMatch) DeepCopy() *HeaderMatch {
+	if in == nil {
+		return nil
+	}
+	out := new(HeaderMatch)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *IngressSpec) DeepCopyInto(out *IngressSpec) {
 	*out = *in
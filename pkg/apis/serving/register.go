@@ -60,4 +60,46 @@ const (
 	// BuildHashLabelKey is the label key attached to a Build indicating the
 	// hash of the spec from which they were created.
 	BuildHashLabelKey = GroupName + "/buildHash"
+
+	// DrainTimeoutAnnotationKey is the annotation to specify for how long at
+	// most the queue-proxy of a terminating Pod should wait for traffic to go
+	// away before it stops accepting requests. For example,
+	//   serving.knative.dev/drainTimeout: "45s"
+	DrainTimeoutAnnotationKey = GroupName + "/drainTimeout"
+
+	// DrainSettlePeriodAnnotationKey is the annotation to specify for how
+	// long no request must have arrived, with none in flight, for the drain
+	// of a terminating Pod to end before its timeout. For example,
+	//   serving.knative.dev/drainSettlePeriod: "2s"
+	DrainSettlePeriodAnnotationKey = GroupName + "/drainSettlePeriod"
+
+	// MaxUpgradedConnectionsAnnotationKey is the annotation to specify how
+	// many upgraded connections (e.g. WebSockets) a single Pod of a Revision
+	// may hold open at the same time. Zero, the default, means no limit.
+	MaxUpgradedConnectionsAnnotationKey = GroupName + "/maxUpgradedConnections"
+
+	// UpgradedConnectionWeightAnnotationKey is the annotation to specify how
+	// much an upgraded connection counts towards the concurrency reported to
+	// the autoscaler, as a value between 0 and 1. For example,
+	//   serving.knative.dev/upgradedConnectionWeight: "0.25"
+	UpgradedConnectionWeightAnnotationKey = GroupName + "/upgradedConnectionWeight"
+
+	// ActivationTimeoutAnnotationKey is the annotation to specify for how
+	// long at most the activator should wait for a Revision to become ready,
+	// overriding the activation-timeout of the activator config. For example,
+	//   serving.knative.dev/activationTimeout: "2m"
+	ActivationTimeoutAnnotationKey = GroupName + "/activationTimeout"
+
+	// MaxUploadBytesAnnotationKey is the annotation to specify the largest
+	// request body the activator accepts for a Revision, overriding the
+	// max-upload-bytes of the activator config.
+	MaxUploadBytesAnnotationKey = GroupName + "/maxUploadBytes"
+
+	// SessionAffinityAnnotationKey is the annotation to specify which key
+	// pins the requests to a Revision to the same Pod: a header, a cookie
+	// (with an optional TTL) or the source IP. For example,
+	//   serving.knative.dev/sessionAffinity: "header:X-User-Id"
+	//   serving.knative.dev/sessionAffinity: "cookie:session:1h"
+	//   serving.knative.dev/sessionAffinity: "sourceIP"
+	SessionAffinityAnnotationKey = GroupName + "/sessionAffinity"
 )
@@ -20,9 +20,12 @@ import (
 	"fmt"
 	"strconv"
 	"strings"
+	"time"
 
 	"github.com/knative/pkg/apis"
 	"github.com/knative/serving/pkg/apis/autoscaling"
+	networkingv1alpha1 "github.com/knative/serving/pkg/apis/networking/v1alpha1"
+	"github.com/knative/serving/pkg/apis/serving"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 )
 
@@ -45,13 +48,30 @@ func ValidateObjectMetadata(meta metav1.Object) *apis.FieldError {
 		}
 	}
 
-	if err := validateScaleBoundsAnnotations(meta.GetAnnotations()); err != nil {
+	if err := validateAnnotations(meta.GetAnnotations()); err != nil {
 		return err.ViaField("annotations")
 	}
 
 	return nil
 }
 
+// validateAnnotations runs the annotation validators in turn and returns
+// the first error found.
+func validateAnnotations(annotations map[string]string) *apis.FieldError {
+	for _, validate := range []func(map[string]string) *apis.FieldError{
+		validateScaleBoundsAnnotations,
+		validateDrainAnnotations,
+		validateUpgradedConnectionAnnotations,
+		validateActivatorAnnotations,
+		validateSessionAffinityAnnotation,
+	} {
+		if err := validate(annotations); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
 func getIntGT0(m map[string]string, k string) (int64, *apis.FieldError) {
 	v, ok := m[k]
 	if ok {
@@ -90,3 +110,78 @@ func validateScaleBoundsAnnotations(annotations map[string]string) *apis.FieldEr
 
 	return nil
 }
+
+func validateDrainAnnotations(annotations map[string]string) *apis.FieldError {
+	for _, k := range []string{serving.DrainTimeoutAnnotationKey, serving.DrainSettlePeriodAnnotationKey} {
+		v, ok := annotations[k]
+		if !ok {
+			continue
+		}
+		if d, err := time.ParseDuration(v); err != nil || d < 0 || d > networkingv1alpha1.DefaultTimeout {
+			return &apis.FieldError{
+				Message: fmt.Sprintf("Invalid %s annotation value: must be a duration between 0s and %s",
+					k, networkingv1alpha1.DefaultTimeout),
+				Paths: []string{k},
+			}
+		}
+	}
+	return nil
+}
+
+func validateUpgradedConnectionAnnotations(annotations map[string]string) *apis.FieldError {
+	if v, ok := annotations[serving.MaxUpgradedConnectionsAnnotationKey]; ok {
+		if i, err := strconv.ParseInt(v, 10, 32); err != nil || i < 0 {
+			return &apis.FieldError{
+				Message: fmt.Sprintf("Invalid %s annotation value: must be an integer greater than or equal to 0",
+					serving.MaxUpgradedConnectionsAnnotationKey),
+				Paths: []string{serving.MaxUpgradedConnectionsAnnotationKey},
+			}
+		}
+	}
+	if v, ok := annotations[serving.UpgradedConnectionWeightAnnotationKey]; ok {
+		if f, err := strconv.ParseFloat(v, 64); err != nil || f < 0 || f > 1 {
+			return &apis.FieldError{
+				Message: fmt.Sprintf("Invalid %s annotation value: must be a number between 0 and 1",
+					serving.UpgradedConnectionWeightAnnotationKey),
+				Paths: []string{serving.UpgradedConnectionWeightAnnotationKey},
+			}
+		}
+	}
+	return nil
+}
+
+func validateActivatorAnnotations(annotations map[string]string) *apis.FieldError {
+	if v, ok := annotations[serving.ActivationTimeoutAnnotationKey]; ok {
+		if d, err := time.ParseDuration(v); err != nil || d <= 0 || d > networkingv1alpha1.DefaultTimeout {
+			return &apis.FieldError{
+				Message: fmt.Sprintf("Invalid %s annotation value: must be a duration greater than 0s and at most %s",
+					serving.ActivationTimeoutAnnotationKey, networkingv1alpha1.DefaultTimeout),
+				Paths: []string{serving.ActivationTimeoutAnnotationKey},
+			}
+		}
+	}
+	if v, ok := annotations[serving.MaxUploadBytesAnnotationKey]; ok {
+		if i, err := strconv.ParseInt(v, 10, 64); err != nil || i <= 0 {
+			return &apis.FieldError{
+				Message: fmt.Sprintf("Invalid %s annotation value: must be an integer greater than 0",
+					serving.MaxUploadBytesAnnotationKey),
+				Paths: []string{serving.MaxUploadBytesAnnotationKey},
+			}
+		}
+	}
+	return nil
+}
+
+func validateSessionAffinityAnnotation(annotations map[string]string) *apis.FieldError {
+	v, ok := annotations[serving.SessionAffinityAnnotationKey]
+	if !ok {
+		return nil
+	}
+	if _, err := ParseSessionAffinity(v); err != nil {
+		return &apis.FieldError{
+			Message: fmt.Sprintf("Invalid %s annotation value: %v", serving.SessionAffinityAnnotationKey, err),
+			Paths:   []string{serving.SessionAffinityAnnotationKey},
+		}
+	}
+	return nil
+}
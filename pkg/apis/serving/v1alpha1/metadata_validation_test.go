@@ -23,9 +23,30 @@ import (
 
 	"github.com/knative/pkg/apis"
 	"github.com/knative/serving/pkg/apis/autoscaling"
+	"github.com/knative/serving/pkg/apis/serving"
 )
 
-func TestValidateScaleBoundAnnotations(t *testing.T) {
+func TestValidateAnnotations(t *testing.T) {
+	invalid := &apis.FieldError{
+		Message: fmt.Sprintf("Invalid %s annotation value: must be a duration between 0s and 5m0s", serving.DrainTimeoutAnnotationKey),
+		Paths:   []string{serving.DrainTimeoutAnnotationKey},
+	}
+	invalidMax := &apis.FieldError{
+		Message: fmt.Sprintf("Invalid %s annotation value: must be an integer greater than or equal to 0", serving.MaxUpgradedConnectionsAnnotationKey),
+		Paths:   []string{serving.MaxUpgradedConnectionsAnnotationKey},
+	}
+	invalidWeight := &apis.FieldError{
+		Message: fmt.Sprintf("Invalid %s annotation value: must be a number between 0 and 1", serving.UpgradedConnectionWeightAnnotationKey),
+		Paths:   []string{serving.UpgradedConnectionWeightAnnotationKey},
+	}
+	invalidTimeout := &apis.FieldError{
+		Message: fmt.Sprintf("Invalid %s annotation value: must be a duration greater than 0s and at most 5m0s", serving.ActivationTimeoutAnnotationKey),
+		Paths:   []string{serving.ActivationTimeoutAnnotationKey},
+	}
+	invalidMaxUpload := &apis.FieldError{
+		Message: fmt.Sprintf("Invalid %s annotation value: must be an integer greater than 0", serving.MaxUploadBytesAnnotationKey),
+		Paths:   []string{serving.MaxUploadBytesAnnotationKey},
+	}
 	cases := []struct {
 		name        string
 		annotations map[string]string
@@ -85,11 +106,131 @@ func TestValidateScaleBoundAnnotations(t *testing.T) {
 			Message: fmt.Sprintf("%s=%v is less than %s=%v", autoscaling.MaxScaleAnnotationKey, 2, autoscaling.MinScaleAnnotationKey, 5),
 			Paths:   []string{autoscaling.MaxScaleAnnotationKey, autoscaling.MinScaleAnnotationKey},
 		},
+	}, {
+		name:        "no drain timeout",
+		annotations: map[string]string{autoscaling.MinScaleAnnotationKey: "1"},
+		expectErr:   nil,
+	}, {
+		name:        "drain timeout is 0s",
+		annotations: map[string]string{serving.DrainTimeoutAnnotationKey: "0s"},
+		expectErr:   nil,
+	}, {
+		name:        "drain timeout is 45s",
+		annotations: map[string]string{serving.DrainTimeoutAnnotationKey: "45s"},
+		expectErr:   nil,
+	}, {
+		name:        "drain timeout is the maximum",
+		annotations: map[string]string{serving.DrainTimeoutAnnotationKey: "5m"},
+		expectErr:   nil,
+	}, {
+		name:        "drain timeout is too long",
+		annotations: map[string]string{serving.DrainTimeoutAnnotationKey: "5m1s"},
+		expectErr:   invalid,
+	}, {
+		name:        "drain timeout is negative",
+		annotations: map[string]string{serving.DrainTimeoutAnnotationKey: "-1s"},
+		expectErr:   invalid,
+	}, {
+		name:        "drain timeout has no unit",
+		annotations: map[string]string{serving.DrainTimeoutAnnotationKey: "45"},
+		expectErr:   invalid,
+	}, {
+		name:        "settle period is 2s",
+		annotations: map[string]string{serving.DrainSettlePeriodAnnotationKey: "2s"},
+		expectErr:   nil,
+	}, {
+		name:        "settle period is negative",
+		annotations: map[string]string{serving.DrainSettlePeriodAnnotationKey: "-1s"},
+		expectErr: &apis.FieldError{
+			Message: fmt.Sprintf("Invalid %s annotation value: must be a duration between 0s and 5m0s", serving.DrainSettlePeriodAnnotationKey),
+			Paths:   []string{serving.DrainSettlePeriodAnnotationKey},
+		},
+	}, {
+		name: "valid limit and weight",
+		annotations: map[string]string{
+			serving.MaxUpgradedConnectionsAnnotationKey:   "100",
+			serving.UpgradedConnectionWeightAnnotationKey: "0.25",
+		},
+		expectErr: nil,
+	}, {
+		name:        "no limit",
+		annotations: map[string]string{serving.MaxUpgradedConnectionsAnnotationKey: "0"},
+		expectErr:   nil,
+	}, {
+		name:        "negative limit",
+		annotations: map[string]string{serving.MaxUpgradedConnectionsAnnotationKey: "-1"},
+		expectErr:   invalidMax,
+	}, {
+		name:        "limit is not an integer",
+		annotations: map[string]string{serving.MaxUpgradedConnectionsAnnotationKey: "1.5"},
+		expectErr:   invalidMax,
+	}, {
+		name:        "zero weight",
+		annotations: map[string]string{serving.UpgradedConnectionWeightAnnotationKey: "0"},
+		expectErr:   nil,
+	}, {
+		name:        "full weight",
+		annotations: map[string]string{serving.UpgradedConnectionWeightAnnotationKey: "1"},
+		expectErr:   nil,
+	}, {
+		name:        "weight above 1",
+		annotations: map[string]string{serving.UpgradedConnectionWeightAnnotationKey: "1.5"},
+		expectErr:   invalidWeight,
+	}, {
+		name:        "negative weight",
+		annotations: map[string]string{serving.UpgradedConnectionWeightAnnotationKey: "-0.5"},
+		expectErr:   invalidWeight,
+	}, {
+		name:        "weight is not a number",
+		annotations: map[string]string{serving.UpgradedConnectionWeightAnnotationKey: "half"},
+		expectErr:   invalidWeight,
+	}, {
+		name: "valid timeout and max upload",
+		annotations: map[string]string{
+			serving.ActivationTimeoutAnnotationKey: "2m",
+			serving.MaxUploadBytesAnnotationKey:    "100000000",
+		},
+		expectErr: nil,
+	}, {
+		name:        "maximum timeout",
+		annotations: map[string]string{serving.ActivationTimeoutAnnotationKey: "5m"},
+		expectErr:   nil,
+	}, {
+		name:        "timeout too long",
+		annotations: map[string]string{serving.ActivationTimeoutAnnotationKey: "5m1s"},
+		expectErr:   invalidTimeout,
+	}, {
+		name:        "zero timeout",
+		annotations: map[string]string{serving.ActivationTimeoutAnnotationKey: "0s"},
+		expectErr:   invalidTimeout,
+	}, {
+		name:        "timeout is not a duration",
+		annotations: map[string]string{serving.ActivationTimeoutAnnotationKey: "60"},
+		expectErr:   invalidTimeout,
+	}, {
+		name:        "zero max upload",
+		annotations: map[string]string{serving.MaxUploadBytesAnnotationKey: "0"},
+		expectErr:   invalidMaxUpload,
+	}, {
+		name:        "max upload is not an integer",
+		annotations: map[string]string{serving.MaxUploadBytesAnnotationKey: "32MB"},
+		expectErr:   invalidMaxUpload,
+	}, {
+		name:        "valid cookie",
+		annotations: map[string]string{serving.SessionAffinityAnnotationKey: "cookie:session:30m"},
+		expectErr:   nil,
+	}, {
+		name:        "unknown kind",
+		annotations: map[string]string{serving.SessionAffinityAnnotationKey: "query:user"},
+		expectErr: &apis.FieldError{
+			Message: fmt.Sprintf("Invalid %s annotation value: unknown session affinity %q", serving.SessionAffinityAnnotationKey, "query"),
+			Paths:   []string{serving.SessionAffinityAnnotationKey},
+		},
 	}}
 
 	for _, c := range cases {
 		t.Run(c.name, func(t *testing.T) {
-			err := validateScaleBoundsAnnotations(c.annotations)
+			err := validateAnnotations(c.annotations)
 			if !reflect.DeepEqual(c.expectErr, err) {
 				t.Errorf("Expected: '%+v', Got: '%+v'", c.expectErr, err)
 			}
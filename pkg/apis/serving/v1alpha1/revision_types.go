@@ -19,12 +19,14 @@ package v1alpha1
 import (
 	"fmt"
 	"strconv"
+	"strings"
 	"time"
 
 	"github.com/knative/pkg/apis"
 	duckv1alpha1 "github.com/knative/pkg/apis/duck/v1alpha1"
 	"github.com/knative/pkg/kmeta"
 	"github.com/knative/serving/pkg/apis/serving"
+	"golang.org/x/net/http/httpguts"
 	corev1 "k8s.io/api/core/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/runtime/schema"
@@ -459,3 +461,68 @@ func (r *Revision) GetConfigurationGeneration() (int64, error) {
 
 	return gen, nil
 }
+
+// SessionAffinity is the key that pins the requests to a Revision to the
+// same Pod, as set by its SessionAffinityAnnotationKey annotation.
+// +k8s:deepcopy-gen=false
+type SessionAffinity struct {
+	// Header is the name of the header whose value is the key.
+	Header string
+	// Cookie is the name of the cookie whose value is the key. The cookie
+	// is generated if the request doesn't have it, and expires after
+	// CookieTTL, or with the session if CookieTTL is zero.
+	Cookie    string
+	CookieTTL time.Duration
+	// SourceIP makes the IP address of the client the key.
+	SourceIP bool
+}
+
+// ParseSessionAffinity parses the value of a SessionAffinityAnnotationKey
+// annotation, one of "header:<name>", "cookie:<name>[:<ttl>]" or "sourceIP".
+func ParseSessionAffinity(s string) (*SessionAffinity, error) {
+	kind, arg := s, ""
+	if i := strings.Index(s, ":"); i >= 0 {
+		kind, arg = s[:i], s[i+1:]
+	}
+	switch kind {
+	case "header":
+		if !httpguts.ValidHeaderFieldName(arg) {
+			return nil, fmt.Errorf("invalid header name %q", arg)
+		}
+		return &SessionAffinity{Header: arg}, nil
+	case "cookie":
+		name, ttl := arg, ""
+		if i := strings.Index(arg, ":"); i >= 0 {
+			name, ttl = arg[:i], arg[i+1:]
+		}
+		// Cookie names are tokens, like header names.
+		if !httpguts.ValidHeaderFieldName(name) {
+			return nil, fmt.Errorf("invalid cookie name %q", name)
+		}
+		sa := &SessionAffinity{Cookie: name}
+		if ttl != "" {
+			d, err := time.ParseDuration(ttl)
+			if err != nil || d < 0 {
+				return nil, fmt.Errorf("invalid cookie TTL %q", ttl)
+			}
+			sa.CookieTTL = d
+		}
+		return sa, nil
+	case "sourceIP":
+		if arg != "" {
+			return nil, fmt.Errorf("unexpected argument %q", arg)
+		}
+		return &SessionAffinity{SourceIP: true}, nil
+	}
+	return nil, fmt.Errorf("unknown session affinity %q", kind)
+}
+
+// GetSessionAffinity returns the session affinity of the Revision, or nil
+// if its requests may go to any Pod.
+func (r *Revision) GetSessionAffinity() (*SessionAffinity, error) {
+	v, ok := r.Annotations[serving.SessionAffinityAnnotationKey]
+	if !ok {
+		return nil, nil
+	}
+	return ParseSessionAffinity(v)
+}
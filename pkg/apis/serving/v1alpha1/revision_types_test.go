@@ -790,3 +790,68 @@ func TestRevisionAnnotations(t *testing.T) {
 		})
 	}
 }
+
+func TestRevisionGetSessionAffinity(t *testing.T) {
+	cases := []struct {
+		name        string
+		annotations map[string]string
+		want        *SessionAffinity
+		wantErr     bool
+	}{{
+		name:        "nil annotations",
+		annotations: nil,
+	}, {
+		name:        "header",
+		annotations: map[string]string{serving.SessionAffinityAnnotationKey: "header:X-User-Id"},
+		want:        &SessionAffinity{Header: "X-User-Id"},
+	}, {
+		name:        "session cookie",
+		annotations: map[string]string{serving.SessionAffinityAnnotationKey: "cookie:session"},
+		want:        &SessionAffinity{Cookie: "session"},
+	}, {
+		name:        "cookie with ttl",
+		annotations: map[string]string{serving.SessionAffinityAnnotationKey: "cookie:session:1h"},
+		want:        &SessionAffinity{Cookie: "session", CookieTTL: time.Hour},
+	}, {
+		name:        "source ip",
+		annotations: map[string]string{serving.SessionAffinityAnnotationKey: "sourceIP"},
+		want:        &SessionAffinity{SourceIP: true},
+	}, {
+		name:        "missing header name",
+		annotations: map[string]string{serving.SessionAffinityAnnotationKey: "header:"},
+		wantErr:     true,
+	}, {
+		name:        "invalid header name",
+		annotations: map[string]string{serving.SessionAffinityAnnotationKey: "header:X User"},
+		wantErr:     true,
+	}, {
+		name:        "invalid cookie ttl",
+		annotations: map[string]string{serving.SessionAffinityAnnotationKey: "cookie:session:-1h"},
+		wantErr:     true,
+	}, {
+		name:        "source ip with argument",
+		annotations: map[string]string{serving.SessionAffinityAnnotationKey: "sourceIP:x"},
+		wantErr:     true,
+	}, {
+		name:        "unknown kind",
+		annotations: map[string]string{serving.SessionAffinityAnnotationKey: "query:user"},
+		wantErr:     true,
+	}}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			rev := Revision{
+				ObjectMeta: metav1.ObjectMeta{
+					Annotations: tc.annotations,
+				},
+			}
+			got, err := rev.GetSessionAffinity()
+			if (err != nil) != tc.wantErr {
+				t.Fatalf("GetSessionAffinity() = %v, wanted error %v", err, tc.wantErr)
+			}
+			if diff := cmp.Diff(tc.want, got); diff != "" {
+				t.Errorf("GetSessionAffinity() (-want, +got) = %v", diff)
+			}
+		})
+	}
+}
@@ -17,11 +17,13 @@ limitations under the License.
 package v1alpha1
 
 import (
+	"fmt"
 	"strconv"
 	"time"
 
 	"github.com/knative/pkg/apis"
 	"github.com/knative/pkg/kmp"
+	"github.com/knative/serving/pkg/apis/networking"
 	networkingv1alpha1 "github.com/knative/serving/pkg/apis/networking/v1alpha1"
 	corev1 "k8s.io/api/core/v1"
 	"k8s.io/apimachinery/pkg/api/equality"
@@ -128,9 +130,6 @@ func validateContainer(container corev1.Container) *apis.FieldError {
 	if container.Name != "" {
 		ignoredFields = append(ignoredFields, "name")
 	}
-	if len(container.Ports) > 0 {
-		ignoredFields = append(ignoredFields, "ports")
-	}
 	if len(container.VolumeMounts) > 0 {
 		ignoredFields = append(ignoredFields, "volumeMounts")
 	}
@@ -142,6 +141,10 @@ func validateContainer(container corev1.Container) *apis.FieldError {
 		// Complain about all ignored fields so that user can remove them all at once.
 		errs = errs.Also(apis.ErrDisallowedFields(ignoredFields...))
 	}
+	// Validate the port the user container listens on, if any.
+	if err := validateContainerPorts(container.Ports).ViaField("ports"); err != nil {
+		errs = errs.Also(err)
+	}
 	// Validate our probes
 	if err := validateProbe(container.ReadinessProbe).ViaField("readinessProbe"); err != nil {
 		errs = errs.Also(err)
@@ -152,6 +155,58 @@ func validateContainer(container corev1.Container) *apis.FieldError {
 	return errs
 }
 
+// reservedPorts are the ports used by the other containers of a Revision's
+// Pods: the queue-proxy's serving, admin and metrics ports.
+var reservedPorts = map[int32]bool{
+	networking.QueueServingPort: true,
+	networking.QueueAdminPort:   true,
+	networking.QueueMetricsPort: true,
+}
+
+func validateContainerPorts(ports []corev1.ContainerPort) *apis.FieldError {
+	if len(ports) == 0 {
+		return nil
+	}
+	if len(ports) > 1 {
+		return &apis.FieldError{
+			Message: "More than one container port is set",
+			Paths:   []string{apis.CurrentField},
+			Details: "Only a single port is allowed",
+		}
+	}
+
+	port := ports[0]
+	// The name of the port is set by Knative Serving controller, and the
+	// port is only exposed to the queue-proxy.
+	var disallowedFields []string
+	if port.Name != "" {
+		disallowedFields = append(disallowedFields, "name")
+	}
+	if port.HostIP != "" {
+		disallowedFields = append(disallowedFields, "hostIP")
+	}
+	if port.HostPort != 0 {
+		disallowedFields = append(disallowedFields, "hostPort")
+	}
+	var errs *apis.FieldError
+	if len(disallowedFields) > 0 {
+		errs = errs.Also(apis.ErrDisallowedFields(disallowedFields...))
+	}
+
+	if port.Protocol != "" && port.Protocol != corev1.ProtocolTCP {
+		errs = errs.Also(apis.ErrInvalidValue(string(port.Protocol), "protocol"))
+	}
+	if port.ContainerPort < 1 || port.ContainerPort > 65535 {
+		errs = errs.Also(apis.ErrOutOfBoundsValue(strconv.Itoa(int(port.ContainerPort)), "1", "65535", "containerPort"))
+	} else if reservedPorts[port.ContainerPort] {
+		errs = errs.Also(&apis.FieldError{
+			Message: fmt.Sprintf("Port %d is reserved", port.ContainerPort),
+			Paths:   []string{"containerPort"},
+		})
+	}
+	return errs.ViaIndex(0)
+}
+
 func validateBuildRef(buildRef *corev1.ObjectReference) *apis.FieldError {
 	if buildRef == nil {
 		return nil
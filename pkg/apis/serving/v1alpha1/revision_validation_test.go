@@ -66,14 +66,100 @@ func TestContainerValidation(t *testing.T) {
 		},
 		want: nil,
 	}, {
-		name: "has ports",
+		name: "has a port",
 		c: corev1.Container{
+			Image: "foo",
+			Ports: []corev1.ContainerPort{{
+				ContainerPort: 8888,
+			}},
+		},
+		want: nil,
+	}, {
+		name: "has a TCP port",
+		c: corev1.Container{
+			Image: "foo",
+			Ports: []corev1.ContainerPort{{
+				ContainerPort: 8888,
+				Protocol:      corev1.ProtocolTCP,
+			}},
+		},
+		want: nil,
+	}, {
+		name: "has multiple ports",
+		c: corev1.Container{
+			Image: "foo",
+			Ports: []corev1.ContainerPort{{
+				ContainerPort: 8888,
+			}, {
+				ContainerPort: 8889,
+			}},
+		},
+		want: &apis.FieldError{
+			Message: "More than one container port is set",
+			Paths:   []string{"ports"},
+			Details: "Only a single port is allowed",
+		},
+	}, {
+		name: "has a named port",
+		c: corev1.Container{
+			Image: "foo",
 			Ports: []corev1.ContainerPort{{
 				Name:          "http",
 				ContainerPort: 8080,
 			}},
 		},
-		want: apis.ErrDisallowedFields("ports"),
+		want: apis.ErrDisallowedFields("ports[0].name"),
+	}, {
+		name: "has a host port",
+		c: corev1.Container{
+			Image: "foo",
+			Ports: []corev1.ContainerPort{{
+				ContainerPort: 8080,
+				HostIP:        "10.0.0.1",
+				HostPort:      80,
+			}},
+		},
+		want: apis.ErrDisallowedFields("ports[0].hostIP", "ports[0].hostPort"),
+	}, {
+		name: "has a UDP port",
+		c: corev1.Container{
+			Image: "foo",
+			Ports: []corev1.ContainerPort{{
+				ContainerPort: 8080,
+				Protocol:      corev1.ProtocolUDP,
+			}},
+		},
+		want: apis.ErrInvalidValue("UDP", "ports[0].protocol"),
+	}, {
+		name: "has an out of range port",
+		c: corev1.Container{
+			Image: "foo",
+			Ports: []corev1.ContainerPort{{
+				ContainerPort: 65536,
+			}},
+		},
+		want: apis.ErrOutOfBoundsValue("65536", "1", "65535", "ports[0].containerPort"),
+	}, {
+		name: "has no port number",
+		c: corev1.Container{
+			Image: "foo",
+			Ports: []corev1.ContainerPort{{
+				Protocol: corev1.ProtocolTCP,
+			}},
+		},
+		want: apis.ErrOutOfBoundsValue("0", "1", "65535", "ports[0].containerPort"),
+	}, {
+		name: "has a reserved port",
+		c: corev1.Container{
+			Image: "foo",
+			Ports: []corev1.ContainerPort{{
+				ContainerPort: 8012,
+			}},
+		},
+		want: &apis.FieldError{
+			Message: "Port 8012 is reserved",
+			Paths:   []string{"ports[0].containerPort"},
+		},
 	}, {
 		name: "has volumeMounts",
 		c: corev1.Container{
@@ -148,7 +234,7 @@ func TestContainerValidation(t *testing.T) {
 			}},
 			Lifecycle: &corev1.Lifecycle{},
 		},
-		want: apis.ErrDisallowedFields("name", "ports", "volumeMounts", "lifecycle"),
+		want: apis.ErrDisallowedFields("name", "ports[0].name", "volumeMounts", "lifecycle"),
 	}}
 
 	for _, test := range tests {
@@ -17,6 +17,8 @@ limitations under the License.
 package v1alpha1
 
 import (
+	"time"
+
 	corev1 "k8s.io/api/core/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/runtime/schema"
@@ -81,9 +83,39 @@ type TrafficTarget struct {
 	// +optional
 	ConfigurationName string `json:"configurationName,omitempty"`
 
+	// LatestRevision may be set to true, instead of RevisionName, to send
+	// this portion of traffic to the latest ready revision of a Service's
+	// configuration. It is only valid in the traffic block of a Service.
+	// +optional
+	LatestRevision *bool `json:"latestRevision,omitempty"`
+
 	// Percent specifies percent of the traffic to this Revision or Configuration.
 	// This defaults to zero if unspecified.
 	Percent int `json:"percent"`
+
+	// BasisPoints specifies the traffic to this Revision or Configuration in
+	// hundredths of a percent, for splits finer than Percent allows, e.g. 10
+	// for 0.1% of the traffic. This is mutually exclusive with Percent.
+	// +optional
+	BasisPoints int `json:"basisPoints,omitempty"`
+
+	// Headers, if specified, sends every request whose headers match all of
+	// the given conditions, keyed by header name, to this target regardless
+	// of Percent. Other requests are still split according to Percent.
+	// +optional
+	Headers map[string]v1alpha1.HeaderMatch `json:"headers,omitempty"`
+
+	// Domain holds the dedicated hostname of a named target. It has the form
+	// {name}.{route.status.domain}
+	// This field is never set in Route's spec, only its status.
+	// +optional
+	Domain string `json:"domain,omitempty"`
+
+	// Address holds the information needed for a named target to be the
+	// target of an event.
+	// This field is never set in Route's spec, only its status.
+	// +optional
+	Address *duckv1alpha1.Addressable `json:"address,omitempty"`
 }
 
 // RouteSpec holds the desired state of the Route (from the client).
@@ -98,6 +130,68 @@ type RouteSpec struct {
 	// Traffic specifies how to distribute traffic over a collection of Knative Serving Revisions and Configurations.
 	// +optional
 	Traffic []TrafficTarget `json:"traffic,omitempty"`
+
+	// Paths specifies how to distribute the traffic of requests whose path
+	// matches one of the given patterns, in order. Requests that match none
+	// of them are distributed according to Traffic.
+	// +optional
+	Paths []PathTraffic `json:"paths,omitempty"`
+
+	// Timeout is the maximum duration to wait for the response to a request
+	// routed by this Route, including retries. It must not be shorter than
+	// the timeoutSeconds of any Revision the Route sends traffic to.
+	// +optional
+	Timeout *metav1.Duration `json:"timeout,omitempty"`
+
+	// Retries is the policy for retrying the failed requests routed by this
+	// Route.
+	// +optional
+	Retries *RetryPolicy `json:"retries,omitempty"`
+
+	// Mirror, if specified, sends a copy of a percentage of the requests
+	// routed by this Route to a Revision, and discards its responses.
+	// +optional
+	Mirror *MirrorTarget `json:"mirror,omitempty"`
+}
+
+// MirrorTarget describes a Revision receiving copies of the requests to a Route.
+type MirrorTarget struct {
+	// RevisionName of the Revision to which to mirror requests.
+	RevisionName string `json:"revisionName"`
+
+	// Percent specifies the percent of requests to mirror, from 1 to 100.
+	// Requests are sampled by their random request id, so below 100 the
+	// percentage is rounded down to a multiple of 1/256, and at least that.
+	Percent int `json:"percent"`
+}
+
+// RetryPolicy describes how to retry the failed requests routed by a Route.
+type RetryPolicy struct {
+	// Attempts is the number of times a failed request is retried.
+	// Zero disables retries.
+	Attempts int `json:"attempts"`
+
+	// PerTryTimeout is the timeout of each attempt of a request. It must not
+	// be longer than the Route's timeout, which it defaults to.
+	// +optional
+	PerTryTimeout *metav1.Duration `json:"perTryTimeout,omitempty"`
+
+	// RetryOn lists the conditions under which a request is retried, e.g.
+	// "5xx" or "connect-failure". If unspecified, the ingress defaults apply.
+	// +optional
+	RetryOn []string `json:"retryOn,omitempty"`
+}
+
+// PathTraffic holds the routing table of the requests to a Route whose path
+// matches a pattern.
+type PathTraffic struct {
+	// Path is an extended POSIX regex matched against the path of an incoming
+	// request to the Route's domain, e.g. "/api/.*". It must begin with a '/'.
+	Path string `json:"path"`
+
+	// Traffic specifies how to distribute the matching requests over a
+	// collection of Knative Serving Revisions and Configurations.
+	Traffic []TrafficTarget `json:"traffic"`
 }
 
 const (
@@ -142,6 +236,11 @@ type RouteStatus struct {
 	// +optional
 	Traffic []TrafficTarget `json:"traffic,omitempty"`
 
+	// Paths holds the configured traffic distribution of each path-scoped
+	// traffic block, flattened to RevisionName references like Traffic.
+	// +optional
+	Paths []PathTraffic `json:"paths,omitempty"`
+
 	// Conditions communicates information about ongoing/complete
 	// reconciliation processes that bring the "spec" inline with the observed
 	// state of the world.
@@ -165,6 +264,22 @@ type RouteList struct {
 	Items []Route `json:"items"`
 }
 
+// GetBasisPoints returns the share of the traffic of the target in basis
+// points, whether it is set as Percent or BasisPoints.
+func (tt *TrafficTarget) GetBasisPoints() int {
+	return tt.Percent*100 + tt.BasisPoints
+}
+
+// SetBasisPoints sets the share of the traffic of the target, as Percent
+// when it is a whole percentage, so that percent-only specs are unchanged.
+func (tt *TrafficTarget) SetBasisPoints(bp int) {
+	if bp%100 == 0 {
+		tt.Percent, tt.BasisPoints = bp/100, 0
+	} else {
+		tt.Percent, tt.BasisPoints = 0, bp
+	}
+}
+
 func (r *Route) GetGroupVersionKind() schema.GroupVersionKind {
 	return SchemeGroupVersion.WithKind("Route")
 }
@@ -219,6 +334,13 @@ func (rs *RouteStatus) MarkMissingTrafficTarget(kind, name string) {
 		"%s %q referenced in traffic not found.", kind, name)
 }
 
+func (rs *RouteStatus) MarkTimeoutTooShort(name string, revisionTimeout, timeout time.Duration) {
+	routeCondSet.Manage(rs).MarkFalse(RouteConditionAllTrafficAssigned,
+		"TimeoutTooShort",
+		"Revision %q may take up to %v to respond, longer than the Route's timeout of %v.",
+		name, revisionTimeout, timeout)
+}
+
 // PropagateClusterIngressStatus update RouteConditionIngressReady condition
 // in RouteStatus according to IngressStatus.
 func (rs *RouteStatus) PropagateClusterIngressStatus(cs v1alpha1.IngressStatus) {
@@ -18,6 +18,7 @@ package v1alpha1
 import (
 	"testing"
 
+	"github.com/google/go-cmp/cmp"
 	"github.com/knative/pkg/apis/duck"
 	duckv1alpha1 "github.com/knative/pkg/apis/duck/v1alpha1"
 	netv1alpha1 "github.com/knative/serving/pkg/apis/networking/v1alpha1"
@@ -325,3 +326,35 @@ func TestRouteGetGroupVersionKind(t *testing.T) {
 		t.Errorf("got: %v, want: %v", got, want)
 	}
 }
+
+func TestTrafficTargetBasisPoints(t *testing.T) {
+	tests := []struct {
+		name string
+		bp   int
+		want TrafficTarget
+	}{{
+		name: "whole percent",
+		bp:   9900,
+		want: TrafficTarget{Percent: 99},
+	}, {
+		name: "fraction of a percent",
+		bp:   10,
+		want: TrafficTarget{BasisPoints: 10},
+	}, {
+		name: "zero",
+		want: TrafficTarget{},
+	}}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			got := TrafficTarget{Percent: 1, BasisPoints: 1}
+			got.SetBasisPoints(test.bp)
+			if diff := cmp.Diff(test.want, got); diff != "" {
+				t.Errorf("SetBasisPoints(%d) (-want, +got) = %v", test.bp, diff)
+			}
+			if bp := got.GetBasisPoints(); bp != test.bp {
+				t.Errorf("GetBasisPoints() = %d, wanted %d", bp, test.bp)
+			}
+		})
+	}
+}
@@ -18,11 +18,16 @@ package v1alpha1
 
 import (
 	"fmt"
+	"regexp"
 	"strconv"
+	"strings"
+	"time"
 
 	"k8s.io/apimachinery/pkg/api/equality"
 
 	"github.com/knative/pkg/apis"
+	networkingv1alpha1 "github.com/knative/serving/pkg/apis/networking/v1alpha1"
+	"golang.org/x/net/http/httpguts"
 	"k8s.io/apimachinery/pkg/util/validation"
 )
 
@@ -47,11 +52,11 @@ func (rs *RouteSpec) Validate() *apis.FieldError {
 	trafficMap := make(map[string]namedTarget)
 
 	var errs *apis.FieldError
-	percentSum := 0
+	bpSum := 0
 	for i, tt := range rs.Traffic {
 		errs = errs.Also(tt.Validate().ViaFieldIndex("traffic", i))
 
-		percentSum += tt.Percent
+		bpSum += tt.GetBasisPoints()
 
 		if tt.Name == "" {
 			// No Name field, so skip the uniqueness check.
@@ -76,15 +81,120 @@ func (rs *RouteSpec) Validate() *apis.FieldError {
 		}
 	}
 
-	if percentSum != 100 {
-		errs = errs.Also(&apis.FieldError{
-			Message: fmt.Sprintf("Traffic targets sum to %d, want 100", percentSum),
-			Paths:   []string{"traffic"},
-		})
+	errs = errs.Also(validateTrafficSum(bpSum))
+
+	// Track the index of the first occurrence of each path (to detect duplicates).
+	pathMap := make(map[string]int)
+	for i, pt := range rs.Paths {
+		errs = errs.Also(pt.Validate().ViaFieldIndex("paths", i))
+
+		if j, ok := pathMap[pt.Path]; !ok {
+			pathMap[pt.Path] = i
+		} else {
+			errs = errs.Also(&apis.FieldError{
+				Message: fmt.Sprintf("Multiple definitions for %q", pt.Path),
+				Paths: []string{
+					fmt.Sprintf("paths[%d].path", j),
+					fmt.Sprintf("paths[%d].path", i),
+				},
+			})
+		}
+	}
+
+	// The Route's timeout bounds the timeout of each retry attempt.
+	timeout := networkingv1alpha1.DefaultTimeout
+	if rs.Timeout != nil {
+		if err := validateRouteTimeout(rs.Timeout.Duration, networkingv1alpha1.DefaultTimeout, "timeout"); err != nil {
+			errs = errs.Also(err)
+		} else {
+			timeout = rs.Timeout.Duration
+		}
+	}
+	if rs.Retries != nil {
+		errs = errs.Also(rs.Retries.validate(timeout).ViaField("retries"))
+	}
+	if rs.Mirror != nil {
+		errs = errs.Also(rs.Mirror.Validate().ViaField("mirror"))
 	}
 	return errs
 }
 
+// Validate verifies that MirrorTarget is properly configured.
+func (mt *MirrorTarget) Validate() *apis.FieldError {
+	var errs *apis.FieldError
+	if mt.RevisionName == "" {
+		errs = apis.ErrMissingField("revisionName")
+	} else if verrs := validation.IsQualifiedName(mt.RevisionName); len(verrs) > 0 {
+		errs = apis.ErrInvalidKeyName(mt.RevisionName, "revisionName", verrs...)
+	}
+	if mt.Percent < 1 || mt.Percent > 100 {
+		errs = errs.Also(apis.ErrOutOfBoundsValue(strconv.Itoa(mt.Percent), "1", "100", "percent"))
+	}
+	return errs
+}
+
+// validate verifies that RetryPolicy is properly configured, with attempts
+// no longer than the given timeout.
+func (rp *RetryPolicy) validate(timeout time.Duration) *apis.FieldError {
+	var errs *apis.FieldError
+	if rp.Attempts < 0 {
+		errs = apis.ErrInvalidValue(strconv.Itoa(rp.Attempts), "attempts")
+	}
+	if rp.PerTryTimeout != nil {
+		errs = errs.Also(validateRouteTimeout(rp.PerTryTimeout.Duration, timeout, "perTryTimeout"))
+	}
+	return errs.Also(networkingv1alpha1.ValidateRetryOn(rp.RetryOn))
+}
+
+// validateRouteTimeout verifies that a timeout is positive and no longer than max.
+func validateRouteTimeout(timeout, max time.Duration, field string) *apis.FieldError {
+	if timeout <= 0 || timeout > max {
+		return apis.ErrOutOfBoundsValue(timeout.String(), "0s", max.String(), field)
+	}
+	return nil
+}
+
+// Validate verifies that PathTraffic is properly configured.
+func (pt *PathTraffic) Validate() *apis.FieldError {
+	var errs *apis.FieldError
+	if pt.Path == "" {
+		errs = apis.ErrMissingField("path")
+	} else if _, err := regexp.CompilePOSIX(pt.Path); err != nil || !strings.HasPrefix(pt.Path, "/") {
+		errs = apis.ErrInvalidValue(pt.Path, "path")
+	}
+	if len(pt.Traffic) == 0 {
+		return errs.Also(apis.ErrMissingField("traffic"))
+	}
+
+	bpSum := 0
+	for i, tt := range pt.Traffic {
+		errs = errs.Also(tt.Validate().ViaFieldIndex("traffic", i))
+		// Named targets and header matches apply to the whole Route,
+		// so they have no meaning within a path.
+		if tt.Name != "" {
+			errs = errs.Also(apis.ErrDisallowedFields("name").ViaFieldIndex("traffic", i))
+		}
+		if len(tt.Headers) > 0 {
+			errs = errs.Also(apis.ErrDisallowedFields("headers").ViaFieldIndex("traffic", i))
+		}
+		bpSum += tt.GetBasisPoints()
+	}
+	return errs.Also(validateTrafficSum(bpSum))
+}
+
+// validateTrafficSum verifies that traffic targets whose shares sum to the
+// given basis points send all of the traffic somewhere.
+func validateTrafficSum(bpSum int) *apis.FieldError {
+	if bpSum == 100*100 {
+		return nil
+	}
+	return &apis.FieldError{
+		Message: fmt.Sprintf("Traffic targets sum to %s, want 100",
+			strconv.FormatFloat(float64(bpSum)/100, 'f', -1, 64)),
+		Paths: []string{"traffic"},
+	}
+}
+
 // Validate verifies that TrafficTarget is properly configured.
 func (tt *TrafficTarget) Validate() *apis.FieldError {
 	var errs *apis.FieldError
@@ -102,8 +212,50 @@ func (tt *TrafficTarget) Validate() *apis.FieldError {
 	default:
 		errs = apis.ErrMissingOneOf("revisionName", "configurationName")
 	}
+	if tt.LatestRevision != nil {
+		errs = errs.Also(apis.ErrDisallowedFields("latestRevision"))
+	}
+	errs = errs.Also(tt.validateStatusFields())
+	return errs.Also(tt.validateShare()).Also(tt.validateHeaders())
+}
+
+// validateShare verifies the share of the traffic of TrafficTarget, given
+// by either Percent or BasisPoints.
+func (tt *TrafficTarget) validateShare() *apis.FieldError {
+	var errs *apis.FieldError
 	if tt.Percent < 0 || tt.Percent > 100 {
-		errs = errs.Also(apis.ErrOutOfBoundsValue(strconv.Itoa(tt.Percent), "0", "100", "percent"))
+		errs = apis.ErrOutOfBoundsValue(strconv.Itoa(tt.Percent), "0", "100", "percent")
+	}
+	if tt.BasisPoints < 0 || tt.BasisPoints > 100*100 {
+		errs = errs.Also(apis.ErrOutOfBoundsValue(strconv.Itoa(tt.BasisPoints), "0", "10000", "basisPoints"))
+	}
+	if tt.Percent != 0 && tt.BasisPoints != 0 {
+		errs = errs.Also(apis.ErrMultipleOneOf("percent", "basisPoints"))
+	}
+	return errs
+}
+
+// validateStatusFields verifies that the fields of TrafficTarget that are
+// only filled in by the controller are not set by the client.
+func (tt *TrafficTarget) validateStatusFields() *apis.FieldError {
+	var errs *apis.FieldError
+	if tt.Domain != "" {
+		errs = errs.Also(apis.ErrDisallowedFields("domain"))
+	}
+	if tt.Address != nil {
+		errs = errs.Also(apis.ErrDisallowedFields("address"))
+	}
+	return errs
+}
+
+// validateHeaders verifies the header match conditions of TrafficTarget.
+func (tt *TrafficTarget) validateHeaders() *apis.FieldError {
+	var errs *apis.FieldError
+	for name, match := range tt.Headers {
+		if !httpguts.ValidHeaderFieldName(name) {
+			errs = errs.Also(apis.ErrInvalidKeyName(name, "headers"))
+		}
+		errs = errs.Also(match.Validate().ViaFieldKey("headers", name))
 	}
 	return errs
 }
@@ -19,11 +19,14 @@ package v1alpha1
 import (
 	"strings"
 	"testing"
+	"time"
 
 	"github.com/google/go-cmp/cmp"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 
 	"github.com/knative/pkg/apis"
+	duckv1alpha1 "github.com/knative/pkg/apis/duck/v1alpha1"
+	netv1alpha1 "github.com/knative/serving/pkg/apis/networking/v1alpha1"
 )
 
 func TestRouteValidation(t *testing.T) {
@@ -249,6 +252,205 @@ func TestRouteSpecValidation(t *testing.T) {
 			Message: "Traffic targets sum to 198, want 100",
 			Paths:   []string{"traffic"},
 		},
+	}, {
+		name: "valid basis point split",
+		rs: &RouteSpec{
+			Traffic: []TrafficTarget{{
+				RevisionName: "bar",
+				BasisPoints:  9990,
+			}, {
+				RevisionName: "baz",
+				BasisPoints:  10,
+			}},
+		},
+		want: nil,
+	}, {
+		name: "invalid total basis points",
+		rs: &RouteSpec{
+			Traffic: []TrafficTarget{{
+				RevisionName: "bar",
+				Percent:      99,
+			}, {
+				RevisionName: "baz",
+				BasisPoints:  90,
+			}},
+		},
+		want: &apis.FieldError{
+			Message: "Traffic targets sum to 99.9, want 100",
+			Paths:   []string{"traffic"},
+		},
+	}, {
+		name: "valid paths",
+		rs: &RouteSpec{
+			Traffic: []TrafficTarget{{
+				ConfigurationName: "web",
+				Percent:           100,
+			}},
+			Paths: []PathTraffic{{
+				Path: "/api/.*",
+				Traffic: []TrafficTarget{{
+					ConfigurationName: "api",
+					Percent:           100,
+				}},
+			}, {
+				Path: "/static/.*",
+				Traffic: []TrafficTarget{{
+					RevisionName: "static-00001",
+					Percent:      50,
+				}, {
+					ConfigurationName: "static",
+					Percent:           50,
+				}},
+			}},
+		},
+		want: nil,
+	}, {
+		name: "invalid path",
+		rs: &RouteSpec{
+			Traffic: []TrafficTarget{{
+				ConfigurationName: "web",
+				Percent:           100,
+			}},
+			Paths: []PathTraffic{{
+				Path: "api/.*",
+				Traffic: []TrafficTarget{{
+					ConfigurationName: "api",
+					Percent:           100,
+				}},
+			}, {
+				Path: "/static/(.*",
+				Traffic: []TrafficTarget{{
+					ConfigurationName: "static",
+					Percent:           100,
+				}},
+			}, {
+				Traffic: []TrafficTarget{{
+					ConfigurationName: "other",
+					Percent:           100,
+				}},
+			}},
+		},
+		want: apis.ErrInvalidValue("api/.*", "paths[0].path").Also(
+			apis.ErrInvalidValue("/static/(.*", "paths[1].path"),
+			apis.ErrMissingField("paths[2].path")),
+	}, {
+		name: "invalid path traffic",
+		rs: &RouteSpec{
+			Traffic: []TrafficTarget{{
+				ConfigurationName: "web",
+				Percent:           100,
+			}},
+			Paths: []PathTraffic{{
+				Path: "/api/.*",
+				Traffic: []TrafficTarget{{
+					Name:              "api",
+					ConfigurationName: "api",
+					Percent:           90,
+					Headers: map[string]netv1alpha1.HeaderMatch{
+						"X-Canary": {Exact: "true"},
+					},
+				}},
+			}, {
+				Path: "/static/.*",
+			}},
+		},
+		want: apis.ErrDisallowedFields("paths[0].traffic[0].name", "paths[0].traffic[0].headers").Also(
+			&apis.FieldError{
+				Message: "Traffic targets sum to 90, want 100",
+				Paths:   []string{"paths[0].traffic"},
+			},
+			apis.ErrMissingField("paths[1].traffic")),
+	}, {
+		name: "duplicate paths",
+		rs: &RouteSpec{
+			Traffic: []TrafficTarget{{
+				ConfigurationName: "web",
+				Percent:           100,
+			}},
+			Paths: []PathTraffic{{
+				Path: "/api/.*",
+				Traffic: []TrafficTarget{{
+					ConfigurationName: "api",
+					Percent:           100,
+				}},
+			}, {
+				Path: "/api/.*",
+				Traffic: []TrafficTarget{{
+					ConfigurationName: "other",
+					Percent:           100,
+				}},
+			}},
+		},
+		want: &apis.FieldError{
+			Message: `Multiple definitions for "/api/.*"`,
+			Paths:   []string{"paths[0].path", "paths[1].path"},
+		},
+	}, {
+		name: "valid timeout and retries",
+		rs: &RouteSpec{
+			Traffic: []TrafficTarget{{
+				RevisionName: "foo",
+				Percent:      100,
+			}},
+			Timeout: &metav1.Duration{Duration: 2 * time.Minute},
+			Retries: &RetryPolicy{
+				Attempts:      2,
+				PerTryTimeout: &metav1.Duration{Duration: time.Minute},
+				RetryOn:       []string{"5xx", "connect-failure"},
+			},
+		},
+		want: nil,
+	}, {
+		name: "invalid timeout",
+		rs: &RouteSpec{
+			Traffic: []TrafficTarget{{
+				RevisionName: "foo",
+				Percent:      100,
+			}},
+			Timeout: &metav1.Duration{Duration: time.Hour},
+		},
+		want: apis.ErrOutOfBoundsValue("1h0m0s", "0s", "5m0s", "timeout"),
+	}, {
+		name: "invalid retries",
+		rs: &RouteSpec{
+			Traffic: []TrafficTarget{{
+				RevisionName: "foo",
+				Percent:      100,
+			}},
+			Timeout: &metav1.Duration{Duration: time.Minute},
+			Retries: &RetryPolicy{
+				Attempts:      -1,
+				PerTryTimeout: &metav1.Duration{Duration: 2 * time.Minute},
+				RetryOn:       []string{"5xx", "sometimes"},
+			},
+		},
+		want: apis.ErrInvalidValue("-1", "retries.attempts").Also(
+			apis.ErrOutOfBoundsValue("2m0s", "0s", "1m0s", "retries.perTryTimeout"),
+			apis.ErrInvalidValue("sometimes", "retries.retryOn[1]")),
+	}, {
+		name: "valid mirror",
+		rs: &RouteSpec{
+			Traffic: []TrafficTarget{{
+				RevisionName: "foo",
+				Percent:      100,
+			}},
+			Mirror: &MirrorTarget{
+				RevisionName: "bar",
+				Percent:      10,
+			},
+		},
+		want: nil,
+	}, {
+		name: "invalid mirror",
+		rs: &RouteSpec{
+			Traffic: []TrafficTarget{{
+				RevisionName: "foo",
+				Percent:      100,
+			}},
+			Mirror: &MirrorTarget{},
+		},
+		want: apis.ErrMissingField("mirror.revisionName").Also(
+			apis.ErrOutOfBoundsValue("0", "1", "100", "mirror.percent")),
 	}}
 
 	for _, test := range tests {
@@ -316,6 +518,46 @@ func TestTrafficTargetValidation(t *testing.T) {
 			Message: "expected exactly one, got neither",
 			Paths:   []string{"revisionName", "configurationName"},
 		},
+	}, {
+		name: "invalid with latest revision",
+		tt: &TrafficTarget{
+			ConfigurationName: "foo",
+			LatestRevision:    &latestRevision,
+			Percent:           100,
+		},
+		want: apis.ErrDisallowedFields("latestRevision"),
+	}, {
+		name: "invalid with status fields",
+		tt: &TrafficTarget{
+			Name:         "foo",
+			RevisionName: "bar",
+			Percent:      100,
+			Domain:       "foo.route.default.example.com",
+			Address: &duckv1alpha1.Addressable{
+				Hostname: "foo.route.default.example.com",
+			},
+		},
+		want: apis.ErrDisallowedFields("domain").Also(apis.ErrDisallowedFields("address")),
+	}, {
+		name: "valid with headers",
+		tt: &TrafficTarget{
+			RevisionName: "bar",
+			Headers: map[string]netv1alpha1.HeaderMatch{
+				"X-Canary": {Exact: "true"},
+			},
+		},
+		want: nil,
+	}, {
+		name: "invalid headers",
+		tt: &TrafficTarget{
+			RevisionName: "bar",
+			Headers: map[string]netv1alpha1.HeaderMatch{
+				"X Canary": {Exact: "true"},
+				"X-Group":  {},
+			},
+		},
+		want: apis.ErrInvalidKeyName("X Canary", "headers").Also(
+			apis.ErrMissingOneOf("exact", "prefix", "regex").ViaFieldKey("headers", "X-Group")),
 	}, {
 		name: "invalid percent too low",
 		tt: &TrafficTarget{
@@ -330,6 +572,28 @@ func TestTrafficTargetValidation(t *testing.T) {
 			Percent:      101,
 		},
 		want: apis.ErrOutOfBoundsValue("101", "0", "100", "percent"),
+	}, {
+		name: "valid with basis points",
+		tt: &TrafficTarget{
+			RevisionName: "foo",
+			BasisPoints:  10,
+		},
+		want: nil,
+	}, {
+		name: "invalid basis points too high",
+		tt: &TrafficTarget{
+			RevisionName: "foo",
+			BasisPoints:  10001,
+		},
+		want: apis.ErrOutOfBoundsValue("10001", "0", "10000", "basisPoints"),
+	}, {
+		name: "invalid percent and basis points",
+		tt: &TrafficTarget{
+			RevisionName: "foo",
+			Percent:      1,
+			BasisPoints:  10,
+		},
+		want: apis.ErrMultipleOneOf("percent", "basisPoints"),
 	}}
 
 	for _, test := range tests {
@@ -16,6 +16,16 @@ limitations under the License.
 
 package v1alpha1
 
+import "time"
+
+const (
+	// defaultRollbackWindow will be set if a rollback policy's window is not specified.
+	defaultRollbackWindow = time.Minute
+
+	// defaultRollbackMinRequests will be set if a rollback policy's minRequests is not specified.
+	defaultRollbackMinRequests = 10
+)
+
 func (s *Service) SetDefaults() {
 	s.Spec.SetDefaults()
 }
@@ -27,5 +37,19 @@ func (ss *ServiceSpec) SetDefaults() {
 		ss.Pinned.Configuration.SetDefaults()
 	} else if ss.Release != nil {
 		ss.Release.Configuration.SetDefaults()
+		if ss.Release.Rollback != nil {
+			ss.Release.Rollback.SetDefaults()
+		}
+	} else if ss.Inline != nil {
+		ss.Inline.Configuration.SetDefaults()
+	}
+}
+
+func (rp *RollbackPolicy) SetDefaults() {
+	if rp.Window.Duration == 0 {
+		rp.Window.Duration = defaultRollbackWindow
+	}
+	if rp.MinRequests == 0 {
+		rp.MinRequests = defaultRollbackMinRequests
 	}
 }
@@ -215,6 +215,70 @@ func TestServiceDefaulting(t *testing.T) {
 				},
 			},
 		},
+	}, {
+		name: "release - rollback",
+		in: &Service{
+			Spec: ServiceSpec{
+				Release: &ReleaseType{
+					Rollback: &RollbackPolicy{
+						MaxErrorPercent: 5,
+					},
+				},
+			},
+		},
+		want: &Service{
+			Spec: ServiceSpec{
+				Release: &ReleaseType{
+					Rollback: &RollbackPolicy{
+						MaxErrorPercent: 5,
+						Window:          metav1.Duration{Duration: time.Minute},
+						MinRequests:     10,
+					},
+					Configuration: ConfigurationSpec{
+						RevisionTemplate: RevisionTemplateSpec{
+							Spec: RevisionSpec{
+								TimeoutSeconds: &metav1.Duration{
+									Duration: 60 * time.Second,
+								},
+							},
+						},
+					},
+				},
+			},
+		},
+	}, {
+		name: "release - rollback no overwrite",
+		in: &Service{
+			Spec: ServiceSpec{
+				Release: &ReleaseType{
+					Rollback: &RollbackPolicy{
+						MaxErrorPercent: 5,
+						Window:          metav1.Duration{Duration: 5 * time.Minute},
+						MinRequests:     1,
+					},
+				},
+			},
+		},
+		want: &Service{
+			Spec: ServiceSpec{
+				Release: &ReleaseType{
+					Rollback: &RollbackPolicy{
+						MaxErrorPercent: 5,
+						Window:          metav1.Duration{Duration: 5 * time.Minute},
+						MinRequests:     1,
+					},
+					Configuration: ConfigurationSpec{
+						RevisionTemplate: RevisionTemplateSpec{
+							Spec: RevisionSpec{
+								TimeoutSeconds: &metav1.Duration{
+									Duration: 60 * time.Second,
+								},
+							},
+						},
+					},
+				},
+			},
+		},
 	}}
 
 	for _, test := range tests {
@@ -17,6 +17,8 @@ limitations under the License.
 package v1alpha1
 
 import (
+	"fmt"
+
 	corev1 "k8s.io/api/core/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/runtime/schema"
@@ -95,6 +97,12 @@ type ServiceSpec struct {
 	// to be split between two revisions. This type replaces the deprecated Pinned type.
 	// +optional
 	Release *ReleaseType `json:"release,omitempty"`
+
+	// Inline enables fine-grained control over how traffic is split between
+	// the revisions of the service's configuration, without having to
+	// manage the underlying Route and Configuration directly.
+	// +optional
+	Inline *InlineType `json:"inline,omitempty"`
 }
 
 // ManualType contains the options for configuring a manual service. See ServiceSpec for
@@ -118,12 +126,95 @@ type ReleaseType struct {
 	// +optional
 	RolloutPercent int `json:"rolloutPercent,omitempty"`
 
+	// Rollout is an optional plan for automatically moving traffic to the
+	// "candidate" revision. When specified, the percent of traffic sent to
+	// the "candidate" revision is driven by the plan instead of RolloutPercent.
+	// +optional
+	Rollout *RolloutPlan `json:"rollout,omitempty"`
+
+	// Rollback, if specified, automatically sends all traffic back to the
+	// "current" revision when the "candidate" revision regresses.
+	// +optional
+	Rollback *RollbackPolicy `json:"rollback,omitempty"`
+
 	// The configuration for this service. All revisions from this service must
 	// come from a single configuration.
 	// +optional
 	Configuration ConfigurationSpec `json:"configuration,omitempty"`
 }
 
+// InlineType contains a configuration together with the traffic targets of the
+// route in front of it. See ServiceSpec for more details.
+type InlineType struct {
+	// Traffic specifies how to distribute traffic over the revisions of
+	// the configuration. Targets may reference a specific revision by
+	// RevisionName, or the latest ready revision by setting LatestRevision.
+	// +optional
+	Traffic []TrafficTarget `json:"traffic,omitempty"`
+
+	// The configuration for this service. All revisions from this service must
+	// come from a single configuration.
+	// +optional
+	Configuration ConfigurationSpec `json:"configuration,omitempty"`
+}
+
+// RolloutPlan describes how traffic is progressively shifted to the "candidate"
+// revision of a ReleaseType. See ReleaseType for more details.
+type RolloutPlan struct {
+	// Steps is the ordered list of traffic percentages that the "candidate"
+	// revision moves through. Percentages must be strictly increasing.
+	Steps []RolloutStep `json:"steps,omitempty"`
+
+	// Paused holds the rollout at its current step until it is unset.
+	// +optional
+	Paused bool `json:"paused,omitempty"`
+
+	// Aborted sends all traffic back to the "current" revision and resets
+	// the rollout's progress. Unsetting it restarts the rollout from the
+	// first step.
+	// +optional
+	Aborted bool `json:"aborted,omitempty"`
+}
+
+// RolloutStep is a single step of a RolloutPlan.
+type RolloutStep struct {
+	// Percent is the percent of traffic that should be sent to the "candidate"
+	// revision during this step. Valid values are between 0 and 99 inclusive.
+	Percent int `json:"percent"`
+
+	// Pause is how long the rollout stays at this step before moving to the
+	// next one. It is ignored for the last step.
+	// +optional
+	Pause metav1.Duration `json:"pause,omitempty"`
+}
+
+// RollbackPolicy describes when the "candidate" revision of a ReleaseType is
+// considered to have regressed. A threshold is only crossed when the "candidate"
+// revision also does worse than the "current" revision, so that problems
+// shared by both revisions do not cause a rollback.
+type RollbackPolicy struct {
+	// MaxErrorPercent is the percent of "candidate" requests that may fail
+	// with a 5xx response code. Valid values are between 1 and 100 inclusive.
+	// Zero disables the check.
+	// +optional
+	MaxErrorPercent int `json:"maxErrorPercent,omitempty"`
+
+	// MaxLatency is the highest 99th percentile latency allowed for
+	// "candidate" requests. Zero disables the check.
+	// +optional
+	MaxLatency metav1.Duration `json:"maxLatency,omitempty"`
+
+	// Window is how far back request outcomes are considered. Outcomes are
+	// checked once per Window. Defaults to one minute.
+	// +optional
+	Window metav1.Duration `json:"window,omitempty"`
+
+	// MinRequests is the number of requests the "candidate" revision must
+	// serve within the Window before it is judged. Defaults to 10.
+	// +optional
+	MinRequests int `json:"minRequests,omitempty"`
+}
+
 // RunLatestType contains the options for always having a route to the latest configuration. See
 // ServiceSpec for more details.
 type RunLatestType struct {
@@ -156,6 +247,10 @@ const (
 	// ServiceConditionConfigurationsReady is set when the service's underlying
 	// configurations have reported readiness.
 	ServiceConditionConfigurationsReady duckv1alpha1.ConditionType = "ConfigurationsReady"
+	// ServiceConditionRolloutFailed is set when traffic was automatically
+	// sent back to the "current" revision because the "candidate" revision
+	// regressed. It does not affect the service's readiness.
+	ServiceConditionRolloutFailed duckv1alpha1.ConditionType = "RolloutFailed"
 )
 
 var serviceCondSet = duckv1alpha1.NewLivingConditionSet(ServiceConditionConfigurationsReady, ServiceConditionRoutesReady)
@@ -206,6 +301,36 @@ type ServiceStatus struct {
 	// was last processed by the controller.
 	// +optional
 	ObservedGeneration int64 `json:"observedGeneration,omitempty"`
+
+	// Rollout holds the progress of the release rollout, if the Service
+	// specifies a rollout plan or a rollback policy.
+	// +optional
+	Rollout *RolloutStatus `json:"rollout,omitempty"`
+}
+
+// RolloutStatus communicates the observed progress of a RolloutPlan.
+type RolloutStatus struct {
+	// Candidate is the name of the "candidate" revision being rolled out.
+	// The rollout restarts from the first step when the candidate changes.
+	// +optional
+	Candidate string `json:"candidate,omitempty"`
+
+	// CurrentStep is the index of the RolloutPlan step that is currently
+	// serving traffic.
+	// +optional
+	CurrentStep int `json:"currentStep,omitempty"`
+
+	// NextTransitionTime is when the rollout moves to the next step. It is
+	// unset once the last step is reached, or while the rollout is paused,
+	// aborted or rolled back.
+	// +optional
+	NextTransitionTime *metav1.Time `json:"nextTransitionTime,omitempty"`
+
+	// RolledBack is set when all traffic was sent back to the "current"
+	// revision because the "candidate" revision regressed. It is cleared
+	// when the candidate changes.
+	// +optional
+	RolledBack bool `json:"rolledBack,omitempty"`
 }
 
 // +k8s:deepcopy-gen:interfaces=k8s.io/apimachinery/pkg/runtime.Object
@@ -272,6 +397,33 @@ func (ss *ServiceStatus) PropagateRouteStatus(rs RouteStatus) {
 	}
 }
 
+// MarkRolloutFailed records that traffic was sent back to the "current"
+// revision because the "candidate" revision regressed.
+func (ss *ServiceStatus) MarkRolloutFailed(reason, messageFormat string, messageA ...interface{}) {
+	serviceCondSet.Manage(ss).SetCondition(duckv1alpha1.Condition{
+		Type:     ServiceConditionRolloutFailed,
+		Status:   corev1.ConditionTrue,
+		Reason:   reason,
+		Message:  fmt.Sprintf(messageFormat, messageA...),
+		Severity: duckv1alpha1.ConditionSeverityWarning,
+	})
+}
+
+// ClearRolloutFailed removes the RolloutFailed condition, e.g. once a new
+// "candidate" revision is being rolled out.
+func (ss *ServiceStatus) ClearRolloutFailed() {
+	if ss.GetCondition(ServiceConditionRolloutFailed) == nil {
+		return
+	}
+	var conditions duckv1alpha1.Conditions
+	for _, c := range ss.Conditions {
+		if c.Type != ServiceConditionRolloutFailed {
+			conditions = append(conditions, c)
+		}
+	}
+	ss.Conditions = conditions
+}
+
 // SetManualStatus updates the service conditions to unknown as the underlying Route
 // can have TrafficTargets to Configurations not owned by the service. We do not want to falsely
 // report Ready.
@@ -563,6 +563,40 @@ func checkConditionService(rs ServiceStatus, rct duckv1alpha1.ConditionType, cs
 	return r
 }
 
+func TestRolloutFailed(t *testing.T) {
+	svc := &Service{}
+	svc.Status.InitializeConditions()
+	svc.Status.PropagateConfigurationStatus(ConfigurationStatus{
+		Conditions: duckv1alpha1.Conditions{{
+			Type:   ConfigurationConditionReady,
+			Status: corev1.ConditionTrue,
+		}},
+	})
+	svc.Status.PropagateRouteStatus(RouteStatus{
+		Conditions: duckv1alpha1.Conditions{{
+			Type:   RouteConditionReady,
+			Status: corev1.ConditionTrue,
+		}},
+	})
+	checkConditionSucceededService(svc.Status, ServiceConditionReady, t)
+
+	svc.Status.MarkRolloutFailed("ErrorRateRegression", "Revision %q failed", "foo")
+	c := checkConditionSucceededService(svc.Status, ServiceConditionRolloutFailed, t)
+	if got, want := c.Message, `Revision "foo" failed`; got != want {
+		t.Errorf("Message = %q, wanted %q", got, want)
+	}
+	// Rolling back does not affect readiness.
+	checkConditionSucceededService(svc.Status, ServiceConditionReady, t)
+
+	svc.Status.ClearRolloutFailed()
+	if c := svc.Status.GetCondition(ServiceConditionRolloutFailed); c != nil {
+		t.Errorf("GetCondition(RolloutFailed) = %v, wanted nil", c)
+	}
+	checkConditionSucceededService(svc.Status, ServiceConditionReady, t)
+	checkConditionSucceededService(svc.Status, ServiceConditionConfigurationsReady, t)
+	checkConditionSucceededService(svc.Status, ServiceConditionRoutesReady, t)
+}
+
 func TestServiceGetGroupVersionKind(t *testing.T) {
 	s := &Service{}
 	want := schema.GroupVersionKind{
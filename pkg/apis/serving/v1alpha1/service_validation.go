@@ -20,6 +20,7 @@ import (
 	"fmt"
 
 	"github.com/knative/pkg/apis"
+	"k8s.io/apimachinery/pkg/util/validation"
 )
 
 // Validate validates the fields belonging to Service
@@ -56,11 +57,15 @@ func (ss *ServiceSpec) Validate() *apis.FieldError {
 		set = append(set, "pinned")
 		errs = errs.Also(ss.Pinned.Validate().ViaField("pinned"))
 	}
+	if ss.Inline != nil {
+		set = append(set, "inline")
+		errs = errs.Also(ss.Inline.Validate().ViaField("inline"))
+	}
 
 	if len(set) > 1 {
 		errs = errs.Also(apis.ErrMultipleOneOf(set...))
 	} else if len(set) == 0 {
-		errs = errs.Also(apis.ErrMissingOneOf("runLatest", "release", "manual", "pinned"))
+		errs = errs.Also(apis.ErrMissingOneOf("runLatest", "release", "manual", "pinned", "inline"))
 	}
 	return errs
 }
@@ -111,5 +116,138 @@ func (rt *ReleaseType) Validate() *apis.FieldError {
 		errs = errs.Also(apis.ErrInvalidValue(fmt.Sprintf("%v", rt.RolloutPercent), "rolloutPercent"))
 	}
 
+	if rt.Rollout != nil {
+		if numRevisions < 2 {
+			errs = errs.Also(apis.ErrDisallowedFields("rollout"))
+		}
+		if rt.RolloutPercent != 0 {
+			errs = errs.Also(apis.ErrMultipleOneOf("rolloutPercent", "rollout"))
+		}
+		errs = errs.Also(rt.Rollout.Validate().ViaField("rollout"))
+	}
+
+	if rt.Rollback != nil {
+		if numRevisions < 2 {
+			errs = errs.Also(apis.ErrDisallowedFields("rollback"))
+		}
+		errs = errs.Also(rt.Rollback.Validate().ViaField("rollback"))
+	}
+
 	return errs.Also(rt.Configuration.Validate().ViaField("configuration"))
 }
+
+// Validate validates the fields belonging to InlineType
+func (it *InlineType) Validate() *apis.FieldError {
+	errs := it.Configuration.Validate().ViaField("configuration")
+	if len(it.Traffic) == 0 {
+		return errs.Also(apis.ErrMissingField("traffic"))
+	}
+
+	// Where a named traffic target points
+	type namedTarget struct {
+		r      string // revision name
+		latest bool   // whether it tracks the latest ready revision
+		i      int    // index of first occurrence
+	}
+
+	// Track the targets of named TrafficTarget entries (to detect duplicates).
+	trafficMap := make(map[string]namedTarget)
+
+	bpSum := 0
+	for i, tt := range it.Traffic {
+		errs = errs.Also(tt.validateInline().ViaFieldIndex("traffic", i))
+
+		bpSum += tt.GetBasisPoints()
+
+		if tt.Name == "" {
+			// No Name field, so skip the uniqueness check.
+			continue
+		}
+		nt := namedTarget{
+			r:      tt.RevisionName,
+			latest: tt.LatestRevision != nil && *tt.LatestRevision,
+			i:      i,
+		}
+		if ent, ok := trafficMap[tt.Name]; !ok {
+			// No entry exists, so add ours
+			trafficMap[tt.Name] = nt
+		} else if ent.r != nt.r || ent.latest != nt.latest {
+			errs = errs.Also(&apis.FieldError{
+				Message: fmt.Sprintf("Multiple definitions for %q", tt.Name),
+				Paths: []string{
+					fmt.Sprintf("traffic[%d].name", ent.i),
+					fmt.Sprintf("traffic[%d].name", nt.i),
+				},
+			})
+		}
+	}
+
+	return errs.Also(validateTrafficSum(bpSum))
+}
+
+// validateInline verifies that a TrafficTarget of an InlineType is properly
+// configured. Unlike Route traffic targets, these may only reference the
+// Service's own configuration, through LatestRevision.
+func (tt *TrafficTarget) validateInline() *apis.FieldError {
+	var errs *apis.FieldError
+	if tt.ConfigurationName != "" {
+		errs = apis.ErrDisallowedFields("configurationName")
+	}
+	latest := tt.LatestRevision != nil && *tt.LatestRevision
+	switch {
+	case tt.RevisionName != "" && latest:
+		errs = errs.Also(apis.ErrMultipleOneOf("revisionName", "latestRevision"))
+	case tt.RevisionName != "":
+		if verrs := validation.IsQualifiedName(tt.RevisionName); len(verrs) > 0 {
+			errs = errs.Also(apis.ErrInvalidKeyName(tt.RevisionName, "revisionName", verrs...))
+		}
+	case !latest:
+		errs = errs.Also(apis.ErrMissingOneOf("revisionName", "latestRevision"))
+	}
+	return errs.Also(tt.validateShare()).Also(tt.validateStatusFields()).Also(tt.validateHeaders())
+}
+
+// Validate validates the fields belonging to RolloutPlan
+func (rp *RolloutPlan) Validate() *apis.FieldError {
+	if len(rp.Steps) == 0 {
+		return apis.ErrMissingField("steps")
+	}
+
+	var errs *apis.FieldError
+	for i, step := range rp.Steps {
+		if step.Percent < 0 || step.Percent > 99 {
+			errs = errs.Also(apis.ErrOutOfBoundsValue(fmt.Sprintf("%v", step.Percent), "0", "99",
+				"percent").ViaFieldIndex("steps", i))
+		} else if i > 0 && step.Percent <= rp.Steps[i-1].Percent {
+			errs = errs.Also((&apis.FieldError{
+				Message: fmt.Sprintf("expected percent greater than the previous step's %v, got %v", rp.Steps[i-1].Percent, step.Percent),
+				Paths:   []string{"percent"},
+			}).ViaFieldIndex("steps", i))
+		}
+		if step.Pause.Duration < 0 {
+			errs = errs.Also(apis.ErrInvalidValue(step.Pause.Duration.String(), "pause").ViaFieldIndex("steps", i))
+		}
+	}
+	return errs
+}
+
+// Validate validates the fields belonging to RollbackPolicy
+func (rp *RollbackPolicy) Validate() *apis.FieldError {
+	var errs *apis.FieldError
+	if rp.MaxErrorPercent == 0 && rp.MaxLatency.Duration == 0 {
+		errs = errs.Also(apis.ErrMissingOneOf("maxErrorPercent", "maxLatency"))
+	}
+	if rp.MaxErrorPercent < 0 || rp.MaxErrorPercent > 100 {
+		errs = errs.Also(apis.ErrOutOfBoundsValue(fmt.Sprintf("%v", rp.MaxErrorPercent), "1", "100", "maxErrorPercent"))
+	}
+	if rp.MaxLatency.Duration < 0 {
+		errs = errs.Also(apis.ErrInvalidValue(rp.MaxLatency.Duration.String(), "maxLatency"))
+	}
+	if rp.Window.Duration < 0 {
+		errs = errs.Also(apis.ErrInvalidValue(rp.Window.Duration.String(), "window"))
+	}
+	if rp.MinRequests < 0 {
+		errs = errs.Also(apis.ErrInvalidValue(fmt.Sprintf("%v", rp.MinRequests), "minRequests"))
+	}
+	return errs
+}
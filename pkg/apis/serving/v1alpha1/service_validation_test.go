@@ -19,6 +19,7 @@ package v1alpha1
 import (
 	"strings"
 	"testing"
+	"time"
 
 	"github.com/google/go-cmp/cmp"
 	corev1 "k8s.io/api/core/v1"
@@ -27,6 +28,8 @@ import (
 	"github.com/knative/pkg/apis"
 )
 
+var latestRevision = true
+
 func TestServiceValidation(t *testing.T) {
 	tests := []struct {
 		name string
@@ -108,6 +111,33 @@ func TestServiceValidation(t *testing.T) {
 			},
 		},
 		want: nil,
+	}, {
+		name: "valid inline",
+		s: &Service{
+			Spec: ServiceSpec{
+				Inline: &InlineType{
+					Traffic: []TrafficTarget{{
+						Name:         "current",
+						RevisionName: "asdf",
+						Percent:      90,
+					}, {
+						Name:           "latest",
+						LatestRevision: &latestRevision,
+						Percent:        10,
+					}},
+					Configuration: ConfigurationSpec{
+						RevisionTemplate: RevisionTemplateSpec{
+							Spec: RevisionSpec{
+								Container: corev1.Container{
+									Image: "hellworld",
+								},
+							},
+						},
+					},
+				},
+			},
+		},
+		want: nil,
 	}, {
 		name: "valid manual",
 		s: &Service{
@@ -154,7 +184,7 @@ func TestServiceValidation(t *testing.T) {
 		s:    &Service{},
 		want: &apis.FieldError{
 			Message: "expected exactly one, got neither",
-			Paths:   []string{"spec.manual", "spec.pinned", "spec.release", "spec.runLatest"},
+			Paths:   []string{"spec.inline", "spec.manual", "spec.pinned", "spec.release", "spec.runLatest"},
 		},
 	}, {
 		name: "invalid runLatest",
@@ -475,3 +505,336 @@ func TestPinnedTypeValidation(t *testing.T) {
 		})
 	}
 }
+
+func TestReleaseTypeRolloutValidation(t *testing.T) {
+	configuration := ConfigurationSpec{
+		RevisionTemplate: RevisionTemplateSpec{
+			Spec: RevisionSpec{
+				Container: corev1.Container{
+					Image: "hellworld",
+				},
+			},
+		},
+	}
+	tests := []struct {
+		name string
+		rt   *ReleaseType
+		want *apis.FieldError
+	}{{
+		name: "valid",
+		rt: &ReleaseType{
+			Revisions: []string{"foo", "bar"},
+			Rollout: &RolloutPlan{
+				Steps: []RolloutStep{{
+					Percent: 0,
+					Pause:   metav1.Duration{Duration: time.Minute},
+				}, {
+					Percent: 10,
+					Pause:   metav1.Duration{Duration: 5 * time.Minute},
+				}, {
+					Percent: 99,
+				}},
+				Paused: true,
+			},
+			Configuration: configuration,
+		},
+		want: nil,
+	}, {
+		name: "rollback for single revision",
+		rt: &ReleaseType{
+			Revisions: []string{"foo"},
+			Rollback: &RollbackPolicy{
+				MaxErrorPercent: 5,
+			},
+			Configuration: configuration,
+		},
+		want: apis.ErrDisallowedFields("rollback"),
+	}, {
+		name: "single revision",
+		rt: &ReleaseType{
+			Revisions: []string{"foo"},
+			Rollout: &RolloutPlan{
+				Steps: []RolloutStep{{Percent: 50}},
+			},
+			Configuration: configuration,
+		},
+		want: apis.ErrDisallowedFields("rollout"),
+	}, {
+		name: "rollout percent and rollout plan",
+		rt: &ReleaseType{
+			Revisions:      []string{"foo", "bar"},
+			RolloutPercent: 10,
+			Rollout: &RolloutPlan{
+				Steps: []RolloutStep{{Percent: 50}},
+			},
+			Configuration: configuration,
+		},
+		want: apis.ErrMultipleOneOf("rolloutPercent", "rollout"),
+	}, {
+		name: "no steps",
+		rt: &ReleaseType{
+			Revisions:     []string{"foo", "bar"},
+			Rollout:       &RolloutPlan{},
+			Configuration: configuration,
+		},
+		want: apis.ErrMissingField("rollout.steps"),
+	}, {
+		name: "percent out of bounds",
+		rt: &ReleaseType{
+			Revisions: []string{"foo", "bar"},
+			Rollout: &RolloutPlan{
+				Steps: []RolloutStep{{Percent: 50}, {Percent: 100}},
+			},
+			Configuration: configuration,
+		},
+		want: apis.ErrOutOfBoundsValue("100", "0", "99", "rollout.steps[1].percent"),
+	}, {
+		name: "percent not increasing",
+		rt: &ReleaseType{
+			Revisions: []string{"foo", "bar"},
+			Rollout: &RolloutPlan{
+				Steps: []RolloutStep{{Percent: 50}, {Percent: 50}},
+			},
+			Configuration: configuration,
+		},
+		want: &apis.FieldError{
+			Message: "expected percent greater than the previous step's 50, got 50",
+			Paths:   []string{"rollout.steps[1].percent"},
+		},
+	}, {
+		name: "negative pause",
+		rt: &ReleaseType{
+			Revisions: []string{"foo", "bar"},
+			Rollout: &RolloutPlan{
+				Steps: []RolloutStep{{
+					Percent: 10,
+					Pause:   metav1.Duration{Duration: -time.Second},
+				}, {
+					Percent: 20,
+				}},
+			},
+			Configuration: configuration,
+		},
+		want: apis.ErrInvalidValue("-1s", "rollout.steps[0].pause"),
+	}}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			got := test.rt.Validate()
+			if diff := cmp.Diff(test.want.Error(), got.Error()); diff != "" {
+				t.Errorf("Validate (-want, +got) = %v", diff)
+			}
+		})
+	}
+}
+
+func TestInlineTypeValidation(t *testing.T) {
+	configuration := ConfigurationSpec{
+		RevisionTemplate: RevisionTemplateSpec{
+			Spec: RevisionSpec{
+				Container: corev1.Container{
+					Image: "hellworld",
+				},
+			},
+		},
+	}
+	notLatest := false
+	tests := []struct {
+		name string
+		it   *InlineType
+		want *apis.FieldError
+	}{{
+		name: "valid",
+		it: &InlineType{
+			Traffic: []TrafficTarget{{
+				Name:         "current",
+				RevisionName: "foo",
+				Percent:      50,
+			}, {
+				RevisionName: "bar",
+				Percent:      25,
+			}, {
+				Name:           "latest",
+				LatestRevision: &latestRevision,
+				Percent:        25,
+			}},
+			Configuration: configuration,
+		},
+		want: nil,
+	}, {
+		name: "missing traffic",
+		it: &InlineType{
+			Configuration: configuration,
+		},
+		want: apis.ErrMissingField("traffic"),
+	}, {
+		name: "configuration name",
+		it: &InlineType{
+			Traffic: []TrafficTarget{{
+				ConfigurationName: "foo",
+				LatestRevision:    &latestRevision,
+				Percent:           100,
+			}},
+			Configuration: configuration,
+		},
+		want: apis.ErrDisallowedFields("traffic[0].configurationName"),
+	}, {
+		name: "revision name and latest revision",
+		it: &InlineType{
+			Traffic: []TrafficTarget{{
+				RevisionName:   "foo",
+				LatestRevision: &latestRevision,
+				Percent:        100,
+			}},
+			Configuration: configuration,
+		},
+		want: apis.ErrMultipleOneOf("traffic[0].revisionName", "traffic[0].latestRevision"),
+	}, {
+		name: "neither revision name nor latest revision",
+		it: &InlineType{
+			Traffic: []TrafficTarget{{
+				LatestRevision: &notLatest,
+				Percent:        100,
+			}},
+			Configuration: configuration,
+		},
+		want: apis.ErrMissingOneOf("traffic[0].revisionName", "traffic[0].latestRevision"),
+	}, {
+		name: "invalid revision name",
+		it: &InlineType{
+			Traffic: []TrafficTarget{{
+				RevisionName: "b@r",
+				Percent:      100,
+			}},
+			Configuration: configuration,
+		},
+		want: &apis.FieldError{
+			Message: `invalid key name "b@r"`,
+			Paths:   []string{"traffic[0].revisionName"},
+			Details: `name part must consist of alphanumeric characters, '-', '_' or '.', and must start and end with an alphanumeric character (e.g. 'MyName',  or 'my.name',  or '123-abc', regex used for validation is '([A-Za-z0-9][-A-Za-z0-9_.]*)?[A-Za-z0-9]')`,
+		},
+	}, {
+		name: "duplicate names",
+		it: &InlineType{
+			Traffic: []TrafficTarget{{
+				Name:         "foo",
+				RevisionName: "bar",
+				Percent:      50,
+			}, {
+				Name:           "foo",
+				LatestRevision: &latestRevision,
+				Percent:        50,
+			}},
+			Configuration: configuration,
+		},
+		want: &apis.FieldError{
+			Message: `Multiple definitions for "foo"`,
+			Paths:   []string{"traffic[0].name", "traffic[1].name"},
+		},
+	}, {
+		name: "valid name collision (same revision)",
+		it: &InlineType{
+			Traffic: []TrafficTarget{{
+				Name:           "foo",
+				LatestRevision: &latestRevision,
+				Percent:        50,
+			}, {
+				Name:           "foo",
+				LatestRevision: &latestRevision,
+				Percent:        50,
+			}},
+			Configuration: configuration,
+		},
+		want: nil,
+	}, {
+		name: "percent out of bounds",
+		it: &InlineType{
+			Traffic: []TrafficTarget{{
+				RevisionName: "foo",
+				Percent:      101,
+			}, {
+				LatestRevision: &latestRevision,
+				Percent:        -1,
+			}},
+			Configuration: configuration,
+		},
+		want: apis.ErrOutOfBoundsValue("101", "0", "100", "traffic[0].percent").Also(
+			apis.ErrOutOfBoundsValue("-1", "0", "100", "traffic[1].percent")),
+	}, {
+		name: "percent sum not 100",
+		it: &InlineType{
+			Traffic: []TrafficTarget{{
+				RevisionName: "foo",
+				Percent:      50,
+			}, {
+				LatestRevision: &latestRevision,
+				Percent:        20,
+			}},
+			Configuration: configuration,
+		},
+		want: &apis.FieldError{
+			Message: "Traffic targets sum to 70, want 100",
+			Paths:   []string{"traffic"},
+		},
+	}}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			got := test.it.Validate()
+			if diff := cmp.Diff(test.want.Error(), got.Error()); diff != "" {
+				t.Errorf("Validate (-want, +got) = %v", diff)
+			}
+		})
+	}
+}
+
+func TestRollbackPolicyValidation(t *testing.T) {
+	tests := []struct {
+		name string
+		rp   *RollbackPolicy
+		want *apis.FieldError
+	}{{
+		name: "valid",
+		rp: &RollbackPolicy{
+			MaxErrorPercent: 5,
+			MaxLatency:      metav1.Duration{Duration: time.Second},
+			Window:          metav1.Duration{Duration: time.Minute},
+			MinRequests:     100,
+		},
+		want: nil,
+	}, {
+		name: "no thresholds",
+		rp:   &RollbackPolicy{},
+		want: apis.ErrMissingOneOf("maxErrorPercent", "maxLatency"),
+	}, {
+		name: "error percent out of bounds",
+		rp: &RollbackPolicy{
+			MaxErrorPercent: 101,
+		},
+		want: apis.ErrOutOfBoundsValue("101", "1", "100", "maxErrorPercent"),
+	}, {
+		name: "negative durations",
+		rp: &RollbackPolicy{
+			MaxLatency: metav1.Duration{Duration: -time.Second},
+			Window:     metav1.Duration{Duration: -time.Minute},
+		},
+		want: apis.ErrInvalidValue("-1s", "maxLatency").Also(
+			apis.ErrInvalidValue("-1m0s", "window")),
+	}, {
+		name: "negative min requests",
+		rp: &RollbackPolicy{
+			MaxErrorPercent: 5,
+			MinRequests:     -1,
+		},
+		want: apis.ErrInvalidValue("-1", "minRequests"),
+	}}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			got := test.rp.Validate()
+			if diff := cmp.Diff(test.want.Error(), got.Error()); diff != "" {
+				t.Errorf("Validate (-want, +got) = %v", diff)
+			}
+		})
+	}
+}
@@ -23,8 +23,9 @@ package v1alpha1
 import (
 	build_v1alpha1 "github.com/knative/build/pkg/apis/build/v1alpha1"
 	duck_v1alpha1 "github.com/knative/pkg/apis/duck/v1alpha1"
-	v1 "k8s.io/api/core/v1"
-	meta_v1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	networking_v1alpha1 "github.com/knative/serving/pkg/apis/networking/v1alpha1"
+	core_v1 "k8s.io/api/core/v1"
+	v1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	runtime "k8s.io/apimachinery/pkg/runtime"
 )
 
@@ -140,6 +141,30 @@ func (in *ConfigurationStatus) DeepCopy() *ConfigurationStatus {
 	return out
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *InlineType) DeepCopyInto(out *InlineType) {
+	*out = *in
+	if in.Traffic != nil {
+		in, out := &in.Traffic, &out.Traffic
+		*out = make([]TrafficTarget, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+	in.Configuration.DeepCopyInto(&out.Configuration)
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new InlineType.
+func (in *InlineType) DeepCopy() *InlineType {
+	if in == nil {
+		return nil
+	}
+	out := new(InlineType)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *ManualType) DeepCopyInto(out *ManualType) {
 	*out = *in
@@ -156,6 +181,45 @@ func (in *ManualType) DeepCopy() *ManualType {
 	return out
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *MirrorTarget) DeepCopyInto(out *MirrorTarget) {
+	*out = *in
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new MirrorTarget.
+func (in *MirrorTarget) DeepCopy() *MirrorTarget {
+	if in == nil {
+		return nil
+	}
+	out := new(MirrorTarget)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *PathTraffic) DeepCopyInto(out *PathTraffic) {
+	*out = *in
+	if in.Traffic != nil {
+		in, out := &in.Traffic, &out.Traffic
+		*out = make([]TrafficTarget, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new PathTraffic.
+func (in *PathTraffic) DeepCopy() *PathTraffic {
+	if in == nil {
+		return nil
+	}
+	out := new(PathTraffic)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *PinnedType) DeepCopyInto(out *PinnedType) {
 	*out = *in
@@ -216,6 +280,24 @@ func (in *ReleaseType) DeepCopyInto(out *ReleaseType) {
 		*out = make([]string, len(*in))
 		copy(*out, *in)
 	}
+	if in.Rollout != nil {
+		in, out := &in.Rollout, &out.Rollout
+		if *in == nil {
+			*out = nil
+		} else {
+			*out = new(RolloutPlan)
+			(*in).DeepCopyInto(*out)
+		}
+	}
+	if in.Rollback != nil {
+		in, out := &in.Rollback, &out.Rollback
+		if *in == nil {
+			*out = nil
+		} else {
+			*out = new(RollbackPolicy)
+			**out = **in
+		}
+	}
 	in.Configuration.DeepCopyInto(&out.Configuration)
 	return
 }
@@ -230,6 +312,36 @@ func (in *ReleaseType) DeepCopy() *ReleaseType {
 	return out
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *RetryPolicy) DeepCopyInto(out *RetryPolicy) {
+	*out = *in
+	if in.PerTryTimeout != nil {
+		in, out := &in.PerTryTimeout, &out.PerTryTimeout
+		if *in == nil {
+			*out = nil
+		} else {
+			*out = new(v1.Duration)
+			**out = **in
+		}
+	}
+	if in.RetryOn != nil {
+		in, out := &in.RetryOn, &out.RetryOn
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new RetryPolicy.
+func (in *RetryPolicy) DeepCopy() *RetryPolicy {
+	if in == nil {
+		return nil
+	}
+	out := new(RetryPolicy)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *Revision) DeepCopyInto(out *Revision) {
 	*out = *in
@@ -301,7 +413,7 @@ func (in *RevisionSpec) DeepCopyInto(out *RevisionSpec) {
 		if *in == nil {
 			*out = nil
 		} else {
-			*out = new(v1.ObjectReference)
+			*out = new(core_v1.ObjectReference)
 			**out = **in
 		}
 	}
@@ -311,7 +423,7 @@ func (in *RevisionSpec) DeepCopyInto(out *RevisionSpec) {
 		if *in == nil {
 			*out = nil
 		} else {
-			*out = new(meta_v1.Duration)
+			*out = new(v1.Duration)
 			**out = **in
 		}
 	}
@@ -369,6 +481,87 @@ func (in *RevisionTemplateSpec) DeepCopy() *RevisionTemplateSpec {
 	return out
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *RollbackPolicy) DeepCopyInto(out *RollbackPolicy) {
+	*out = *in
+	out.MaxLatency = in.MaxLatency
+	out.Window = in.Window
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new RollbackPolicy.
+func (in *RollbackPolicy) DeepCopy() *RollbackPolicy {
+	if in == nil {
+		return nil
+	}
+	out := new(RollbackPolicy)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *RolloutPlan) DeepCopyInto(out *RolloutPlan) {
+	*out = *in
+	if in.Steps != nil {
+		in, out := &in.Steps, &out.Steps
+		*out = make([]RolloutStep, len(*in))
+		copy(*out, *in)
+	}
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new RolloutPlan.
+func (in *RolloutPlan) DeepCopy() *RolloutPlan {
+	if in == nil {
+		return nil
+	}
+	out := new(RolloutPlan)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *RolloutStatus) DeepCopyInto(out *RolloutStatus) {
+	*out = *in
+	if in.NextTransitionTime != nil {
+		in, out := &in.NextTransitionTime, &out.NextTransitionTime
+		if *in == nil {
+			*out = nil
+		} else {
+			*out = new(v1.Time)
+			(*in).DeepCopyInto(*out)
+		}
+	}
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new RolloutStatus.
+func (in *RolloutStatus) DeepCopy() *RolloutStatus {
+	if in == nil {
+		return nil
+	}
+	out := new(RolloutStatus)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *RolloutStep) DeepCopyInto(out *RolloutStep) {
+	*out = *in
+	out.Pause = in.Pause
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new RolloutStep.
+func (in *RolloutStep) DeepCopy() *RolloutStep {
+	if in == nil {
+		return nil
+	}
+	out := new(RolloutStep)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *Route) DeepCopyInto(out *Route) {
 	*out = *in
@@ -438,7 +631,43 @@ func (in *RouteSpec) DeepCopyInto(out *RouteSpec) {
 	if in.Traffic != nil {
 		in, out := &in.Traffic, &out.Traffic
 		*out = make([]TrafficTarget, len(*in))
-		copy(*out, *in)
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+	if in.Paths != nil {
+		in, out := &in.Paths, &out.Paths
+		*out = make([]PathTraffic, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+	if in.Timeout != nil {
+		in, out := &in.Timeout, &out.Timeout
+		if *in == nil {
+			*out = nil
+		} else {
+			*out = new(v1.Duration)
+			**out = **in
+		}
+	}
+	if in.Retries != nil {
+		in, out := &in.Retries, &out.Retries
+		if *in == nil {
+			*out = nil
+		} else {
+			*out = new(RetryPolicy)
+			(*in).DeepCopyInto(*out)
+		}
+	}
+	if in.Mirror != nil {
+		in, out := &in.Mirror, &out.Mirror
+		if *in == nil {
+			*out = nil
+		} else {
+			*out = new(MirrorTarget)
+			**out = **in
+		}
 	}
 	return
 }
@@ -468,7 +697,16 @@ func (in *RouteStatus) DeepCopyInto(out *RouteStatus) {
 	if in.Traffic != nil {
 		in, out := &in.Traffic, &out.Traffic
 		*out = make([]TrafficTarget, len(*in))
-		copy(*out, *in)
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+	if in.Paths != nil {
+		in, out := &in.Paths, &out.Paths
+		*out = make([]PathTraffic, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
 	}
 	if in.Conditions != nil {
 		in, out := &in.Conditions, &out.Conditions
@@ -609,6 +847,15 @@ func (in *ServiceSpec) DeepCopyInto(out *ServiceSpec) {
 			(*in).DeepCopyInto(*out)
 		}
 	}
+	if in.Inline != nil {
+		in, out := &in.Inline, &out.Inline
+		if *in == nil {
+			*out = nil
+		} else {
+			*out = new(InlineType)
+			(*in).DeepCopyInto(*out)
+		}
+	}
 	return
 }
 
@@ -644,7 +891,18 @@ func (in *ServiceStatus) DeepCopyInto(out *ServiceStatus) {
 	if in.Traffic != nil {
 		in, out := &in.Traffic, &out.Traffic
 		*out = make([]TrafficTarget, len(*in))
-		copy(*out, *in)
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+	if in.Rollout != nil {
+		in, out := &in.Rollout, &out.Rollout
+		if *in == nil {
+			*out = nil
+		} else {
+			*out = new(RolloutStatus)
+			(*in).DeepCopyInto(*out)
+		}
 	}
 	return
 }
@@ -662,6 +920,31 @@ func (in *ServiceStatus) DeepCopy() *ServiceStatus {
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *TrafficTarget) DeepCopyInto(out *TrafficTarget) {
 	*out = *in
+	if in.LatestRevision != nil {
+		in, out := &in.LatestRevision, &out.LatestRevision
+		if *in == nil {
+			*out = nil
+		} else {
+			*out = new(bool)
+			**out = **in
+		}
+	}
+	if in.Headers != nil {
+		in, out := &in.Headers, &out.Headers
+		*out = make(map[string]networking_v1alpha1.HeaderMatch, len(*in))
+		for key, val := range *in {
+			(*out)[key] = val
+		}
+	}
+	if in.Address != nil {
+		in, out := &in.Address, &out.Address
+		if *in == nil {
+			*out = nil
+		} else {
+			*out = new(duck_v1alpha1.Addressable)
+			**out = **in
+		}
+	}
 	return
 }
 
@@ -0,0 +1,91 @@
+/*
+Copyright 2018 The Knative Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package http
+
+import (
+	"net/http"
+	"strconv"
+	"strings"
+
+	"google.golang.org/grpc/codes"
+)
+
+const (
+	// GRPCStatusHeaderName is the trailer, or header for responses
+	// without a body, carrying the status of a gRPC call.
+	GRPCStatusHeaderName = "Grpc-Status"
+
+	grpcContentType = "application/grpc"
+)
+
+// IsGRPCRequest returns true if r is a gRPC call.
+func IsGRPCRequest(r *http.Request) bool {
+	ct := r.Header.Get("Content-Type")
+	return ct == grpcContentType || strings.HasPrefix(ct, grpcContentType+"+") ||
+		strings.HasPrefix(ct, grpcContentType+";")
+}
+
+// GRPCStatus returns the status of the gRPC call whose response headers
+// are h. It looks for the status both in the headers and in the trailers
+// that have been set in h by an http.Handler, e.g. a httputil.ReverseProxy.
+func GRPCStatus(h http.Header) (codes.Code, bool) {
+	for _, key := range []string{GRPCStatusHeaderName, http.TrailerPrefix + GRPCStatusHeaderName} {
+		if v := LastHeaderValue(h, key); v != "" {
+			code, err := strconv.ParseUint(v, 10, 32)
+			if err != nil {
+				return codes.Unknown, true
+			}
+			return codes.Code(code), true
+		}
+	}
+	return codes.OK, false
+}
+
+// HTTPStatusFromGRPCCode returns the HTTP status code corresponding to the
+// gRPC status code c, so that gRPC calls can be reported alongside HTTP
+// requests.
+// See https://github.com/googleapis/googleapis/blob/master/google/rpc/code.proto
+func HTTPStatusFromGRPCCode(c codes.Code) int {
+	switch c {
+	case codes.OK:
+		return http.StatusOK
+	case codes.Canceled:
+		// Client Closed Request, as used by nginx.
+		return 499
+	case codes.InvalidArgument, codes.FailedPrecondition, codes.OutOfRange:
+		return http.StatusBadRequest
+	case codes.DeadlineExceeded:
+		return http.StatusGatewayTimeout
+	case codes.NotFound:
+		return http.StatusNotFound
+	case codes.AlreadyExists, codes.Aborted:
+		return http.StatusConflict
+	case codes.PermissionDenied:
+		return http.StatusForbidden
+	case codes.Unauthenticated:
+		return http.StatusUnauthorized
+	case codes.ResourceExhausted:
+		return http.StatusTooManyRequests
+	case codes.Unimplemented:
+		return http.StatusNotImplemented
+	case codes.Unavailable:
+		return http.StatusServiceUnavailable
+	default:
+		// Unknown, Internal, DataLoss and codes we don't know about.
+		return http.StatusInternalServerError
+	}
+}
@@ -0,0 +1,127 @@
+/*
+Copyright 2018 The Knative Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package http
+
+import (
+	"net/http"
+	"testing"
+
+	"google.golang.org/grpc/codes"
+)
+
+func TestIsGRPCRequest(t *testing.T) {
+	tests := []struct {
+		contentType string
+		want        bool
+	}{{
+		contentType: "application/grpc",
+		want:        true,
+	}, {
+		contentType: "application/grpc+proto",
+		want:        true,
+	}, {
+		contentType: "application/grpc; charset=utf-8",
+		want:        true,
+	}, {
+		contentType: "application/grpc-web",
+		want:        false,
+	}, {
+		contentType: "application/json",
+		want:        false,
+	}, {
+		contentType: "",
+		want:        false,
+	}}
+
+	for _, test := range tests {
+		t.Run(test.contentType, func(t *testing.T) {
+			r, err := http.NewRequest(http.MethodPost, "http://example.com/", nil)
+			if err != nil {
+				t.Fatalf("Failed to create request: %v", err)
+			}
+			r.Header.Set("Content-Type", test.contentType)
+			if got := IsGRPCRequest(r); got != test.want {
+				t.Errorf("IsGRPCRequest() = %v, want %v", got, test.want)
+			}
+		})
+	}
+}
+
+func TestGRPCStatus(t *testing.T) {
+	tests := []struct {
+		name     string
+		header   http.Header
+		wantCode codes.Code
+		wantOK   bool
+	}{{
+		name:     "no status",
+		header:   http.Header{"Content-Type": {"application/grpc"}},
+		wantCode: codes.OK,
+		wantOK:   false,
+	}, {
+		name:     "status in header",
+		header:   http.Header{"Grpc-Status": {"5"}},
+		wantCode: codes.NotFound,
+		wantOK:   true,
+	}, {
+		name:     "status in trailer",
+		header:   http.Header{http.TrailerPrefix + "Grpc-Status": {"14"}},
+		wantCode: codes.Unavailable,
+		wantOK:   true,
+	}, {
+		name:     "ok status",
+		header:   http.Header{"Grpc-Status": {"0"}},
+		wantCode: codes.OK,
+		wantOK:   true,
+	}, {
+		name:     "invalid status",
+		header:   http.Header{"Grpc-Status": {"foo"}},
+		wantCode: codes.Unknown,
+		wantOK:   true,
+	}}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			code, ok := GRPCStatus(test.header)
+			if code != test.wantCode || ok != test.wantOK {
+				t.Errorf("GRPCStatus() = (%v, %v), want (%v, %v)", code, ok, test.wantCode, test.wantOK)
+			}
+		})
+	}
+}
+
+func TestHTTPStatusFromGRPCCode(t *testing.T) {
+	tests := map[codes.Code]int{
+		codes.OK:                http.StatusOK,
+		codes.Canceled:          499,
+		codes.InvalidArgument:   http.StatusBadRequest,
+		codes.DeadlineExceeded:  http.StatusGatewayTimeout,
+		codes.NotFound:          http.StatusNotFound,
+		codes.PermissionDenied:  http.StatusForbidden,
+		codes.Unauthenticated:   http.StatusUnauthorized,
+		codes.ResourceExhausted: http.StatusTooManyRequests,
+		codes.Unimplemented:     http.StatusNotImplemented,
+		codes.Unavailable:       http.StatusServiceUnavailable,
+		codes.Internal:          http.StatusInternalServerError,
+		codes.Code(42):          http.StatusInternalServerError,
+	}
+	for code, want := range tests {
+		if got := HTTPStatusFromGRPCCode(code); got != want {
+			t.Errorf("HTTPStatusFromGRPCCode(%v) = %d, want %d", code, got, want)
+		}
+	}
+}
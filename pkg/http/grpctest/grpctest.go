@@ -0,0 +1,154 @@
+/*
+Copyright 2018 The Knative Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package grpctest provides an in-process gRPC server, and a client for
+// it, to test the proxying of gRPC calls.
+package grpctest
+
+import (
+	"context"
+	"net"
+
+	"github.com/golang/protobuf/ptypes/wrappers"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+const (
+	serviceName      = "grpctest.Test"
+	unaryMethod      = "/" + serviceName + "/Unary"
+	serverStreamName = "ServerStream"
+	serverStream     = "/" + serviceName + "/" + serverStreamName
+)
+
+// Server is an in-process gRPC server. Its Unary call echoes its request
+// and its ServerStream call echoes its request, then echoes it again once
+// Release is closed.
+// Both fail with Code if it isn't codes.OK.
+type Server struct {
+	// Addr is the address the server listens on, as host:port.
+	Addr string
+	// Code is the status the calls fail with, unless it is codes.OK.
+	Code codes.Code
+	// Release is closed to end the ServerStream calls.
+	Release chan struct{}
+
+	listener net.Listener
+	server   *grpc.Server
+}
+
+// NewServer starts a Server on a random local port.
+func NewServer() (*Server, error) {
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		return nil, err
+	}
+	s := &Server{
+		Addr:     l.Addr().String(),
+		Code:     codes.OK,
+		Release:  make(chan struct{}),
+		listener: l,
+		server:   grpc.NewServer(),
+	}
+	s.server.RegisterService(&grpc.ServiceDesc{
+		ServiceName: serviceName,
+		HandlerType: (*interface{})(nil),
+		Methods: []grpc.MethodDesc{{
+			MethodName: "Unary",
+			Handler:    s.unary,
+		}},
+		Streams: []grpc.StreamDesc{{
+			StreamName:    serverStreamName,
+			Handler:       s.serverStream,
+			ServerStreams: true,
+		}},
+	}, s)
+	go s.server.Serve(l)
+	return s, nil
+}
+
+// Close stops the server.
+func (s *Server) Close() {
+	s.server.Stop()
+}
+
+func (s *Server) unary(_ interface{}, ctx context.Context, dec func(interface{}) error, _ grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := &wrappers.StringValue{}
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if s.Code != codes.OK {
+		return nil, status.Error(s.Code, in.Value)
+	}
+	return in, nil
+}
+
+func (s *Server) serverStream(_ interface{}, stream grpc.ServerStream) error {
+	in := &wrappers.StringValue{}
+	if err := stream.RecvMsg(in); err != nil {
+		return err
+	}
+	if s.Code != codes.OK {
+		return status.Error(s.Code, in.Value)
+	}
+	if err := stream.SendMsg(in); err != nil {
+		return err
+	}
+	select {
+	case <-s.Release:
+	case <-stream.Context().Done():
+		return stream.Context().Err()
+	}
+	return stream.SendMsg(in)
+}
+
+// Dial connects to the gRPC server at addr, without TLS.
+func Dial(addr string) (*grpc.ClientConn, error) {
+	return grpc.Dial(addr, grpc.WithInsecure())
+}
+
+// Unary makes a Unary call with msg over conn and returns its response.
+func Unary(ctx context.Context, conn *grpc.ClientConn, msg string) (string, error) {
+	out := &wrappers.StringValue{}
+	if err := conn.Invoke(ctx, unaryMethod, &wrappers.StringValue{Value: msg}, out); err != nil {
+		return "", err
+	}
+	return out.Value, nil
+}
+
+// ServerStream makes a ServerStream call with msg over conn, and returns a
+// func receiving the next response, which returns io.EOF once the call has
+// ended successfully.
+func ServerStream(ctx context.Context, conn *grpc.ClientConn, msg string) (func() (string, error), error) {
+	stream, err := conn.NewStream(ctx, &grpc.StreamDesc{ServerStreams: true}, serverStream)
+	if err != nil {
+		return nil, err
+	}
+	if err := stream.SendMsg(&wrappers.StringValue{Value: msg}); err != nil {
+		return nil, err
+	}
+	if err := stream.CloseSend(); err != nil {
+		return nil, err
+	}
+	return func() (string, error) {
+		out := &wrappers.StringValue{}
+		if err := stream.RecvMsg(out); err != nil {
+			return "", err
+		}
+		return out.Value, nil
+	}, nil
+}
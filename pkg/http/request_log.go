@@ -0,0 +1,153 @@
+/*
+Copyright 2018 The Knative Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package http
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"text/template"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+// RequestIDHeaderName is the header carrying the id of the request
+// that is propagated by Istio and included in request logs.
+const RequestIDHeaderName = "X-Request-Id"
+
+// RequestLogHandler implements an http.Handler that writes a structured
+// log entry for every request it serves.
+type RequestLogHandler struct {
+	handler  http.Handler
+	logger   *zap.SugaredLogger
+	template *template.Template
+}
+
+// RequestLogResponse captures the properties of the response that
+// are available to the request log template.
+type RequestLogResponse struct {
+	Code    int
+	Size    int
+	Latency float64
+}
+
+// RequestLogTemplateInput is the data handed to the request log
+// template for every request.
+type RequestLogTemplateInput struct {
+	Request  *http.Request
+	Response *RequestLogResponse
+}
+
+// NewRequestLogHandler creates an http.Handler that logs every request
+// served by h through logger. The message of every log entry is produced by
+// executing templateStr against a RequestLogTemplateInput.
+func NewRequestLogHandler(h http.Handler, logger *zap.SugaredLogger, templateStr string) (*RequestLogHandler, error) {
+	t, err := template.New("requestLog").Parse(templateStr)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse request log template: %v", err)
+	}
+	return &RequestLogHandler{
+		handler:  h,
+		logger:   logger,
+		template: t,
+	}, nil
+}
+
+func (h *RequestLogHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	rr := &responseRecorder{
+		ResponseWriter: w,
+		statusCode:     http.StatusOK,
+	}
+	start := time.Now()
+	defer func() {
+		// Log even when the next handler panics, so that aborted
+		// requests still show up.
+		h.write(r, &RequestLogResponse{
+			Code:    rr.statusCode,
+			Size:    rr.size,
+			Latency: time.Since(start).Seconds(),
+		})
+	}()
+	h.handler.ServeHTTP(rr, r)
+}
+
+func (h *RequestLogHandler) write(r *http.Request, resp *RequestLogResponse) {
+	buf := &bytes.Buffer{}
+	if err := h.template.Execute(buf, &RequestLogTemplateInput{Request: r, Response: resp}); err != nil {
+		h.logger.Errorw("Failed to execute request log template", zap.Error(err))
+		return
+	}
+	h.logger.Infow(buf.String(),
+		zap.String("method", r.Method),
+		zap.String("path", r.URL.Path),
+		zap.String("host", r.Host),
+		zap.String("protocol", r.Proto),
+		zap.String("remoteAddr", r.RemoteAddr),
+		zap.String("userAgent", r.UserAgent()),
+		zap.String("requestId", r.Header.Get(RequestIDHeaderName)),
+		zap.Int("status", resp.Code),
+		zap.Int("bytes", resp.Size),
+		zap.Float64("latency", resp.Latency))
+}
+
+// responseRecorder records the status code and the number of bytes
+// written by the wrapped handler.
+type responseRecorder struct {
+	http.ResponseWriter
+	statusCode  int
+	size        int
+	wroteHeader bool
+}
+
+// Flush implements http.Flusher so that streaming responses keep working.
+func (rr *responseRecorder) Flush() {
+	if f, ok := rr.ResponseWriter.(http.Flusher); ok {
+		f.Flush()
+	}
+}
+
+// Hijack implements http.Hijacker so that connections can be upgraded,
+// e.g. to WebSockets.
+func (rr *responseRecorder) Hijack() (net.Conn, *bufio.ReadWriter, error) {
+	h, ok := rr.ResponseWriter.(http.Hijacker)
+	if !ok {
+		return nil, nil, errors.New("the underlying ResponseWriter doesn't implement http.Hijacker")
+	}
+	rr.wroteHeader = true
+	rr.statusCode = http.StatusSwitchingProtocols
+	return h.Hijack()
+}
+
+func (rr *responseRecorder) Write(p []byte) (int, error) {
+	rr.wroteHeader = true
+	n, err := rr.ResponseWriter.Write(p)
+	rr.size += n
+	return n, err
+}
+
+func (rr *responseRecorder) WriteHeader(code int) {
+	if rr.wroteHeader {
+		return
+	}
+	rr.wroteHeader = true
+	rr.statusCode = code
+	rr.ResponseWriter.WriteHeader(code)
+}
@@ -0,0 +1,169 @@
+/*
+Copyright 2018 The Knative Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package http
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+)
+
+func newBufferLogger(buf *bytes.Buffer) *zap.SugaredLogger {
+	encoder := zapcore.NewJSONEncoder(zapcore.EncoderConfig{
+		MessageKey: "msg",
+	})
+	return zap.New(zapcore.NewCore(encoder, zapcore.AddSync(buf), zap.DebugLevel)).Sugar()
+}
+
+func TestRequestLogHandler(t *testing.T) {
+	tests := []struct {
+		name     string
+		template string
+		status   int
+		body     string
+		wantMsg  string
+	}{{
+		name:     "static template",
+		template: "static",
+		status:   http.StatusOK,
+		body:     "hello",
+		wantMsg:  "static",
+	}, {
+		name:     "request and response fields",
+		template: `{{.Request.Method}} {{.Request.URL.Path}} {{.Response.Code}} {{.Response.Size}}`,
+		status:   http.StatusTeapot,
+		body:     "short and stout",
+		wantMsg:  "GET /foo 418 15",
+	}, {
+		name:     "request header",
+		template: `{{.Request.Header.Get "X-Request-Id"}}`,
+		status:   http.StatusOK,
+		wantMsg:  "the-request-id",
+	}}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			buf := &bytes.Buffer{}
+			h, err := NewRequestLogHandler(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				w.WriteHeader(test.status)
+				w.Write([]byte(test.body))
+			}), newBufferLogger(buf), test.template)
+			if err != nil {
+				t.Fatalf("NewRequestLogHandler() = %v", err)
+			}
+
+			req := httptest.NewRequest(http.MethodGet, "http://example.com/foo", nil)
+			req.Header.Set(RequestIDHeaderName, "the-request-id")
+			resp := httptest.NewRecorder()
+			h.ServeHTTP(resp, req)
+
+			if resp.Code != test.status {
+				t.Errorf("Status = %d, want %d", resp.Code, test.status)
+			}
+
+			got := map[string]interface{}{}
+			if err := json.Unmarshal(buf.Bytes(), &got); err != nil {
+				t.Fatalf("Failed to parse log entry %q: %v", buf.String(), err)
+			}
+			if got["msg"] != test.wantMsg {
+				t.Errorf("msg = %v, want %q", got["msg"], test.wantMsg)
+			}
+			if got["method"] != http.MethodGet {
+				t.Errorf("method = %v, want %q", got["method"], http.MethodGet)
+			}
+			if got["path"] != "/foo" {
+				t.Errorf("path = %v, want %q", got["path"], "/foo")
+			}
+			if got["requestId"] != "the-request-id" {
+				t.Errorf("requestId = %v, want %q", got["requestId"], "the-request-id")
+			}
+			if got["status"] != float64(test.status) {
+				t.Errorf("status = %v, want %d", got["status"], test.status)
+			}
+			if got["bytes"] != float64(len(test.body)) {
+				t.Errorf("bytes = %v, want %d", got["bytes"], len(test.body))
+			}
+			if _, ok := got["latency"]; !ok {
+				t.Error("Expected latency to be logged")
+			}
+		})
+	}
+}
+
+func TestRequestLogHandlerBadTemplate(t *testing.T) {
+	if _, err := NewRequestLogHandler(http.NotFoundHandler(), zap.NewNop().Sugar(), "{{.Request.Method"); err == nil {
+		t.Error("NewRequestLogHandler() = nil, wanted error")
+	}
+}
+
+func TestRequestLogHandlerHijack(t *testing.T) {
+	buf := &bytes.Buffer{}
+	h, err := NewRequestLogHandler(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		conn, brw, err := w.(http.Hijacker).Hijack()
+		if err != nil {
+			t.Errorf("Hijack() = %v", err)
+			return
+		}
+		defer conn.Close()
+		brw.WriteString("HTTP/1.1 101 Switching Protocols\r\nConnection: Upgrade\r\nUpgrade: test\r\n\r\n")
+		brw.Flush()
+	}), newBufferLogger(buf), "upgraded")
+	if err != nil {
+		t.Fatalf("NewRequestLogHandler() = %v", err)
+	}
+	logged := make(chan struct{})
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		defer close(logged)
+		h.ServeHTTP(w, r)
+	}))
+	defer server.Close()
+
+	req, _ := http.NewRequest(http.MethodGet, server.URL, nil)
+	req.Header.Set("Connection", "Upgrade")
+	req.Header.Set("Upgrade", "test")
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatalf("Do() = %v", err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusSwitchingProtocols {
+		t.Errorf("Status = %d, want %d", resp.StatusCode, http.StatusSwitchingProtocols)
+	}
+
+	// The log entry is written once the handler returns, which may be
+	// after the client got the response.
+	<-logged
+	got := map[string]interface{}{}
+	if err := json.Unmarshal(buf.Bytes(), &got); err != nil {
+		t.Fatalf("Failed to parse log entry %q: %v", buf.String(), err)
+	}
+	if got["status"] != float64(http.StatusSwitchingProtocols) {
+		t.Errorf("status = %v, want %d", got["status"], http.StatusSwitchingProtocols)
+	}
+}
+
+func TestResponseRecorderHijackUnsupported(t *testing.T) {
+	rr := &responseRecorder{ResponseWriter: httptest.NewRecorder()}
+	if _, _, err := rr.Hijack(); err == nil {
+		t.Error("Hijack() = nil, wanted error")
+	}
+}
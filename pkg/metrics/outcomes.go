@@ -0,0 +1,190 @@
+/*
+Copyright 2018 The Knative Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package metrics
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"sync"
+	"time"
+
+	"github.com/prometheus/client_golang/api"
+	prometheusv1 "github.com/prometheus/client_golang/api/prometheus/v1"
+	"github.com/prometheus/common/model"
+	"go.uber.org/zap"
+	corev1 "k8s.io/api/core/v1"
+)
+
+const (
+	// QueryURLKey is the config-observability key holding the address of
+	// the Prometheus server that request outcomes are queried from.
+	QueryURLKey = "metrics.query-url"
+)
+
+// ErrNoOutcomesSource is returned when request outcomes are queried while
+// no source for them is configured.
+var ErrNoOutcomesSource = errors.New("no source for request outcomes is configured")
+
+// RequestOutcomes summarizes the requests served by a Revision over a
+// window of time.
+type RequestOutcomes struct {
+	// Requests is the number of requests served.
+	Requests float64
+
+	// Errors is the number of requests that failed with a 5xx response code.
+	Errors float64
+
+	// P99Latency is the 99th percentile latency of the requests.
+	P99Latency time.Duration
+}
+
+// ErrorPercent returns the percent of requests that failed.
+func (o *RequestOutcomes) ErrorPercent() float64 {
+	if o.Requests == 0 {
+		return 0
+	}
+	return 100 * o.Errors / o.Requests
+}
+
+// RequestOutcomesSource queries the outcomes of the requests served by
+// Revisions.
+type RequestOutcomesSource interface {
+	// RevisionOutcomes returns the outcomes of the requests served by the
+	// named Revision during the trailing window.
+	RevisionOutcomes(ctx context.Context, namespace, revision string, window time.Duration) (*RequestOutcomes, error)
+}
+
+// prometheusOutcomes reads request outcomes from the Istio revision
+// metrics scraped by Prometheus.
+type prometheusOutcomes struct {
+	api prometheusv1.API
+}
+
+var _ RequestOutcomesSource = (*prometheusOutcomes)(nil)
+
+// NewPrometheusOutcomesSource creates a RequestOutcomesSource that queries
+// the Prometheus server at the given address.
+func NewPrometheusOutcomesSource(address string) (RequestOutcomesSource, error) {
+	client, err := api.NewClient(api.Config{Address: address})
+	if err != nil {
+		return nil, err
+	}
+	return &prometheusOutcomes{api: prometheusv1.NewAPI(client)}, nil
+}
+
+// RevisionOutcomes implements RequestOutcomesSource.
+func (p *prometheusOutcomes) RevisionOutcomes(ctx context.Context, namespace, revision string, window time.Duration) (*RequestOutcomes, error) {
+	selector := fmt.Sprintf("destination_namespace=%q, destination_revision=%q", namespace, revision)
+	rng := fmt.Sprintf("%ds", int64(math.Ceil(window.Seconds())))
+
+	requests, err := p.query(ctx, fmt.Sprintf("sum(increase(istio_revision_request_count{%s}[%s]))", selector, rng))
+	if err != nil {
+		return nil, err
+	}
+	errs, err := p.query(ctx, fmt.Sprintf(`sum(increase(istio_revision_request_count{%s, response_code=~"5.."}[%s]))`, selector, rng))
+	if err != nil {
+		return nil, err
+	}
+	latency, err := p.query(ctx, fmt.Sprintf("histogram_quantile(0.99, sum(rate(istio_revision_request_duration_bucket{%s}[%s])) by (le))", selector, rng))
+	if err != nil {
+		return nil, err
+	}
+
+	return &RequestOutcomes{
+		Requests:   requests,
+		Errors:     errs,
+		P99Latency: time.Duration(latency * float64(time.Second)),
+	}, nil
+}
+
+// query runs the given query and returns its single result, or zero if
+// there is no data for it.
+func (p *prometheusOutcomes) query(ctx context.Context, query string) (float64, error) {
+	value, err := p.api.Query(ctx, query, time.Time{})
+	if err != nil {
+		return 0, fmt.Errorf("failed to query %q: %v", query, err)
+	}
+	vector, ok := value.(model.Vector)
+	if !ok {
+		return 0, fmt.Errorf("unexpected result type %v for query %q", value.Type(), query)
+	}
+	if len(vector) == 0 {
+		return 0, nil
+	}
+	if v := float64(vector[0].Value); !math.IsNaN(v) {
+		return v, nil
+	}
+	return 0, nil
+}
+
+// DynamicOutcomesSource is a RequestOutcomesSource whose Prometheus server is
+// kept up to date with the config-observability ConfigMap.
+type DynamicOutcomesSource struct {
+	mutex   sync.RWMutex
+	address string
+	source  RequestOutcomesSource
+
+	logger *zap.SugaredLogger
+}
+
+var _ RequestOutcomesSource = (*DynamicOutcomesSource)(nil)
+
+// NewDynamicOutcomesSource creates a DynamicOutcomesSource that has no
+// Prometheus server until Update is called.
+func NewDynamicOutcomesSource(logger *zap.SugaredLogger) *DynamicOutcomesSource {
+	return &DynamicOutcomesSource{logger: logger}
+}
+
+// RevisionOutcomes implements RequestOutcomesSource. It returns
+// ErrNoOutcomesSource if no Prometheus server is configured.
+func (d *DynamicOutcomesSource) RevisionOutcomes(ctx context.Context, namespace, revision string, window time.Duration) (*RequestOutcomes, error) {
+	d.mutex.RLock()
+	source := d.source
+	d.mutex.RUnlock()
+
+	if source == nil {
+		return nil, ErrNoOutcomesSource
+	}
+	return source.RevisionOutcomes(ctx, namespace, revision, window)
+}
+
+// Update switches to the Prometheus server named in the config-observability
+// ConfigMap. An empty or missing address disables the source.
+func (d *DynamicOutcomesSource) Update(configMap *corev1.ConfigMap) {
+	address := configMap.Data[QueryURLKey]
+
+	d.mutex.Lock()
+	defer d.mutex.Unlock()
+
+	if address == d.address {
+		return
+	}
+	if address == "" {
+		d.logger.Info("Disabled querying request outcomes")
+		d.address, d.source = "", nil
+		return
+	}
+	source, err := NewPrometheusOutcomesSource(address)
+	if err != nil {
+		d.logger.Errorf("Error updating request outcomes source to %q: %v", address, err)
+		return
+	}
+	d.logger.Infof("Querying request outcomes from %q", address)
+	d.address, d.source = address, source
+}
@@ -0,0 +1,163 @@
+/*
+Copyright 2018 The Knative Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package metrics
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+	"go.uber.org/zap"
+	corev1 "k8s.io/api/core/v1"
+)
+
+type queryResult struct {
+	match string
+	value string
+}
+
+// fakePrometheus answers instant queries with the value of the first result
+// whose match is contained in the query, and with no data otherwise.
+func fakePrometheus(t *testing.T, results []queryResult) *httptest.Server {
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/api/v1/query" {
+			t.Errorf("Path = %q, want /api/v1/query", r.URL.Path)
+		}
+		query := r.URL.Query().Get("query")
+		if !strings.Contains(query, `destination_namespace="foo", destination_revision="bar"`) {
+			t.Errorf("Query = %q, wanted it to select the revision", query)
+		}
+		if !strings.Contains(query, "[60s]") {
+			t.Errorf("Query = %q, wanted it to cover the window", query)
+		}
+		result := "[]"
+		for _, qr := range results {
+			if strings.Contains(query, qr.match) {
+				result = fmt.Sprintf(`[{"metric": {}, "value": [1541000000, %q]}]`, qr.value)
+				break
+			}
+		}
+		fmt.Fprintf(w, `{"status": "success", "data": {"resultType": "vector", "result": %s}}`, result)
+	}))
+}
+
+func TestPrometheusOutcomes(t *testing.T) {
+	tests := []struct {
+		name    string
+		results []queryResult
+		want    *RequestOutcomes
+	}{{
+		name: "no data",
+		want: &RequestOutcomes{},
+	}, {
+		name: "requests",
+		results: []queryResult{
+			{"histogram_quantile", "0.25"},
+			{`response_code=~"5.."`, "5"},
+			{"istio_revision_request_count", "200"},
+		},
+		want: &RequestOutcomes{
+			Requests:   200,
+			Errors:     5,
+			P99Latency: 250 * time.Millisecond,
+		},
+	}, {
+		name: "no latency",
+		results: []queryResult{
+			{"histogram_quantile", "NaN"},
+			{`response_code=~"5.."`, "0"},
+			{"istio_revision_request_count", "0"},
+		},
+		want: &RequestOutcomes{},
+	}}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			server := fakePrometheus(t, test.results)
+			defer server.Close()
+
+			source, err := NewPrometheusOutcomesSource(server.URL)
+			if err != nil {
+				t.Fatalf("NewPrometheusOutcomesSource() = %v", err)
+			}
+			got, err := source.RevisionOutcomes(context.Background(), "foo", "bar", time.Minute)
+			if err != nil {
+				t.Fatalf("RevisionOutcomes() = %v", err)
+			}
+			if diff := cmp.Diff(test.want, got); diff != "" {
+				t.Errorf("RevisionOutcomes (-want, +got) = %v", diff)
+			}
+		})
+	}
+}
+
+func TestPrometheusOutcomesError(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, `{"status": "error", "errorType": "bad_data", "error": "nope"}`, http.StatusBadRequest)
+	}))
+	defer server.Close()
+
+	source, err := NewPrometheusOutcomesSource(server.URL)
+	if err != nil {
+		t.Fatalf("NewPrometheusOutcomesSource() = %v", err)
+	}
+	if _, err := source.RevisionOutcomes(context.Background(), "foo", "bar", time.Minute); err == nil {
+		t.Error("RevisionOutcomes() = nil, wanted an error")
+	}
+}
+
+func TestRequestOutcomesErrorPercent(t *testing.T) {
+	if got, want := (&RequestOutcomes{}).ErrorPercent(), 0.0; got != want {
+		t.Errorf("ErrorPercent() = %v, wanted %v", got, want)
+	}
+	if got, want := (&RequestOutcomes{Requests: 200, Errors: 5}).ErrorPercent(), 2.5; got != want {
+		t.Errorf("ErrorPercent() = %v, wanted %v", got, want)
+	}
+}
+
+func TestDynamicOutcomesSource(t *testing.T) {
+	server := fakePrometheus(t, []queryResult{{"istio_revision_request_count", "10"}})
+	defer server.Close()
+
+	source := NewDynamicOutcomesSource(zap.NewNop().Sugar())
+	if _, err := source.RevisionOutcomes(context.Background(), "foo", "bar", time.Minute); err != ErrNoOutcomesSource {
+		t.Errorf("RevisionOutcomes() = %v, wanted %v", err, ErrNoOutcomesSource)
+	}
+
+	source.Update(&corev1.ConfigMap{
+		Data: map[string]string{
+			QueryURLKey: server.URL,
+		},
+	})
+	got, err := source.RevisionOutcomes(context.Background(), "foo", "bar", time.Minute)
+	if err != nil {
+		t.Fatalf("RevisionOutcomes() = %v", err)
+	}
+	if got, want := got.Requests, 10.0; got != want {
+		t.Errorf("Requests = %v, wanted %v", got, want)
+	}
+
+	source.Update(&corev1.ConfigMap{})
+	if _, err := source.RevisionOutcomes(context.Background(), "foo", "bar", time.Minute); err != ErrNoOutcomesSource {
+		t.Errorf("RevisionOutcomes() = %v, wanted %v", err, ErrNoOutcomesSource)
+	}
+}
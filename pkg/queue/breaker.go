@@ -17,6 +17,7 @@ limitations under the License.
 package queue
 
 import (
+	"context"
 	"errors"
 	"fmt"
 	"sync"
@@ -24,6 +25,14 @@ import (
 
 type token struct{}
 
+// ErrUpdateCapacity is returned when the capacity of a Semaphore can't be
+// updated to the requested size.
+var ErrUpdateCapacity = errors.New("failed to update the capacity of the semaphore")
+
+// ErrRequestQueueFull is returned by Breaker.MaybeContext when the queue of
+// pending requests is full.
+var ErrRequestQueueFull = errors.New("pending request queue is full")
+
 // Breaker is a component that enforces a concurrency limit on the
 // execution of a function. It also maintains a queue of function
 // executions in excess of the concurrency limit. Function call attempts
@@ -57,25 +66,48 @@ func NewBreaker(queueDepth, maxConcurrency, initialCapacity int32) *Breaker {
 // already consumed, Maybe returns immediately without calling thunk. If
 // the thunk was executed, Maybe returns true, else false.
 func (b *Breaker) Maybe(thunk func()) bool {
+	return b.MaybeContext(context.Background(), thunk) == nil
+}
+
+// MaybeContext is like Maybe, but gives up waiting for capacity once ctx
+// is done, returning its error without calling thunk. It returns
+// ErrRequestQueueFull if the queue is full, and nil if thunk was executed.
+func (b *Breaker) MaybeContext(ctx context.Context, thunk func()) error {
 
 	var t token
 	select {
 	default:
 		// Pending request queue is full.  Report failure.
-		return false
+		return ErrRequestQueueFull
 	case b.pendingRequests <- t:
 		// Pending request has capacity.
+		// Defer releasing capacity in the pending request queue.
+		defer func() { <-b.pendingRequests }()
 		// Wait for capacity in the active queue.
-		b.sem.Acquire()
-		// Defer releasing capacity in the active and pending request queue.
-		defer func() { b.sem.Release(); <-b.pendingRequests }()
+		if err := b.sem.AcquireContext(ctx); err != nil {
+			return err
+		}
+		// Defer releasing capacity in the active queue.
+		defer b.sem.Release()
 		// Do the thing.
 		thunk()
 		// Report success
-		return true
+		return nil
 	}
 }
 
+// UpdateConcurrency changes the concurrency limit of the Breaker to size,
+// which must be between 0 and the max concurrency it was created with.
+// Requests that are already running are not affected.
+func (b *Breaker) UpdateConcurrency(size int32) error {
+	return b.sem.UpdateCapacity(size)
+}
+
+// Capacity returns the current concurrency limit of the Breaker.
+func (b *Breaker) Capacity() int32 {
+	return b.sem.Capacity()
+}
+
 // NewSemaphore creates a semaphore with the desired maximal and initial capacity
 func NewSemaphore(maxCapacity, initialCapacity int32) *Semaphore {
 	if initialCapacity < 0 || initialCapacity > maxCapacity {
@@ -104,10 +136,30 @@ func (s *Semaphore) Acquire() {
 	<-s.queue
 }
 
-// Release releases the token to the queue
+// AcquireContext receives the token from the semaphore, blocking until one
+// is available or ctx is done, in which case it returns the error of ctx.
+func (s *Semaphore) AcquireContext(ctx context.Context) error {
+	select {
+	case <-s.queue:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// Release releases the token to the queue, or takes it out of the rotation
+// if the capacity was reduced while it was acquired. The capacity of the
+// semaphore is left as is.
 // The operation is potentially blocking when the queue is full
 func (s *Semaphore) Release() {
-	s.AddCapacity(1)
+	s.mux.Lock()
+	defer s.mux.Unlock()
+	if s.reducers > 0 {
+		s.reducers--
+		s.capacity--
+		return
+	}
+	s.queue <- s.token
 }
 
 // ReduceCapacity removes tokens from the rotation
@@ -156,3 +208,54 @@ func (s *Semaphore) AddCapacity(size int32) {
 		}
 	}
 }
+
+// UpdateCapacity sets the capacity of the semaphore to size, which must be
+// between 0 and its maximal capacity. Tokens that are currently acquired
+// are taken out of the rotation when released, if the capacity shrinks
+// below them.
+func (s *Semaphore) UpdateCapacity(size int32) error {
+	if size < 0 || size > int32(cap(s.queue)) {
+		return ErrUpdateCapacity
+	}
+	s.mux.Lock()
+	defer s.mux.Unlock()
+
+	current := s.capacity - s.reducers
+	switch {
+	case size > current:
+		toAdd := size - current
+		// Cancel pending reductions first.
+		if s.reducers > 0 {
+			canceled := toAdd
+			if canceled > s.reducers {
+				canceled = s.reducers
+			}
+			s.reducers -= canceled
+			toAdd -= canceled
+		}
+		// This never blocks, as the capacity stays within the size
+		// of the queue.
+		for i := int32(0); i < toAdd; i++ {
+			s.queue <- s.token
+			s.capacity++
+		}
+	case size < current:
+		for i := int32(0); i < current-size; i++ {
+			select {
+			case <-s.queue:
+				s.capacity--
+			default:
+				s.reducers++
+			}
+		}
+	}
+	return nil
+}
+
+// Capacity returns the capacity of the semaphore, not counting the tokens
+// that are due to be taken out of the rotation.
+func (s *Semaphore) Capacity() int32 {
+	s.mux.Lock()
+	defer s.mux.Unlock()
+	return s.capacity - s.reducers
+}
@@ -17,6 +17,7 @@ limitations under the License.
 package queue
 
 import (
+	"context"
 	"reflect"
 	"sync"
 	"testing"
@@ -213,6 +214,130 @@ func TestSemaphore_WrongInitialCapacity(t *testing.T) {
 	_ = NewSemaphore(1, 2)
 }
 
+func TestSemaphore_UpdateCapacity(t *testing.T) {
+	sem := NewSemaphore(5, 1)
+	if err := sem.UpdateCapacity(3); err != nil {
+		t.Fatalf("UpdateCapacity(3) = %v", err)
+	}
+	assertEqual(int32(3), sem.Capacity(), t)
+	assertEqual(3, len(sem.queue), t)
+
+	if err := sem.UpdateCapacity(1); err != nil {
+		t.Fatalf("UpdateCapacity(1) = %v", err)
+	}
+	assertEqual(int32(1), sem.Capacity(), t)
+	assertEqual(1, len(sem.queue), t)
+}
+
+// Test shrinking the capacity below the acquired tokens, and growing it back
+func TestSemaphore_UpdateCapacity_Acquired(t *testing.T) {
+	sem := NewSemaphore(3, 2)
+	sem.Acquire()
+	sem.Acquire()
+	if err := sem.UpdateCapacity(0); err != nil {
+		t.Fatalf("UpdateCapacity(0) = %v", err)
+	}
+	assertEqual(int32(2), sem.reducers, t)
+	assertEqual(int32(0), sem.Capacity(), t)
+
+	// Growing the capacity cancels the pending reductions first.
+	if err := sem.UpdateCapacity(3); err != nil {
+		t.Fatalf("UpdateCapacity(3) = %v", err)
+	}
+	assertEqual(int32(0), sem.reducers, t)
+	assertEqual(int32(3), sem.Capacity(), t)
+	assertEqual(1, len(sem.queue), t)
+
+	sem.Release()
+	sem.Release()
+	assertEqual(3, len(sem.queue), t)
+}
+
+// Test that released tokens don't count as added capacity
+func TestSemaphore_UpdateCapacity_AfterRelease(t *testing.T) {
+	sem := NewSemaphore(3, 1)
+	for i := 0; i < 3; i++ {
+		sem.Acquire()
+		sem.Release()
+	}
+	assertEqual(int32(1), sem.Capacity(), t)
+
+	if err := sem.UpdateCapacity(1); err != nil {
+		t.Fatalf("UpdateCapacity(1) = %v", err)
+	}
+	assertEqual(int32(0), sem.reducers, t)
+	assertEqual(1, len(sem.queue), t)
+
+	if err := sem.UpdateCapacity(3); err != nil {
+		t.Fatalf("UpdateCapacity(3) = %v", err)
+	}
+	assertEqual(int32(3), sem.Capacity(), t)
+	assertEqual(3, len(sem.queue), t)
+}
+
+func TestSemaphore_UpdateCapacity_OutOfBound(t *testing.T) {
+	sem := NewSemaphore(1, 0)
+	assertEqual(ErrUpdateCapacity, sem.UpdateCapacity(2), t)
+	assertEqual(ErrUpdateCapacity, sem.UpdateCapacity(-1), t)
+}
+
+func TestBreakerUpdateConcurrency(t *testing.T) {
+	b := NewBreaker(1, 2, 0)                // Breaker capacity = 3, without concurrency
+	want := []bool{true, true, true, false} // Queued requests run once concurrency is added
+
+	locks := b.concurrentRequests(4)
+	if err := b.UpdateConcurrency(2); err != nil {
+		t.Fatalf("UpdateConcurrency(2) = %v", err)
+	}
+	waitForQueue(b.sem.queue, 0)
+	assertEqual(int32(2), b.Capacity(), t)
+	unlockAll(locks)
+
+	assertEqual(want, accepted(locks), t)
+}
+
+func TestBreakerUpdateConcurrencyAfterRequests(t *testing.T) {
+	b := NewBreaker(1, 2, 1)
+	for i := 0; i < 3; i++ {
+		if !b.Maybe(func() {}) {
+			t.Fatalf("Maybe() = false for request %d", i)
+		}
+	}
+	if err := b.UpdateConcurrency(1); err != nil {
+		t.Fatalf("UpdateConcurrency(1) = %v", err)
+	}
+	assertEqual(int32(1), b.Capacity(), t)
+
+	// The unchanged concurrency still admits requests.
+	ctx, cancel := context.WithTimeout(context.Background(), semSleepInterval)
+	defer cancel()
+	assertEqual(nil, b.MaybeContext(ctx, func() {}), t)
+}
+
+func TestBreakerMaybeContextDone(t *testing.T) {
+	b := NewBreaker(1, 1, 0) // Breaker capacity = 2, without concurrency
+	ctx, cancel := context.WithTimeout(context.Background(), semSleepInterval)
+	defer cancel()
+
+	err := b.MaybeContext(ctx, func() {
+		t.Error("Unexpected call of the thunk")
+	})
+	assertEqual(context.DeadlineExceeded, err, t)
+	// The request gave up its place in the queue.
+	assertEqual(0, len(b.pendingRequests), t)
+}
+
+func TestBreakerMaybeContextQueueFull(t *testing.T) {
+	b := NewBreaker(1, 1, 0) // Breaker capacity = 2, without concurrency
+	b.pendingRequests <- token{}
+	b.pendingRequests <- token{}
+
+	err := b.MaybeContext(context.Background(), func() {
+		t.Error("Unexpected call of the thunk")
+	})
+	assertEqual(ErrRequestQueueFull, err, t)
+}
+
 // Attempts to perform a concurrent request against the specified breaker.
 // Will wait for request to either be performed, enqueued or rejected.
 func (b *Breaker) concurrentRequest() request {
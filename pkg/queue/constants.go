@@ -16,6 +16,8 @@ limitations under the License.
 
 package queue
 
+import "github.com/knative/serving/pkg/apis/networking"
+
 const (
 	// RequestQueuePortName specifies the port name to use for http requests
 	// in queue-proxy container.
@@ -23,7 +25,7 @@ const (
 
 	// RequestQueuePort specifies the port number to use for http requests
 	// in queue-proxy container.
-	RequestQueuePort = 8012
+	RequestQueuePort = networking.QueueServingPort
 
 	// RequestQueueAdminPortName specifies the port name for
 	// health check and lifecyle hooks for queue-proxy.
@@ -31,17 +33,26 @@ const (
 
 	// RequestQueueAdminPort specifies the port number for
 	// health check and lifecyle hooks for queue-proxy.
-	RequestQueueAdminPort = 8022
+	RequestQueueAdminPort = networking.QueueAdminPort
+
+	// RequestQueueMetricsPort specifies the port number the queue-proxy
+	// exposes its Prometheus metrics on.
+	RequestQueueMetricsPort = networking.QueueMetricsPort
 
 	// RequestQueueQuitPath specifies the path to send quit request to
 	// queue-proxy. This is used for preStop hook of queue-proxy. It:
 	// - marks the service as not ready, so that requests will no longer
 	//   be routed to it,
-	// - adds a small delay, so that the container doesn't get killed at
-	//   the same time the pod is marked for removal.
+	// - waits for traffic to go away, so that the container doesn't get
+	//   killed at the same time the pod is marked for removal.
 	RequestQueueQuitPath = "quitquitquit"
 
+	// RequestQueueDrainPath specifies the path reporting the progress of
+	// the drain started by a quit request, as JSON.
+	RequestQueueDrainPath = "drain"
+
 	// RequestQueueHealthPath specifies the path for health checks for
-	// queue-proxy.
+	// queue-proxy. It reports healthy once the user container has been
+	// seen ready, until a quit request is received.
 	RequestQueueHealthPath = "health"
 )
@@ -0,0 +1,174 @@
+/*
+Copyright 2018 The Knative Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package queue
+
+import (
+	"sync"
+	"time"
+)
+
+const (
+	// DefaultDrainTimeout is for how long at most the queue-proxy waits for
+	// traffic to go away when its Pod is terminated, unless the Revision
+	// specifies otherwise. This gives Istio time to remove the Pod from its
+	// configuration and propagate that to all istio-proxies in the mesh.
+	DefaultDrainTimeout = 20 * time.Second
+
+	// DefaultDrainSettlePeriod is for how long no new request must have
+	// arrived, with none in flight, for a drain to end before its timeout,
+	// unless the Revision specifies otherwise.
+	DefaultDrainSettlePeriod = 5 * time.Second
+
+	maxDrainPollInterval = 100 * time.Millisecond
+)
+
+// Drainer keeps track of the requests served by the queue-proxy so that,
+// when the Pod is terminated, it can wait for traffic to go away and no
+// longer than needed.
+type Drainer struct {
+	settlePeriod time.Duration
+	pollInterval time.Duration
+
+	mu          sync.Mutex
+	inFlight    int32
+	lastArrival time.Time
+	started     time.Time
+	timeout     time.Duration
+	finished    bool
+	done        chan struct{}
+}
+
+// DrainStatus describes the progress of a drain.
+type DrainStatus struct {
+	// Draining is true once a drain has been started.
+	Draining bool `json:"draining"`
+	// Done is true once the drain has ended.
+	Done bool `json:"done"`
+	// InFlight is the number of requests, including upgraded connections,
+	// currently being served.
+	InFlight int32 `json:"inFlight"`
+	// ElapsedSeconds is how long the drain has been going on.
+	ElapsedSeconds float64 `json:"elapsedSeconds"`
+	// TimeoutSeconds is for how long at most the drain goes on.
+	TimeoutSeconds float64 `json:"timeoutSeconds"`
+}
+
+// NewDrainer creates a Drainer whose drains end early once no request is
+// in flight and none has arrived for settlePeriod.
+func NewDrainer(settlePeriod time.Duration) *Drainer {
+	pollInterval := settlePeriod / 4
+	if pollInterval > maxDrainPollInterval {
+		pollInterval = maxDrainPollInterval
+	}
+	if pollInterval < time.Millisecond {
+		pollInterval = time.Millisecond
+	}
+	return &Drainer{
+		settlePeriod: settlePeriod,
+		pollInterval: pollInterval,
+	}
+}
+
+// RequestStarted records the arrival of a request.
+func (d *Drainer) RequestStarted() {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.inFlight++
+	d.lastArrival = time.Now()
+}
+
+// RequestFinished records that a request has been served.
+func (d *Drainer) RequestFinished() {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.inFlight--
+}
+
+// Drain blocks until no request is in flight and none has arrived for the
+// settle period since the drain started, or until timeout has passed.
+// Concurrent and later calls wait for the drain started by the first one.
+func (d *Drainer) Drain(timeout time.Duration) {
+	d.mu.Lock()
+	if d.done == nil {
+		d.done = make(chan struct{})
+		d.started = time.Now()
+		d.timeout = timeout
+		go d.run()
+	}
+	done := d.done
+	d.mu.Unlock()
+	<-done
+}
+
+// Status returns the progress of the drain, if any.
+func (d *Drainer) Status() DrainStatus {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	s := DrainStatus{
+		Draining: d.done != nil,
+		Done:     d.finished,
+		InFlight: d.inFlight,
+	}
+	if s.Draining {
+		s.ElapsedSeconds = time.Since(d.started).Seconds()
+		s.TimeoutSeconds = d.timeout.Seconds()
+	}
+	return s
+}
+
+func (d *Drainer) run() {
+	d.mu.Lock()
+	timeout := d.timeout
+	d.mu.Unlock()
+
+	deadline := time.NewTimer(timeout)
+	defer deadline.Stop()
+	ticker := time.NewTicker(d.pollInterval)
+	defer ticker.Stop()
+
+	defer func() {
+		d.mu.Lock()
+		d.finished = true
+		close(d.done)
+		d.mu.Unlock()
+	}()
+	for {
+		if d.settled() {
+			return
+		}
+		select {
+		case <-deadline.C:
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// settled returns true if no request is in flight and none has arrived
+// for the settle period, counting from the start of the drain.
+func (d *Drainer) settled() bool {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	if d.inFlight > 0 {
+		return false
+	}
+	quietSince := d.started
+	if d.lastArrival.After(quietSince) {
+		quietSince = d.lastArrival
+	}
+	return time.Since(quietSince) >= d.settlePeriod
+}
@@ -0,0 +1,173 @@
+/*
+Copyright 2018 The Knative Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package queue
+
+import (
+	"sync"
+	"testing"
+	"time"
+)
+
+const drainTestSettlePeriod = 50 * time.Millisecond
+
+// timeDrain returns how long d.Drain(timeout) blocked.
+func timeDrain(d *Drainer, timeout time.Duration) time.Duration {
+	start := time.Now()
+	d.Drain(timeout)
+	return time.Since(start)
+}
+
+func TestDrainerEndsAfterSettlePeriodWhenIdle(t *testing.T) {
+	d := NewDrainer(drainTestSettlePeriod)
+
+	// Requests served before the drain don't delay it beyond the settle period.
+	d.RequestStarted()
+	d.RequestFinished()
+
+	took := timeDrain(d, time.Minute)
+	if took < drainTestSettlePeriod {
+		t.Errorf("Drain took %v, want at least the settle period %v", took, drainTestSettlePeriod)
+	}
+	if took > 5*time.Second {
+		t.Errorf("Drain took %v, want it to end early", took)
+	}
+}
+
+func TestDrainerWaitsForRequestsInFlight(t *testing.T) {
+	d := NewDrainer(drainTestSettlePeriod)
+	d.RequestStarted()
+
+	const requestDuration = 200 * time.Millisecond
+	go func() {
+		time.Sleep(requestDuration)
+		d.RequestFinished()
+	}()
+
+	took := timeDrain(d, time.Minute)
+	if took < requestDuration {
+		t.Errorf("Drain took %v, want at least the request duration %v", took, requestDuration)
+	}
+	if took > 5*time.Second {
+		t.Errorf("Drain took %v, want it to end early", took)
+	}
+}
+
+func TestDrainerWaitsForNewArrivalsToSettle(t *testing.T) {
+	d := NewDrainer(drainTestSettlePeriod)
+
+	const arrivalsDuration = 300 * time.Millisecond
+	go func() {
+		for start := time.Now(); time.Since(start) < arrivalsDuration; {
+			d.RequestStarted()
+			d.RequestFinished()
+			time.Sleep(drainTestSettlePeriod / 5)
+		}
+	}()
+
+	took := timeDrain(d, time.Minute)
+	if took < arrivalsDuration {
+		t.Errorf("Drain took %v, want at least the arrivals duration %v", took, arrivalsDuration)
+	}
+}
+
+func TestDrainerTimeout(t *testing.T) {
+	d := NewDrainer(drainTestSettlePeriod)
+	// This request never finishes.
+	d.RequestStarted()
+
+	const timeout = 200 * time.Millisecond
+	took := timeDrain(d, timeout)
+	if took < timeout {
+		t.Errorf("Drain took %v, want at least the timeout %v", took, timeout)
+	}
+	if took > 5*time.Second {
+		t.Errorf("Drain took %v, want it to end at the timeout %v", took, timeout)
+	}
+}
+
+func TestDrainerZeroTimeout(t *testing.T) {
+	d := NewDrainer(time.Minute)
+	d.RequestStarted()
+
+	if took := timeDrain(d, 0); took > 5*time.Second {
+		t.Errorf("Drain took %v, want it to end immediately", took)
+	}
+}
+
+func TestDrainerConcurrentDrains(t *testing.T) {
+	d := NewDrainer(drainTestSettlePeriod)
+	d.RequestStarted()
+
+	var wg sync.WaitGroup
+	for i := 0; i < 3; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			d.Drain(time.Minute)
+		}()
+	}
+	time.Sleep(drainTestSettlePeriod)
+	d.RequestFinished()
+
+	done := make(chan struct{})
+	go func() {
+		wg.Wait()
+		close(done)
+	}()
+	select {
+	case <-done:
+	case <-time.After(5 * time.Second):
+		t.Fatal("Concurrent drains didn't end")
+	}
+
+	// Draining again returns immediately.
+	if took := timeDrain(d, time.Minute); took > time.Second {
+		t.Errorf("Drain took %v, want it to return immediately once drained", took)
+	}
+}
+
+func TestDrainerStatus(t *testing.T) {
+	d := NewDrainer(drainTestSettlePeriod)
+	d.RequestStarted()
+	d.RequestStarted()
+	d.RequestFinished()
+
+	if got, want := d.Status(), (DrainStatus{InFlight: 1}); got != want {
+		t.Errorf("Status() before the drain = %+v, want %+v", got, want)
+	}
+
+	go d.Drain(time.Minute)
+	time.Sleep(drainTestSettlePeriod)
+
+	got := d.Status()
+	if !got.Draining || got.Done || got.InFlight != 1 {
+		t.Errorf("Status() during the drain = %+v, want draining with 1 request in flight", got)
+	}
+	if got.TimeoutSeconds != 60 {
+		t.Errorf("TimeoutSeconds = %v, want 60", got.TimeoutSeconds)
+	}
+	if got.ElapsedSeconds <= 0 {
+		t.Errorf("ElapsedSeconds = %v, want > 0", got.ElapsedSeconds)
+	}
+
+	d.RequestFinished()
+	d.Drain(time.Minute)
+	got = d.Status()
+	if !got.Draining || !got.Done || got.InFlight != 0 {
+		t.Errorf("Status() after the drain = %+v, want done with no request in flight", got)
+	}
+}
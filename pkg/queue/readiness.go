@@ -0,0 +1,195 @@
+/*
+Copyright 2018 The Knative Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package queue
+
+import (
+	"crypto/tls"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync/atomic"
+	"time"
+
+	"go.uber.org/zap"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/util/intstr"
+)
+
+const (
+	// ReadinessProbeInterval is how often the queue-proxy probes the user
+	// container until it is ready. It is much shorter than what the kubelet
+	// allows, so that the Pod becomes ready as soon as possible.
+	ReadinessProbeInterval = 50 * time.Millisecond
+
+	// defaultProbeTimeout matches the default timeout of Kubernetes probes.
+	defaultProbeTimeout = time.Second
+
+	// probeUserAgent is the User-Agent of the kubelet probes, so that user
+	// containers treating those specially do the same with ours.
+	probeUserAgent = "kube-probe/queue-proxy"
+)
+
+// ReadinessProber probes the user container from within its Pod until it
+// succeeds once.
+// HTTP probes are sent to the user container, bypassing the queue-proxy,
+// and TCP probes connect to it. Probes that can't be run from the
+// queue-proxy, e.g. Exec probes, and a missing probe fall back to a TCP
+// connect to the user container.
+// Probes go to the port of the user container unless they set a numeric
+// port of their own; named ports can't be resolved from the queue-proxy.
+type ReadinessProber struct {
+	probe  *corev1.Probe
+	port   int
+	logger *zap.SugaredLogger
+	client *http.Client
+
+	ready int32
+}
+
+// NewReadinessProber creates a ReadinessProber running probe against the
+// user container listening on port.
+func NewReadinessProber(probe *corev1.Probe, port int, logger *zap.SugaredLogger) *ReadinessProber {
+	if probe == nil {
+		probe = &corev1.Probe{}
+	}
+	timeout := defaultProbeTimeout
+	if probe.TimeoutSeconds > 0 {
+		timeout = time.Duration(probe.TimeoutSeconds) * time.Second
+	}
+	return &ReadinessProber{
+		probe:  probe,
+		port:   port,
+		logger: logger,
+		client: &http.Client{
+			Timeout: timeout,
+			Transport: &http.Transport{
+				// Like the kubelet, don't verify certificates of HTTPS probes.
+				TLSClientConfig:   &tls.Config{InsecureSkipVerify: true},
+				DisableKeepAlives: true,
+			},
+			// Like the kubelet, don't follow redirects to other hosts.
+			CheckRedirect: func(req *http.Request, via []*http.Request) error {
+				if req.URL.Host != via[0].URL.Host {
+					return http.ErrUseLastResponse
+				}
+				return nil
+			},
+		},
+	}
+}
+
+// IsReady returns true once a probe has succeeded.
+func (p *ReadinessProber) IsReady() bool {
+	return atomic.LoadInt32(&p.ready) == 1
+}
+
+// Run probes the user container every interval until a probe succeeds or
+// stopCh is closed. It returns whether the user container is ready.
+func (p *ReadinessProber) Run(interval time.Duration, stopCh <-chan struct{}) bool {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		err := p.ProbeOnce()
+		if err == nil {
+			return true
+		}
+		p.logger.Debugw("User container is not ready yet", zap.Error(err))
+		select {
+		case <-stopCh:
+			return false
+		case <-ticker.C:
+		}
+	}
+}
+
+// ProbeOnce probes the user container once, and marks it ready on success.
+func (p *ReadinessProber) ProbeOnce() error {
+	var err error
+	if p.probe.HTTPGet != nil {
+		err = p.probeHTTP(p.probe.HTTPGet)
+	} else {
+		err = p.probeTCP(p.probe.TCPSocket)
+	}
+	if err == nil {
+		atomic.StoreInt32(&p.ready, 1)
+	}
+	return err
+}
+
+func (p *ReadinessProber) host(host string, port intstr.IntOrString) string {
+	if host == "" {
+		host = "127.0.0.1"
+	}
+	portNum := p.port
+	if port.Type == intstr.Int && port.IntValue() > 0 {
+		portNum = port.IntValue()
+	}
+	return net.JoinHostPort(host, strconv.Itoa(portNum))
+}
+
+func (p *ReadinessProber) probeTCP(action *corev1.TCPSocketAction) error {
+	if action == nil {
+		action = &corev1.TCPSocketAction{}
+	}
+	conn, err := net.DialTimeout("tcp", p.host(action.Host, action.Port), p.client.Timeout)
+	if err != nil {
+		return err
+	}
+	return conn.Close()
+}
+
+func (p *ReadinessProber) probeHTTP(action *corev1.HTTPGetAction) error {
+	scheme := "http"
+	if action.Scheme == corev1.URISchemeHTTPS {
+		scheme = "https"
+	}
+	// Like the kubelet, accept paths without a leading slash.
+	path := action.Path
+	if !strings.HasPrefix(path, "/") {
+		path = "/" + path
+	}
+	url := fmt.Sprintf("%s://%s%s", scheme, p.host(action.Host, action.Port), path)
+	req, err := http.NewRequest(http.MethodGet, url, nil)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("User-Agent", probeUserAgent)
+	for _, h := range action.HTTPHeaders {
+		if h.Name == "Host" {
+			req.Host = h.Value
+			continue
+		}
+		req.Header.Add(h.Name, h.Value)
+	}
+
+	resp, err := p.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	io.Copy(ioutil.Discard, resp.Body)
+
+	// Like the kubelet, any code in [200, 400) is a success.
+	if resp.StatusCode < http.StatusOK || resp.StatusCode >= http.StatusBadRequest {
+		return fmt.Errorf("unexpected status code %d from %s", resp.StatusCode, url)
+	}
+	return nil
+}
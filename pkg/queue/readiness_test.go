@@ -0,0 +1,238 @@
+/*
+Copyright 2018 The Knative Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package queue
+
+import (
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strconv"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"go.uber.org/zap"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/util/intstr"
+)
+
+// serverPort returns the port s listens on.
+func serverPort(t *testing.T, s *httptest.Server) int {
+	t.Helper()
+	u, err := url.Parse(s.URL)
+	if err != nil {
+		t.Fatalf("Failed to parse %q: %v", s.URL, err)
+	}
+	port, err := strconv.Atoi(u.Port())
+	if err != nil {
+		t.Fatalf("Failed to parse the port of %q: %v", s.URL, err)
+	}
+	return port
+}
+
+// unusedPort returns a port nothing listens on.
+func unusedPort(t *testing.T) int {
+	t.Helper()
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("Failed to listen: %v", err)
+	}
+	defer l.Close()
+	return l.Addr().(*net.TCPAddr).Port
+}
+
+func TestReadinessProberHTTP(t *testing.T) {
+	tests := []struct {
+		name      string
+		status    int
+		wantReady bool
+	}{{
+		name:      "ok",
+		status:    http.StatusOK,
+		wantReady: true,
+	}, {
+		name:      "redirect",
+		status:    http.StatusFound,
+		wantReady: true,
+	}, {
+		name:      "not found",
+		status:    http.StatusNotFound,
+		wantReady: false,
+	}, {
+		name:      "unavailable",
+		status:    http.StatusServiceUnavailable,
+		wantReady: false,
+	}}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			var gotPath, gotHeader, gotUserAgent string
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				gotPath = r.URL.Path
+				gotHeader = r.Header.Get("X-Custom")
+				gotUserAgent = r.UserAgent()
+				if test.status == http.StatusFound {
+					// Redirect to another host, which mustn't be followed.
+					w.Header().Set("Location", "http://example.com/")
+				}
+				w.WriteHeader(test.status)
+			}))
+			defer server.Close()
+
+			p := NewReadinessProber(&corev1.Probe{
+				Handler: corev1.Handler{
+					HTTPGet: &corev1.HTTPGetAction{
+						Path:        "/healthz",
+						HTTPHeaders: []corev1.HTTPHeader{{Name: "X-Custom", Value: "foo"}},
+					},
+				},
+			}, serverPort(t, server), zap.NewNop().Sugar())
+
+			err := p.ProbeOnce()
+			if got := err == nil; got != test.wantReady {
+				t.Errorf("ProbeOnce() = %v, want success %v", err, test.wantReady)
+			}
+			if got := p.IsReady(); got != test.wantReady {
+				t.Errorf("IsReady() = %v, want %v", got, test.wantReady)
+			}
+			if gotPath != "/healthz" {
+				t.Errorf("Probe path = %q, want /healthz", gotPath)
+			}
+			if gotHeader != "foo" {
+				t.Errorf("Probe header X-Custom = %q, want foo", gotHeader)
+			}
+			if gotUserAgent != probeUserAgent {
+				t.Errorf("Probe User-Agent = %q, want %q", gotUserAgent, probeUserAgent)
+			}
+		})
+	}
+}
+
+func TestReadinessProberHTTPPortAndPath(t *testing.T) {
+	var gotPath string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		gotPath = r.URL.Path
+	}))
+	defer server.Close()
+
+	// The probe's own port wins over the user port, which nothing listens on.
+	p := NewReadinessProber(&corev1.Probe{
+		Handler: corev1.Handler{
+			HTTPGet: &corev1.HTTPGetAction{
+				Path: "healthz",
+				Port: intstr.FromInt(serverPort(t, server)),
+			},
+		},
+	}, unusedPort(t), zap.NewNop().Sugar())
+
+	if err := p.ProbeOnce(); err != nil {
+		t.Errorf("ProbeOnce() = %v, want success", err)
+	}
+	if gotPath != "/healthz" {
+		t.Errorf("Probe path = %q, want /healthz", gotPath)
+	}
+}
+
+func TestReadinessProberTCP(t *testing.T) {
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("Failed to listen: %v", err)
+	}
+	defer l.Close()
+	port := l.Addr().(*net.TCPAddr).Port
+
+	probes := map[string]*corev1.Probe{
+		"no probe": nil,
+		"tcp probe": {
+			Handler: corev1.Handler{
+				TCPSocket: &corev1.TCPSocketAction{},
+			},
+		},
+		"exec probe": {
+			Handler: corev1.Handler{
+				Exec: &corev1.ExecAction{Command: []string{"true"}},
+			},
+		},
+	}
+	for name, probe := range probes {
+		t.Run(name, func(t *testing.T) {
+			p := NewReadinessProber(probe, port, zap.NewNop().Sugar())
+			if err := p.ProbeOnce(); err != nil {
+				t.Errorf("ProbeOnce() = %v, want success", err)
+			}
+			if !p.IsReady() {
+				t.Error("IsReady() = false, want true")
+			}
+
+			p = NewReadinessProber(probe, unusedPort(t), zap.NewNop().Sugar())
+			if err := p.ProbeOnce(); err == nil {
+				t.Error("ProbeOnce() succeeded without a listener, want failure")
+			}
+			if p.IsReady() {
+				t.Error("IsReady() = true without a listener, want false")
+			}
+		})
+	}
+}
+
+func TestReadinessProberRunUntilReady(t *testing.T) {
+	var attempts int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if atomic.AddInt32(&attempts, 1) < 3 {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+	}))
+	defer server.Close()
+
+	p := NewReadinessProber(&corev1.Probe{
+		Handler: corev1.Handler{
+			HTTPGet: &corev1.HTTPGetAction{Path: "/"},
+		},
+	}, serverPort(t, server), zap.NewNop().Sugar())
+
+	if !p.Run(time.Millisecond, make(chan struct{})) {
+		t.Fatal("Run() = false, want true")
+	}
+	if got := atomic.LoadInt32(&attempts); got != 3 {
+		t.Errorf("Probe attempts = %d, want 3", got)
+	}
+	if !p.IsReady() {
+		t.Error("IsReady() = false, want true")
+	}
+}
+
+func TestReadinessProberRunStopped(t *testing.T) {
+	p := NewReadinessProber(nil, unusedPort(t), zap.NewNop().Sugar())
+
+	stopCh := make(chan struct{})
+	done := make(chan bool)
+	go func() {
+		done <- p.Run(time.Millisecond, stopCh)
+	}()
+	close(stopCh)
+
+	select {
+	case ready := <-done:
+		if ready {
+			t.Error("Run() = true, want false")
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("Run() didn't return after being stopped")
+	}
+}
@@ -36,6 +36,11 @@ const (
 	ReqIn ReqEventType = iota
 	// ReqOut represents a finished request
 	ReqOut
+	// ReqUpgradedIn represents an incoming request whose connection
+	// is upgraded, e.g. to a WebSocket
+	ReqUpgradedIn
+	// ReqUpgradedOut represents a closed upgraded connection
+	ReqUpgradedOut
 )
 
 // Channels is a structure for holding the channels for driving Stats.
@@ -51,23 +56,27 @@ type Channels struct {
 
 // Stats is a structure for holding channels per pod.
 type Stats struct {
-	podName string
-	ch      Channels
+	podName        string
+	ch             Channels
+	upgradedWeight float64
 }
 
-// NewStats instantiates a new instance of Stats.
-func NewStats(podName string, channels Channels, startedAt time.Time) *Stats {
+// NewStats instantiates a new instance of Stats. Upgraded connections
+// count as upgradedWeight requests in the average concurrency.
+func NewStats(podName string, channels Channels, startedAt time.Time, upgradedWeight float64) *Stats {
 	s := &Stats{
-		podName: podName,
-		ch:      channels,
+		podName:        podName,
+		ch:             channels,
+		upgradedWeight: upgradedWeight,
 	}
 
 	go func() {
 		var requestCount int32
 		var concurrency int32
+		var upgraded int32
 
 		lastChange := startedAt
-		timeOnConcurrency := make(map[int32]time.Duration)
+		timeOnConcurrency := make(map[float64]time.Duration)
 
 		// Updates the lastChanged/timeOnConcurrency state
 		// Note: Due to nature of the channels used below, the ReportChan
@@ -77,7 +86,8 @@ func NewStats(podName string, channels Channels, startedAt time.Time) *Stats {
 		updateState := func(time time.Time) {
 			if time.After(lastChange) {
 				durationSinceChange := time.Sub(lastChange)
-				timeOnConcurrency[concurrency] += durationSinceChange
+				c := float64(concurrency) + s.upgradedWeight*float64(upgraded)
+				timeOnConcurrency[c] += durationSinceChange
 				lastChange = time
 			}
 		}
@@ -93,6 +103,11 @@ func NewStats(podName string, channels Channels, startedAt time.Time) *Stats {
 					concurrency = concurrency + 1
 				case ReqOut:
 					concurrency = concurrency - 1
+				case ReqUpgradedIn:
+					requestCount = requestCount + 1
+					upgraded = upgraded + 1
+				case ReqUpgradedOut:
+					upgraded = upgraded - 1
 				}
 			case now := <-s.ch.ReportChan:
 				updateState(now)
@@ -106,7 +121,7 @@ func NewStats(podName string, channels Channels, startedAt time.Time) *Stats {
 				if totalTimeUsed > 0 {
 					for c, val := range timeOnConcurrency {
 						ratio := float64(val) / float64(totalTimeUsed)
-						avg += float64(c) * ratio
+						avg += c * ratio
 					}
 				}
 
@@ -121,7 +136,7 @@ func NewStats(podName string, channels Channels, startedAt time.Time) *Stats {
 				s.ch.StatChan <- stat
 
 				// Reset the stat counts which have been reported.
-				timeOnConcurrency = make(map[int32]time.Duration)
+				timeOnConcurrency = make(map[float64]time.Duration)
 				requestCount = 0
 			}
 		}
@@ -195,6 +195,84 @@ func TestOneRequestAcrossReportings(t *testing.T) {
 	}
 }
 
+func TestUpgradedConnectionDefaultWeight(t *testing.T) {
+	now := time.Now()
+	s := newTestStats(now)
+
+	s.upgradedStart(now)
+	now = now.Add(1 * time.Second)
+	got := s.report(now)
+
+	want := &autoscaler.Stat{
+		Time:                      &now,
+		PodName:                   podName,
+		AverageConcurrentRequests: 1.0,
+		RequestCount:              1,
+	}
+	if diff := cmp.Diff(want, got); diff != "" {
+		t.Errorf("Unexpected stat (-want +got): %v", diff)
+	}
+}
+
+func TestUpgradedConnectionWeighted(t *testing.T) {
+	now := time.Now()
+	s := newWeightedTestStats(now, 0.25)
+
+	// Two connections and a request over the whole time.
+	s.upgradedStart(now)
+	s.upgradedStart(now)
+	s.requestStart(now)
+	now = now.Add(1 * time.Second)
+	s.requestEnd(now)
+	got := s.report(now)
+
+	want := &autoscaler.Stat{
+		Time:                      &now,
+		PodName:                   podName,
+		AverageConcurrentRequests: 1.5,
+		RequestCount:              3,
+	}
+	if diff := cmp.Diff(want, got); diff != "" {
+		t.Errorf("Unexpected stat (-want +got): %v", diff)
+	}
+
+	// The connections stay open across reportings, but aren't counted
+	// as new requests.
+	s.upgradedEnd(now)
+	now = now.Add(1 * time.Second)
+	got = s.report(now)
+
+	want = &autoscaler.Stat{
+		Time:                      &now,
+		PodName:                   podName,
+		AverageConcurrentRequests: 0.25,
+		RequestCount:              0,
+	}
+	if diff := cmp.Diff(want, got); diff != "" {
+		t.Errorf("Unexpected stat (-want +got): %v", diff)
+	}
+}
+
+func TestUpgradedConnectionNotWeighted(t *testing.T) {
+	now := time.Now()
+	s := newWeightedTestStats(now, 0)
+
+	s.upgradedStart(now)
+	now = now.Add(1 * time.Second)
+	s.upgradedEnd(now)
+	got := s.report(now)
+
+	want := &autoscaler.Stat{
+		Time:                      &now,
+		PodName:                   podName,
+		AverageConcurrentRequests: 0.0,
+		RequestCount:              1,
+	}
+	if diff := cmp.Diff(want, got); diff != "" {
+		t.Errorf("Unexpected stat (-want +got): %v", diff)
+	}
+}
+
 // Test type to hold the bi-directional time channels
 type testStats struct {
 	Stats
@@ -202,13 +280,17 @@ type testStats struct {
 }
 
 func newTestStats(now time.Time) *testStats {
+	return newWeightedTestStats(now, DefaultUpgradedConnectionWeight)
+}
+
+func newWeightedTestStats(now time.Time, upgradedWeight float64) *testStats {
 	reportBiChan := make(chan time.Time)
 	ch := Channels{
 		ReqChan:    make(chan ReqEvent),
 		ReportChan: (<-chan time.Time)(reportBiChan),
 		StatChan:   make(chan *autoscaler.Stat),
 	}
-	s := NewStats(podName, ch, now)
+	s := NewStats(podName, ch, now, upgradedWeight)
 	t := &testStats{
 		Stats:        *s,
 		reportBiChan: reportBiChan,
@@ -224,6 +306,14 @@ func (s *testStats) requestEnd(now time.Time) {
 	s.ch.ReqChan <- ReqEvent{Time: now, EventType: ReqOut}
 }
 
+func (s *testStats) upgradedStart(now time.Time) {
+	s.ch.ReqChan <- ReqEvent{Time: now, EventType: ReqUpgradedIn}
+}
+
+func (s *testStats) upgradedEnd(now time.Time) {
+	s.ch.ReqChan <- ReqEvent{Time: now, EventType: ReqUpgradedOut}
+}
+
 func (s *testStats) report(now time.Time) *autoscaler.Stat {
 	s.reportBiChan <- now
 	return <-s.ch.StatChan
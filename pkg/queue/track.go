@@ -0,0 +1,73 @@
+/*
+Copyright 2018 The Knative Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package queue
+
+import (
+	"net/http"
+	"sync"
+	"time"
+
+	pkghttp "github.com/knative/serving/pkg/http"
+)
+
+// TrackRequest records the arrival of r on reqChan, for Stats to account
+// for it. It returns the http.ResponseWriter r must be served with, and a
+// func to call once r has been served.
+//
+// Requests are in flight until they have been served, except for gRPC
+// calls, which are only in flight until the server starts responding.
+// Past that point a streaming call mostly waits for messages, and counting
+// it for its whole length would inflate the concurrency of Revisions
+// serving long-lived streams. Unary calls are responded to at their end,
+// so this makes no difference for them.
+func TrackRequest(reqChan chan<- ReqEvent, w http.ResponseWriter, r *http.Request) (http.ResponseWriter, func()) {
+	reqChan <- ReqEvent{Time: time.Now(), EventType: ReqIn}
+
+	var once sync.Once
+	out := func() {
+		once.Do(func() {
+			reqChan <- ReqEvent{Time: time.Now(), EventType: ReqOut}
+		})
+	}
+	if pkghttp.IsGRPCRequest(r) {
+		w = &responseStartWriter{ResponseWriter: w, onStart: out}
+	}
+	return w, out
+}
+
+// responseStartWriter calls onStart when the response is started.
+type responseStartWriter struct {
+	http.ResponseWriter
+	onStart func()
+}
+
+func (w *responseStartWriter) WriteHeader(code int) {
+	w.onStart()
+	w.ResponseWriter.WriteHeader(code)
+}
+
+func (w *responseStartWriter) Write(b []byte) (int, error) {
+	w.onStart()
+	return w.ResponseWriter.Write(b)
+}
+
+// Flush lets streamed responses through as they come.
+func (w *responseStartWriter) Flush() {
+	if f, ok := w.ResponseWriter.(http.Flusher); ok {
+		f.Flush()
+	}
+}
@@ -0,0 +1,149 @@
+/*
+Copyright 2018 The Knative Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package queue
+
+import (
+	"context"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"net/http/httputil"
+	"net/url"
+	"testing"
+	"time"
+
+	"github.com/knative/serving/pkg/http/grpctest"
+	"github.com/knative/serving/pkg/http/h2c"
+)
+
+// nextEvent returns the next event sent on reqChan, or fails the test.
+func nextEvent(t *testing.T, reqChan chan ReqEvent) ReqEventType {
+	t.Helper()
+	select {
+	case e := <-reqChan:
+		return e.EventType
+	case <-time.After(5 * time.Second):
+		t.Fatal("Timed out waiting for a request event")
+		return 0
+	}
+}
+
+// assertNoEvent fails the test if an event is sent on reqChan.
+func assertNoEvent(t *testing.T, reqChan chan ReqEvent) {
+	t.Helper()
+	select {
+	case e := <-reqChan:
+		t.Fatalf("Unexpected request event %v", e.EventType)
+	case <-time.After(50 * time.Millisecond):
+	}
+}
+
+func TestTrackRequestHTTP(t *testing.T) {
+	reqChan := make(chan ReqEvent, 10)
+
+	r := httptest.NewRequest(http.MethodGet, "http://example.com", nil)
+	w, done := TrackRequest(reqChan, httptest.NewRecorder(), r)
+	if got := nextEvent(t, reqChan); got != ReqIn {
+		t.Errorf("Event on arrival = %v, want %v", got, ReqIn)
+	}
+
+	// Responding doesn't end plain HTTP requests.
+	w.WriteHeader(http.StatusOK)
+	io.WriteString(w, "hello")
+	assertNoEvent(t, reqChan)
+
+	done()
+	if got := nextEvent(t, reqChan); got != ReqOut {
+		t.Errorf("Event once served = %v, want %v", got, ReqOut)
+	}
+	done()
+	assertNoEvent(t, reqChan)
+}
+
+func TestTrackRequestGRPC(t *testing.T) {
+	backend, err := grpctest.NewServer()
+	if err != nil {
+		t.Fatalf("Failed to start gRPC server: %v", err)
+	}
+	defer backend.Close()
+
+	// Proxy to the gRPC server like the queue-proxy does.
+	reqChan := make(chan ReqEvent, 10)
+	proxy := httputil.NewSingleHostReverseProxy(&url.URL{Scheme: "http", Host: backend.Addr})
+	proxy.Transport = h2c.DefaultTransport
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("Failed to listen: %v", err)
+	}
+	frontend := h2c.NewServer("", http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w, done := TrackRequest(reqChan, w, r)
+		defer done()
+		proxy.ServeHTTP(w, r)
+	}))
+	go frontend.Serve(l)
+	defer frontend.Close()
+
+	conn, err := grpctest.Dial(l.Addr().String())
+	if err != nil {
+		t.Fatalf("Failed to dial the proxy: %v", err)
+	}
+	defer conn.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	t.Run("unary", func(t *testing.T) {
+		if _, err := grpctest.Unary(ctx, conn, "hello"); err != nil {
+			t.Fatalf("Unary() = %v", err)
+		}
+		if got := nextEvent(t, reqChan); got != ReqIn {
+			t.Errorf("First event = %v, want %v", got, ReqIn)
+		}
+		if got := nextEvent(t, reqChan); got != ReqOut {
+			t.Errorf("Second event = %v, want %v", got, ReqOut)
+		}
+		assertNoEvent(t, reqChan)
+	})
+
+	t.Run("server stream", func(t *testing.T) {
+		recv, err := grpctest.ServerStream(ctx, conn, "hello")
+		if err != nil {
+			t.Fatalf("ServerStream() = %v", err)
+		}
+		if _, err := recv(); err != nil {
+			t.Fatalf("First message = %v", err)
+		}
+		if got := nextEvent(t, reqChan); got != ReqIn {
+			t.Errorf("First event = %v, want %v", got, ReqIn)
+		}
+		// The call is no longer in flight while the stream is open.
+		if got := nextEvent(t, reqChan); got != ReqOut {
+			t.Errorf("Event once the stream started = %v, want %v", got, ReqOut)
+		}
+
+		close(backend.Release)
+		if _, err := recv(); err != nil {
+			t.Fatalf("Second message = %v", err)
+		}
+		if _, err := recv(); err != io.EOF {
+			t.Fatalf("End of stream = %v, want %v", err, io.EOF)
+		}
+		// The end of the stream isn't accounted for twice.
+		assertNoEvent(t, reqChan)
+	})
+}
@@ -0,0 +1,111 @@
+/*
+Copyright 2018 The Knative Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package queue
+
+import (
+	"context"
+	"net/http"
+	"strings"
+	"sync"
+)
+
+const (
+	// DefaultUpgradedConnectionWeight is how much an upgraded connection
+	// weighs in the concurrency reported to the autoscaler, relative to a
+	// request, unless the Revision specifies otherwise.
+	DefaultUpgradedConnectionWeight = 1.0
+)
+
+// IsUpgradeRequest returns true if r asks for its connection to be
+// upgraded to another protocol, e.g. to a WebSocket.
+func IsUpgradeRequest(r *http.Request) bool {
+	if r.Header.Get("Upgrade") == "" {
+		return false
+	}
+	for _, v := range r.Header["Connection"] {
+		for _, token := range strings.Split(v, ",") {
+			if strings.EqualFold(strings.TrimSpace(token), "upgrade") {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// UpgradedConnections keeps track of the long-lived connections upgraded
+// through the queue-proxy. Those are limited separately from requests, so
+// that they can't take all the request slots of a Revision, and are closed
+// when the queue-proxy is done draining, since http.Server.Shutdown leaves
+// them alone.
+type UpgradedConnections struct {
+	max int
+
+	mu     sync.Mutex
+	nextID int
+	cancel map[int]context.CancelFunc
+	closed bool
+}
+
+// NewUpgradedConnections creates an UpgradedConnections admitting at most
+// max connections at once, or any number if max is 0.
+func NewUpgradedConnections(max int) *UpgradedConnections {
+	return &UpgradedConnections{
+		max:    max,
+		cancel: make(map[int]context.CancelFunc),
+	}
+}
+
+// Track admits the upgrade request r, if the limit of connections isn't
+// reached and the connections haven't been closed. It returns r with a
+// context that is canceled when the connection has to be closed, and a
+// func to call once the connection has ended.
+func (u *UpgradedConnections) Track(r *http.Request) (*http.Request, func(), bool) {
+	u.mu.Lock()
+	defer u.mu.Unlock()
+	if u.closed || (u.max > 0 && len(u.cancel) >= u.max) {
+		return r, nil, false
+	}
+
+	id := u.nextID
+	u.nextID++
+	ctx, cancel := context.WithCancel(r.Context())
+	u.cancel[id] = cancel
+	return r.WithContext(ctx), func() {
+		u.mu.Lock()
+		defer u.mu.Unlock()
+		cancel()
+		delete(u.cancel, id)
+	}, true
+}
+
+// Len returns the number of open connections.
+func (u *UpgradedConnections) Len() int {
+	u.mu.Lock()
+	defer u.mu.Unlock()
+	return len(u.cancel)
+}
+
+// CloseAll closes all the open connections, by canceling their requests,
+// and rejects new ones.
+func (u *UpgradedConnections) CloseAll() {
+	u.mu.Lock()
+	defer u.mu.Unlock()
+	u.closed = true
+	for _, cancel := range u.cancel {
+		cancel()
+	}
+}
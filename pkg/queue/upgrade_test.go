@@ -0,0 +1,206 @@
+/*
+Copyright 2018 The Knative Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package queue
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"net/http/httputil"
+	"net/url"
+	"testing"
+	"time"
+)
+
+func TestIsUpgradeRequest(t *testing.T) {
+	tests := []struct {
+		name    string
+		headers http.Header
+		want    bool
+	}{{
+		name:    "plain request",
+		headers: http.Header{},
+		want:    false,
+	}, {
+		name: "websocket",
+		headers: http.Header{
+			"Connection": {"Upgrade"},
+			"Upgrade":    {"websocket"},
+		},
+		want: true,
+	}, {
+		name: "upgrade among other tokens",
+		headers: http.Header{
+			"Connection": {"keep-alive, upgrade"},
+			"Upgrade":    {"websocket"},
+		},
+		want: true,
+	}, {
+		name: "upgrade header without connection upgrade",
+		headers: http.Header{
+			"Connection": {"keep-alive"},
+			"Upgrade":    {"websocket"},
+		},
+		want: false,
+	}, {
+		name: "connection upgrade without upgrade header",
+		headers: http.Header{
+			"Connection": {"Upgrade"},
+		},
+		want: false,
+	}}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			r := httptest.NewRequest(http.MethodGet, "http://example.com", nil)
+			r.Header = test.headers
+			if got := IsUpgradeRequest(r); got != test.want {
+				t.Errorf("IsUpgradeRequest() = %v, want %v", got, test.want)
+			}
+		})
+	}
+}
+
+func TestUpgradedConnectionsLimit(t *testing.T) {
+	u := NewUpgradedConnections(2)
+	r := httptest.NewRequest(http.MethodGet, "http://example.com", nil)
+
+	_, release1, ok := u.Track(r)
+	if !ok {
+		t.Fatal("Track() rejected the first connection")
+	}
+	if _, _, ok := u.Track(r); !ok {
+		t.Fatal("Track() rejected the second connection")
+	}
+	if _, _, ok := u.Track(r); ok {
+		t.Error("Track() admitted a connection beyond the limit")
+	}
+	if got := u.Len(); got != 2 {
+		t.Errorf("Len() = %d, want 2", got)
+	}
+
+	release1()
+	if _, _, ok := u.Track(r); !ok {
+		t.Error("Track() rejected a connection after one was released")
+	}
+}
+
+func TestUpgradedConnectionsUnlimited(t *testing.T) {
+	u := NewUpgradedConnections(0)
+	r := httptest.NewRequest(http.MethodGet, "http://example.com", nil)
+	for i := 0; i < 100; i++ {
+		if _, _, ok := u.Track(r); !ok {
+			t.Fatalf("Track() rejected connection %d without a limit", i)
+		}
+	}
+}
+
+func TestUpgradedConnectionsCloseAll(t *testing.T) {
+	u := NewUpgradedConnections(0)
+	r := httptest.NewRequest(http.MethodGet, "http://example.com", nil)
+
+	tracked, release, ok := u.Track(r)
+	if !ok {
+		t.Fatal("Track() rejected the connection")
+	}
+	defer release()
+
+	u.CloseAll()
+	select {
+	case <-tracked.Context().Done():
+	default:
+		t.Error("CloseAll() didn't cancel the connection's context")
+	}
+	if _, _, ok := u.Track(r); ok {
+		t.Error("Track() admitted a connection after CloseAll()")
+	}
+}
+
+// upgradingServer accepts connection upgrades to the "echo" protocol,
+// and echoes what it reads on upgraded connections.
+func upgradingServer() *httptest.Server {
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		conn, brw, err := w.(http.Hijacker).Hijack()
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+		fmt.Fprint(brw, "HTTP/1.1 101 Switching Protocols\r\nConnection: Upgrade\r\nUpgrade: echo\r\n\r\n")
+		brw.Flush()
+		io.Copy(conn, brw)
+	}))
+}
+
+func TestUpgradedConnectionsProxied(t *testing.T) {
+	backend := upgradingServer()
+	defer backend.Close()
+	target, _ := url.Parse(backend.URL)
+	proxy := httputil.NewSingleHostReverseProxy(target)
+
+	u := NewUpgradedConnections(0)
+	ended := make(chan struct{})
+	frontend := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		r, release, ok := u.Track(r)
+		if !ok {
+			http.Error(w, "too many connections", http.StatusServiceUnavailable)
+			return
+		}
+		defer close(ended)
+		defer release()
+		proxy.ServeHTTP(w, r)
+	}))
+	defer frontend.Close()
+
+	conn, err := net.Dial("tcp", frontend.Listener.Addr().String())
+	if err != nil {
+		t.Fatalf("Failed to dial: %v", err)
+	}
+	defer conn.Close()
+	fmt.Fprint(conn, "GET / HTTP/1.1\r\nHost: example.com\r\nConnection: Upgrade\r\nUpgrade: echo\r\n\r\n")
+	br := bufio.NewReader(conn)
+	resp, err := http.ReadResponse(br, nil)
+	if err != nil {
+		t.Fatalf("Failed to read the upgrade response: %v", err)
+	}
+	if resp.StatusCode != http.StatusSwitchingProtocols {
+		t.Fatalf("Upgrade response status = %d, want %d", resp.StatusCode, http.StatusSwitchingProtocols)
+	}
+
+	fmt.Fprint(conn, "ping\n")
+	if got, err := br.ReadString('\n'); err != nil || got != "ping\n" {
+		t.Fatalf("Echo = (%q, %v), want %q", got, err, "ping\n")
+	}
+
+	// Closing the tracked connections ends the proxying and closes the
+	// client's connection.
+	u.CloseAll()
+	select {
+	case <-ended:
+	case <-time.After(5 * time.Second):
+		t.Fatal("Proxying didn't end after CloseAll()")
+	}
+	conn.SetReadDeadline(time.Now().Add(5 * time.Second))
+	if _, err := br.ReadString('\n'); err != io.EOF {
+		t.Errorf("Read after CloseAll() = %v, want %v", err, io.EOF)
+	}
+	if got := u.Len(); got != 0 {
+		t.Errorf("Len() = %d, want 0", got)
+	}
+}
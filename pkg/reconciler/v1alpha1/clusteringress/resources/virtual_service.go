@@ -18,6 +18,7 @@ package resources
 
 import (
 	"sort"
+	"strings"
 
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/util/intstr"
@@ -33,6 +34,22 @@ import (
 	"github.com/knative/serving/pkg/system"
 )
 
+const (
+	// retryOnHeaderName is the request header Envoy reads the retry conditions from.
+	retryOnHeaderName = "x-envoy-retry-on"
+
+	// requestIDHeaderName is the request header holding the random request id
+	// Envoy generates for each request.
+	requestIDHeaderName = "x-request-id"
+
+	// requestIDBuckets is the number of buckets requests are spread over to
+	// split traffic finer than a percent, by the last four hex digits of
+	// their request id.
+	requestIDBuckets = 1 << 16
+
+	hexDigits = "0123456789abcdef"
+)
+
 // MakeVirtualService creates an Istio VirtualService as network programming.
 // Such VirtualService specifies which Gateways and Hosts that it applies to,
 // as well as the routing rules.
@@ -73,7 +90,21 @@ func makeVirtualServiceSpec(ci *v1alpha1.ClusterIngress, gateways []string) *v1a
 	for _, rule := range ci.Spec.Rules {
 		hosts := rule.Hosts
 		for _, p := range rule.HTTP.Paths {
-			spec.Http = append(spec.Http, *makeVirtualServiceRoute(hosts, &p))
+			if p.Mirror != nil && p.Mirror.Percent > 0 && p.Mirror.Percent < 100 {
+				// The Istio HTTPRoute mirrors every request it matches, so
+				// sample the requests to mirror by their random request id,
+				// and route the others without mirroring.
+				sampled := p.DeepCopy()
+				if sampled.Headers == nil {
+					sampled.Headers = make(map[string]v1alpha1.HeaderMatch)
+				}
+				sampled.Headers[requestIDHeaderName] = v1alpha1.HeaderMatch{
+					Regex: requestIDSample(p.Mirror.Percent),
+				}
+				spec.Http = append(spec.Http, makeVirtualServiceRoutes(hosts, sampled)...)
+				p.Mirror = nil
+			}
+			spec.Http = append(spec.Http, makeVirtualServiceRoutes(hosts, &p)...)
 		}
 	}
 	return &spec
@@ -90,10 +121,68 @@ func makePortSelector(ios intstr.IntOrString) v1alpha3.PortSelector {
 	}
 }
 
+// makeVirtualServiceRoutes constructs the HTTPRoutes of an IngressPath.  The
+// Istio weights are whole percentages, so an IngressPath split finer than that
+// is routed as one HTTPRoute per split instead, each matching a range of the
+// random request ids.  The last split takes the requests no other one matches.
+//
+// The request id is trusted: Envoy only generates one for requests that don't
+// have it, so callers inside the mesh can set it to pick the split they get.
+// Also, a path split N ways becomes up to N HTTPRoutes of the VirtualService.
+func makeVirtualServiceRoutes(hosts []string, http *v1alpha1.HTTPClusterIngressPath) []v1alpha3.HTTPRoute {
+	if !hasBasisPoints(http.Splits) {
+		return []v1alpha3.HTTPRoute{*makeVirtualServiceRoute(hosts, http)}
+	}
+	routes := []v1alpha3.HTTPRoute{}
+	total, last := 0, 0
+	for i, split := range http.Splits {
+		bucket := http.DeepCopy()
+		bucket.Splits = []v1alpha1.ClusterIngressBackendSplit{split}
+		bucket.Splits[0].SetBasisPoints(100 * 100)
+		total += split.GetBasisPoints()
+		// The earlier routes take the request ids below theirs, so each
+		// route gets the ones from the previous threshold up to its own.
+		threshold := (total*requestIDBuckets + 5000) / 10000
+		if i == len(http.Splits)-1 || threshold >= requestIDBuckets {
+			routes = append(routes, *makeVirtualServiceRoute(hosts, bucket))
+			break
+		}
+		if threshold <= last {
+			// Too small a split to get any request id.
+			continue
+		}
+		if bucket.Headers == nil {
+			bucket.Headers = make(map[string]v1alpha1.HeaderMatch)
+		}
+		// Keep matching the request id prefix the mirrored requests are
+		// sampled by, if any.
+		regex := ".*"
+		if m, ok := bucket.Headers[requestIDHeaderName]; ok {
+			regex = m.Regex
+		}
+		bucket.Headers[requestIDHeaderName] = v1alpha1.HeaderMatch{
+			Regex: regex + hexBelow(threshold, 4),
+		}
+		routes = append(routes, *makeVirtualServiceRoute(hosts, bucket))
+		last = threshold
+	}
+	return routes
+}
+
+// hasBasisPoints returns whether any of the splits is finer than a percent.
+func hasBasisPoints(splits []v1alpha1.ClusterIngressBackendSplit) bool {
+	for _, split := range splits {
+		if split.BasisPoints%100 != 0 {
+			return true
+		}
+	}
+	return false
+}
+
 func makeVirtualServiceRoute(hosts []string, http *v1alpha1.HTTPClusterIngressPath) *v1alpha3.HTTPRoute {
 	matches := []v1alpha3.HTTPMatchRequest{}
 	for _, host := range hosts {
-		matches = append(matches, makeMatch(host, http.Path))
+		matches = append(matches, makeMatch(host, http.Path, http.Headers))
 	}
 	weights := []v1alpha3.DestinationWeight{}
 	for _, split := range http.Splits {
@@ -103,9 +192,17 @@ func makeVirtualServiceRoute(hosts []string, http *v1alpha1.HTTPClusterIngressPa
 					split.ServiceName, split.ServiceNamespace),
 				Port: makePortSelector(split.ServicePort),
 			},
-			Weight: split.Percent,
+			Weight: split.GetBasisPoints() / 100,
 		})
 	}
+	var mirror *v1alpha3.Destination
+	if http.Mirror != nil {
+		mirror = &v1alpha3.Destination{
+			Host: reconciler.GetK8sServiceFullname(
+				http.Mirror.ServiceName, http.Mirror.ServiceNamespace),
+			Port: makePortSelector(http.Mirror.ServicePort),
+		}
+	}
 	return &v1alpha3.HTTPRoute{
 		Match:   matches,
 		Route:   weights,
@@ -114,11 +211,54 @@ func makeVirtualServiceRoute(hosts []string, http *v1alpha1.HTTPClusterIngressPa
 			Attempts:      http.Retries.Attempts,
 			PerTryTimeout: http.Retries.PerTryTimeout.Duration.String(),
 		},
-		AppendHeaders: http.AppendHeaders,
+		Mirror:        mirror,
+		AppendHeaders: makeAppendHeaders(http),
+	}
+}
+
+// requestIDSample returns a regex matching about the given percent of the
+// random request ids, in steps of 1/256, using their first two hex digits.
+func requestIDSample(percent int) string {
+	buckets := percent * 256 / 100
+	if buckets == 0 {
+		buckets = 1
+	}
+	return hexBelow(buckets, 2) + ".*"
+}
+
+// hexBelow returns a regex group matching the lowercase hex numbers of the
+// given number of digits that are below n, which must be positive.
+func hexBelow(n, digits int) string {
+	alternatives := []string{}
+	prefix := ""
+	for i := digits - 1; i >= 0; i-- {
+		d := (n >> uint(4*i)) & 0xf
+		if d > 0 {
+			alternatives = append(alternatives,
+				prefix+"["+hexDigits[:d]+"]"+strings.Repeat("[0-9a-f]", i))
+		}
+		prefix += hexDigits[d : d+1]
 	}
+	return "(" + strings.Join(alternatives, "|") + ")"
 }
 
-func makeMatch(host string, pathRegExp string) v1alpha3.HTTPMatchRequest {
+// makeAppendHeaders returns the headers to append to the requests of an
+// IngressPath.  The Istio HTTPRetry has no retry conditions, so these are
+// set through the Envoy retry header instead.
+func makeAppendHeaders(http *v1alpha1.HTTPClusterIngressPath) map[string]string {
+	if len(http.Retries.RetryOn) == 0 {
+		return http.AppendHeaders
+	}
+	headers := map[string]string{
+		retryOnHeaderName: strings.Join(http.Retries.RetryOn, ","),
+	}
+	for k, v := range http.AppendHeaders {
+		headers[k] = v
+	}
+	return headers
+}
+
+func makeMatch(host string, pathRegExp string, headers map[string]v1alpha1.HeaderMatch) v1alpha3.HTTPMatchRequest {
 	match := v1alpha3.HTTPMatchRequest{
 		Authority: &istiov1alpha1.StringMatch{
 			Exact: host,
@@ -131,6 +271,17 @@ func makeMatch(host string, pathRegExp string) v1alpha3.HTTPMatchRequest {
 			Regex: pathRegExp,
 		}
 	}
+	if len(headers) > 0 {
+		match.Headers = make(map[string]istiov1alpha1.StringMatch, len(headers))
+		for name, m := range headers {
+			// Istio requires header names to be in lowercase.
+			match.Headers[strings.ToLower(name)] = istiov1alpha1.StringMatch{
+				Exact:  m.Exact,
+				Prefix: m.Prefix,
+				Regex:  m.Regex,
+			}
+		}
+	}
 	return match
 }
 
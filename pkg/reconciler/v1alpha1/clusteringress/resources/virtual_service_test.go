@@ -18,7 +18,9 @@ package resources
 
 import (
 	"fmt"
+	"regexp"
 	"testing"
+	"time"
 
 	"github.com/google/go-cmp/cmp"
 	istiov1alpha1 "github.com/knative/pkg/apis/istio/common/v1alpha1"
@@ -232,6 +234,379 @@ func TestMakeVirtualServiceRoute_Vanilla(t *testing.T) {
 }
 
 // Two active targets.
+func TestMakeVirtualServiceRoute_Headers(t *testing.T) {
+	ingressPath := &v1alpha1.HTTPClusterIngressPath{
+		Headers: map[string]v1alpha1.HeaderMatch{
+			"X-Canary": {Exact: "true"},
+		},
+		Splits: []v1alpha1.ClusterIngressBackendSplit{{
+			ClusterIngressBackend: v1alpha1.ClusterIngressBackend{
+				ServiceNamespace: "test-ns",
+				ServiceName:      "revision-service",
+				ServicePort:      intstr.FromInt(80),
+			},
+			Percent: 100,
+		}},
+		Timeout: &metav1.Duration{Duration: v1alpha1.DefaultTimeout},
+		Retries: &v1alpha1.HTTPRetry{
+			PerTryTimeout: &metav1.Duration{Duration: v1alpha1.DefaultTimeout},
+			Attempts:      v1alpha1.DefaultRetryCount,
+		},
+	}
+	hosts := []string{"a.com"}
+	route := makeVirtualServiceRoute(hosts, ingressPath)
+	expected := v1alpha3.HTTPRoute{
+		Match: []v1alpha3.HTTPMatchRequest{{
+			Authority: &istiov1alpha1.StringMatch{Exact: "a.com"},
+			Headers: map[string]istiov1alpha1.StringMatch{
+				"x-canary": {Exact: "true"},
+			},
+		}},
+		Route: []v1alpha3.DestinationWeight{{
+			Destination: v1alpha3.Destination{
+				Host: "revision-service.test-ns.svc.cluster.local",
+				Port: v1alpha3.PortSelector{Number: 80},
+			},
+			Weight: 100,
+		}},
+		Timeout: v1alpha1.DefaultTimeout.String(),
+		Retries: &v1alpha3.HTTPRetry{
+			Attempts:      v1alpha1.DefaultRetryCount,
+			PerTryTimeout: v1alpha1.DefaultTimeout.String(),
+		},
+	}
+	if diff := cmp.Diff(&expected, route); diff != "" {
+		t.Errorf("Unexpected route  (-want +got): %v", diff)
+	}
+}
+
+func TestMakeVirtualServiceRoute_RetryOn(t *testing.T) {
+	ingressPath := &v1alpha1.HTTPClusterIngressPath{
+		Splits: []v1alpha1.ClusterIngressBackendSplit{{
+			ClusterIngressBackend: v1alpha1.ClusterIngressBackend{
+				ServiceNamespace: "test-ns",
+				ServiceName:      "revision-service",
+				ServicePort:      intstr.FromInt(80),
+			},
+			Percent: 100,
+		}},
+		AppendHeaders: map[string]string{
+			"foo": "bar",
+		},
+		Timeout: &metav1.Duration{Duration: time.Minute},
+		Retries: &v1alpha1.HTTPRetry{
+			PerTryTimeout: &metav1.Duration{Duration: 10 * time.Second},
+			Attempts:      2,
+			RetryOn:       []string{"5xx", "connect-failure"},
+		},
+	}
+	route := makeVirtualServiceRoute([]string{"a.com"}, ingressPath)
+	expected := v1alpha3.HTTPRoute{
+		Match: []v1alpha3.HTTPMatchRequest{{
+			Authority: &istiov1alpha1.StringMatch{Exact: "a.com"},
+		}},
+		Route: []v1alpha3.DestinationWeight{{
+			Destination: v1alpha3.Destination{
+				Host: "revision-service.test-ns.svc.cluster.local",
+				Port: v1alpha3.PortSelector{Number: 80},
+			},
+			Weight: 100,
+		}},
+		Timeout: "1m0s",
+		Retries: &v1alpha3.HTTPRetry{
+			Attempts:      2,
+			PerTryTimeout: "10s",
+		},
+		AppendHeaders: map[string]string{
+			"foo":              "bar",
+			"x-envoy-retry-on": "5xx,connect-failure",
+		},
+	}
+	if diff := cmp.Diff(&expected, route); diff != "" {
+		t.Errorf("Unexpected route  (-want +got): %v", diff)
+	}
+	if len(ingressPath.AppendHeaders) != 1 {
+		t.Errorf("AppendHeaders of the ClusterIngress were modified: %v", ingressPath.AppendHeaders)
+	}
+}
+
+func TestMakeVirtualServiceSpec_Mirror(t *testing.T) {
+	path := v1alpha1.HTTPClusterIngressPath{
+		Splits: []v1alpha1.ClusterIngressBackendSplit{{
+			ClusterIngressBackend: v1alpha1.ClusterIngressBackend{
+				ServiceNamespace: "test-ns",
+				ServiceName:      "v1-service",
+				ServicePort:      intstr.FromInt(80),
+			},
+			Percent: 100,
+		}},
+		Mirror: &v1alpha1.ClusterIngressBackendMirror{
+			ClusterIngressBackend: v1alpha1.ClusterIngressBackend{
+				ServiceNamespace: "test-ns",
+				ServiceName:      "v2-service",
+				ServicePort:      intstr.FromInt(80),
+			},
+			Percent: 100,
+		},
+		Timeout: &metav1.Duration{Duration: v1alpha1.DefaultTimeout},
+		Retries: &v1alpha1.HTTPRetry{
+			PerTryTimeout: &metav1.Duration{Duration: v1alpha1.DefaultTimeout},
+			Attempts:      v1alpha1.DefaultRetryCount,
+		},
+	}
+	sampledPath := *path.DeepCopy()
+	sampledPath.Mirror.Percent = 25
+	route := func(mirrored bool, headers map[string]istiov1alpha1.StringMatch) v1alpha3.HTTPRoute {
+		r := v1alpha3.HTTPRoute{
+			Match: []v1alpha3.HTTPMatchRequest{{
+				Authority: &istiov1alpha1.StringMatch{Exact: "a.com"},
+				Headers:   headers,
+			}},
+			Route: []v1alpha3.DestinationWeight{{
+				Destination: v1alpha3.Destination{
+					Host: "v1-service.test-ns.svc.cluster.local",
+					Port: v1alpha3.PortSelector{Number: 80},
+				},
+				Weight: 100,
+			}},
+			Timeout: v1alpha1.DefaultTimeout.String(),
+			Retries: &v1alpha3.HTTPRetry{
+				Attempts:      v1alpha1.DefaultRetryCount,
+				PerTryTimeout: v1alpha1.DefaultTimeout.String(),
+			},
+		}
+		if mirrored {
+			r.Mirror = &v1alpha3.Destination{
+				Host: "v2-service.test-ns.svc.cluster.local",
+				Port: v1alpha3.PortSelector{Number: 80},
+			}
+		}
+		return r
+	}
+	tests := []struct {
+		name string
+		path v1alpha1.HTTPClusterIngressPath
+		want []v1alpha3.HTTPRoute
+	}{{
+		name: "mirror all",
+		path: path,
+		want: []v1alpha3.HTTPRoute{route(true, nil)},
+	}, {
+		name: "mirror sample",
+		path: sampledPath,
+		want: []v1alpha3.HTTPRoute{
+			route(true, map[string]istiov1alpha1.StringMatch{
+				"x-request-id": {Regex: "([0123][0-9a-f]).*"},
+			}),
+			route(false, nil),
+		},
+	}}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			ci := &v1alpha1.ClusterIngress{
+				Spec: v1alpha1.IngressSpec{
+					Rules: []v1alpha1.ClusterIngressRule{{
+						Hosts: []string{"a.com"},
+						HTTP: &v1alpha1.HTTPClusterIngressRuleValue{
+							Paths: []v1alpha1.HTTPClusterIngressPath{test.path},
+						},
+					}},
+				},
+			}
+			routes := makeVirtualServiceSpec(ci, []string{}).Http
+			if diff := cmp.Diff(test.want, routes); diff != "" {
+				t.Errorf("Unexpected routes (-want +got): %v", diff)
+			}
+		})
+	}
+}
+
+func TestRequestIDSample(t *testing.T) {
+	tests := []struct {
+		percent int
+		want    string
+	}{{
+		percent: 0,
+		want:    "(0[0]).*",
+	}, {
+		percent: 1,
+		want:    "(0[01]).*",
+	}, {
+		percent: 10,
+		want:    "([0][0-9a-f]|1[012345678]).*",
+	}, {
+		percent: 50,
+		want:    "([01234567][0-9a-f]).*",
+	}, {
+		percent: 99,
+		want:    "([0123456789abcde][0-9a-f]|f[0123456789abc]).*",
+	}}
+	for _, test := range tests {
+		if got := requestIDSample(test.percent); got != test.want {
+			t.Errorf("requestIDSample(%d) = %q, want %q", test.percent, got, test.want)
+		}
+	}
+}
+
+func TestHexBelow(t *testing.T) {
+	tests := []struct {
+		n      int
+		digits int
+		want   string
+	}{{
+		n:      1,
+		digits: 4,
+		want:   "(000[0])",
+	}, {
+		n:      0x0120,
+		digits: 4,
+		want:   "(0[0][0-9a-f][0-9a-f]|01[01][0-9a-f])",
+	}, {
+		n:      0xffbe,
+		digits: 4,
+		want:   "([0123456789abcde][0-9a-f][0-9a-f][0-9a-f]|f[0123456789abcde][0-9a-f][0-9a-f]|ff[0123456789a][0-9a-f]|ffb[0123456789abcd])",
+	}}
+	for _, test := range tests {
+		if got := hexBelow(test.n, test.digits); got != test.want {
+			t.Errorf("hexBelow(%#x, %d) = %q, want %q", test.n, test.digits, got, test.want)
+		}
+	}
+}
+
+func TestMakeVirtualServiceRoutes_BasisPoints(t *testing.T) {
+	split := func(name string, bp int) v1alpha1.ClusterIngressBackendSplit {
+		s := v1alpha1.ClusterIngressBackendSplit{
+			ClusterIngressBackend: v1alpha1.ClusterIngressBackend{
+				ServiceNamespace: "test-ns",
+				ServiceName:      name,
+				ServicePort:      intstr.FromInt(80),
+			},
+		}
+		s.SetBasisPoints(bp)
+		return s
+	}
+	ingressPath := &v1alpha1.HTTPClusterIngressPath{
+		Splits: []v1alpha1.ClusterIngressBackendSplit{
+			split("v1-service", 9900),
+			split("v2-service", 90),
+			split("v3-service", 10),
+		},
+		Timeout: &metav1.Duration{Duration: v1alpha1.DefaultTimeout},
+		Retries: &v1alpha1.HTTPRetry{
+			PerTryTimeout: &metav1.Duration{Duration: v1alpha1.DefaultTimeout},
+			Attempts:      v1alpha1.DefaultRetryCount,
+		},
+	}
+	route := func(name string, headers map[string]istiov1alpha1.StringMatch) v1alpha3.HTTPRoute {
+		return v1alpha3.HTTPRoute{
+			Match: []v1alpha3.HTTPMatchRequest{{
+				Authority: &istiov1alpha1.StringMatch{Exact: "test.org"},
+				Headers:   headers,
+			}},
+			Route: []v1alpha3.DestinationWeight{{
+				Destination: v1alpha3.Destination{
+					Host: name + ".test-ns.svc.cluster.local",
+					Port: v1alpha3.PortSelector{Number: 80},
+				},
+				Weight: 100,
+			}},
+			Timeout: v1alpha1.DefaultTimeout.String(),
+			Retries: &v1alpha3.HTTPRetry{
+				Attempts:      v1alpha1.DefaultRetryCount,
+				PerTryTimeout: v1alpha1.DefaultTimeout.String(),
+			},
+		}
+	}
+	expected := []v1alpha3.HTTPRoute{
+		// 99% of the 65536 request id buckets, below 0xfd71.
+		route("v1-service", map[string]istiov1alpha1.StringMatch{
+			"x-request-id": {Regex: ".*" + hexBelow(0xfd71, 4)},
+		}),
+		// 99.9% of the buckets, below 0xffbe.
+		route("v2-service", map[string]istiov1alpha1.StringMatch{
+			"x-request-id": {Regex: ".*" + hexBelow(0xffbe, 4)},
+		}),
+		route("v3-service", nil),
+	}
+	routes := makeVirtualServiceRoutes([]string{"test.org"}, ingressPath)
+	if diff := cmp.Diff(expected, routes); diff != "" {
+		t.Errorf("Unexpected routes (-want +got): %v", diff)
+	}
+}
+
+func TestMakeVirtualServiceRoutes_BucketCoverage(t *testing.T) {
+	tests := []struct {
+		name        string
+		basisPoints []int
+	}{{
+		name:        "uneven",
+		basisPoints: []int{9900, 90, 10},
+	}, {
+		name:        "thirds",
+		basisPoints: []int{3333, 3333, 3334},
+	}, {
+		name:        "too small to get a bucket",
+		basisPoints: []int{1, 9999},
+	}}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			ingressPath := &v1alpha1.HTTPClusterIngressPath{
+				Timeout: &metav1.Duration{Duration: v1alpha1.DefaultTimeout},
+				Retries: &v1alpha1.HTTPRetry{
+					PerTryTimeout: &metav1.Duration{Duration: v1alpha1.DefaultTimeout},
+				},
+			}
+			for i, bp := range test.basisPoints {
+				split := v1alpha1.ClusterIngressBackendSplit{
+					ClusterIngressBackend: v1alpha1.ClusterIngressBackend{
+						ServiceNamespace: "test-ns",
+						ServiceName:      fmt.Sprintf("v%d-service", i),
+						ServicePort:      intstr.FromInt(80),
+					},
+				}
+				split.SetBasisPoints(bp)
+				ingressPath.Splits = append(ingressPath.Splits, split)
+			}
+			routes := makeVirtualServiceRoutes([]string{"test.org"}, ingressPath)
+
+			// Envoy matches the whole header value against the regex.
+			regexes := []*regexp.Regexp{}
+			for _, route := range routes[:len(routes)-1] {
+				regexes = append(regexes, regexp.MustCompile("^("+route.Match[0].Headers[requestIDHeaderName].Regex+")$"))
+			}
+			if _, ok := routes[len(routes)-1].Match[0].Headers[requestIDHeaderName]; ok {
+				t.Fatalf("The last route matches the request id, want it to take the remaining requests")
+			}
+			counts := make(map[string]int)
+			for id := 0; id < requestIDBuckets; id++ {
+				// Envoy picks the first route that matches.
+				requestID := fmt.Sprintf("f81d4fae-7dec-11d0-a765-00a0c91e%04x", id)
+				matched := len(routes) - 1
+				for i, re := range regexes {
+					if re.MatchString(requestID) {
+						matched = i
+						break
+					}
+				}
+				counts[routes[matched].Route[0].Destination.Host]++
+			}
+
+			// Every request id goes to exactly one split, and each split gets
+			// its share of them.
+			total, last := 0, 0
+			for i, bp := range test.basisPoints {
+				total += bp
+				threshold := (total*requestIDBuckets + 5000) / 10000
+				host := fmt.Sprintf("v%d-service.test-ns.svc.cluster.local", i)
+				if got, want := counts[host], threshold-last; got != want {
+					t.Errorf("%s gets %d request ids, want %d", host, got, want)
+				}
+				last = threshold
+			}
+		})
+	}
+}
+
 func TestMakeVirtualServiceRoute_TwoTargets(t *testing.T) {
 	ingressPath := &v1alpha1.HTTPClusterIngressPath{
 		Splits: []v1alpha1.ClusterIngressBackendSplit{{
@@ -19,7 +19,9 @@ package config
 import (
 	"fmt"
 	"strings"
+	"text/template"
 
+	"github.com/knative/serving/pkg/tracing"
 	corev1 "k8s.io/api/core/v1"
 )
 
@@ -46,6 +48,15 @@ type Observability struct {
 	// LoggingURLTemplate is a string containing the logging url template where
 	// the variable REVISION_UID will be replaced with the created revision's UID.
 	LoggingURLTemplate string
+
+	// RequestLogTemplate is the go template used to render the message of the
+	// access log entries written by the queue-proxy. Access logging is
+	// disabled when it is empty.
+	RequestLogTemplate string
+
+	// Tracing is the tracing configuration handed to the queue-proxy. It is
+	// read from its own ConfigMap, and filled in by the Store.
+	Tracing tracing.Config
 }
 
 // NewObservabilityFromConfigMap creates a Observability from the supplied ConfigMap
@@ -66,5 +77,12 @@ func NewObservabilityFromConfigMap(configMap *corev1.ConfigMap) (*Observability,
 	if rut, ok := configMap.Data["logging.revision-url-template"]; ok {
 		oc.LoggingURLTemplate = rut
 	}
+	if rlt, ok := configMap.Data["logging.request-log-template"]; ok {
+		// Verify that we get valid templates.
+		if _, err := template.New("requestLog").Parse(rlt); err != nil {
+			return nil, err
+		}
+		oc.RequestLogTemplate = rlt
+	}
 	return oc, nil
 }
@@ -46,6 +46,7 @@ func TestObservabilityConfiguration(t *testing.T) {
 		wantErr: false,
 		wantController: &Observability{
 			LoggingURLTemplate:         "https://logging.io",
+			RequestLogTemplate:         `{"requestMethod": "{{.Request.Method}}"}`,
 			FluentdSidecarOutputConfig: "the-config",
 			FluentdSidecarImage:        "gcr.io/log-stuff/fluentd:latest",
 			EnableVarLogCollection:     true,
@@ -60,6 +61,7 @@ func TestObservabilityConfiguration(t *testing.T) {
 				"logging.fluentd-sidecar-image":         "gcr.io/log-stuff/fluentd:latest",
 				"logging.fluentd-sidecar-output-config": "the-config",
 				"logging.revision-url-template":         "https://logging.io",
+				"logging.request-log-template":          `{"requestMethod": "{{.Request.Method}}"}`,
 			},
 		},
 	}, {
@@ -88,6 +90,19 @@ func TestObservabilityConfiguration(t *testing.T) {
 				"logging.enable-var-log-collection": "true",
 			},
 		},
+	}, {
+		name:           "observability configuration with bad request log template",
+		wantErr:        true,
+		wantController: (*Observability)(nil),
+		config: &corev1.ConfigMap{
+			ObjectMeta: metav1.ObjectMeta{
+				Namespace: system.Namespace,
+				Name:      ObservabilityConfigName,
+			},
+			Data: map[string]string{
+				"logging.request-log-template": `{{ something }}`,
+			},
+		},
 	}}
 
 	for _, tt := range observabilityConfigTests {
@@ -23,6 +23,7 @@ import (
 	pkglogging "github.com/knative/pkg/logging"
 	"github.com/knative/serving/pkg/autoscaler"
 	"github.com/knative/serving/pkg/logging"
+	"github.com/knative/serving/pkg/tracing"
 )
 
 type cfgKey struct{}
@@ -58,6 +59,7 @@ func NewStore(logger configmap.Logger) *Store {
 				ControllerConfigName:    NewControllerConfigFromConfigMap,
 				NetworkConfigName:       NewNetworkFromConfigMap,
 				ObservabilityConfigName: NewObservabilityFromConfigMap,
+				tracing.ConfigName:      tracing.NewTracingConfigFromConfigMap,
 				autoscaler.ConfigName:   autoscaler.NewConfigFromConfigMap,
 				logging.ConfigName:      logging.NewConfigFromConfigMap,
 			},
@@ -72,10 +74,13 @@ func (s *Store) ToContext(ctx context.Context) context.Context {
 }
 
 func (s *Store) Load() *Config {
+	observability := s.UntypedLoad(ObservabilityConfigName).(*Observability).DeepCopy()
+	observability.Tracing = *s.UntypedLoad(tracing.ConfigName).(*tracing.Config)
+
 	return &Config{
 		Controller:    s.UntypedLoad(ControllerConfigName).(*Controller).DeepCopy(),
 		Network:       s.UntypedLoad(NetworkConfigName).(*Network).DeepCopy(),
-		Observability: s.UntypedLoad(ObservabilityConfigName).(*Observability).DeepCopy(),
+		Observability: observability,
 		Logging:       s.UntypedLoad(logging.ConfigName).(*pkglogging.Config).DeepCopy(),
 		Autoscaler:    s.UntypedLoad(autoscaler.ConfigName).(*autoscaler.Config).DeepCopy(),
 	}
@@ -24,6 +24,7 @@ import (
 	"github.com/google/go-cmp/cmp"
 	"github.com/knative/serving/pkg/autoscaler"
 	"github.com/knative/serving/pkg/logging"
+	"github.com/knative/serving/pkg/tracing"
 
 	. "github.com/knative/serving/pkg/reconciler/v1alpha1/testing"
 )
@@ -34,12 +35,14 @@ func TestStoreLoadWithContext(t *testing.T) {
 	controllerConfig := ConfigMapFromTestFile(t, ControllerConfigName)
 	networkConfig := ConfigMapFromTestFile(t, NetworkConfigName)
 	observabilityConfig := ConfigMapFromTestFile(t, ObservabilityConfigName)
+	tracingConfig := ConfigMapFromTestFile(t, tracing.ConfigName)
 	loggingConfig := ConfigMapFromTestFile(t, logging.ConfigName)
 	autoscalerConfig := ConfigMapFromTestFile(t, autoscaler.ConfigName)
 
 	store.OnConfigChanged(controllerConfig)
 	store.OnConfigChanged(networkConfig)
 	store.OnConfigChanged(observabilityConfig)
+	store.OnConfigChanged(tracingConfig)
 	store.OnConfigChanged(loggingConfig)
 	store.OnConfigChanged(autoscalerConfig)
 
@@ -61,6 +64,8 @@ func TestStoreLoadWithContext(t *testing.T) {
 
 	t.Run("observability", func(t *testing.T) {
 		expected, _ := NewObservabilityFromConfigMap(observabilityConfig)
+		tc, _ := tracing.NewTracingConfigFromConfigMap(tracingConfig)
+		expected.Tracing = *tc
 		if diff := cmp.Diff(expected, config.Observability); diff != "" {
 			t.Errorf("Unexpected observability config (-want, +got): %v", diff)
 		}
@@ -87,6 +92,7 @@ func TestStoreImmutableConfig(t *testing.T) {
 	store.OnConfigChanged(ConfigMapFromTestFile(t, ControllerConfigName))
 	store.OnConfigChanged(ConfigMapFromTestFile(t, NetworkConfigName))
 	store.OnConfigChanged(ConfigMapFromTestFile(t, ObservabilityConfigName))
+	store.OnConfigChanged(ConfigMapFromTestFile(t, tracing.ConfigName))
 	store.OnConfigChanged(ConfigMapFromTestFile(t, logging.ConfigName))
 	store.OnConfigChanged(ConfigMapFromTestFile(t, autoscaler.ConfigName))
 
@@ -95,6 +101,7 @@ func TestStoreImmutableConfig(t *testing.T) {
 	config.Controller.QueueSidecarImage = "mutated"
 	config.Network.IstioOutboundIPRanges = "mutated"
 	config.Observability.FluentdSidecarImage = "mutated"
+	config.Observability.Tracing.ZipkinEndpoint = "mutated"
 	config.Logging.LoggingConfig = "mutated"
 	config.Autoscaler.MaxScaleUpRate = rand.Float64()
 
@@ -109,6 +116,9 @@ func TestStoreImmutableConfig(t *testing.T) {
 	if newConfig.Observability.FluentdSidecarImage == "mutated" {
 		t.Error("Observability config is not immutable")
 	}
+	if newConfig.Observability.Tracing.ZipkinEndpoint == "mutated" {
+		t.Error("Tracing config is not immutable")
+	}
 	if newConfig.Logging.LoggingConfig == "mutated" {
 		t.Error("Logging config is not immutable")
 	}
@@ -24,6 +24,8 @@ import (
 	fakecachingclientset "github.com/knative/caching/pkg/client/clientset/versioned/fake"
 	cachinginformers "github.com/knative/caching/pkg/client/informers/externalversions"
 	"github.com/knative/pkg/apis/duck"
+	fakesharedclientset "github.com/knative/pkg/client/clientset/versioned/fake"
+	sharedinformers "github.com/knative/pkg/client/informers/externalversions"
 	"github.com/knative/pkg/configmap"
 	ctrl "github.com/knative/pkg/controller"
 	"github.com/knative/serving/pkg/apis/serving"
@@ -35,6 +37,7 @@ import (
 	rclr "github.com/knative/serving/pkg/reconciler"
 	"github.com/knative/serving/pkg/reconciler/v1alpha1/revision/config"
 	"github.com/knative/serving/pkg/system"
+	"github.com/knative/serving/pkg/tracing"
 	"golang.org/x/sync/errgroup"
 	corev1 "k8s.io/api/core/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
@@ -149,12 +152,14 @@ func newTestController(t *testing.T, stopCh <-chan struct{}, servingObjects ...r
 	kubeClient = fakekubeclientset.NewSimpleClientset()
 	servingClient = fakeclientset.NewSimpleClientset(servingObjects...)
 	cachingClient = fakecachingclientset.NewSimpleClientset()
+	sharedClient := fakesharedclientset.NewSimpleClientset()
 	dynamicClient = fakedynamicclientset.NewSimpleDynamicClient(runtime.NewScheme())
 
 	configMapWatcher = &configmap.ManualWatcher{Namespace: system.Namespace}
 
 	opt := rclr.Options{
 		KubeClientSet:    kubeClient,
+		SharedClientSet:  sharedClient,
 		ServingClientSet: servingClient,
 		DynamicClientSet: dynamicClient,
 		CachingClientSet: cachingClient,
@@ -170,6 +175,7 @@ func newTestController(t *testing.T, stopCh <-chan struct{}, servingObjects ...r
 	kubeInformer = kubeinformers.NewSharedInformerFactory(kubeClient, opt.ResyncPeriod)
 	servingInformer = informers.NewSharedInformerFactory(servingClient, opt.ResyncPeriod)
 	cachingInformer = cachinginformers.NewSharedInformerFactory(cachingClient, opt.ResyncPeriod)
+	sharedInformer := sharedinformers.NewSharedInformerFactory(sharedClient, opt.ResyncPeriod)
 
 	controller = NewController(
 		opt,
@@ -180,6 +186,7 @@ func newTestController(t *testing.T, stopCh <-chan struct{}, servingObjects ...r
 		kubeInformer.Core().V1().Services(),
 		kubeInformer.Core().V1().Endpoints(),
 		kubeInformer.Core().V1().ConfigMaps(),
+		sharedInformer.Networking().V1alpha3().DestinationRules(),
 		buildInformerFactory,
 	)
 
@@ -208,6 +215,10 @@ func newTestController(t *testing.T, stopCh <-chan struct{}, servingObjects ...r
 				"logging.fluentd-sidecar-image":         testFluentdImage,
 				"logging.fluentd-sidecar-output-config": testFluentdSidecarOutputConfig,
 			}}, {
+			ObjectMeta: metav1.ObjectMeta{
+				Namespace: system.Namespace,
+				Name:      tracing.ConfigName,
+			}}, {
 			ObjectMeta: metav1.ObjectMeta{
 				Namespace: system.Namespace,
 				Name:      autoscaler.ConfigName,
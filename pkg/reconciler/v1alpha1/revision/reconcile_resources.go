@@ -33,6 +33,7 @@ import (
 	corev1 "k8s.io/api/core/v1"
 	"k8s.io/apimachinery/pkg/api/equality"
 	apierrs "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 )
 
 const (
@@ -251,3 +252,52 @@ func (c *Reconciler) reconcileFluentdConfigMap(ctx context.Context, rev *v1alpha
 	}
 	return nil
 }
+
+func (c *Reconciler) reconcileDestinationRule(ctx context.Context, rev *v1alpha1.Revision) error {
+	ns := rev.Namespace
+	name := resourcenames.DestinationRule(rev)
+	logger := logging.FromContext(ctx)
+
+	sa, err := rev.GetSessionAffinity()
+	if err != nil {
+		// The webhook rejects invalid annotations, so this should not
+		// happen. Fall back to the default load balancing of the Service.
+		logger.Errorf("Ignoring the session affinity of revision %q: %v", rev.Name, err)
+	}
+
+	dr, err := c.destinationRuleLister.DestinationRules(ns).Get(name)
+	if apierrs.IsNotFound(err) {
+		if sa == nil {
+			return nil
+		}
+		desired := resources.MakeDestinationRule(rev, sa)
+		if _, err := c.SharedClientSet.NetworkingV1alpha3().DestinationRules(ns).Create(desired); err != nil {
+			logger.Errorf("Error creating DestinationRule %q: %v", name, err)
+			return err
+		}
+		logger.Infof("Created DestinationRule %q", name)
+	} else if err != nil {
+		logger.Errorf("Error reconciling DestinationRule %q: %v", name, err)
+		return err
+	} else if !metav1.IsControlledBy(dr, rev) {
+		// Leave alone the DestinationRules that we didn't create.
+		return nil
+	} else if sa == nil {
+		// The session affinity was removed.
+		if err := c.SharedClientSet.NetworkingV1alpha3().DestinationRules(ns).Delete(name, &metav1.DeleteOptions{}); err != nil {
+			logger.Errorf("Error deleting DestinationRule %q: %v", name, err)
+			return err
+		}
+		logger.Infof("Deleted DestinationRule %q", name)
+	} else if desired := resources.MakeDestinationRule(rev, sa); !equality.Semantic.DeepEqual(dr.Spec, desired.Spec) {
+		// Don't modify the informers copy
+		existing := dr.DeepCopy()
+		existing.Spec = desired.Spec
+		if _, err := c.SharedClientSet.NetworkingV1alpha3().DestinationRules(ns).Update(existing); err != nil {
+			logger.Errorf("Error updating DestinationRule %q: %v", name, err)
+			return err
+		}
+		logger.Infof("Updated DestinationRule %q", name)
+	}
+	return nil
+}
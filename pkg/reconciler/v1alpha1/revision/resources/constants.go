@@ -31,7 +31,7 @@ const (
 	IstioOutboundIPRangeAnnotation = "traffic.sidecar.istio.io/includeOutboundIPRanges"
 
 	userPortName    = "user-port"
-	userPort        = 8080
+	userPort        = 8080 // Unless the user container specifies a port.
 	userPortEnvName = "PORT"
 
 	autoscalerPort = 8080
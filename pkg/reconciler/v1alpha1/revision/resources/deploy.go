@@ -17,10 +17,12 @@ limitations under the License.
 package resources
 
 import (
+	"math"
 	"strconv"
 
 	"github.com/knative/pkg/kmeta"
 	"github.com/knative/pkg/logging"
+	"github.com/knative/serving/pkg/apis/serving"
 	"github.com/knative/serving/pkg/apis/serving/v1alpha1"
 	"github.com/knative/serving/pkg/autoscaler"
 	"github.com/knative/serving/pkg/queue"
@@ -48,17 +50,6 @@ var (
 		MountPath: "/var/log",
 	}
 
-	userPorts = []corev1.ContainerPort{{
-		Name:          userPortName,
-		ContainerPort: int32(userPort),
-	}}
-
-	// Expose containerPort as env PORT.
-	userEnv = corev1.EnvVar{
-		Name:  userPortEnvName,
-		Value: strconv.Itoa(userPort),
-	}
-
 	userResources = corev1.ResourceRequirements{
 		Requests: corev1.ResourceList{
 			corev1.ResourceCPU: userContainerCPU,
@@ -79,7 +70,31 @@ var (
 	}
 )
 
-func rewriteUserProbe(p *corev1.Probe) {
+// getUserPort returns the port the user container of rev listens on: the
+// single containerPort it specifies, if any, or userPort.
+func getUserPort(rev *v1alpha1.Revision) int {
+	if ports := rev.Spec.Container.Ports; len(ports) == 1 && ports[0].ContainerPort != 0 {
+		return int(ports[0].ContainerPort)
+	}
+	return userPort
+}
+
+func makeUserPorts(port int) []corev1.ContainerPort {
+	return []corev1.ContainerPort{{
+		Name:          userPortName,
+		ContainerPort: int32(port),
+	}}
+}
+
+// Expose containerPort as env PORT.
+func makeUserPortEnv(port int) corev1.EnvVar {
+	return corev1.EnvVar{
+		Name:  userPortEnvName,
+		Value: strconv.Itoa(port),
+	}
+}
+
+func rewriteUserProbe(p *corev1.Probe, userPort int) {
 	if p == nil {
 		return
 	}
@@ -123,10 +138,11 @@ func makePodSpec(rev *v1alpha1.Revision, loggingConfig *logging.Config, observab
 	// If client provides for some resources, override default values
 	applyDefaultResources(userResources, &userContainer.Resources)
 
-	userContainer.Ports = userPorts
+	userPort := getUserPort(rev)
+	userContainer.Ports = makeUserPorts(userPort)
 	userContainer.VolumeMounts = append(userContainer.VolumeMounts, varLogVolumeMount)
 	userContainer.Lifecycle = userLifecycle
-	userContainer.Env = append(userContainer.Env, userEnv)
+	userContainer.Env = append(userContainer.Env, makeUserPortEnv(userPort))
 	userContainer.Env = append(userContainer.Env, getKnativeEnvVar(rev)...)
 	// Prefer imageDigest from revision if available
 	if rev.Status.ImageDigest != "" {
@@ -134,19 +150,27 @@ func makePodSpec(rev *v1alpha1.Revision, loggingConfig *logging.Config, observab
 	}
 
 	// If the client provides probes, we should fill in the port for them.
-	rewriteUserProbe(userContainer.ReadinessProbe)
-	rewriteUserProbe(userContainer.LivenessProbe)
-
-	revisionTimeout := int64(rev.Spec.TimeoutSeconds.Duration.Seconds())
+	rewriteUserProbe(userContainer.ReadinessProbe, userPort)
+	rewriteUserProbe(userContainer.LivenessProbe, userPort)
+
+	// The Pod is drained before the requests in flight are given the
+	// revision timeout to finish. Without an explicit drain timeout the
+	// grace period stays the revision timeout, so that upgrading doesn't
+	// restart every Pod.
+	gracePeriodDuration := rev.Spec.TimeoutSeconds.Duration
+	if _, ok := rev.Annotations[serving.DrainTimeoutAnnotationKey]; ok {
+		gracePeriodDuration += drainTimeout(rev)
+	}
+	gracePeriod := int64(math.Ceil(gracePeriodDuration.Seconds()))
 
 	podSpec := &corev1.PodSpec{
 		Containers: []corev1.Container{
 			*userContainer,
-			*makeQueueContainer(rev, loggingConfig, autoscalerConfig, controllerConfig),
+			*makeQueueContainer(rev, loggingConfig, observabilityConfig, autoscalerConfig, controllerConfig),
 		},
 		Volumes:                       []corev1.Volume{varLogVolume},
 		ServiceAccountName:            rev.Spec.ServiceAccountName,
-		TerminationGracePeriodSeconds: &revisionTimeout,
+		TerminationGracePeriodSeconds: &gracePeriod,
 	}
 
 	// Add Fluentd sidecar and its config map volume if var log collection is enabled.
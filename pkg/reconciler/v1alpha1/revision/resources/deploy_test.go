@@ -37,6 +37,10 @@ import (
 
 var (
 	one int32 = 1
+
+	// The ports and env of user containers that don't specify a port.
+	userPorts = makeUserPorts(userPort)
+	userEnv   = makeUserPortEnv(userPort)
 )
 
 func refInt64(num int64) *int64 {
@@ -123,6 +127,24 @@ func TestMakePodSpec(t *testing.T) {
 				}, {
 					Name:  "REVISION_TIMEOUT_SECONDS",
 					Value: "45",
+				}, {
+					Name:  "SERVING_DRAIN_TIMEOUT",
+					Value: "20s",
+				}, {
+					Name:  "SERVING_DRAIN_SETTLE_PERIOD",
+					Value: "5s",
+				}, {
+					Name:  "SERVING_MAX_UPGRADED_CONNECTIONS",
+					Value: "0",
+				}, {
+					Name:  "SERVING_UPGRADED_CONNECTION_WEIGHT",
+					Value: "1",
+				}, {
+					Name:  "USER_PORT",
+					Value: "8080",
+				}, {
+					Name: "SERVING_READINESS_PROBE",
+					// No readiness probe
 				}, {
 					Name: "SERVING_POD",
 					ValueFrom: &corev1.EnvVarSource{
@@ -134,6 +156,9 @@ func TestMakePodSpec(t *testing.T) {
 				}, {
 					Name: "SERVING_LOGGING_LEVEL",
 					// No logging level
+				}, {
+					Name: "SERVING_REQUEST_LOG_TEMPLATE",
+					// No request log template
 				}},
 			}},
 			Volumes:                       []corev1.Volume{varLogVolume},
@@ -212,6 +237,24 @@ func TestMakePodSpec(t *testing.T) {
 				}, {
 					Name:  "REVISION_TIMEOUT_SECONDS",
 					Value: "45",
+				}, {
+					Name:  "SERVING_DRAIN_TIMEOUT",
+					Value: "20s",
+				}, {
+					Name:  "SERVING_DRAIN_SETTLE_PERIOD",
+					Value: "5s",
+				}, {
+					Name:  "SERVING_MAX_UPGRADED_CONNECTIONS",
+					Value: "0",
+				}, {
+					Name:  "SERVING_UPGRADED_CONNECTION_WEIGHT",
+					Value: "1",
+				}, {
+					Name:  "USER_PORT",
+					Value: "8080",
+				}, {
+					Name: "SERVING_READINESS_PROBE",
+					// No readiness probe
 				}, {
 					Name: "SERVING_POD",
 					ValueFrom: &corev1.EnvVarSource{
@@ -223,6 +266,9 @@ func TestMakePodSpec(t *testing.T) {
 				}, {
 					Name: "SERVING_LOGGING_LEVEL",
 					// No logging level
+				}, {
+					Name: "SERVING_REQUEST_LOG_TEMPLATE",
+					// No request log template
 				}},
 			}},
 			Volumes:                       []corev1.Volume{varLogVolume},
@@ -305,6 +351,24 @@ func TestMakePodSpec(t *testing.T) {
 				}, {
 					Name:  "REVISION_TIMEOUT_SECONDS",
 					Value: "45",
+				}, {
+					Name:  "SERVING_DRAIN_TIMEOUT",
+					Value: "20s",
+				}, {
+					Name:  "SERVING_DRAIN_SETTLE_PERIOD",
+					Value: "5s",
+				}, {
+					Name:  "SERVING_MAX_UPGRADED_CONNECTIONS",
+					Value: "0",
+				}, {
+					Name:  "SERVING_UPGRADED_CONNECTION_WEIGHT",
+					Value: "1",
+				}, {
+					Name:  "USER_PORT",
+					Value: "8080",
+				}, {
+					Name: "SERVING_READINESS_PROBE",
+					// No readiness probe
 				}, {
 					Name: "SERVING_POD",
 					ValueFrom: &corev1.EnvVarSource{
@@ -316,6 +380,9 @@ func TestMakePodSpec(t *testing.T) {
 				}, {
 					Name: "SERVING_LOGGING_LEVEL",
 					// No logging level
+				}, {
+					Name: "SERVING_REQUEST_LOG_TEMPLATE",
+					// No request log template
 				}},
 			}},
 			Volumes:                       []corev1.Volume{varLogVolume},
@@ -407,6 +474,24 @@ func TestMakePodSpec(t *testing.T) {
 				}, {
 					Name:  "REVISION_TIMEOUT_SECONDS",
 					Value: "45",
+				}, {
+					Name:  "SERVING_DRAIN_TIMEOUT",
+					Value: "20s",
+				}, {
+					Name:  "SERVING_DRAIN_SETTLE_PERIOD",
+					Value: "5s",
+				}, {
+					Name:  "SERVING_MAX_UPGRADED_CONNECTIONS",
+					Value: "0",
+				}, {
+					Name:  "SERVING_UPGRADED_CONNECTION_WEIGHT",
+					Value: "1",
+				}, {
+					Name:  "USER_PORT",
+					Value: "8080",
+				}, {
+					Name:  "SERVING_READINESS_PROBE",
+					Value: `{"httpGet":{"path":"/","port":8080}}`,
 				}, {
 					Name: "SERVING_POD",
 					ValueFrom: &corev1.EnvVarSource{
@@ -418,6 +503,9 @@ func TestMakePodSpec(t *testing.T) {
 				}, {
 					Name: "SERVING_LOGGING_LEVEL",
 					// No logging level
+				}, {
+					Name: "SERVING_REQUEST_LOG_TEMPLATE",
+					// No request log template
 				}},
 			}},
 			Volumes:                       []corev1.Volume{varLogVolume},
@@ -507,6 +595,24 @@ func TestMakePodSpec(t *testing.T) {
 				}, {
 					Name:  "REVISION_TIMEOUT_SECONDS",
 					Value: "45",
+				}, {
+					Name:  "SERVING_DRAIN_TIMEOUT",
+					Value: "20s",
+				}, {
+					Name:  "SERVING_DRAIN_SETTLE_PERIOD",
+					Value: "5s",
+				}, {
+					Name:  "SERVING_MAX_UPGRADED_CONNECTIONS",
+					Value: "0",
+				}, {
+					Name:  "SERVING_UPGRADED_CONNECTION_WEIGHT",
+					Value: "1",
+				}, {
+					Name:  "USER_PORT",
+					Value: "8080",
+				}, {
+					Name:  "SERVING_READINESS_PROBE",
+					Value: `{"exec":{"command":["echo","hello"]}}`,
 				}, {
 					Name: "SERVING_POD",
 					ValueFrom: &corev1.EnvVarSource{
@@ -518,6 +624,9 @@ func TestMakePodSpec(t *testing.T) {
 				}, {
 					Name: "SERVING_LOGGING_LEVEL",
 					// No logging level
+				}, {
+					Name: "SERVING_REQUEST_LOG_TEMPLATE",
+					// No request log template
 				}},
 			}},
 			Volumes:                       []corev1.Volume{varLogVolume},
@@ -609,6 +718,24 @@ func TestMakePodSpec(t *testing.T) {
 				}, {
 					Name:  "REVISION_TIMEOUT_SECONDS",
 					Value: "45",
+				}, {
+					Name:  "SERVING_DRAIN_TIMEOUT",
+					Value: "20s",
+				}, {
+					Name:  "SERVING_DRAIN_SETTLE_PERIOD",
+					Value: "5s",
+				}, {
+					Name:  "SERVING_MAX_UPGRADED_CONNECTIONS",
+					Value: "0",
+				}, {
+					Name:  "SERVING_UPGRADED_CONNECTION_WEIGHT",
+					Value: "1",
+				}, {
+					Name:  "USER_PORT",
+					Value: "8080",
+				}, {
+					Name:  "SERVING_READINESS_PROBE",
+					Value: `{"httpGet":{"path":"/","port":0}}`,
 				}, {
 					Name: "SERVING_POD",
 					ValueFrom: &corev1.EnvVarSource{
@@ -620,6 +747,9 @@ func TestMakePodSpec(t *testing.T) {
 				}, {
 					Name: "SERVING_LOGGING_LEVEL",
 					// No logging level
+				}, {
+					Name: "SERVING_REQUEST_LOG_TEMPLATE",
+					// No request log template
 				}},
 			}},
 			Volumes:                       []corev1.Volume{varLogVolume},
@@ -707,6 +837,24 @@ func TestMakePodSpec(t *testing.T) {
 				}, {
 					Name:  "REVISION_TIMEOUT_SECONDS",
 					Value: "45",
+				}, {
+					Name:  "SERVING_DRAIN_TIMEOUT",
+					Value: "20s",
+				}, {
+					Name:  "SERVING_DRAIN_SETTLE_PERIOD",
+					Value: "5s",
+				}, {
+					Name:  "SERVING_MAX_UPGRADED_CONNECTIONS",
+					Value: "0",
+				}, {
+					Name:  "SERVING_UPGRADED_CONNECTION_WEIGHT",
+					Value: "1",
+				}, {
+					Name:  "USER_PORT",
+					Value: "8080",
+				}, {
+					Name: "SERVING_READINESS_PROBE",
+					// No readiness probe
 				}, {
 					Name: "SERVING_POD",
 					ValueFrom: &corev1.EnvVarSource{
@@ -718,6 +866,9 @@ func TestMakePodSpec(t *testing.T) {
 				}, {
 					Name: "SERVING_LOGGING_LEVEL",
 					// No logging level
+				}, {
+					Name: "SERVING_REQUEST_LOG_TEMPLATE",
+					// No request log template
 				}},
 			}},
 			Volumes:                       []corev1.Volume{varLogVolume},
@@ -796,6 +947,24 @@ func TestMakePodSpec(t *testing.T) {
 				}, {
 					Name:  "REVISION_TIMEOUT_SECONDS",
 					Value: "45",
+				}, {
+					Name:  "SERVING_DRAIN_TIMEOUT",
+					Value: "20s",
+				}, {
+					Name:  "SERVING_DRAIN_SETTLE_PERIOD",
+					Value: "5s",
+				}, {
+					Name:  "SERVING_MAX_UPGRADED_CONNECTIONS",
+					Value: "0",
+				}, {
+					Name:  "SERVING_UPGRADED_CONNECTION_WEIGHT",
+					Value: "1",
+				}, {
+					Name:  "USER_PORT",
+					Value: "8080",
+				}, {
+					Name: "SERVING_READINESS_PROBE",
+					// No readiness probe
 				}, {
 					Name: "SERVING_POD",
 					ValueFrom: &corev1.EnvVarSource{
@@ -807,6 +976,9 @@ func TestMakePodSpec(t *testing.T) {
 				}, {
 					Name: "SERVING_LOGGING_LEVEL",
 					// No logging level
+				}, {
+					Name: "SERVING_REQUEST_LOG_TEMPLATE",
+					// No request log template
 				}},
 			}, {
 				Name:      fluentdContainerName,
@@ -956,6 +1128,24 @@ func TestMakePodSpec(t *testing.T) {
 				}, {
 					Name:  "REVISION_TIMEOUT_SECONDS",
 					Value: "45",
+				}, {
+					Name:  "SERVING_DRAIN_TIMEOUT",
+					Value: "20s",
+				}, {
+					Name:  "SERVING_DRAIN_SETTLE_PERIOD",
+					Value: "5s",
+				}, {
+					Name:  "SERVING_MAX_UPGRADED_CONNECTIONS",
+					Value: "0",
+				}, {
+					Name:  "SERVING_UPGRADED_CONNECTION_WEIGHT",
+					Value: "1",
+				}, {
+					Name:  "USER_PORT",
+					Value: "8080",
+				}, {
+					Name: "SERVING_READINESS_PROBE",
+					// No readiness probe
 				}, {
 					Name: "SERVING_POD",
 					ValueFrom: &corev1.EnvVarSource{
@@ -967,6 +1157,241 @@ func TestMakePodSpec(t *testing.T) {
 				}, {
 					Name: "SERVING_LOGGING_LEVEL",
 					// No logging level
+				}, {
+					Name: "SERVING_REQUEST_LOG_TEMPLATE",
+					// No request log template
+				}},
+			}},
+			Volumes:                       []corev1.Volume{varLogVolume},
+			TerminationGracePeriodSeconds: refInt64(45),
+		},
+	}, {
+		name: "drain timeout extends grace period",
+		rev: &v1alpha1.Revision{
+			ObjectMeta: metav1.ObjectMeta{
+				Namespace: "foo",
+				Name:      "bar",
+				UID:       "1234",
+				Labels:    labels,
+				Annotations: map[string]string{
+					serving.DrainTimeoutAnnotationKey: "1m30s",
+				},
+			},
+			Spec: v1alpha1.RevisionSpec{
+				ContainerConcurrency: 1,
+				Container: corev1.Container{
+					Image: "busybox",
+				},
+				TimeoutSeconds: &metav1.Duration{
+					Duration: 45 * time.Second,
+				},
+			},
+		},
+		lc: &logging.Config{},
+		oc: &config.Observability{},
+		ac: &autoscaler.Config{},
+		cc: &config.Controller{},
+		want: &corev1.PodSpec{
+			Containers: []corev1.Container{{
+				Name:         userContainerName,
+				Image:        "busybox",
+				Resources:    userResources,
+				Ports:        userPorts,
+				VolumeMounts: []corev1.VolumeMount{varLogVolumeMount},
+				Lifecycle:    userLifecycle,
+				Env: []corev1.EnvVar{userEnv,
+					{
+						Name:  "K_REVISION",
+						Value: "bar",
+					}, {
+						Name:  "K_CONFIGURATION",
+						Value: "cfg",
+					}, {
+						Name:  "K_SERVICE",
+						Value: "svc",
+					}},
+			}, {
+				Name:           queueContainerName,
+				Resources:      queueResources,
+				Ports:          queuePorts,
+				Lifecycle:      queueLifecycle,
+				ReadinessProbe: queueReadinessProbe,
+				// These changed based on the Revision and configs passed in.
+				Env: []corev1.EnvVar{{
+					Name:  "SERVING_NAMESPACE",
+					Value: "foo", // matches namespace
+				}, {
+					Name: "SERVING_CONFIGURATION",
+					// No OwnerReference
+				}, {
+					Name:  "SERVING_REVISION",
+					Value: "bar", // matches name
+				}, {
+					Name:  "SERVING_AUTOSCALER",
+					Value: "autoscaler", // no autoscaler configured.
+				}, {
+					Name:  "SERVING_AUTOSCALER_PORT",
+					Value: "8080",
+				}, {
+					Name:  "CONTAINER_CONCURRENCY",
+					Value: "1",
+				}, {
+					Name:  "REVISION_TIMEOUT_SECONDS",
+					Value: "45",
+				}, {
+					Name:  "SERVING_DRAIN_TIMEOUT",
+					Value: "1m30s",
+				}, {
+					Name:  "SERVING_DRAIN_SETTLE_PERIOD",
+					Value: "5s",
+				}, {
+					Name:  "SERVING_MAX_UPGRADED_CONNECTIONS",
+					Value: "0",
+				}, {
+					Name:  "SERVING_UPGRADED_CONNECTION_WEIGHT",
+					Value: "1",
+				}, {
+					Name:  "USER_PORT",
+					Value: "8080",
+				}, {
+					Name: "SERVING_READINESS_PROBE",
+					// No readiness probe
+				}, {
+					Name: "SERVING_POD",
+					ValueFrom: &corev1.EnvVarSource{
+						FieldRef: &corev1.ObjectFieldSelector{FieldPath: "metadata.name"},
+					},
+				}, {
+					Name: "SERVING_LOGGING_CONFIG",
+					// No logging configuration
+				}, {
+					Name: "SERVING_LOGGING_LEVEL",
+					// No logging level
+				}, {
+					Name: "SERVING_REQUEST_LOG_TEMPLATE",
+					// No request log template
+				}},
+			}},
+			Volumes:                       []corev1.Volume{varLogVolume},
+			TerminationGracePeriodSeconds: refInt64(135),
+		},
+	}, {
+		name: "custom port, livenessprobe=tcp",
+		rev: &v1alpha1.Revision{
+			ObjectMeta: metav1.ObjectMeta{
+				Namespace: "foo",
+				Name:      "bar",
+				UID:       "1234",
+				Labels:    labels,
+			},
+			Spec: v1alpha1.RevisionSpec{
+				ContainerConcurrency: 0,
+				Container: corev1.Container{
+					Image: "busybox",
+					Ports: []corev1.ContainerPort{{
+						ContainerPort: 8888,
+					}},
+					LivenessProbe: &corev1.Probe{
+						Handler: corev1.Handler{
+							TCPSocket: &corev1.TCPSocketAction{},
+						},
+					},
+				},
+				TimeoutSeconds: &metav1.Duration{
+					Duration: 45 * time.Second,
+				},
+			},
+		},
+		lc: &logging.Config{},
+		oc: &config.Observability{},
+		ac: &autoscaler.Config{},
+		cc: &config.Controller{},
+		want: &corev1.PodSpec{
+			Containers: []corev1.Container{{
+				Name:  userContainerName,
+				Image: "busybox",
+				LivenessProbe: &corev1.Probe{
+					Handler: corev1.Handler{
+						TCPSocket: &corev1.TCPSocketAction{
+							Port: intstr.FromInt(8888),
+						},
+					},
+				},
+				Resources:    userResources,
+				Ports:        makeUserPorts(8888),
+				VolumeMounts: []corev1.VolumeMount{varLogVolumeMount},
+				Lifecycle:    userLifecycle,
+				Env: []corev1.EnvVar{makeUserPortEnv(8888),
+					{
+						Name:  "K_REVISION",
+						Value: "bar",
+					}, {
+						Name:  "K_CONFIGURATION",
+						Value: "cfg",
+					}, {
+						Name:  "K_SERVICE",
+						Value: "svc",
+					}},
+			}, {
+				Name:           queueContainerName,
+				Resources:      queueResources,
+				Ports:          queuePorts,
+				Lifecycle:      queueLifecycle,
+				ReadinessProbe: queueReadinessProbe,
+				// These changed based on the Revision and configs passed in.
+				Env: []corev1.EnvVar{{
+					Name:  "SERVING_NAMESPACE",
+					Value: "foo", // matches namespace
+				}, {
+					Name: "SERVING_CONFIGURATION",
+					// No OwnerReference
+				}, {
+					Name:  "SERVING_REVISION",
+					Value: "bar", // matches name
+				}, {
+					Name:  "SERVING_AUTOSCALER",
+					Value: "autoscaler", // no autoscaler configured.
+				}, {
+					Name:  "SERVING_AUTOSCALER_PORT",
+					Value: "8080",
+				}, {
+					Name:  "CONTAINER_CONCURRENCY",
+					Value: "0",
+				}, {
+					Name:  "REVISION_TIMEOUT_SECONDS",
+					Value: "45",
+				}, {
+					Name:  "SERVING_DRAIN_TIMEOUT",
+					Value: "20s",
+				}, {
+					Name:  "SERVING_DRAIN_SETTLE_PERIOD",
+					Value: "5s",
+				}, {
+					Name:  "SERVING_MAX_UPGRADED_CONNECTIONS",
+					Value: "0",
+				}, {
+					Name:  "SERVING_UPGRADED_CONNECTION_WEIGHT",
+					Value: "1",
+				}, {
+					Name:  "USER_PORT",
+					Value: "8888",
+				}, {
+					Name: "SERVING_READINESS_PROBE",
+					// No readiness probe
+				}, {
+					Name: "SERVING_POD",
+					ValueFrom: &corev1.EnvVarSource{
+						FieldRef: &corev1.ObjectFieldSelector{FieldPath: "metadata.name"},
+					},
+				}, {
+					Name: "SERVING_LOGGING_CONFIG",
+					// No logging configuration
+				}, {
+					Name: "SERVING_LOGGING_LEVEL",
+					// No logging level
+				}, {
+					Name: "SERVING_REQUEST_LOG_TEMPLATE",
+					// No request log template
 				}},
 			}},
 			Volumes:                       []corev1.Volume{varLogVolume},
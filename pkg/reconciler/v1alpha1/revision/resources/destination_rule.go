@@ -0,0 +1,62 @@
+/*
+Copyright 2018 The Knative Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package resources
+
+import (
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	"github.com/knative/pkg/apis/istio/v1alpha3"
+	"github.com/knative/pkg/kmeta"
+	"github.com/knative/serving/pkg/apis/serving/v1alpha1"
+	"github.com/knative/serving/pkg/reconciler"
+	"github.com/knative/serving/pkg/reconciler/v1alpha1/revision/resources/names"
+)
+
+// MakeDestinationRule creates an Istio DestinationRule that consistently
+// hashes the requests to the K8s Service of the revision on the key of
+// its session affinity, so that requests with the same key go to the
+// same pod for as long as the pods of the revision don't change.
+func MakeDestinationRule(rev *v1alpha1.Revision, sa *v1alpha1.SessionAffinity) *v1alpha3.DestinationRule {
+	hash := &v1alpha3.ConsistentHashLB{
+		HttpHeaderName: sa.Header,
+		UseSourceIp:    sa.SourceIP,
+	}
+	if sa.Cookie != "" {
+		hash.HttpCookie = &v1alpha3.HTTPCookie{
+			Name: sa.Cookie,
+			// A zero TTL makes Envoy generate a session cookie.
+			Ttl: sa.CookieTTL.String(),
+		}
+	}
+	return &v1alpha3.DestinationRule{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:            names.DestinationRule(rev),
+			Namespace:       rev.Namespace,
+			Labels:          makeLabels(rev),
+			Annotations:     makeAnnotations(rev),
+			OwnerReferences: []metav1.OwnerReference{*kmeta.NewControllerRef(rev)},
+		},
+		Spec: v1alpha3.DestinationRuleSpec{
+			Host: reconciler.GetK8sServiceFullname(names.K8sService(rev), rev.Namespace),
+			TrafficPolicy: &v1alpha3.TrafficPolicy{
+				LoadBalancer: &v1alpha3.LoadBalancerSettings{
+					ConsistentHash: hash,
+				},
+			},
+		},
+	}
+}
@@ -0,0 +1,107 @@
+/*
+Copyright 2018 The Knative Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package resources
+
+import (
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	"github.com/knative/pkg/apis/istio/v1alpha3"
+	"github.com/knative/serving/pkg/apis/serving"
+	"github.com/knative/serving/pkg/apis/serving/v1alpha1"
+)
+
+func TestMakeDestinationRule(t *testing.T) {
+	rev := &v1alpha1.Revision{
+		ObjectMeta: metav1.ObjectMeta{
+			Namespace: "foo",
+			Name:      "bar",
+			UID:       "1234",
+			Annotations: map[string]string{
+				serving.SessionAffinityAnnotationKey: "cookie:session:1h",
+			},
+		},
+	}
+	meta := metav1.ObjectMeta{
+		Namespace: "foo",
+		Name:      "bar-service",
+		Labels: map[string]string{
+			serving.RevisionLabelKey: "bar",
+			serving.RevisionUID:      "1234",
+			AppLabelKey:              "bar",
+		},
+		Annotations: map[string]string{
+			serving.SessionAffinityAnnotationKey: "cookie:session:1h",
+		},
+		OwnerReferences: []metav1.OwnerReference{{
+			APIVersion:         v1alpha1.SchemeGroupVersion.String(),
+			Kind:               "Revision",
+			Name:               "bar",
+			UID:                "1234",
+			Controller:         &boolTrue,
+			BlockOwnerDeletion: &boolTrue,
+		}},
+	}
+	tests := []struct {
+		name string
+		sa   *v1alpha1.SessionAffinity
+		want *v1alpha3.ConsistentHashLB
+	}{{
+		name: "header",
+		sa:   &v1alpha1.SessionAffinity{Header: "X-User-Id"},
+		want: &v1alpha3.ConsistentHashLB{HttpHeaderName: "X-User-Id"},
+	}, {
+		name: "cookie",
+		sa:   &v1alpha1.SessionAffinity{Cookie: "session", CookieTTL: time.Hour},
+		want: &v1alpha3.ConsistentHashLB{
+			HttpCookie: &v1alpha3.HTTPCookie{Name: "session", Ttl: "1h0m0s"},
+		},
+	}, {
+		name: "session cookie",
+		sa:   &v1alpha1.SessionAffinity{Cookie: "session"},
+		want: &v1alpha3.ConsistentHashLB{
+			HttpCookie: &v1alpha3.HTTPCookie{Name: "session", Ttl: "0s"},
+		},
+	}, {
+		name: "source ip",
+		sa:   &v1alpha1.SessionAffinity{SourceIP: true},
+		want: &v1alpha3.ConsistentHashLB{UseSourceIp: true},
+	}}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			want := &v1alpha3.DestinationRule{
+				ObjectMeta: meta,
+				Spec: v1alpha3.DestinationRuleSpec{
+					Host: "bar-service.foo.svc.cluster.local",
+					TrafficPolicy: &v1alpha3.TrafficPolicy{
+						LoadBalancer: &v1alpha3.LoadBalancerSettings{
+							ConsistentHash: test.want,
+						},
+					},
+				},
+			}
+			got := MakeDestinationRule(rev, test.sa)
+			if diff := cmp.Diff(want, got); diff != "" {
+				t.Errorf("MakeDestinationRule (-want, +got) = %v", diff)
+			}
+		})
+	}
+}
@@ -41,3 +41,9 @@ func K8sService(rev *v1alpha1.Revision) string {
 func FluentdConfigMap(rev *v1alpha1.Revision) string {
 	return rev.Name + "-fluentd"
 }
+
+// DestinationRule is named after the K8sService whose traffic it sets the
+// policy of.
+func DestinationRule(rev *v1alpha1.Revision) string {
+	return K8sService(rev)
+}
@@ -75,6 +75,15 @@ func TestNamer(t *testing.T) {
 		},
 		f:    FluentdConfigMap,
 		want: "bazinga-fluentd",
+	}, {
+		name: "DestinationRule",
+		rev: &v1alpha1.Revision{
+			ObjectMeta: metav1.ObjectMeta{
+				Name: "blah",
+			},
+		},
+		f:    DestinationRule,
+		want: "blah-service",
 	}}
 
 	for _, test := range tests {
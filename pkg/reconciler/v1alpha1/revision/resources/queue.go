@@ -17,9 +17,12 @@ limitations under the License.
 package resources
 
 import (
+	"encoding/json"
 	"strconv"
+	"time"
 
 	"github.com/knative/pkg/logging"
+	"github.com/knative/serving/pkg/apis/serving"
 	"github.com/knative/serving/pkg/apis/serving/v1alpha1"
 	"github.com/knative/serving/pkg/autoscaler"
 	"github.com/knative/serving/pkg/queue"
@@ -44,7 +47,7 @@ var (
 		ContainerPort: int32(queue.RequestQueueAdminPort),
 	}}
 	// This handler (1) marks the service as not ready and (2)
-	// adds a small delay before the container is killed.
+	// waits for traffic to go away before the container is killed.
 	queueLifecycle = &corev1.Lifecycle{
 		PreStop: &corev1.Handler{
 			HTTPGet: &corev1.HTTPGetAction{
@@ -63,14 +66,74 @@ var (
 		// We want to mark the service as not ready as soon as the
 		// PreStop handler is called, so we need to check a little
 		// bit more often than the default.  It is a small
-		// sacrifice for a low rate of 503s.
+		// sacrifice for a low rate of 503s. This also bounds how
+		// long it takes for the Pod to become ready once the
+		// queue-proxy has seen the user container ready.
 		PeriodSeconds: 1,
 	}
 )
 
+// The annotations read by the funcs below are validated by the webhook.
+// Values it would reject fall back to the defaults.
+
+// drainTimeout returns for how long at most the queue-proxy of rev waits
+// for traffic to go away when its Pod is terminated.
+func drainTimeout(rev *v1alpha1.Revision) time.Duration {
+	if v, ok := rev.Annotations[serving.DrainTimeoutAnnotationKey]; ok {
+		if d, err := time.ParseDuration(v); err == nil && d >= 0 {
+			return d
+		}
+	}
+	return queue.DefaultDrainTimeout
+}
+
+// drainSettlePeriod returns for how long no request must have arrived for
+// the drain of a Pod of rev to end before its timeout.
+func drainSettlePeriod(rev *v1alpha1.Revision) time.Duration {
+	if v, ok := rev.Annotations[serving.DrainSettlePeriodAnnotationKey]; ok {
+		if d, err := time.ParseDuration(v); err == nil && d >= 0 {
+			return d
+		}
+	}
+	return queue.DefaultDrainSettlePeriod
+}
+
+// maxUpgradedConnections returns how many upgraded connections a single
+// queue-proxy of rev admits at the same time, or 0 if there is no limit.
+func maxUpgradedConnections(rev *v1alpha1.Revision) int {
+	if v, ok := rev.Annotations[serving.MaxUpgradedConnectionsAnnotationKey]; ok {
+		if i, err := strconv.Atoi(v); err == nil && i >= 0 {
+			return i
+		}
+	}
+	return 0
+}
+
+// upgradedConnectionWeight returns how much an upgraded connection to rev
+// counts towards the concurrency reported to the autoscaler.
+func upgradedConnectionWeight(rev *v1alpha1.Revision) float64 {
+	if v, ok := rev.Annotations[serving.UpgradedConnectionWeightAnnotationKey]; ok {
+		if f, err := strconv.ParseFloat(v, 64); err == nil && f >= 0 && f <= 1 {
+			return f
+		}
+	}
+	return queue.DefaultUpgradedConnectionWeight
+}
+
+// userReadinessProbe returns the JSON encoded readiness probe of the user
+// container of rev, or "" if it has none.
+func userReadinessProbe(rev *v1alpha1.Revision) string {
+	if rev.Spec.Container.ReadinessProbe == nil {
+		return ""
+	}
+	// A corev1.Probe always encodes.
+	b, _ := json.Marshal(rev.Spec.Container.ReadinessProbe)
+	return string(b)
+}
+
 // makeQueueContainer creates the container spec for queue sidecar.
-func makeQueueContainer(rev *v1alpha1.Revision, loggingConfig *logging.Config, autoscalerConfig *autoscaler.Config,
-	controllerConfig *config.Controller) *corev1.Container {
+func makeQueueContainer(rev *v1alpha1.Revision, loggingConfig *logging.Config, observabilityConfig *config.Observability,
+	autoscalerConfig *autoscaler.Config, controllerConfig *config.Controller) *corev1.Container {
 	configName := ""
 	if owner := metav1.GetControllerOf(rev); owner != nil && owner.Kind == "Configuration" {
 		configName = owner.Name
@@ -83,7 +146,7 @@ func makeQueueContainer(rev *v1alpha1.Revision, loggingConfig *logging.Config, a
 		loggingLevel = ll.String()
 	}
 
-	return &corev1.Container{
+	container := &corev1.Container{
 		Name:           queueContainerName,
 		Image:          controllerConfig.QueueSidecarImage,
 		Resources:      queueResources,
@@ -111,6 +174,24 @@ func makeQueueContainer(rev *v1alpha1.Revision, loggingConfig *logging.Config, a
 		}, {
 			Name:  "REVISION_TIMEOUT_SECONDS",
 			Value: strconv.Itoa(int(rev.Spec.TimeoutSeconds.Duration.Seconds())),
+		}, {
+			Name:  "SERVING_DRAIN_TIMEOUT",
+			Value: drainTimeout(rev).String(),
+		}, {
+			Name:  "SERVING_DRAIN_SETTLE_PERIOD",
+			Value: drainSettlePeriod(rev).String(),
+		}, {
+			Name:  "SERVING_MAX_UPGRADED_CONNECTIONS",
+			Value: strconv.Itoa(maxUpgradedConnections(rev)),
+		}, {
+			Name:  "SERVING_UPGRADED_CONNECTION_WEIGHT",
+			Value: strconv.FormatFloat(upgradedConnectionWeight(rev), 'f', -1, 64),
+		}, {
+			Name:  "USER_PORT",
+			Value: strconv.Itoa(getUserPort(rev)),
+		}, {
+			Name:  "SERVING_READINESS_PROBE",
+			Value: userReadinessProbe(rev),
 		}, {
 			Name: "SERVING_POD",
 			ValueFrom: &corev1.EnvVarSource{
@@ -124,6 +205,27 @@ func makeQueueContainer(rev *v1alpha1.Revision, loggingConfig *logging.Config, a
 		}, {
 			Name:  "SERVING_LOGGING_LEVEL",
 			Value: loggingLevel,
+		}, {
+			Name:  "SERVING_REQUEST_LOG_TEMPLATE",
+			Value: observabilityConfig.RequestLogTemplate,
 		}},
 	}
+
+	// The queue-proxy leaves tracing disabled when these are not set.
+	if tracingConfig := observabilityConfig.Tracing; tracingConfig.Enable {
+		container.Env = append(container.Env, corev1.EnvVar{
+			Name:  "TRACING_CONFIG_ENABLE",
+			Value: strconv.FormatBool(tracingConfig.Enable),
+		}, corev1.EnvVar{
+			Name:  "TRACING_CONFIG_ZIPKIN_ENDPOINT",
+			Value: tracingConfig.ZipkinEndpoint,
+		}, corev1.EnvVar{
+			Name:  "TRACING_CONFIG_DEBUG",
+			Value: strconv.FormatBool(tracingConfig.Debug),
+		}, corev1.EnvVar{
+			Name:  "TRACING_CONFIG_SAMPLE_RATE",
+			Value: strconv.FormatFloat(tracingConfig.SampleRate, 'f', -1, 64),
+		})
+	}
+	return container
 }
@@ -23,9 +23,11 @@ import (
 	"github.com/google/go-cmp/cmp"
 	"github.com/google/go-cmp/cmp/cmpopts"
 	"github.com/knative/pkg/logging"
+	"github.com/knative/serving/pkg/apis/serving"
 	"github.com/knative/serving/pkg/apis/serving/v1alpha1"
 	"github.com/knative/serving/pkg/autoscaler"
 	"github.com/knative/serving/pkg/reconciler/v1alpha1/revision/config"
+	"github.com/knative/serving/pkg/tracing"
 	"go.uber.org/zap/zapcore"
 	corev1 "k8s.io/api/core/v1"
 	"k8s.io/apimachinery/pkg/api/resource"
@@ -39,6 +41,7 @@ func TestMakeQueueContainer(t *testing.T) {
 		name string
 		rev  *v1alpha1.Revision
 		lc   *logging.Config
+		oc   *config.Observability
 		ac   *autoscaler.Config
 		cc   *config.Controller
 		want *corev1.Container
@@ -58,6 +61,7 @@ func TestMakeQueueContainer(t *testing.T) {
 			},
 		},
 		lc: &logging.Config{},
+		oc: &config.Observability{},
 		ac: &autoscaler.Config{},
 		cc: &config.Controller{},
 		want: &corev1.Container{
@@ -89,6 +93,24 @@ func TestMakeQueueContainer(t *testing.T) {
 			}, {
 				Name:  "REVISION_TIMEOUT_SECONDS",
 				Value: "45",
+			}, {
+				Name:  "SERVING_DRAIN_TIMEOUT",
+				Value: "20s",
+			}, {
+				Name:  "SERVING_DRAIN_SETTLE_PERIOD",
+				Value: "5s",
+			}, {
+				Name:  "SERVING_MAX_UPGRADED_CONNECTIONS",
+				Value: "0",
+			}, {
+				Name:  "SERVING_UPGRADED_CONNECTION_WEIGHT",
+				Value: "1",
+			}, {
+				Name:  "USER_PORT",
+				Value: "8080",
+			}, {
+				Name: "SERVING_READINESS_PROBE",
+				// No readiness probe
 			}, {
 				Name: "SERVING_POD",
 				ValueFrom: &corev1.EnvVarSource{
@@ -100,6 +122,9 @@ func TestMakeQueueContainer(t *testing.T) {
 			}, {
 				Name: "SERVING_LOGGING_LEVEL",
 				// No logging level
+			}, {
+				Name: "SERVING_REQUEST_LOG_TEMPLATE",
+				// No request log template
 			}},
 		},
 	}, {
@@ -118,6 +143,7 @@ func TestMakeQueueContainer(t *testing.T) {
 			},
 		},
 		lc: &logging.Config{},
+		oc: &config.Observability{},
 		ac: &autoscaler.Config{},
 		cc: &config.Controller{
 			QueueSidecarImage: "alpine",
@@ -152,6 +178,24 @@ func TestMakeQueueContainer(t *testing.T) {
 			}, {
 				Name:  "REVISION_TIMEOUT_SECONDS",
 				Value: "45",
+			}, {
+				Name:  "SERVING_DRAIN_TIMEOUT",
+				Value: "20s",
+			}, {
+				Name:  "SERVING_DRAIN_SETTLE_PERIOD",
+				Value: "5s",
+			}, {
+				Name:  "SERVING_MAX_UPGRADED_CONNECTIONS",
+				Value: "0",
+			}, {
+				Name:  "SERVING_UPGRADED_CONNECTION_WEIGHT",
+				Value: "1",
+			}, {
+				Name:  "USER_PORT",
+				Value: "8080",
+			}, {
+				Name: "SERVING_READINESS_PROBE",
+				// No readiness probe
 			}, {
 				Name: "SERVING_POD",
 				ValueFrom: &corev1.EnvVarSource{
@@ -163,6 +207,9 @@ func TestMakeQueueContainer(t *testing.T) {
 			}, {
 				Name: "SERVING_LOGGING_LEVEL",
 				// No logging level
+			}, {
+				Name: "SERVING_REQUEST_LOG_TEMPLATE",
+				// No request log template
 			}},
 		},
 	}, {
@@ -188,6 +235,7 @@ func TestMakeQueueContainer(t *testing.T) {
 			},
 		},
 		lc: &logging.Config{},
+		oc: &config.Observability{},
 		ac: &autoscaler.Config{},
 		cc: &config.Controller{},
 		want: &corev1.Container{
@@ -219,6 +267,24 @@ func TestMakeQueueContainer(t *testing.T) {
 			}, {
 				Name:  "REVISION_TIMEOUT_SECONDS",
 				Value: "45",
+			}, {
+				Name:  "SERVING_DRAIN_TIMEOUT",
+				Value: "20s",
+			}, {
+				Name:  "SERVING_DRAIN_SETTLE_PERIOD",
+				Value: "5s",
+			}, {
+				Name:  "SERVING_MAX_UPGRADED_CONNECTIONS",
+				Value: "0",
+			}, {
+				Name:  "SERVING_UPGRADED_CONNECTION_WEIGHT",
+				Value: "1",
+			}, {
+				Name:  "USER_PORT",
+				Value: "8080",
+			}, {
+				Name: "SERVING_READINESS_PROBE",
+				// No readiness probe
 			}, {
 				Name: "SERVING_POD",
 				ValueFrom: &corev1.EnvVarSource{
@@ -230,6 +296,9 @@ func TestMakeQueueContainer(t *testing.T) {
 			}, {
 				Name: "SERVING_LOGGING_LEVEL",
 				// No logging level
+			}, {
+				Name: "SERVING_REQUEST_LOG_TEMPLATE",
+				// No request log template
 			}},
 		},
 	}, {
@@ -253,6 +322,7 @@ func TestMakeQueueContainer(t *testing.T) {
 				"queueproxy": zapcore.ErrorLevel,
 			},
 		},
+		oc: &config.Observability{},
 		ac: &autoscaler.Config{},
 		cc: &config.Controller{},
 		want: &corev1.Container{
@@ -284,6 +354,24 @@ func TestMakeQueueContainer(t *testing.T) {
 			}, {
 				Name:  "REVISION_TIMEOUT_SECONDS",
 				Value: "45",
+			}, {
+				Name:  "SERVING_DRAIN_TIMEOUT",
+				Value: "20s",
+			}, {
+				Name:  "SERVING_DRAIN_SETTLE_PERIOD",
+				Value: "5s",
+			}, {
+				Name:  "SERVING_MAX_UPGRADED_CONNECTIONS",
+				Value: "0",
+			}, {
+				Name:  "SERVING_UPGRADED_CONNECTION_WEIGHT",
+				Value: "1",
+			}, {
+				Name:  "USER_PORT",
+				Value: "8080",
+			}, {
+				Name: "SERVING_READINESS_PROBE",
+				// No readiness probe
 			}, {
 				Name: "SERVING_POD",
 				ValueFrom: &corev1.EnvVarSource{
@@ -295,6 +383,9 @@ func TestMakeQueueContainer(t *testing.T) {
 			}, {
 				Name:  "SERVING_LOGGING_LEVEL",
 				Value: "error", // from logging config
+			}, {
+				Name: "SERVING_REQUEST_LOG_TEMPLATE",
+				// No request log template
 			}},
 		},
 	}, {
@@ -313,6 +404,7 @@ func TestMakeQueueContainer(t *testing.T) {
 			},
 		},
 		lc: &logging.Config{},
+		oc: &config.Observability{},
 		ac: &autoscaler.Config{},
 		cc: &config.Controller{},
 		want: &corev1.Container{
@@ -344,6 +436,24 @@ func TestMakeQueueContainer(t *testing.T) {
 			}, {
 				Name:  "REVISION_TIMEOUT_SECONDS",
 				Value: "45",
+			}, {
+				Name:  "SERVING_DRAIN_TIMEOUT",
+				Value: "20s",
+			}, {
+				Name:  "SERVING_DRAIN_SETTLE_PERIOD",
+				Value: "5s",
+			}, {
+				Name:  "SERVING_MAX_UPGRADED_CONNECTIONS",
+				Value: "0",
+			}, {
+				Name:  "SERVING_UPGRADED_CONNECTION_WEIGHT",
+				Value: "1",
+			}, {
+				Name:  "USER_PORT",
+				Value: "8080",
+			}, {
+				Name: "SERVING_READINESS_PROBE",
+				// No readiness probe
 			}, {
 				Name: "SERVING_POD",
 				ValueFrom: &corev1.EnvVarSource{
@@ -355,13 +465,373 @@ func TestMakeQueueContainer(t *testing.T) {
 			}, {
 				Name: "SERVING_LOGGING_LEVEL",
 				// No logging level
+			}, {
+				Name: "SERVING_REQUEST_LOG_TEMPLATE",
+				// No request log template
+			}},
+		},
+	}, {
+		name: "request log as env var",
+		rev: &v1alpha1.Revision{
+			ObjectMeta: metav1.ObjectMeta{
+				Namespace: "foo",
+				Name:      "bar",
+				UID:       "1234",
+			},
+			Spec: v1alpha1.RevisionSpec{
+				ContainerConcurrency: 0,
+				TimeoutSeconds: &metav1.Duration{
+					Duration: 45 * time.Second,
+				},
+			},
+		},
+		lc: &logging.Config{},
+		oc: &config.Observability{
+			RequestLogTemplate: "test template",
+		},
+		ac: &autoscaler.Config{},
+		cc: &config.Controller{},
+		want: &corev1.Container{
+			// These are effectively constant
+			Name:           queueContainerName,
+			Resources:      queueResources,
+			Ports:          queuePorts,
+			Lifecycle:      queueLifecycle,
+			ReadinessProbe: queueReadinessProbe,
+			// These changed based on the Revision and configs passed in.
+			Env: []corev1.EnvVar{{
+				Name:  "SERVING_NAMESPACE",
+				Value: "foo", // matches namespace
+			}, {
+				Name: "SERVING_CONFIGURATION",
+				// No OwnerReference
+			}, {
+				Name:  "SERVING_REVISION",
+				Value: "bar", // matches name
+			}, {
+				Name:  "SERVING_AUTOSCALER",
+				Value: "autoscaler", // no autoscaler configured.
+			}, {
+				Name:  "SERVING_AUTOSCALER_PORT",
+				Value: "8080",
+			}, {
+				Name:  "CONTAINER_CONCURRENCY",
+				Value: "0",
+			}, {
+				Name:  "REVISION_TIMEOUT_SECONDS",
+				Value: "45",
+			}, {
+				Name:  "SERVING_DRAIN_TIMEOUT",
+				Value: "20s",
+			}, {
+				Name:  "SERVING_DRAIN_SETTLE_PERIOD",
+				Value: "5s",
+			}, {
+				Name:  "SERVING_MAX_UPGRADED_CONNECTIONS",
+				Value: "0",
+			}, {
+				Name:  "SERVING_UPGRADED_CONNECTION_WEIGHT",
+				Value: "1",
+			}, {
+				Name:  "USER_PORT",
+				Value: "8080",
+			}, {
+				Name: "SERVING_READINESS_PROBE",
+				// No readiness probe
+			}, {
+				Name: "SERVING_POD",
+				ValueFrom: &corev1.EnvVarSource{
+					FieldRef: &corev1.ObjectFieldSelector{FieldPath: "metadata.name"},
+				},
+			}, {
+				Name: "SERVING_LOGGING_CONFIG",
+				// No logging configuration
+			}, {
+				Name: "SERVING_LOGGING_LEVEL",
+				// No logging level
+			}, {
+				Name:  "SERVING_REQUEST_LOG_TEMPLATE",
+				Value: "test template", // from observability config
+			}},
+		},
+	}, {
+		name: "tracing config as env vars",
+		rev: &v1alpha1.Revision{
+			ObjectMeta: metav1.ObjectMeta{
+				Namespace: "foo",
+				Name:      "bar",
+				UID:       "1234",
+			},
+			Spec: v1alpha1.RevisionSpec{
+				ContainerConcurrency: 0,
+				TimeoutSeconds: &metav1.Duration{
+					Duration: 45 * time.Second,
+				},
+			},
+		},
+		lc: &logging.Config{},
+		oc: &config.Observability{
+			Tracing: tracing.Config{
+				Enable:         true,
+				ZipkinEndpoint: "http://zipkin.local:9411/api/v2/spans",
+				Debug:          true,
+				SampleRate:     0.5,
+			},
+		},
+		ac: &autoscaler.Config{},
+		cc: &config.Controller{},
+		want: &corev1.Container{
+			// These are effectively constant
+			Name:           queueContainerName,
+			Resources:      queueResources,
+			Ports:          queuePorts,
+			Lifecycle:      queueLifecycle,
+			ReadinessProbe: queueReadinessProbe,
+			// These changed based on the Revision and configs passed in.
+			Env: []corev1.EnvVar{{
+				Name:  "SERVING_NAMESPACE",
+				Value: "foo", // matches namespace
+			}, {
+				Name: "SERVING_CONFIGURATION",
+				// No OwnerReference
+			}, {
+				Name:  "SERVING_REVISION",
+				Value: "bar", // matches name
+			}, {
+				Name:  "SERVING_AUTOSCALER",
+				Value: "autoscaler", // no autoscaler configured.
+			}, {
+				Name:  "SERVING_AUTOSCALER_PORT",
+				Value: "8080",
+			}, {
+				Name:  "CONTAINER_CONCURRENCY",
+				Value: "0",
+			}, {
+				Name:  "REVISION_TIMEOUT_SECONDS",
+				Value: "45",
+			}, {
+				Name:  "SERVING_DRAIN_TIMEOUT",
+				Value: "20s",
+			}, {
+				Name:  "SERVING_DRAIN_SETTLE_PERIOD",
+				Value: "5s",
+			}, {
+				Name:  "SERVING_MAX_UPGRADED_CONNECTIONS",
+				Value: "0",
+			}, {
+				Name:  "SERVING_UPGRADED_CONNECTION_WEIGHT",
+				Value: "1",
+			}, {
+				Name:  "USER_PORT",
+				Value: "8080",
+			}, {
+				Name: "SERVING_READINESS_PROBE",
+				// No readiness probe
+			}, {
+				Name: "SERVING_POD",
+				ValueFrom: &corev1.EnvVarSource{
+					FieldRef: &corev1.ObjectFieldSelector{FieldPath: "metadata.name"},
+				},
+			}, {
+				Name: "SERVING_LOGGING_CONFIG",
+				// No logging configuration
+			}, {
+				Name: "SERVING_LOGGING_LEVEL",
+				// No logging level
+			}, {
+				Name: "SERVING_REQUEST_LOG_TEMPLATE",
+				// No request log template
+			}, {
+				Name:  "TRACING_CONFIG_ENABLE",
+				Value: "true", // from tracing config
+			}, {
+				Name:  "TRACING_CONFIG_ZIPKIN_ENDPOINT",
+				Value: "http://zipkin.local:9411/api/v2/spans", // from tracing config
+			}, {
+				Name:  "TRACING_CONFIG_DEBUG",
+				Value: "true", // from tracing config
+			}, {
+				Name:  "TRACING_CONFIG_SAMPLE_RATE",
+				Value: "0.5", // from tracing config
+			}},
+		},
+	}, {
+		name: "drain settings from annotations",
+		rev: &v1alpha1.Revision{
+			ObjectMeta: metav1.ObjectMeta{
+				Namespace: "foo",
+				Name:      "bar",
+				UID:       "1234",
+				Annotations: map[string]string{
+					serving.DrainTimeoutAnnotationKey:      "1m30s",
+					serving.DrainSettlePeriodAnnotationKey: "2s",
+				},
+			},
+			Spec: v1alpha1.RevisionSpec{
+				ContainerConcurrency: 0,
+				TimeoutSeconds: &metav1.Duration{
+					Duration: 45 * time.Second,
+				},
+			},
+		},
+		lc: &logging.Config{},
+		oc: &config.Observability{},
+		ac: &autoscaler.Config{},
+		cc: &config.Controller{},
+		want: &corev1.Container{
+			// These are effectively constant
+			Name:           queueContainerName,
+			Resources:      queueResources,
+			Ports:          queuePorts,
+			Lifecycle:      queueLifecycle,
+			ReadinessProbe: queueReadinessProbe,
+			// These changed based on the Revision and configs passed in.
+			Env: []corev1.EnvVar{{
+				Name:  "SERVING_NAMESPACE",
+				Value: "foo", // matches namespace
+			}, {
+				Name: "SERVING_CONFIGURATION",
+				// No OwnerReference
+			}, {
+				Name:  "SERVING_REVISION",
+				Value: "bar", // matches name
+			}, {
+				Name:  "SERVING_AUTOSCALER",
+				Value: "autoscaler", // no autoscaler configured.
+			}, {
+				Name:  "SERVING_AUTOSCALER_PORT",
+				Value: "8080",
+			}, {
+				Name:  "CONTAINER_CONCURRENCY",
+				Value: "0",
+			}, {
+				Name:  "REVISION_TIMEOUT_SECONDS",
+				Value: "45",
+			}, {
+				Name:  "SERVING_DRAIN_TIMEOUT",
+				Value: "1m30s", // from annotation
+			}, {
+				Name:  "SERVING_DRAIN_SETTLE_PERIOD",
+				Value: "2s", // from annotation
+			}, {
+				Name:  "SERVING_MAX_UPGRADED_CONNECTIONS",
+				Value: "0",
+			}, {
+				Name:  "SERVING_UPGRADED_CONNECTION_WEIGHT",
+				Value: "1",
+			}, {
+				Name:  "USER_PORT",
+				Value: "8080",
+			}, {
+				Name: "SERVING_READINESS_PROBE",
+				// No readiness probe
+			}, {
+				Name: "SERVING_POD",
+				ValueFrom: &corev1.EnvVarSource{
+					FieldRef: &corev1.ObjectFieldSelector{FieldPath: "metadata.name"},
+				},
+			}, {
+				Name: "SERVING_LOGGING_CONFIG",
+				// No logging configuration
+			}, {
+				Name: "SERVING_LOGGING_LEVEL",
+				// No logging level
+			}, {
+				Name: "SERVING_REQUEST_LOG_TEMPLATE",
+				// No request log template
+			}},
+		},
+	}, {
+		name: "upgraded connection policy from annotations",
+		rev: &v1alpha1.Revision{
+			ObjectMeta: metav1.ObjectMeta{
+				Namespace: "foo",
+				Name:      "bar",
+				UID:       "1234",
+				Annotations: map[string]string{
+					serving.MaxUpgradedConnectionsAnnotationKey:   "100",
+					serving.UpgradedConnectionWeightAnnotationKey: "0.25",
+				},
+			},
+			Spec: v1alpha1.RevisionSpec{
+				ContainerConcurrency: 0,
+				TimeoutSeconds: &metav1.Duration{
+					Duration: 45 * time.Second,
+				},
+			},
+		},
+		lc: &logging.Config{},
+		oc: &config.Observability{},
+		ac: &autoscaler.Config{},
+		cc: &config.Controller{},
+		want: &corev1.Container{
+			// These are effectively constant
+			Name:           queueContainerName,
+			Resources:      queueResources,
+			Ports:          queuePorts,
+			Lifecycle:      queueLifecycle,
+			ReadinessProbe: queueReadinessProbe,
+			// These changed based on the Revision and configs passed in.
+			Env: []corev1.EnvVar{{
+				Name:  "SERVING_NAMESPACE",
+				Value: "foo", // matches namespace
+			}, {
+				Name: "SERVING_CONFIGURATION",
+				// No OwnerReference
+			}, {
+				Name:  "SERVING_REVISION",
+				Value: "bar", // matches name
+			}, {
+				Name:  "SERVING_AUTOSCALER",
+				Value: "autoscaler", // no autoscaler configured.
+			}, {
+				Name:  "SERVING_AUTOSCALER_PORT",
+				Value: "8080",
+			}, {
+				Name:  "CONTAINER_CONCURRENCY",
+				Value: "0",
+			}, {
+				Name:  "REVISION_TIMEOUT_SECONDS",
+				Value: "45",
+			}, {
+				Name:  "SERVING_DRAIN_TIMEOUT",
+				Value: "20s", // default
+			}, {
+				Name:  "SERVING_DRAIN_SETTLE_PERIOD",
+				Value: "5s",
+			}, {
+				Name:  "SERVING_MAX_UPGRADED_CONNECTIONS",
+				Value: "100", // from annotation
+			}, {
+				Name:  "SERVING_UPGRADED_CONNECTION_WEIGHT",
+				Value: "0.25", // from annotation
+			}, {
+				Name:  "USER_PORT",
+				Value: "8080",
+			}, {
+				Name: "SERVING_READINESS_PROBE",
+				// No readiness probe
+			}, {
+				Name: "SERVING_POD",
+				ValueFrom: &corev1.EnvVarSource{
+					FieldRef: &corev1.ObjectFieldSelector{FieldPath: "metadata.name"},
+				},
+			}, {
+				Name: "SERVING_LOGGING_CONFIG",
+				// No logging configuration
+			}, {
+				Name: "SERVING_LOGGING_LEVEL",
+				// No logging level
+			}, {
+				Name: "SERVING_REQUEST_LOG_TEMPLATE",
+				// No request log template
 			}},
 		},
 	}}
 
 	for _, test := range tests {
 		t.Run(test.name, func(t *testing.T) {
-			got := makeQueueContainer(test.rev, test.lc, test.ac, test.cc)
+			got := makeQueueContainer(test.rev, test.lc, test.oc, test.ac, test.cc)
 			if diff := cmp.Diff(test.want, got, cmpopts.IgnoreUnexported(resource.Quantity{})); diff != "" {
 				t.Errorf("makeQueueContainer (-want, +got) = %v", diff)
 			}
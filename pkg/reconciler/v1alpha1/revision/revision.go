@@ -27,6 +27,8 @@ import (
 	cachinglisters "github.com/knative/caching/pkg/client/listers/caching/v1alpha1"
 	"github.com/knative/pkg/apis/duck"
 	duckv1alpha1 "github.com/knative/pkg/apis/duck/v1alpha1"
+	istioinformers "github.com/knative/pkg/client/informers/externalversions/istio/v1alpha3"
+	istiolisters "github.com/knative/pkg/client/listers/istio/v1alpha3"
 	"github.com/knative/pkg/configmap"
 	"github.com/knative/pkg/controller"
 	commonlogging "github.com/knative/pkg/logging"
@@ -85,13 +87,14 @@ type Reconciler struct {
 	*reconciler.Base
 
 	// lister indexes properties about Revision
-	revisionLister      listers.RevisionLister
-	podAutoscalerLister kpalisters.PodAutoscalerLister
-	imageLister         cachinglisters.ImageLister
-	deploymentLister    appsv1listers.DeploymentLister
-	serviceLister       corev1listers.ServiceLister
-	endpointsLister     corev1listers.EndpointsLister
-	configMapLister     corev1listers.ConfigMapLister
+	revisionLister        listers.RevisionLister
+	podAutoscalerLister   kpalisters.PodAutoscalerLister
+	imageLister           cachinglisters.ImageLister
+	deploymentLister      appsv1listers.DeploymentLister
+	serviceLister         corev1listers.ServiceLister
+	endpointsLister       corev1listers.EndpointsLister
+	configMapLister       corev1listers.ConfigMapLister
+	destinationRuleLister istiolisters.DestinationRuleLister
 
 	buildInformerFactory duck.InformerFactory
 
@@ -117,6 +120,7 @@ func NewController(
 	serviceInformer corev1informers.ServiceInformer,
 	endpointsInformer corev1informers.EndpointsInformer,
 	configMapInformer corev1informers.ConfigMapInformer,
+	destinationRuleInformer istioinformers.DestinationRuleInformer,
 	buildInformerFactory duck.InformerFactory,
 ) *controller.Impl {
 	transport := http.DefaultTransport
@@ -127,14 +131,15 @@ func NewController(
 	}
 
 	c := &Reconciler{
-		Base:                reconciler.NewBase(opt, controllerAgentName),
-		revisionLister:      revisionInformer.Lister(),
-		podAutoscalerLister: podAutoscalerInformer.Lister(),
-		imageLister:         imageInformer.Lister(),
-		deploymentLister:    deploymentInformer.Lister(),
-		serviceLister:       serviceInformer.Lister(),
-		endpointsLister:     endpointsInformer.Lister(),
-		configMapLister:     configMapInformer.Lister(),
+		Base:                  reconciler.NewBase(opt, controllerAgentName),
+		revisionLister:        revisionInformer.Lister(),
+		podAutoscalerLister:   podAutoscalerInformer.Lister(),
+		imageLister:           imageInformer.Lister(),
+		deploymentLister:      deploymentInformer.Lister(),
+		serviceLister:         serviceInformer.Lister(),
+		endpointsLister:       endpointsInformer.Lister(),
+		configMapLister:       configMapInformer.Lister(),
+		destinationRuleLister: destinationRuleInformer.Lister(),
 		resolver: &digestResolver{
 			client:    opt.KubeClientSet,
 			transport: transport,
@@ -174,6 +179,15 @@ func NewController(
 		},
 	})
 
+	destinationRuleInformer.Informer().AddEventHandler(cache.FilteringResourceEventHandler{
+		FilterFunc: controller.Filter(v1alpha1.SchemeGroupVersion.WithKind("Revision")),
+		Handler: cache.ResourceEventHandlerFuncs{
+			AddFunc:    impl.EnqueueControllerOf,
+			UpdateFunc: controller.PassNew(impl.EnqueueControllerOf),
+			DeleteFunc: impl.EnqueueControllerOf,
+		},
+	})
+
 	c.tracker = tracker.New(impl.EnqueueKey, opt.GetTrackerLease())
 
 	// We don't watch for changes to Image because we don't incorporate any of its
@@ -371,6 +385,10 @@ func (c *Reconciler) reconcile(ctx context.Context, rev *v1alpha1.Revision) erro
 		}, {
 			name: "user k8s service",
 			f:    c.reconcileService,
+		}, {
+			// Pins the requests with the same key to the same pod.
+			name: "destination rule",
+			f:    c.reconcileDestinationRule,
 		}, {
 			// Ensures our namespace has the configuration for the fluentd sidecar.
 			name: "fluentd configmap",
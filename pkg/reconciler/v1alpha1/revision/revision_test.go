@@ -34,6 +34,8 @@ import (
 	cachinginformers "github.com/knative/caching/pkg/client/informers/externalversions"
 	"github.com/knative/pkg/apis/duck"
 	duckv1alpha1 "github.com/knative/pkg/apis/duck/v1alpha1"
+	fakesharedclientset "github.com/knative/pkg/client/clientset/versioned/fake"
+	sharedinformers "github.com/knative/pkg/client/informers/externalversions"
 	"github.com/knative/pkg/configmap"
 	ctrl "github.com/knative/pkg/controller"
 	"github.com/knative/pkg/kmeta"
@@ -49,6 +51,7 @@ import (
 	"github.com/knative/serving/pkg/reconciler/v1alpha1/revision/resources"
 	resourcenames "github.com/knative/serving/pkg/reconciler/v1alpha1/revision/resources/names"
 	"github.com/knative/serving/pkg/system"
+	"github.com/knative/serving/pkg/tracing"
 	appsv1 "k8s.io/api/apps/v1"
 	corev1 "k8s.io/api/core/v1"
 	apierrs "k8s.io/apimachinery/pkg/api/errors"
@@ -137,12 +140,14 @@ func newTestControllerWithConfig(t *testing.T, controllerConfig *config.Controll
 	kubeClient = fakekubeclientset.NewSimpleClientset()
 	servingClient = fakeclientset.NewSimpleClientset()
 	cachingClient = fakecachingclientset.NewSimpleClientset()
+	sharedClient := fakesharedclientset.NewSimpleClientset()
 	dynamicClient = fakedynamic.NewSimpleDynamicClient(runtime.NewScheme())
 
 	configMapWatcher = &configmap.ManualWatcher{Namespace: system.Namespace}
 
 	opt := rclr.Options{
 		KubeClientSet:    kubeClient,
+		SharedClientSet:  sharedClient,
 		ServingClientSet: servingClient,
 		DynamicClientSet: dynamicClient,
 		CachingClientSet: cachingClient,
@@ -157,6 +162,7 @@ func newTestControllerWithConfig(t *testing.T, controllerConfig *config.Controll
 	kubeInformer = kubeinformers.NewSharedInformerFactory(kubeClient, opt.ResyncPeriod)
 	servingInformer = informers.NewSharedInformerFactory(servingClient, opt.ResyncPeriod)
 	cachingInformer = cachinginformers.NewSharedInformerFactory(cachingClient, opt.ResyncPeriod)
+	sharedInformer := sharedinformers.NewSharedInformerFactory(sharedClient, opt.ResyncPeriod)
 	buildInformerFactory = KResourceTypedInformerFactory(opt)
 
 	controller = NewController(
@@ -168,6 +174,7 @@ func newTestControllerWithConfig(t *testing.T, controllerConfig *config.Controll
 		kubeInformer.Core().V1().Services(),
 		kubeInformer.Core().V1().Endpoints(),
 		kubeInformer.Core().V1().ConfigMaps(),
+		sharedInformer.Networking().V1alpha3().DestinationRules(),
 		buildInformerFactory,
 	)
 
@@ -198,6 +205,11 @@ func newTestControllerWithConfig(t *testing.T, controllerConfig *config.Controll
 			"logging.fluentd-sidecar-image":         testFluentdImage,
 			"logging.fluentd-sidecar-output-config": testFluentdSidecarOutputConfig,
 		},
+	}, &corev1.ConfigMap{
+		ObjectMeta: metav1.ObjectMeta{
+			Namespace: system.Namespace,
+			Name:      tracing.ConfigName,
+		},
 	}, &corev1.ConfigMap{
 		ObjectMeta: metav1.ObjectMeta{
 			Namespace: system.Namespace,
@@ -22,10 +22,12 @@ import (
 
 	caching "github.com/knative/caching/pkg/apis/caching/v1alpha1"
 	"github.com/knative/pkg/apis/duck"
+	"github.com/knative/pkg/apis/istio/v1alpha3"
 	"github.com/knative/pkg/configmap"
 	"github.com/knative/pkg/controller"
 	"github.com/knative/pkg/logging"
 	autoscalingv1alpha1 "github.com/knative/serving/pkg/apis/autoscaling/v1alpha1"
+	"github.com/knative/serving/pkg/apis/serving"
 	"github.com/knative/serving/pkg/apis/serving/v1alpha1"
 	"github.com/knative/serving/pkg/autoscaler"
 	"github.com/knative/serving/pkg/reconciler"
@@ -190,6 +192,66 @@ func TestReconcile(t *testing.T) {
 		},
 		// No changes are made to any objects.
 		Key: "foo/stable-reconcile",
+	}, {
+		Name: "session affinity creates destination rule",
+		// Test that a Revision with a session affinity gets a DestinationRule
+		// that hashes the requests to its K8s Service.
+		Objects: []runtime.Object{
+			rev("foo", "create-destination-rule", WithSessionAffinity("header:X-User-Id"),
+				WithK8sServiceName, WithLogURL, AllUnknownConditions),
+			kpa("foo", "create-destination-rule"),
+			makeDeploy(rev("foo", "create-destination-rule", WithSessionAffinity("header:X-User-Id"))),
+			svc("foo", "create-destination-rule"),
+			image("foo", "create-destination-rule"),
+		},
+		WantCreates: []metav1.Object{
+			destinationRule("foo", "create-destination-rule", WithSessionAffinity("header:X-User-Id")),
+		},
+		Key: "foo/create-destination-rule",
+	}, {
+		Name: "changed session affinity updates destination rule",
+		Objects: []runtime.Object{
+			rev("foo", "update-destination-rule", WithSessionAffinity("cookie:session"),
+				WithK8sServiceName, WithLogURL, AllUnknownConditions),
+			kpa("foo", "update-destination-rule"),
+			makeDeploy(rev("foo", "update-destination-rule", WithSessionAffinity("cookie:session"))),
+			svc("foo", "update-destination-rule"),
+			image("foo", "update-destination-rule"),
+			destinationRule("foo", "update-destination-rule", WithSessionAffinity("sourceIP")),
+		},
+		WantUpdates: []clientgotesting.UpdateActionImpl{{
+			// Only the spec of the DestinationRule is reconciled.
+			Object: func() *v1alpha3.DestinationRule {
+				dr := destinationRule("foo", "update-destination-rule", WithSessionAffinity("sourceIP"))
+				dr.Spec = destinationRule("foo", "update-destination-rule", WithSessionAffinity("cookie:session")).Spec
+				return dr
+			}(),
+		}},
+		Key: "foo/update-destination-rule",
+	}, {
+		Name: "removed session affinity deletes destination rule",
+		Objects: []runtime.Object{
+			rev("foo", "delete-destination-rule",
+				WithK8sServiceName, WithLogURL, AllUnknownConditions),
+			kpa("foo", "delete-destination-rule"),
+			deploy("foo", "delete-destination-rule"),
+			svc("foo", "delete-destination-rule"),
+			image("foo", "delete-destination-rule"),
+			destinationRule("foo", "delete-destination-rule", WithSessionAffinity("sourceIP")),
+		},
+		WantDeletes: []clientgotesting.DeleteActionImpl{{
+			ActionImpl: clientgotesting.ActionImpl{
+				Namespace: "foo",
+				Verb:      "delete",
+				Resource: schema.GroupVersionResource{
+					Group:    "networking.istio.io",
+					Version:  "v1alpha3",
+					Resource: "destinationrules",
+				},
+			},
+			Name: "delete-destination-rule-service",
+		}},
+		Key: "foo/delete-destination-rule",
 	}, {
 		Name: "update deployment containers",
 		// Test that we update a deployment with new containers when they disagree
@@ -571,17 +633,18 @@ func TestReconcile(t *testing.T) {
 		t := &rtesting.NullTracker{}
 		buildInformerFactory := KResourceTypedInformerFactory(opt)
 		return &Reconciler{
-			Base:                reconciler.NewBase(opt, controllerAgentName),
-			revisionLister:      listers.GetRevisionLister(),
-			podAutoscalerLister: listers.GetPodAutoscalerLister(),
-			imageLister:         listers.GetImageLister(),
-			deploymentLister:    listers.GetDeploymentLister(),
-			serviceLister:       listers.GetK8sServiceLister(),
-			endpointsLister:     listers.GetEndpointsLister(),
-			configMapLister:     listers.GetConfigMapLister(),
-			resolver:            &nopResolver{},
-			tracker:             t,
-			configStore:         &testConfigStore{config: ReconcilerTestConfig()},
+			Base:                  reconciler.NewBase(opt, controllerAgentName),
+			revisionLister:        listers.GetRevisionLister(),
+			podAutoscalerLister:   listers.GetPodAutoscalerLister(),
+			imageLister:           listers.GetImageLister(),
+			deploymentLister:      listers.GetDeploymentLister(),
+			serviceLister:         listers.GetK8sServiceLister(),
+			endpointsLister:       listers.GetEndpointsLister(),
+			configMapLister:       listers.GetConfigMapLister(),
+			destinationRuleLister: listers.GetDestinationRuleLister(),
+			resolver:              &nopResolver{},
+			tracker:               t,
+			configStore:           &testConfigStore{config: ReconcilerTestConfig()},
 
 			buildInformerFactory: newDuckInformerFactory(t, buildInformerFactory),
 		}
@@ -708,17 +771,18 @@ func TestReconcileWithVarLogEnabled(t *testing.T) {
 
 	table.Test(t, MakeFactory(func(listers *Listers, opt reconciler.Options) controller.Reconciler {
 		return &Reconciler{
-			Base:                reconciler.NewBase(opt, controllerAgentName),
-			revisionLister:      listers.GetRevisionLister(),
-			podAutoscalerLister: listers.GetPodAutoscalerLister(),
-			imageLister:         listers.GetImageLister(),
-			deploymentLister:    listers.GetDeploymentLister(),
-			serviceLister:       listers.GetK8sServiceLister(),
-			endpointsLister:     listers.GetEndpointsLister(),
-			configMapLister:     listers.GetConfigMapLister(),
-			resolver:            &nopResolver{},
-			tracker:             &rtesting.NullTracker{},
-			configStore:         &testConfigStore{config: config},
+			Base:                  reconciler.NewBase(opt, controllerAgentName),
+			revisionLister:        listers.GetRevisionLister(),
+			podAutoscalerLister:   listers.GetPodAutoscalerLister(),
+			imageLister:           listers.GetImageLister(),
+			deploymentLister:      listers.GetDeploymentLister(),
+			serviceLister:         listers.GetK8sServiceLister(),
+			endpointsLister:       listers.GetEndpointsLister(),
+			configMapLister:       listers.GetConfigMapLister(),
+			destinationRuleLister: listers.GetDestinationRuleLister(),
+			resolver:              &nopResolver{},
+			tracker:               &rtesting.NullTracker{},
+			configStore:           &testConfigStore{config: config},
 		}
 	}))
 }
@@ -782,6 +846,16 @@ func WithK8sServiceName(r *v1alpha1.Revision) {
 	r.Status.ServiceName = svc(r.Namespace, r.Name).Name
 }
 
+// WithSessionAffinity sets the session affinity annotation of the Revision.
+func WithSessionAffinity(affinity string) RevisionOption {
+	return func(r *v1alpha1.Revision) {
+		if r.Annotations == nil {
+			r.Annotations = make(map[string]string)
+		}
+		r.Annotations[serving.SessionAffinityAnnotationKey] = affinity
+	}
+}
+
 // TODO(mattmoor): Come up with a better name for this.
 func AllUnknownConditions(r *v1alpha1.Revision) {
 	WithInitRevConditions(r)
@@ -793,12 +867,15 @@ func AllUnknownConditions(r *v1alpha1.Revision) {
 type configOption func(*config.Config)
 
 func deploy(namespace, name string, co ...configOption) *appsv1.Deployment {
+	return makeDeploy(rev(namespace, name), co...)
+}
+
+func makeDeploy(rev *v1alpha1.Revision, co ...configOption) *appsv1.Deployment {
 	config := ReconcilerTestConfig()
 	for _, opt := range co {
 		opt(config)
 	}
 
-	rev := rev(namespace, name)
 	// Do this here instead of in `rev` itself to ensure that we populate defaults
 	// before calling MakeDeployment within Reconcile.
 	rev.SetDefaults()
@@ -854,6 +931,15 @@ func svc(namespace, name string, so ...K8sServiceOption) *corev1.Service {
 	return s
 }
 
+func destinationRule(namespace, name string, ro ...RevisionOption) *v1alpha3.DestinationRule {
+	rev := rev(namespace, name, ro...)
+	sa, err := rev.GetSessionAffinity()
+	if err != nil {
+		panic(err.Error())
+	}
+	return resources.MakeDestinationRule(rev, sa)
+}
+
 func endpoints(namespace, name string, eo ...EndpointsOption) *corev1.Endpoints {
 	service := svc(namespace, name)
 	ep := &corev1.Endpoints{
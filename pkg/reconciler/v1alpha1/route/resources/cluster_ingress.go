@@ -50,16 +50,16 @@ func MakeClusterIngress(r *servingv1alpha1.Route, tc *traffic.TrafficConfig) *v1
 			OwnerReferences: []metav1.OwnerReference{*kmeta.NewControllerRef(r)},
 			Annotations:     r.ObjectMeta.Annotations,
 		},
-		Spec: makeClusterIngressSpec(r, tc.Targets),
+		Spec: makeClusterIngressSpec(r, tc),
 	}
 }
 
-func makeClusterIngressSpec(r *servingv1alpha1.Route, targets map[string][]traffic.RevisionTarget) v1alpha1.IngressSpec {
+func makeClusterIngressSpec(r *servingv1alpha1.Route, tc *traffic.TrafficConfig) v1alpha1.IngressSpec {
 	// Domain should have been specified in route status
 	// before calling this func.
 	domain := r.Status.Domain
 	names := []string{}
-	for name := range targets {
+	for name := range tc.Targets {
 		names = append(names, name)
 	}
 	// Sort the names to give things a deterministic ordering.
@@ -67,7 +67,18 @@ func makeClusterIngressSpec(r *servingv1alpha1.Route, targets map[string][]traff
 	// The routes are matching rule based on domain name to traffic split targets.
 	rules := []v1alpha1.ClusterIngressRule{}
 	for _, name := range names {
-		rules = append(rules, *makeClusterIngressRule(getRouteDomains(name, r, domain), r.Namespace, targets[name]))
+		rule := makeClusterIngressRule(getRouteDomains(name, r, domain), r.Namespace, tc.Targets[name])
+		if name == "" {
+			// Header matches take precedence over the path-scoped splits,
+			// which take precedence over the catch-all splits, so their
+			// paths come first.
+			paths := makeHeaderPaths(r.Namespace, tc.Matches)
+			paths = append(paths, makeScopedPaths(r.Namespace, tc.Paths)...)
+			rule.HTTP.Paths = append(paths, rule.HTTP.Paths...)
+		}
+		applyTimeoutAndRetries(&r.Spec, rule.HTTP.Paths)
+		applyMirror(r.Namespace, tc.Mirror, rule.HTTP.Paths)
+		rules = append(rules, *rule)
 	}
 	return v1alpha1.IngressSpec{
 		Rules: rules,
@@ -87,7 +98,7 @@ func getRouteDomains(targetName string, r *servingv1alpha1.Route, domain string)
 		return dedup(domains)
 	}
 
-	return []string{fmt.Sprintf("%s.%s", targetName, domain)}
+	return []string{names.TargetDomain(targetName, domain)}
 }
 
 // groupTargets group given targets into active ones and inactive ones.
@@ -103,10 +114,46 @@ func groupTargets(targets []traffic.RevisionTarget) (active []traffic.RevisionTa
 }
 
 func makeClusterIngressRule(domains []string, ns string, targets []traffic.RevisionTarget) *v1alpha1.ClusterIngressRule {
+	return &v1alpha1.ClusterIngressRule{
+		Hosts: domains,
+		HTTP: &v1alpha1.HTTPClusterIngressRuleValue{
+			Paths: []v1alpha1.HTTPClusterIngressPath{
+				*makeClusterIngressPath(ns, targets),
+			},
+		},
+	}
+}
+
+// makeHeaderPaths constructs one IngressPath per header-matched target,
+// sending all of the matching requests to the target's Revision.
+func makeHeaderPaths(ns string, matches []traffic.RevisionTarget) []v1alpha1.HTTPClusterIngressPath {
+	paths := []v1alpha1.HTTPClusterIngressPath{}
+	for _, t := range matches {
+		t.SetBasisPoints(100 * 100)
+		path := makeClusterIngressPath(ns, []traffic.RevisionTarget{t})
+		path.Headers = t.Headers
+		paths = append(paths, *path)
+	}
+	return paths
+}
+
+// makeScopedPaths constructs one IngressPath per path-scoped traffic block,
+// splitting the matching requests over its targets.
+func makeScopedPaths(ns string, scoped []traffic.PathTargets) []v1alpha1.HTTPClusterIngressPath {
+	paths := []v1alpha1.HTTPClusterIngressPath{}
+	for _, pt := range scoped {
+		path := makeClusterIngressPath(ns, pt.Targets)
+		path.Path = pt.Path
+		paths = append(paths, *path)
+	}
+	return paths
+}
+
+func makeClusterIngressPath(ns string, targets []traffic.RevisionTarget) *v1alpha1.HTTPClusterIngressPath {
 	active, inactive := groupTargets(targets)
 	splits := []v1alpha1.ClusterIngressBackendSplit{}
 	for _, t := range active {
-		if t.Percent == 0 {
+		if t.GetBasisPoints() == 0 {
 			// Don't include 0% routes.
 			continue
 		}
@@ -116,47 +163,98 @@ func makeClusterIngressRule(domains []string, ns string, targets []traffic.Revis
 				ServiceName:      reconciler.GetServingK8SServiceNameForObj(t.TrafficTarget.RevisionName),
 				ServicePort:      intstr.FromInt(int(revisionresources.ServicePort)),
 			},
-			Percent: t.Percent,
+			Percent:     t.Percent,
+			BasisPoints: t.BasisPoints,
 		})
 	}
 	path := v1alpha1.HTTPClusterIngressPath{
 		Splits: splits,
-		// TODO(lichuqiang): #2201, plumbing to config timeout and retries.
-
 	}
 	path.SetDefaults()
-	return &v1alpha1.ClusterIngressRule{
-		Hosts: domains,
-		HTTP: &v1alpha1.HTTPClusterIngressRuleValue{
-			Paths: []v1alpha1.HTTPClusterIngressPath{
-				*addInactive(&path, ns, inactive),
-			},
-		},
+	return addInactive(&path, ns, inactive)
+}
+
+// applyTimeoutAndRetries overrides the default timeout and retry policy of the
+// given IngressPaths with the ones of the Route, if specified.
+func applyTimeoutAndRetries(rs *servingv1alpha1.RouteSpec, paths []v1alpha1.HTTPClusterIngressPath) {
+	for i := range paths {
+		p := &paths[i]
+		if rs.Timeout != nil {
+			p.Timeout = rs.Timeout.DeepCopy()
+			// An attempt can't outlive its request.
+			p.Retries.PerTryTimeout = rs.Timeout.DeepCopy()
+		}
+		if rs.Retries != nil {
+			p.Retries.Attempts = rs.Retries.Attempts
+			if rs.Retries.PerTryTimeout != nil {
+				p.Retries.PerTryTimeout = rs.Retries.PerTryTimeout.DeepCopy()
+			}
+			p.Retries.RetryOn = rs.Retries.RetryOn
+		}
+	}
+}
+
+// applyMirror mirrors the requests of the given IngressPaths to the mirror
+// target, if any.  Requests to an inactive target are mirrored to the
+// activator, so that the target gets activated.
+func applyMirror(ns string, mirror *traffic.RevisionTarget, paths []v1alpha1.HTTPClusterIngressPath) {
+	if mirror == nil {
+		return
+	}
+	for i := range paths {
+		p := &paths[i]
+		backend := v1alpha1.ClusterIngressBackend{
+			ServiceNamespace: ns,
+			ServiceName:      reconciler.GetServingK8SServiceNameForObj(mirror.RevisionName),
+			ServicePort:      intstr.FromInt(int(revisionresources.ServicePort)),
+		}
+		if !mirror.Active {
+			if len(p.AppendHeaders) > 0 {
+				// The appended headers of the inactive splits would apply
+				// to the mirrored requests too, and have the activator
+				// send them to the wrong Revision, so don't mirror them.
+				continue
+			}
+			backend = v1alpha1.ClusterIngressBackend{
+				ServiceNamespace: system.Namespace,
+				ServiceName:      activator.K8sServiceName,
+				ServicePort:      intstr.FromInt(int(revisionresources.ServicePort)),
+			}
+			p.AppendHeaders = map[string]string{
+				activator.RevisionHeaderName:      mirror.RevisionName,
+				activator.RevisionHeaderNamespace: ns,
+			}
+		}
+		p.Mirror = &v1alpha1.ClusterIngressBackendMirror{
+			ClusterIngressBackend: backend,
+			Percent:               mirror.Percent,
+		}
 	}
 }
 
 // addInactive constructs Splits for the inactive targets, and add into given IngressPath.
 func addInactive(r *v1alpha1.HTTPClusterIngressPath, ns string, inactive []traffic.RevisionTarget) *v1alpha1.HTTPClusterIngressPath {
-	totalInactivePercent := 0
+	totalInactiveBasisPoints := 0
 	maxInactiveTarget := traffic.RevisionTarget{}
 	for _, t := range inactive {
-		totalInactivePercent += t.Percent
-		if t.Percent >= maxInactiveTarget.Percent {
+		totalInactiveBasisPoints += t.GetBasisPoints()
+		if t.GetBasisPoints() >= maxInactiveTarget.GetBasisPoints() {
 			maxInactiveTarget = t
 		}
 	}
-	if totalInactivePercent == 0 {
+	if totalInactiveBasisPoints == 0 {
 		// There is actually no inactive Revisions.
 		return r
 	}
-	r.Splits = append(r.Splits, v1alpha1.ClusterIngressBackendSplit{
+	split := v1alpha1.ClusterIngressBackendSplit{
 		ClusterIngressBackend: v1alpha1.ClusterIngressBackend{
 			ServiceNamespace: system.Namespace,
 			ServiceName:      activator.K8sServiceName,
 			ServicePort:      intstr.FromInt(int(revisionresources.ServicePort)),
 		},
-		Percent: totalInactivePercent,
-	})
+	}
+	split.SetBasisPoints(totalInactiveBasisPoints)
+	r.Splits = append(r.Splits, split)
 	r.AppendHeaders = map[string]string{
 		activator.RevisionHeaderName:      maxInactiveTarget.RevisionName,
 		activator.RevisionHeaderNamespace: ns,
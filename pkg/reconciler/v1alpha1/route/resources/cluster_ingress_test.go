@@ -19,6 +19,7 @@ package resources
 import (
 	"fmt"
 	"testing"
+	"time"
 
 	"github.com/google/go-cmp/cmp"
 	"github.com/knative/pkg/kmeta"
@@ -133,7 +134,7 @@ func TestMakeClusterIngressSpec_CorrectRules(t *testing.T) {
 			}},
 		},
 	}}
-	rules := makeClusterIngressSpec(r, targets).Rules
+	rules := makeClusterIngressSpec(r, &traffic.TrafficConfig{Targets: targets}).Rules
 	if diff := cmp.Diff(expected, rules); diff != "" {
 		fmt.Printf("%+v\n", rules)
 		fmt.Printf("%+v\n", expected)
@@ -141,6 +142,368 @@ func TestMakeClusterIngressSpec_CorrectRules(t *testing.T) {
 	}
 }
 
+func TestMakeClusterIngressSpec_HeaderMatches(t *testing.T) {
+	canary := map[string]netv1alpha1.HeaderMatch{
+		"X-Canary": {Exact: "true"},
+	}
+	group := map[string]netv1alpha1.HeaderMatch{
+		"X-Group": {Prefix: "beta"},
+	}
+	targets := map[string][]traffic.RevisionTarget{
+		"": {{
+			TrafficTarget: v1alpha1.TrafficTarget{
+				ConfigurationName: "config",
+				RevisionName:      "v1",
+				Percent:           100,
+			},
+			Active: true,
+		}},
+	}
+	matches := []traffic.RevisionTarget{{
+		TrafficTarget: v1alpha1.TrafficTarget{
+			ConfigurationName: "config",
+			RevisionName:      "v2",
+			Headers:           canary,
+		},
+		Active: true,
+	}, {
+		TrafficTarget: v1alpha1.TrafficTarget{
+			ConfigurationName: "config",
+			RevisionName:      "v3",
+			Headers:           group,
+		},
+		Active: false,
+	}}
+	r := &v1alpha1.Route{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "test-route",
+			Namespace: "test-ns",
+		},
+		Status: v1alpha1.RouteStatus{Domain: "domain.com"},
+	}
+	expected := []netv1alpha1.ClusterIngressRule{{
+		Hosts: []string{
+			"domain.com",
+			"test-route.test-ns.svc.cluster.local",
+			"test-route.test-ns.svc",
+			"test-route.test-ns",
+		},
+		HTTP: &netv1alpha1.HTTPClusterIngressRuleValue{
+			Paths: []netv1alpha1.HTTPClusterIngressPath{{
+				Headers: canary,
+				Splits: []netv1alpha1.ClusterIngressBackendSplit{{
+					ClusterIngressBackend: netv1alpha1.ClusterIngressBackend{
+						ServiceNamespace: "test-ns",
+						ServiceName:      "v2-service",
+						ServicePort:      intstr.FromInt(80),
+					},
+					Percent: 100,
+				}},
+				Timeout: &metav1.Duration{Duration: netv1alpha1.DefaultTimeout},
+				Retries: &netv1alpha1.HTTPRetry{
+					PerTryTimeout: &metav1.Duration{Duration: netv1alpha1.DefaultTimeout},
+					Attempts:      netv1alpha1.DefaultRetryCount,
+				},
+			}, {
+				Headers: group,
+				Splits: []netv1alpha1.ClusterIngressBackendSplit{{
+					ClusterIngressBackend: netv1alpha1.ClusterIngressBackend{
+						ServiceNamespace: "knative-serving",
+						ServiceName:      "activator-service",
+						ServicePort:      intstr.FromInt(80),
+					},
+					Percent: 100,
+				}},
+				Timeout: &metav1.Duration{Duration: netv1alpha1.DefaultTimeout},
+				Retries: &netv1alpha1.HTTPRetry{
+					PerTryTimeout: &metav1.Duration{Duration: netv1alpha1.DefaultTimeout},
+					Attempts:      netv1alpha1.DefaultRetryCount,
+				},
+				AppendHeaders: map[string]string{
+					"knative-serving-revision":  "v3",
+					"knative-serving-namespace": "test-ns",
+				},
+			}, {
+				Splits: []netv1alpha1.ClusterIngressBackendSplit{{
+					ClusterIngressBackend: netv1alpha1.ClusterIngressBackend{
+						ServiceNamespace: "test-ns",
+						ServiceName:      "v1-service",
+						ServicePort:      intstr.FromInt(80),
+					},
+					Percent: 100,
+				}},
+				Timeout: &metav1.Duration{Duration: netv1alpha1.DefaultTimeout},
+				Retries: &netv1alpha1.HTTPRetry{
+					PerTryTimeout: &metav1.Duration{Duration: netv1alpha1.DefaultTimeout},
+					Attempts:      netv1alpha1.DefaultRetryCount,
+				},
+			}},
+		},
+	}}
+	rules := makeClusterIngressSpec(r, &traffic.TrafficConfig{Targets: targets, Matches: matches}).Rules
+	if diff := cmp.Diff(expected, rules); diff != "" {
+		t.Errorf("Unexpected rules (-want +got): %v", diff)
+	}
+}
+
+func TestMakeClusterIngressSpec_Paths(t *testing.T) {
+	tc := &traffic.TrafficConfig{
+		Targets: map[string][]traffic.RevisionTarget{
+			"": {{
+				TrafficTarget: v1alpha1.TrafficTarget{
+					ConfigurationName: "web",
+					RevisionName:      "web-v1",
+					Percent:           100,
+				},
+				Active: true,
+			}},
+		},
+		Paths: []traffic.PathTargets{{
+			Path: "/api/.*",
+			Targets: []traffic.RevisionTarget{{
+				TrafficTarget: v1alpha1.TrafficTarget{
+					ConfigurationName: "api",
+					RevisionName:      "api-v1",
+					Percent:           100,
+				},
+				Active: true,
+			}},
+		}, {
+			Path: "/static/.*",
+			Targets: []traffic.RevisionTarget{{
+				TrafficTarget: v1alpha1.TrafficTarget{
+					ConfigurationName: "static",
+					RevisionName:      "static-v1",
+					Percent:           100,
+				},
+				Active: false,
+			}},
+		}},
+	}
+	r := &v1alpha1.Route{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "test-route",
+			Namespace: "test-ns",
+		},
+		Status: v1alpha1.RouteStatus{Domain: "domain.com"},
+	}
+	expected := []netv1alpha1.ClusterIngressRule{{
+		Hosts: []string{
+			"domain.com",
+			"test-route.test-ns.svc.cluster.local",
+			"test-route.test-ns.svc",
+			"test-route.test-ns",
+		},
+		HTTP: &netv1alpha1.HTTPClusterIngressRuleValue{
+			Paths: []netv1alpha1.HTTPClusterIngressPath{{
+				Path: "/api/.*",
+				Splits: []netv1alpha1.ClusterIngressBackendSplit{{
+					ClusterIngressBackend: netv1alpha1.ClusterIngressBackend{
+						ServiceNamespace: "test-ns",
+						ServiceName:      "api-v1-service",
+						ServicePort:      intstr.FromInt(80),
+					},
+					Percent: 100,
+				}},
+				Timeout: &metav1.Duration{Duration: netv1alpha1.DefaultTimeout},
+				Retries: &netv1alpha1.HTTPRetry{
+					PerTryTimeout: &metav1.Duration{Duration: netv1alpha1.DefaultTimeout},
+					Attempts:      netv1alpha1.DefaultRetryCount,
+				},
+			}, {
+				Path: "/static/.*",
+				Splits: []netv1alpha1.ClusterIngressBackendSplit{{
+					ClusterIngressBackend: netv1alpha1.ClusterIngressBackend{
+						ServiceNamespace: "knative-serving",
+						ServiceName:      "activator-service",
+						ServicePort:      intstr.FromInt(80),
+					},
+					Percent: 100,
+				}},
+				Timeout: &metav1.Duration{Duration: netv1alpha1.DefaultTimeout},
+				Retries: &netv1alpha1.HTTPRetry{
+					PerTryTimeout: &metav1.Duration{Duration: netv1alpha1.DefaultTimeout},
+					Attempts:      netv1alpha1.DefaultRetryCount,
+				},
+				AppendHeaders: map[string]string{
+					"knative-serving-revision":  "static-v1",
+					"knative-serving-namespace": "test-ns",
+				},
+			}, {
+				Splits: []netv1alpha1.ClusterIngressBackendSplit{{
+					ClusterIngressBackend: netv1alpha1.ClusterIngressBackend{
+						ServiceNamespace: "test-ns",
+						ServiceName:      "web-v1-service",
+						ServicePort:      intstr.FromInt(80),
+					},
+					Percent: 100,
+				}},
+				Timeout: &metav1.Duration{Duration: netv1alpha1.DefaultTimeout},
+				Retries: &netv1alpha1.HTTPRetry{
+					PerTryTimeout: &metav1.Duration{Duration: netv1alpha1.DefaultTimeout},
+					Attempts:      netv1alpha1.DefaultRetryCount,
+				},
+			}},
+		},
+	}}
+	rules := makeClusterIngressSpec(r, tc).Rules
+	if diff := cmp.Diff(expected, rules); diff != "" {
+		t.Errorf("Unexpected rules (-want +got): %v", diff)
+	}
+}
+
+func TestMakeClusterIngressSpec_TimeoutAndRetries(t *testing.T) {
+	targets := map[string][]traffic.RevisionTarget{
+		"": {{
+			TrafficTarget: v1alpha1.TrafficTarget{
+				ConfigurationName: "config",
+				RevisionName:      "v1",
+				Percent:           100,
+			},
+			Active: true,
+		}},
+	}
+	tests := []struct {
+		name    string
+		spec    v1alpha1.RouteSpec
+		timeout *metav1.Duration
+		retries *netv1alpha1.HTTPRetry
+	}{{
+		name:    "defaults",
+		timeout: &metav1.Duration{Duration: netv1alpha1.DefaultTimeout},
+		retries: &netv1alpha1.HTTPRetry{
+			PerTryTimeout: &metav1.Duration{Duration: netv1alpha1.DefaultTimeout},
+			Attempts:      netv1alpha1.DefaultRetryCount,
+		},
+	}, {
+		name: "timeout",
+		spec: v1alpha1.RouteSpec{
+			Timeout: &metav1.Duration{Duration: time.Minute},
+		},
+		timeout: &metav1.Duration{Duration: time.Minute},
+		retries: &netv1alpha1.HTTPRetry{
+			PerTryTimeout: &metav1.Duration{Duration: time.Minute},
+			Attempts:      netv1alpha1.DefaultRetryCount,
+		},
+	}, {
+		name: "timeout and retries",
+		spec: v1alpha1.RouteSpec{
+			Timeout: &metav1.Duration{Duration: time.Minute},
+			Retries: &v1alpha1.RetryPolicy{
+				Attempts:      1,
+				PerTryTimeout: &metav1.Duration{Duration: 10 * time.Second},
+				RetryOn:       []string{"5xx"},
+			},
+		},
+		timeout: &metav1.Duration{Duration: time.Minute},
+		retries: &netv1alpha1.HTTPRetry{
+			PerTryTimeout: &metav1.Duration{Duration: 10 * time.Second},
+			Attempts:      1,
+			RetryOn:       []string{"5xx"},
+		},
+	}}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			r := &v1alpha1.Route{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:      "test-route",
+					Namespace: "test-ns",
+				},
+				Spec:   test.spec,
+				Status: v1alpha1.RouteStatus{Domain: "domain.com"},
+			}
+			paths := makeClusterIngressSpec(r, &traffic.TrafficConfig{Targets: targets}).Rules[0].HTTP.Paths
+			if diff := cmp.Diff(test.timeout, paths[0].Timeout); diff != "" {
+				t.Errorf("Unexpected timeout (-want +got): %v", diff)
+			}
+			if diff := cmp.Diff(test.retries, paths[0].Retries); diff != "" {
+				t.Errorf("Unexpected retries (-want +got): %v", diff)
+			}
+		})
+	}
+}
+
+func TestApplyMirror(t *testing.T) {
+	activatorHeaders := map[string]string{
+		"knative-serving-revision":  "inactive",
+		"knative-serving-namespace": "test-ns",
+	}
+	tests := []struct {
+		name   string
+		mirror *traffic.RevisionTarget
+		path   netv1alpha1.HTTPClusterIngressPath
+		want   netv1alpha1.HTTPClusterIngressPath
+	}{{
+		name: "no mirror",
+	}, {
+		name: "active mirror",
+		mirror: &traffic.RevisionTarget{
+			TrafficTarget: v1alpha1.TrafficTarget{
+				RevisionName: "mirror",
+				Percent:      10,
+			},
+			Active: true,
+		},
+		want: netv1alpha1.HTTPClusterIngressPath{
+			Mirror: &netv1alpha1.ClusterIngressBackendMirror{
+				ClusterIngressBackend: netv1alpha1.ClusterIngressBackend{
+					ServiceNamespace: "test-ns",
+					ServiceName:      "mirror-service",
+					ServicePort:      intstr.FromInt(80),
+				},
+				Percent: 10,
+			},
+		},
+	}, {
+		name: "inactive mirror",
+		mirror: &traffic.RevisionTarget{
+			TrafficTarget: v1alpha1.TrafficTarget{
+				RevisionName: "mirror",
+				Percent:      100,
+			},
+			Active: false,
+		},
+		want: netv1alpha1.HTTPClusterIngressPath{
+			Mirror: &netv1alpha1.ClusterIngressBackendMirror{
+				ClusterIngressBackend: netv1alpha1.ClusterIngressBackend{
+					ServiceNamespace: "knative-serving",
+					ServiceName:      "activator-service",
+					ServicePort:      intstr.FromInt(80),
+				},
+				Percent: 100,
+			},
+			AppendHeaders: map[string]string{
+				"knative-serving-revision":  "mirror",
+				"knative-serving-namespace": "test-ns",
+			},
+		},
+	}, {
+		name: "inactive mirror and inactive splits",
+		mirror: &traffic.RevisionTarget{
+			TrafficTarget: v1alpha1.TrafficTarget{
+				RevisionName: "mirror",
+				Percent:      100,
+			},
+			Active: false,
+		},
+		path: netv1alpha1.HTTPClusterIngressPath{
+			AppendHeaders: activatorHeaders,
+		},
+		want: netv1alpha1.HTTPClusterIngressPath{
+			AppendHeaders: activatorHeaders,
+		},
+	}}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			paths := []netv1alpha1.HTTPClusterIngressPath{test.path}
+			applyMirror("test-ns", test.mirror, paths)
+			if diff := cmp.Diff(test.want, paths[0]); diff != "" {
+				t.Errorf("Unexpected path (-want +got): %v", diff)
+			}
+		})
+	}
+}
+
 func TestGetRouteDomains_NamelessTarget(t *testing.T) {
 	r := &v1alpha1.Route{
 		ObjectMeta: metav1.ObjectMeta{
@@ -458,3 +821,58 @@ func TestMakeClusterIngressRule_ZeroPercentTargetInactive(t *testing.T) {
 		t.Errorf("Unexpected rule (-want +got): %v", diff)
 	}
 }
+
+func TestMakeClusterIngressRule_BasisPointsInactiveTarget(t *testing.T) {
+	targets := []traffic.RevisionTarget{{
+		TrafficTarget: v1alpha1.TrafficTarget{
+			ConfigurationName: "config",
+			RevisionName:      "revision",
+			BasisPoints:       9990,
+		},
+		Active: true,
+	}, {
+		TrafficTarget: v1alpha1.TrafficTarget{
+			ConfigurationName: "new-config",
+			RevisionName:      "new-revision",
+			BasisPoints:       10,
+		},
+		Active: false,
+	}}
+	domains := []string{"a.com"}
+	ns := "test-ns"
+	rule := makeClusterIngressRule(domains, ns, targets)
+	expected := netv1alpha1.ClusterIngressRule{
+		Hosts: []string{"a.com"},
+		HTTP: &netv1alpha1.HTTPClusterIngressRuleValue{
+			Paths: []netv1alpha1.HTTPClusterIngressPath{{
+				Splits: []netv1alpha1.ClusterIngressBackendSplit{{
+					ClusterIngressBackend: netv1alpha1.ClusterIngressBackend{
+						ServiceNamespace: ns,
+						ServiceName:      "revision-service",
+						ServicePort:      intstr.FromInt(80),
+					},
+					BasisPoints: 9990,
+				}, {
+					ClusterIngressBackend: netv1alpha1.ClusterIngressBackend{
+						ServiceNamespace: "knative-serving",
+						ServiceName:      "activator-service",
+						ServicePort:      intstr.FromInt(80),
+					},
+					BasisPoints: 10,
+				}},
+				AppendHeaders: map[string]string{
+					"knative-serving-revision":  "new-revision",
+					"knative-serving-namespace": "test-ns",
+				},
+				Timeout: &metav1.Duration{Duration: netv1alpha1.DefaultTimeout},
+				Retries: &netv1alpha1.HTTPRetry{
+					PerTryTimeout: &metav1.Duration{Duration: netv1alpha1.DefaultTimeout},
+					Attempts:      netv1alpha1.DefaultRetryCount,
+				},
+			}},
+		},
+	}
+	if diff := cmp.Diff(&expected, rule); diff != "" {
+		t.Errorf("Unexpected rule (-want +got): %v", diff)
+	}
+}
@@ -36,3 +36,9 @@ func K8sServiceFullname(route *v1alpha1.Route) string {
 func ClusterIngressPrefix(route *v1alpha1.Route) string {
 	return fmt.Sprintf("%s-", route.Name)
 }
+
+// TargetDomain returns the dedicated domain of the named traffic target
+// of a Route served at the given domain.
+func TargetDomain(name, domain string) string {
+	return fmt.Sprintf("%s.%s", name, domain)
+}
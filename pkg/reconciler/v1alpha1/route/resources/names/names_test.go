@@ -71,3 +71,10 @@ func TestNamer(t *testing.T) {
 		})
 	}
 }
+
+func TestTargetDomain(t *testing.T) {
+	got := TargetDomain("candidate", "bar.default.example.com")
+	if want := "candidate.bar.default.example.com"; got != want {
+		t.Errorf("TargetDomain() = %v, wanted %v", got, want)
+	}
+}
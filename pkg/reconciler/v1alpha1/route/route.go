@@ -243,6 +243,7 @@ func (c *Reconciler) reconcile(ctx context.Context, r *v1alpha1.Route) error {
 	r.Status.Address = &duckv1alpha1.Addressable{
 		Hostname: resourcenames.K8sServiceFullname(r),
 	}
+	setTargetDomains(r)
 
 	logger.Info("Creating ClusterIngress.")
 	clusterIngress, err := c.reconcileClusterIngress(ctx, r, resources.MakeClusterIngress(r, traffic))
@@ -306,6 +307,7 @@ func (c *Reconciler) configureTraffic(ctx context.Context, r *v1alpha1.Route) (*
 
 	logger.Info("All referred targets are routable, marking AllTrafficAssigned with traffic information.")
 	r.Status.Traffic = t.GetRevisionTrafficTargets()
+	r.Status.Paths = t.GetPathTraffic()
 	r.Status.MarkTrafficAssigned()
 
 	return t, nil
@@ -335,6 +337,21 @@ func objectRef(a accessor, gvk schema.GroupVersionKind) corev1.ObjectReference {
 	}
 }
 
+// setTargetDomains makes each named traffic target in the Route's status
+// Addressable at its dedicated domain.
+func setTargetDomains(route *v1alpha1.Route) {
+	for i := range route.Status.Traffic {
+		tt := &route.Status.Traffic[i]
+		if tt.Name == "" {
+			continue
+		}
+		tt.Domain = resourcenames.TargetDomain(tt.Name, route.Status.Domain)
+		tt.Address = &duckv1alpha1.Addressable{
+			Hostname: tt.Domain,
+		}
+	}
+}
+
 func routeDomain(ctx context.Context, route *v1alpha1.Route) string {
 	domainConfig := config.FromContext(ctx).Domain
 	domain := domainConfig.LookupDomainForLabels(route.ObjectMeta.Labels)
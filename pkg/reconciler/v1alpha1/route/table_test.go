@@ -22,6 +22,7 @@ import (
 	"testing"
 	"time"
 
+	duckv1alpha1 "github.com/knative/pkg/apis/duck/v1alpha1"
 	"github.com/knative/pkg/configmap"
 	"github.com/knative/pkg/controller"
 	netv1alpha1 "github.com/knative/serving/pkg/apis/networking/v1alpha1"
@@ -1014,10 +1015,19 @@ func TestReconcile(t *testing.T) {
 						Name:         "gray",
 						RevisionName: "gray-00001",
 						Percent:      50,
+						// Named targets are Addressable at their own domain.
+						Domain: "gray.same-revision-targets.default.example.com",
+						Address: &duckv1alpha1.Addressable{
+							Hostname: "gray.same-revision-targets.default.example.com",
+						},
 					}, v1alpha1.TrafficTarget{
 						Name:         "also-gray",
 						RevisionName: "gray-00001",
 						Percent:      50,
+						Domain:       "also-gray.same-revision-targets.default.example.com",
+						Address: &duckv1alpha1.Addressable{
+							Hostname: "also-gray.same-revision-targets.default.example.com",
+						},
 					})),
 		}},
 		WantEvents: []string{
@@ -18,6 +18,7 @@ package traffic
 
 import (
 	"fmt"
+	"time"
 
 	"github.com/knative/serving/pkg/apis/serving/v1alpha1"
 	corev1 "k8s.io/api/core/v1"
@@ -108,6 +109,29 @@ func (e *unreadyRevisionError) IsFailure() bool {
 	return e.isFailure
 }
 
+type timeoutTooShortError struct {
+	name            string        // Name of the Revision that may outlive the Route's timeout.
+	revisionTimeout time.Duration // The timeoutSeconds of the Revision.
+	timeout         time.Duration // The timeout of the Route.
+}
+
+var _ TargetError = (*timeoutTooShortError)(nil)
+
+// Error implements error.
+func (e *timeoutTooShortError) Error() string {
+	return fmt.Sprintf("Revision %q timeout %v exceeds the Route timeout %v", e.name, e.revisionTimeout, e.timeout)
+}
+
+// MarkBadTrafficTarget implements TargetError.
+func (e *timeoutTooShortError) MarkBadTrafficTarget(rs *v1alpha1.RouteStatus) {
+	rs.MarkTimeoutTooShort(e.name, e.revisionTimeout, e.timeout)
+}
+
+// IsFailure implements TargetError.
+func (e *timeoutTooShortError) IsFailure() bool {
+	return true
+}
+
 // errUnreadyConfiguration returns a TargetError for a Configuration that is not ready.
 func errUnreadyConfiguration(config *v1alpha1.Configuration) TargetError {
 	status := corev1.ConditionUnknown
@@ -132,6 +156,15 @@ func errUnreadyRevision(rev *v1alpha1.Revision) TargetError {
 	}
 }
 
+// errTimeoutTooShort returns a TargetError for a Revision that may take longer to respond than the Route's timeout.
+func errTimeoutTooShort(rev *v1alpha1.Revision, timeout time.Duration) TargetError {
+	return &timeoutTooShortError{
+		name:            rev.Name,
+		revisionTimeout: rev.Spec.TimeoutSeconds.Duration,
+		timeout:         timeout,
+	}
+}
+
 // errMissingConfiguration returns a TargetError for a Configuration what does not exist.
 func errMissingConfiguration(name string) TargetError {
 	return &missingTargetError{
@@ -17,11 +17,13 @@ package traffic
 
 import (
 	"testing"
+	"time"
 
 	"github.com/google/go-cmp/cmp"
 	duckv1alpha1 "github.com/knative/pkg/apis/duck/v1alpha1"
 	"github.com/knative/serving/pkg/apis/serving/v1alpha1"
 	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 )
 
 func TestIsFailure_Missing(t *testing.T) {
@@ -175,3 +177,32 @@ func TestMarkBadTrafficTarget_RevisionNotYetReady(t *testing.T) {
 		}
 	}
 }
+
+func TestMarkBadTrafficTarget_TimeoutTooShort(t *testing.T) {
+	rev := goodNewRev.DeepCopy()
+	rev.Spec.TimeoutSeconds = &metav1.Duration{Duration: time.Minute}
+	err := errTimeoutTooShort(rev, 30*time.Second)
+	if !err.IsFailure() {
+		t.Error("IsFailure() = false, wanted true")
+	}
+	r := getTestRouteWithTrafficTargets([]v1alpha1.TrafficTarget{})
+
+	err.MarkBadTrafficTarget(&r.Status)
+	for _, condType := range []duckv1alpha1.ConditionType{
+		v1alpha1.RouteConditionAllTrafficAssigned,
+		v1alpha1.RouteConditionReady,
+	} {
+		got := r.Status.GetCondition(condType)
+		want := &duckv1alpha1.Condition{
+			Type:               condType,
+			Status:             corev1.ConditionFalse,
+			Reason:             "TimeoutTooShort",
+			Message:            `Revision "good-revision-2" may take up to 1m0s to respond, longer than the Route's timeout of 30s.`,
+			LastTransitionTime: got.LastTransitionTime,
+			Severity:           "Error",
+		}
+		if diff := cmp.Diff(want, got); diff != "" {
+			t.Errorf("Unexpected condition diff (-want +got): %v", diff)
+		}
+	}
+}
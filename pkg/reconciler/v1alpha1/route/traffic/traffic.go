@@ -17,6 +17,8 @@ limitations under the License.
 package traffic
 
 import (
+	"time"
+
 	"k8s.io/apimachinery/pkg/api/errors"
 
 	"github.com/knative/serving/pkg/apis/serving"
@@ -30,6 +32,13 @@ type RevisionTarget struct {
 	Active bool
 }
 
+// PathTargets holds the traffic splits of a path-scoped traffic block,
+// flattened to the Revision level.
+type PathTargets struct {
+	Path    string
+	Targets []RevisionTarget
+}
+
 // TrafficConfig encapsulates details of our traffic so that we don't need to make API calls, or use details of the
 // route beyond its ObjectMeta to make routing changes.
 type TrafficConfig struct {
@@ -43,6 +52,21 @@ type TrafficConfig struct {
 	// is used to populate the Route.Status.TrafficTarget field.
 	RevisionTargets []RevisionTarget
 
+	// A list of traffic targets with header match conditions, flattened to
+	// the Revision level, in the order they appear in the Route spec.  Requests
+	// to the Route's default domains that match a target's headers are sent
+	// entirely to its Revision, ahead of the percentage splits.
+	Matches []RevisionTarget
+
+	// Group of traffic splits of each path-scoped traffic block, in the
+	// order they appear in the Route spec.  These only apply to the
+	// Route's default domains.
+	Paths []PathTargets
+
+	// The Revision to which a percentage of the requests is mirrored, if
+	// any, with Percent holding that percentage.
+	Mirror *RevisionTarget
+
 	// The referred Configurations and Revisions.
 	Configurations map[string]*v1alpha1.Configuration
 	Revisions      map[string]*v1alpha1.Revision
@@ -56,12 +80,28 @@ type TrafficConfig struct {
 func BuildTrafficConfiguration(configLister listers.ConfigurationLister, revLister listers.RevisionLister,
 	u *v1alpha1.Route) (*TrafficConfig, error) {
 	builder := newBuilder(configLister, revLister, u.Namespace)
+	if u.Spec.Timeout != nil {
+		builder.timeout = u.Spec.Timeout.Duration
+	}
 	for _, tt := range u.Spec.Traffic {
 		if err := builder.addTrafficTarget(&tt); err != nil {
 			// Other non-traffic target errors shouldn't be ignored.
 			return nil, err
 		}
 	}
+	for i, pt := range u.Spec.Paths {
+		builder.paths = append(builder.paths, PathTargets{Path: pt.Path})
+		for _, tt := range pt.Traffic {
+			if err := builder.addPathTrafficTarget(i, &tt); err != nil {
+				return nil, err
+			}
+		}
+	}
+	if u.Spec.Mirror != nil {
+		if err := builder.addMirrorTarget(u.Spec.Mirror); err != nil {
+			return nil, err
+		}
+	}
 	return builder.build()
 }
 
@@ -69,7 +109,21 @@ func BuildTrafficConfiguration(configLister listers.ConfigurationLister, revList
 func (t *TrafficConfig) GetRevisionTrafficTargets() []v1alpha1.TrafficTarget {
 	results := []v1alpha1.TrafficTarget{}
 	for _, tt := range t.RevisionTargets {
-		results = append(results, v1alpha1.TrafficTarget{RevisionName: tt.RevisionName, Name: tt.Name, Percent: tt.Percent})
+		results = append(results, v1alpha1.TrafficTarget{RevisionName: tt.RevisionName, Name: tt.Name, Percent: tt.Percent, BasisPoints: tt.BasisPoints, Headers: tt.Headers})
+	}
+	return results
+}
+
+// GetPathTraffic returns the path-scoped traffic blocks with their targets flattened to the RevisionName, and having
+// ConfigurationName cleared out.
+func (t *TrafficConfig) GetPathTraffic() []v1alpha1.PathTraffic {
+	var results []v1alpha1.PathTraffic
+	for _, pt := range t.Paths {
+		traffic := []v1alpha1.TrafficTarget{}
+		for _, tt := range pt.Targets {
+			traffic = append(traffic, v1alpha1.TrafficTarget{RevisionName: tt.RevisionName, Percent: tt.Percent, BasisPoints: tt.BasisPoints})
+		}
+		results = append(results, v1alpha1.PathTraffic{Path: pt.Path, Traffic: traffic})
 	}
 	return results
 }
@@ -79,12 +133,24 @@ type trafficConfigBuilder struct {
 	revLister    listers.RevisionLister
 	namespace    string
 
+	// timeout is the Route's timeout, if specified.
+	timeout time.Duration
+
 	// targets is a grouping of traffic targets serving the same origin.
 	targets map[string][]RevisionTarget
 
 	// revisionTargets is the original list of targets, at the Revision level.
 	revisionTargets []RevisionTarget
 
+	// matches is the list of targets with header match conditions, at the Revision level.
+	matches []RevisionTarget
+
+	// paths is the list of path-scoped traffic blocks, at the Revision level.
+	paths []PathTargets
+
+	// mirror is the mirrored Revision, if any.
+	mirror *RevisionTarget
+
 	// configurations contains all the referred Configuration, keyed by their name.
 	configurations map[string]*v1alpha1.Configuration
 	// revisions contains all the referred Revision, keyed by their name.
@@ -141,53 +207,88 @@ func (t *trafficConfigBuilder) deferTargetError(err TargetError) {
 }
 
 func (t *trafficConfigBuilder) addTrafficTarget(tt *v1alpha1.TrafficTarget) error {
-	var err error
-	if tt.RevisionName != "" {
-		err = t.addRevisionTarget(tt)
-	} else if tt.ConfigurationName != "" {
-		err = t.addConfigurationTarget(tt)
+	target, err := t.flattenTrafficTarget(tt)
+	if target != nil {
+		t.addFlattenedTarget(*target)
+	}
+	return t.checkTargetError(err)
+}
+
+// addPathTrafficTarget adds a traffic target to the i-th path-scoped traffic block.
+func (t *trafficConfigBuilder) addPathTrafficTarget(i int, tt *v1alpha1.TrafficTarget) error {
+	target, err := t.flattenTrafficTarget(tt)
+	if target != nil {
+		t.paths[i].Targets = append(t.paths[i].Targets, *target)
 	}
+	return t.checkTargetError(err)
+}
+
+// addMirrorTarget sets the Revision to which requests are mirrored.
+func (t *trafficConfigBuilder) addMirrorTarget(mt *v1alpha1.MirrorTarget) error {
+	target, err := t.flattenRevisionTarget(&v1alpha1.TrafficTarget{
+		RevisionName: mt.RevisionName,
+		Percent:      mt.Percent,
+	})
+	t.mirror = target
+	return t.checkTargetError(err)
+}
+
+// checkTargetError defers target errors, as we still want to compile a list of
+// all referred targets, including missing ones.  Other errors are returned.
+func (t *trafficConfigBuilder) checkTargetError(err error) error {
 	if err, ok := err.(TargetError); err != nil && ok {
-		// Defer target errors, as we still want to compile a list of
-		// all referred targets, including missing ones.
 		t.deferTargetError(err)
 		return nil
 	}
 	return err
 }
 
-// addConfigurationTarget flattens a traffic target to the Revision level, by looking up for the LatestReadyRevisionName
-// on the referred Configuration.  It adds both to the lists of directly referred targets.
-func (t *trafficConfigBuilder) addConfigurationTarget(tt *v1alpha1.TrafficTarget) error {
+func (t *trafficConfigBuilder) flattenTrafficTarget(tt *v1alpha1.TrafficTarget) (*RevisionTarget, error) {
+	if tt.RevisionName != "" {
+		return t.flattenRevisionTarget(tt)
+	} else if tt.ConfigurationName != "" {
+		return t.flattenConfigurationTarget(tt)
+	}
+	return nil, nil
+}
+
+// flattenConfigurationTarget flattens a traffic target to the Revision level, by looking up for the
+// LatestReadyRevisionName on the referred Configuration.  It adds both to the lists of directly referred targets.
+func (t *trafficConfigBuilder) flattenConfigurationTarget(tt *v1alpha1.TrafficTarget) (*RevisionTarget, error) {
 	config, err := t.getConfiguration(tt.ConfigurationName)
 	if err != nil {
-		return err
+		return nil, err
 	}
 	if config.Status.LatestReadyRevisionName == "" {
-		return errUnreadyConfiguration(config)
+		return nil, errUnreadyConfiguration(config)
 	}
 	rev, err := t.getRevision(config.Status.LatestReadyRevisionName)
 	if err != nil {
-		return err
+		return nil, err
+	}
+	if err := t.checkTimeout(rev); err != nil {
+		return nil, err
 	}
-	target := RevisionTarget{
+	target := &RevisionTarget{
 		TrafficTarget: *tt,
 		Active:        !rev.Status.IsActivationRequired(),
 	}
 	target.TrafficTarget.RevisionName = rev.Name
-	t.addFlattenedTarget(target)
-	return nil
+	return target, nil
 }
 
-func (t *trafficConfigBuilder) addRevisionTarget(tt *v1alpha1.TrafficTarget) error {
+func (t *trafficConfigBuilder) flattenRevisionTarget(tt *v1alpha1.TrafficTarget) (*RevisionTarget, error) {
 	rev, err := t.getRevision(tt.RevisionName)
 	if err != nil {
-		return err
+		return nil, err
 	}
 	if !rev.Status.IsRoutable() {
-		return errUnreadyRevision(rev)
+		return nil, errUnreadyRevision(rev)
+	}
+	if err := t.checkTimeout(rev); err != nil {
+		return nil, err
 	}
-	target := RevisionTarget{
+	target := &RevisionTarget{
 		TrafficTarget: *tt,
 		Active:        !rev.Status.IsActivationRequired(),
 	}
@@ -195,16 +296,30 @@ func (t *trafficConfigBuilder) addRevisionTarget(tt *v1alpha1.TrafficTarget) err
 	if configName, ok := rev.Labels[serving.ConfigurationLabelKey]; ok {
 		target.TrafficTarget.ConfigurationName = configName
 		if _, err := t.getConfiguration(configName); err != nil {
-			return err
+			return nil, err
 		}
 	}
-	t.addFlattenedTarget(target)
+	return target, nil
+}
+
+// checkTimeout verifies that the Revision can't take longer to respond than the Route's timeout,
+// as the Route would cut off the requests the Revision is allowed to serve.
+func (t *trafficConfigBuilder) checkTimeout(rev *v1alpha1.Revision) TargetError {
+	if t.timeout != 0 && rev.Spec.TimeoutSeconds != nil && rev.Spec.TimeoutSeconds.Duration > t.timeout {
+		return errTimeoutTooShort(rev, t.timeout)
+	}
 	return nil
 }
 
 func (t *trafficConfigBuilder) addFlattenedTarget(target RevisionTarget) {
 	name := target.TrafficTarget.Name
 	t.revisionTargets = append(t.revisionTargets, target)
+	if len(target.Headers) > 0 {
+		t.matches = append(t.matches, target)
+		// Header matches are routed separately, so they don't take part
+		// in the percentage splits beyond their Percent.
+		target.TrafficTarget.Headers = nil
+	}
 	t.targets[""] = append(t.targets[""], target)
 	if name != "" {
 		t.targets[name] = append(t.targets[name], target)
@@ -221,7 +336,7 @@ func consolidate(targets []RevisionTarget) []RevisionTarget {
 			byName[name] = tt
 			names = append(names, name)
 		} else {
-			cur.TrafficTarget.Percent += tt.TrafficTarget.Percent
+			cur.TrafficTarget.SetBasisPoints(cur.TrafficTarget.GetBasisPoints() + tt.TrafficTarget.GetBasisPoints())
 			byName[name] = cur
 		}
 	}
@@ -230,7 +345,7 @@ func consolidate(targets []RevisionTarget) []RevisionTarget {
 		consolidated = append(consolidated, byName[name])
 	}
 	if len(consolidated) == 1 {
-		consolidated[0].TrafficTarget.Percent = 100
+		consolidated[0].TrafficTarget.SetBasisPoints(100 * 100)
 	}
 	return consolidated
 }
@@ -247,10 +362,19 @@ func (t *trafficConfigBuilder) build() (*TrafficConfig, error) {
 	if t.deferredTargetErr != nil {
 		t.targets = nil
 		t.revisionTargets = nil
+		t.matches = nil
+		t.paths = nil
+		t.mirror = nil
+	}
+	for i, pt := range t.paths {
+		t.paths[i].Targets = consolidate(pt.Targets)
 	}
 	return &TrafficConfig{
 		Targets:         consolidateAll(t.targets),
 		RevisionTargets: t.revisionTargets,
+		Matches:         t.matches,
+		Paths:           t.paths,
+		Mirror:          t.mirror,
 		Configurations:  t.configurations,
 		Revisions:       t.revisions,
 	}, t.deferredTargetErr
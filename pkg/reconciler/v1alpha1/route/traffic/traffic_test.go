@@ -19,9 +19,11 @@ import (
 	"fmt"
 	"os"
 	"testing"
+	"time"
 
 	"github.com/google/go-cmp/cmp"
 	duckv1alpha1 "github.com/knative/pkg/apis/duck/v1alpha1"
+	netv1alpha1 "github.com/knative/serving/pkg/apis/networking/v1alpha1"
 	"github.com/knative/serving/pkg/apis/serving"
 	"github.com/knative/serving/pkg/apis/serving/v1alpha1"
 	fakeclientset "github.com/knative/serving/pkg/client/clientset/versioned/fake"
@@ -385,6 +387,68 @@ func TestBuildTrafficConfiguration_Consolidated(t *testing.T) {
 	}
 }
 
+// Splitting traffic in basis points, consolidated to the Revision level.
+func TestBuildTrafficConfiguration_BasisPoints(t *testing.T) {
+	tts := []v1alpha1.TrafficTarget{{
+		RevisionName: goodOldRev.Name,
+		BasisPoints:  9990,
+	}, {
+		RevisionName: goodNewRev.Name,
+		BasisPoints:  5,
+	}, {
+		ConfigurationName: goodConfig.Name,
+		BasisPoints:       5,
+	}}
+	expected := &TrafficConfig{
+		Targets: map[string][]RevisionTarget{
+			"": {{
+				TrafficTarget: v1alpha1.TrafficTarget{
+					ConfigurationName: goodConfig.Name,
+					RevisionName:      goodOldRev.Name,
+					BasisPoints:       9990,
+				},
+				Active: true,
+			}, {
+				TrafficTarget: v1alpha1.TrafficTarget{
+					ConfigurationName: goodConfig.Name,
+					RevisionName:      goodNewRev.Name,
+					BasisPoints:       10,
+				},
+				Active: true,
+			}},
+		},
+		RevisionTargets: []RevisionTarget{{
+			TrafficTarget: v1alpha1.TrafficTarget{
+				ConfigurationName: goodConfig.Name,
+				RevisionName:      goodOldRev.Name,
+				BasisPoints:       9990,
+			},
+			Active: true,
+		}, {
+			TrafficTarget: v1alpha1.TrafficTarget{
+				ConfigurationName: goodConfig.Name,
+				RevisionName:      goodNewRev.Name,
+				BasisPoints:       5,
+			},
+			Active: true,
+		}, {
+			TrafficTarget: v1alpha1.TrafficTarget{
+				ConfigurationName: goodConfig.Name,
+				RevisionName:      goodNewRev.Name,
+				BasisPoints:       5,
+			},
+			Active: true,
+		}},
+		Configurations: map[string]*v1alpha1.Configuration{goodConfig.Name: goodConfig},
+		Revisions:      map[string]*v1alpha1.Revision{goodNewRev.Name: goodNewRev, goodOldRev.Name: goodOldRev},
+	}
+	if tc, err := BuildTrafficConfiguration(configLister, revLister, getTestRouteWithTrafficTargets(tts)); err != nil {
+		t.Errorf("Unexpected error %v", err)
+	} else if diff := cmp.Diff(expected, tc); diff != "" {
+		t.Errorf("Unexpected traffic diff (-want +got): %v", diff)
+	}
+}
+
 // Splitting traffic between a two fixed revisions.
 func TestBuildTrafficConfiguration_TwoFixedRevisions(t *testing.T) {
 	tts := []v1alpha1.TrafficTarget{{
@@ -438,6 +502,71 @@ func TestBuildTrafficConfiguration_TwoFixedRevisions(t *testing.T) {
 }
 
 // Splitting traffic between a two fixed revisions of two configurations.
+func TestBuildTrafficConfiguration_HeaderMatch(t *testing.T) {
+	canary := map[string]netv1alpha1.HeaderMatch{
+		"X-Canary": {Exact: "true"},
+	}
+	tts := []v1alpha1.TrafficTarget{{
+		RevisionName: goodOldRev.Name,
+		Percent:      100,
+	}, {
+		RevisionName: goodNewRev.Name,
+		Percent:      0,
+		Headers:      canary,
+	}}
+	expected := &TrafficConfig{
+		Targets: map[string][]RevisionTarget{
+			"": {{
+				TrafficTarget: v1alpha1.TrafficTarget{
+					ConfigurationName: goodConfig.Name,
+					RevisionName:      goodOldRev.Name,
+					Percent:           100,
+				},
+				Active: true,
+			}, {
+				TrafficTarget: v1alpha1.TrafficTarget{
+					ConfigurationName: goodConfig.Name,
+					RevisionName:      goodNewRev.Name,
+					Percent:           0,
+				},
+				Active: true,
+			}},
+		},
+		RevisionTargets: []RevisionTarget{{
+			TrafficTarget: v1alpha1.TrafficTarget{
+				ConfigurationName: goodConfig.Name,
+				RevisionName:      goodOldRev.Name,
+				Percent:           100,
+			},
+			Active: true,
+		}, {
+			TrafficTarget: v1alpha1.TrafficTarget{
+				ConfigurationName: goodConfig.Name,
+				RevisionName:      goodNewRev.Name,
+				Percent:           0,
+				Headers:           canary,
+			},
+			Active: true,
+		}},
+		Matches: []RevisionTarget{{
+			TrafficTarget: v1alpha1.TrafficTarget{
+				ConfigurationName: goodConfig.Name,
+				RevisionName:      goodNewRev.Name,
+				Percent:           0,
+				Headers:           canary,
+			},
+			Active: true,
+		}},
+		Configurations: map[string]*v1alpha1.Configuration{goodConfig.Name: goodConfig},
+		Revisions:      map[string]*v1alpha1.Revision{goodNewRev.Name: goodNewRev, goodOldRev.Name: goodOldRev},
+	}
+	if tc, err := BuildTrafficConfiguration(configLister, revLister, getTestRouteWithTrafficTargets(tts)); err != nil {
+		t.Errorf("Unexpected error %v", err)
+	} else if diff := cmp.Diff(expected, tc); diff != "" {
+		t.Errorf("Unexpected traffic diff (-want +got): %v", diff)
+	}
+}
+
 func TestBuildTrafficConfiguration_TwoFixedRevisionsFromTwoConfigurations(t *testing.T) {
 	tts := []v1alpha1.TrafficTarget{{
 		RevisionName: goodNewRev.Name,
@@ -571,6 +700,218 @@ func TestBuildTrafficConfiguration_Preliminary(t *testing.T) {
 	}
 }
 
+func TestBuildTrafficConfiguration_Paths(t *testing.T) {
+	tts := []v1alpha1.TrafficTarget{{
+		ConfigurationName: goodConfig.Name,
+		Percent:           100,
+	}}
+	r := getTestRouteWithTrafficTargets(tts)
+	r.Spec.Paths = []v1alpha1.PathTraffic{{
+		Path: "/api/.*",
+		Traffic: []v1alpha1.TrafficTarget{{
+			RevisionName: niceOldRev.Name,
+			Percent:      20,
+		}, {
+			ConfigurationName: niceConfig.Name,
+			Percent:           80,
+		}},
+	}, {
+		Path: "/idle/.*",
+		Traffic: []v1alpha1.TrafficTarget{{
+			ConfigurationName: inactiveConfig.Name,
+			Percent:           100,
+		}},
+	}}
+	expected := &TrafficConfig{
+		Targets: map[string][]RevisionTarget{
+			"": {{
+				TrafficTarget: v1alpha1.TrafficTarget{
+					ConfigurationName: goodConfig.Name,
+					RevisionName:      goodNewRev.Name,
+					Percent:           100,
+				},
+				Active: true,
+			}},
+		},
+		RevisionTargets: []RevisionTarget{{
+			TrafficTarget: v1alpha1.TrafficTarget{
+				ConfigurationName: goodConfig.Name,
+				RevisionName:      goodNewRev.Name,
+				Percent:           100,
+			},
+			Active: true,
+		}},
+		Paths: []PathTargets{{
+			Path: "/api/.*",
+			Targets: []RevisionTarget{{
+				TrafficTarget: v1alpha1.TrafficTarget{
+					ConfigurationName: niceConfig.Name,
+					RevisionName:      niceOldRev.Name,
+					Percent:           20,
+				},
+				Active: true,
+			}, {
+				TrafficTarget: v1alpha1.TrafficTarget{
+					ConfigurationName: niceConfig.Name,
+					RevisionName:      niceNewRev.Name,
+					Percent:           80,
+				},
+				Active: true,
+			}},
+		}, {
+			Path: "/idle/.*",
+			Targets: []RevisionTarget{{
+				TrafficTarget: v1alpha1.TrafficTarget{
+					ConfigurationName: inactiveConfig.Name,
+					RevisionName:      inactiveRev.Name,
+					Percent:           100,
+				},
+				Active: false,
+			}},
+		}},
+		Configurations: map[string]*v1alpha1.Configuration{
+			goodConfig.Name:     goodConfig,
+			niceConfig.Name:     niceConfig,
+			inactiveConfig.Name: inactiveConfig,
+		},
+		Revisions: map[string]*v1alpha1.Revision{
+			goodNewRev.Name:  goodNewRev,
+			niceOldRev.Name:  niceOldRev,
+			niceNewRev.Name:  niceNewRev,
+			inactiveRev.Name: inactiveRev,
+		},
+	}
+	if tc, err := BuildTrafficConfiguration(configLister, revLister, r); err != nil {
+		t.Errorf("Unexpected error %v", err)
+	} else if diff := cmp.Diff(expected, tc); diff != "" {
+		t.Errorf("Unexpected traffic diff (-want +got): %v", diff)
+	}
+	if diff := cmp.Diff([]v1alpha1.PathTraffic{{
+		Path: "/api/.*",
+		Traffic: []v1alpha1.TrafficTarget{{
+			RevisionName: niceOldRev.Name,
+			Percent:      20,
+		}, {
+			RevisionName: niceNewRev.Name,
+			Percent:      80,
+		}},
+	}, {
+		Path: "/idle/.*",
+		Traffic: []v1alpha1.TrafficTarget{{
+			RevisionName: inactiveRev.Name,
+			Percent:      100,
+		}},
+	}}, expected.GetPathTraffic()); diff != "" {
+		t.Errorf("Unexpected path traffic diff (-want +got): %v", diff)
+	}
+}
+
+func TestBuildTrafficConfiguration_PathMissingConfig(t *testing.T) {
+	tts := []v1alpha1.TrafficTarget{{
+		RevisionName: goodOldRev.Name,
+		Percent:      100,
+	}}
+	r := getTestRouteWithTrafficTargets(tts)
+	r.Spec.Paths = []v1alpha1.PathTraffic{{
+		Path: "/api/.*",
+		Traffic: []v1alpha1.TrafficTarget{{
+			ConfigurationName: missingConfig.Name,
+			Percent:           100,
+		}},
+	}}
+	expected := &TrafficConfig{
+		Targets:        map[string][]RevisionTarget{},
+		Configurations: map[string]*v1alpha1.Configuration{goodConfig.Name: goodConfig},
+		Revisions:      map[string]*v1alpha1.Revision{goodOldRev.Name: goodOldRev},
+	}
+	expectedErr := errMissingConfiguration(missingConfig.Name)
+	tc, err := BuildTrafficConfiguration(configLister, revLister, r)
+	if expectedErr.Error() != err.Error() {
+		t.Errorf("Expected %v, saw %v", expectedErr, err)
+	}
+	if diff := cmp.Diff(expected, tc); diff != "" {
+		t.Errorf("Unexpected traffic diff (-want +got): %v", diff)
+	}
+}
+
+func TestBuildTrafficConfiguration_Mirror(t *testing.T) {
+	tts := []v1alpha1.TrafficTarget{{
+		RevisionName: goodOldRev.Name,
+		Percent:      100,
+	}}
+	r := getTestRouteWithTrafficTargets(tts)
+	r.Spec.Mirror = &v1alpha1.MirrorTarget{
+		RevisionName: inactiveRev.Name,
+		Percent:      10,
+	}
+	expected := &TrafficConfig{
+		Targets: map[string][]RevisionTarget{
+			"": {{
+				TrafficTarget: v1alpha1.TrafficTarget{
+					ConfigurationName: goodConfig.Name,
+					RevisionName:      goodOldRev.Name,
+					Percent:           100,
+				},
+				Active: true,
+			}},
+		},
+		RevisionTargets: []RevisionTarget{{
+			TrafficTarget: v1alpha1.TrafficTarget{
+				ConfigurationName: goodConfig.Name,
+				RevisionName:      goodOldRev.Name,
+				Percent:           100,
+			},
+			Active: true,
+		}},
+		Mirror: &RevisionTarget{
+			TrafficTarget: v1alpha1.TrafficTarget{
+				ConfigurationName: inactiveConfig.Name,
+				RevisionName:      inactiveRev.Name,
+				Percent:           10,
+			},
+			Active: false,
+		},
+		Configurations: map[string]*v1alpha1.Configuration{
+			goodConfig.Name:     goodConfig,
+			inactiveConfig.Name: inactiveConfig,
+		},
+		Revisions: map[string]*v1alpha1.Revision{
+			goodOldRev.Name:  goodOldRev,
+			inactiveRev.Name: inactiveRev,
+		},
+	}
+	if tc, err := BuildTrafficConfiguration(configLister, revLister, r); err != nil {
+		t.Errorf("Unexpected error %v", err)
+	} else if diff := cmp.Diff(expected, tc); diff != "" {
+		t.Errorf("Unexpected traffic diff (-want +got): %v", diff)
+	}
+}
+
+func TestBuildTrafficConfiguration_MirrorMissingRevision(t *testing.T) {
+	tts := []v1alpha1.TrafficTarget{{
+		RevisionName: goodOldRev.Name,
+		Percent:      100,
+	}}
+	r := getTestRouteWithTrafficTargets(tts)
+	r.Spec.Mirror = &v1alpha1.MirrorTarget{
+		RevisionName: missingRev.Name,
+		Percent:      10,
+	}
+	expected := &TrafficConfig{
+		Targets:        map[string][]RevisionTarget{},
+		Configurations: map[string]*v1alpha1.Configuration{goodConfig.Name: goodConfig},
+		Revisions:      map[string]*v1alpha1.Revision{goodOldRev.Name: goodOldRev},
+	}
+	expectedErr := errMissingRevision(missingRev.Name)
+	tc, err := BuildTrafficConfiguration(configLister, revLister, r)
+	if expectedErr.Error() != err.Error() {
+		t.Errorf("Expected %v, saw %v", expectedErr, err)
+	}
+	if diff := cmp.Diff(expected, tc); diff != "" {
+		t.Errorf("Unexpected traffic diff (-want +got): %v", diff)
+	}
+}
+
 func TestBuildTrafficConfiguration_MissingConfig(t *testing.T) {
 	tts := []v1alpha1.TrafficTarget{{
 		RevisionName: goodOldRev.Name,
@@ -739,6 +1080,41 @@ func TestBuildTrafficConfiguration_MissingRevision(t *testing.T) {
 	}
 }
 
+func TestCheckTimeout(t *testing.T) {
+	rev := goodNewRev.DeepCopy()
+	rev.Spec.TimeoutSeconds = &metav1.Duration{Duration: time.Minute}
+	tests := []struct {
+		name    string
+		timeout time.Duration
+		want    error
+	}{{
+		name: "no route timeout",
+	}, {
+		name:    "longer route timeout",
+		timeout: 2 * time.Minute,
+	}, {
+		name:    "same route timeout",
+		timeout: time.Minute,
+	}, {
+		name:    "shorter route timeout",
+		timeout: 30 * time.Second,
+		want:    errTimeoutTooShort(rev, 30*time.Second),
+	}}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			b := newBuilder(configLister, revLister, testNamespace)
+			b.timeout = test.timeout
+			var got error
+			if err := b.checkTimeout(rev); err != nil {
+				got = err
+			}
+			if diff := cmp.Diff(fmt.Sprint(test.want), fmt.Sprint(got)); diff != "" {
+				t.Errorf("checkTimeout (-want +got): %v", diff)
+			}
+		})
+	}
+}
+
 func TestRoundTripping(t *testing.T) {
 	tts := []v1alpha1.TrafficTarget{{
 		RevisionName: goodOldRev.Name,
@@ -45,9 +45,11 @@ func MakeConfiguration(service *v1alpha1.Service) (*v1alpha1.Configuration, erro
 		c.Spec = service.Spec.Pinned.Configuration
 	} else if service.Spec.Release != nil {
 		c.Spec = service.Spec.Release.Configuration
+	} else if service.Spec.Inline != nil {
+		c.Spec = service.Spec.Inline.Configuration
 	} else {
 		// Manual does not have a configuration and should not reach this path.
-		return nil, errors.New("malformed Service: MakeConfiguration requires one of runLatest, pinned, or release must be present")
+		return nil, errors.New("malformed Service: MakeConfiguration requires one of runLatest, pinned, release, or inline must be present")
 	}
 	return c, nil
 }
@@ -0,0 +1,102 @@
+/*
+Copyright 2018 The Knative Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package resources
+
+import (
+	"time"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	"github.com/knative/serving/pkg/apis/serving/v1alpha1"
+)
+
+// AdvanceRollout moves the Service's release rollout plan forward as of now,
+// recording its progress in the Service's status. It returns how long until
+// the next step should be taken, or zero if no further step is scheduled.
+func AdvanceRollout(service *v1alpha1.Service, now time.Time) time.Duration {
+	release := service.Spec.Release
+	if release == nil || len(release.Revisions) < 2 || (!hasRolloutPlan(release) && release.Rollback == nil) {
+		service.Status.Rollout = nil
+		return 0
+	}
+
+	candidate := release.Revisions[1]
+	rs := service.Status.Rollout
+	if rs == nil || rs.Candidate != candidate {
+		// A new candidate starts the rollout over.
+		rs = &v1alpha1.RolloutStatus{Candidate: candidate}
+		service.Status.Rollout = rs
+	}
+	if !hasRolloutPlan(release) {
+		return 0
+	}
+	plan := release.Rollout
+
+	if plan.Aborted {
+		rs.CurrentStep = 0
+		rs.NextTransitionTime = nil
+		return 0
+	}
+
+	last := len(plan.Steps) - 1
+	if rs.CurrentStep > last {
+		// The plan was shortened underneath us.
+		rs.CurrentStep = last
+	}
+	if rs.NextTransitionTime != nil && !now.Before(rs.NextTransitionTime.Time) && rs.CurrentStep < last && !rs.RolledBack {
+		rs.CurrentStep++
+		rs.NextTransitionTime = nil
+	}
+	if plan.Paused || rs.RolledBack || rs.CurrentStep == last {
+		rs.NextTransitionTime = nil
+		return 0
+	}
+	if rs.NextTransitionTime == nil {
+		next := metav1.NewTime(now.Add(plan.Steps[rs.CurrentStep].Pause.Duration))
+		rs.NextTransitionTime = &next
+	}
+	return rs.NextTransitionTime.Sub(now)
+}
+
+func hasRolloutPlan(release *v1alpha1.ReleaseType) bool {
+	return release.Rollout != nil && len(release.Rollout.Steps) > 0
+}
+
+// candidatePercent returns the percent of traffic that should be sent to the
+// "candidate" revision of the Service's release. A rolled back candidate
+// receives no traffic.
+func candidatePercent(service *v1alpha1.Service) int {
+	release := service.Spec.Release
+	rs := service.Status.Rollout
+	if len(release.Revisions) < 2 {
+		return release.RolloutPercent
+	}
+	if rs != nil && rs.RolledBack && rs.Candidate == release.Revisions[1] {
+		return 0
+	}
+	if release.Rollout == nil {
+		return release.RolloutPercent
+	}
+	if release.Rollout.Aborted || rs == nil || len(release.Rollout.Steps) == 0 || rs.Candidate != release.Revisions[1] {
+		return 0
+	}
+	steps := release.Rollout.Steps
+	if rs.CurrentStep >= len(steps) {
+		return steps[len(steps)-1].Percent
+	}
+	return steps[rs.CurrentStep].Percent
+}
@@ -0,0 +1,270 @@
+/*
+Copyright 2018 The Knative Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package resources
+
+import (
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	"github.com/knative/serving/pkg/apis/serving/v1alpha1"
+)
+
+func TestAdvanceRollout(t *testing.T) {
+	now := time.Date(2018, 11, 1, 12, 0, 0, 0, time.UTC)
+	at := func(d time.Duration) *metav1.Time {
+		t := metav1.NewTime(now.Add(d))
+		return &t
+	}
+	steps := []v1alpha1.RolloutStep{{
+		Percent: 5,
+		Pause:   metav1.Duration{Duration: time.Minute},
+	}, {
+		Percent: 25,
+		Pause:   metav1.Duration{Duration: 10 * time.Minute},
+	}, {
+		Percent: 75,
+	}}
+
+	rollback := &v1alpha1.RollbackPolicy{MaxErrorPercent: 5}
+
+	tests := []struct {
+		name        string
+		plan        *v1alpha1.RolloutPlan
+		rollback    *v1alpha1.RollbackPolicy
+		status      *v1alpha1.RolloutStatus
+		want        *v1alpha1.RolloutStatus
+		wantRequeue time.Duration
+		wantPercent int
+	}{{
+		name: "no plan",
+		status: &v1alpha1.RolloutStatus{
+			Candidate: testCandidateRevisionName,
+		},
+		want:        nil,
+		wantPercent: 10,
+	}, {
+		name: "start",
+		plan: &v1alpha1.RolloutPlan{Steps: steps},
+		want: &v1alpha1.RolloutStatus{
+			Candidate:          testCandidateRevisionName,
+			NextTransitionTime: at(time.Minute),
+		},
+		wantRequeue: time.Minute,
+		wantPercent: 5,
+	}, {
+		name: "waiting",
+		plan: &v1alpha1.RolloutPlan{Steps: steps},
+		status: &v1alpha1.RolloutStatus{
+			Candidate:          testCandidateRevisionName,
+			NextTransitionTime: at(20 * time.Second),
+		},
+		want: &v1alpha1.RolloutStatus{
+			Candidate:          testCandidateRevisionName,
+			NextTransitionTime: at(20 * time.Second),
+		},
+		wantRequeue: 20 * time.Second,
+		wantPercent: 5,
+	}, {
+		name: "advance",
+		plan: &v1alpha1.RolloutPlan{Steps: steps},
+		status: &v1alpha1.RolloutStatus{
+			Candidate:          testCandidateRevisionName,
+			NextTransitionTime: at(-time.Second),
+		},
+		want: &v1alpha1.RolloutStatus{
+			Candidate:          testCandidateRevisionName,
+			CurrentStep:        1,
+			NextTransitionTime: at(10 * time.Minute),
+		},
+		wantRequeue: 10 * time.Minute,
+		wantPercent: 25,
+	}, {
+		name: "advance to last step",
+		plan: &v1alpha1.RolloutPlan{Steps: steps},
+		status: &v1alpha1.RolloutStatus{
+			Candidate:          testCandidateRevisionName,
+			CurrentStep:        1,
+			NextTransitionTime: at(0),
+		},
+		want: &v1alpha1.RolloutStatus{
+			Candidate:   testCandidateRevisionName,
+			CurrentStep: 2,
+		},
+		wantPercent: 75,
+	}, {
+		name: "paused",
+		plan: &v1alpha1.RolloutPlan{Steps: steps, Paused: true},
+		status: &v1alpha1.RolloutStatus{
+			Candidate:          testCandidateRevisionName,
+			CurrentStep:        1,
+			NextTransitionTime: at(time.Minute),
+		},
+		want: &v1alpha1.RolloutStatus{
+			Candidate:   testCandidateRevisionName,
+			CurrentStep: 1,
+		},
+		wantPercent: 25,
+	}, {
+		name: "resumed",
+		plan: &v1alpha1.RolloutPlan{Steps: steps},
+		status: &v1alpha1.RolloutStatus{
+			Candidate:   testCandidateRevisionName,
+			CurrentStep: 1,
+		},
+		want: &v1alpha1.RolloutStatus{
+			Candidate:          testCandidateRevisionName,
+			CurrentStep:        1,
+			NextTransitionTime: at(10 * time.Minute),
+		},
+		wantRequeue: 10 * time.Minute,
+		wantPercent: 25,
+	}, {
+		name: "aborted",
+		plan: &v1alpha1.RolloutPlan{Steps: steps, Aborted: true},
+		status: &v1alpha1.RolloutStatus{
+			Candidate:          testCandidateRevisionName,
+			CurrentStep:        1,
+			NextTransitionTime: at(time.Minute),
+		},
+		want: &v1alpha1.RolloutStatus{
+			Candidate: testCandidateRevisionName,
+		},
+		wantPercent: 0,
+	}, {
+		name: "new candidate",
+		plan: &v1alpha1.RolloutPlan{Steps: steps},
+		status: &v1alpha1.RolloutStatus{
+			Candidate:   "some-older-revision",
+			CurrentStep: 2,
+		},
+		want: &v1alpha1.RolloutStatus{
+			Candidate:          testCandidateRevisionName,
+			NextTransitionTime: at(time.Minute),
+		},
+		wantRequeue: time.Minute,
+		wantPercent: 5,
+	}, {
+		name: "plan shortened",
+		plan: &v1alpha1.RolloutPlan{Steps: steps[:2]},
+		status: &v1alpha1.RolloutStatus{
+			Candidate:   testCandidateRevisionName,
+			CurrentStep: 2,
+		},
+		want: &v1alpha1.RolloutStatus{
+			Candidate:   testCandidateRevisionName,
+			CurrentStep: 1,
+		},
+		wantPercent: 25,
+	}, {
+		name: "rolled back",
+		plan: &v1alpha1.RolloutPlan{Steps: steps},
+		status: &v1alpha1.RolloutStatus{
+			Candidate:          testCandidateRevisionName,
+			CurrentStep:        1,
+			NextTransitionTime: at(-time.Second),
+			RolledBack:         true,
+		},
+		want: &v1alpha1.RolloutStatus{
+			Candidate:   testCandidateRevisionName,
+			CurrentStep: 1,
+			RolledBack:  true,
+		},
+		wantPercent: 0,
+	}, {
+		name:     "rollback policy without plan",
+		rollback: rollback,
+		want: &v1alpha1.RolloutStatus{
+			Candidate: testCandidateRevisionName,
+		},
+		wantPercent: 10,
+	}, {
+		name:     "rolled back without plan",
+		rollback: rollback,
+		status: &v1alpha1.RolloutStatus{
+			Candidate:  testCandidateRevisionName,
+			RolledBack: true,
+		},
+		want: &v1alpha1.RolloutStatus{
+			Candidate:  testCandidateRevisionName,
+			RolledBack: true,
+		},
+		wantPercent: 0,
+	}, {
+		name:     "new candidate after rollback",
+		rollback: rollback,
+		status: &v1alpha1.RolloutStatus{
+			Candidate:  "some-older-revision",
+			RolledBack: true,
+		},
+		want: &v1alpha1.RolloutStatus{
+			Candidate: testCandidateRevisionName,
+		},
+		wantPercent: 10,
+	}}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			s := createServiceWithRelease(2, 10)
+			if test.plan != nil {
+				s.Spec.Release.RolloutPercent = 0
+				s.Spec.Release.Rollout = test.plan
+			}
+			s.Spec.Release.Rollback = test.rollback
+			s.Status.Rollout = test.status
+
+			if got, want := AdvanceRollout(s, now), test.wantRequeue; got != want {
+				t.Errorf("AdvanceRollout() = %v, wanted %v", got, want)
+			}
+			if diff := cmp.Diff(test.want, s.Status.Rollout); diff != "" {
+				t.Errorf("Unexpected rollout status diff (-want +got): %v", diff)
+			}
+
+			r, err := MakeRoute(s)
+			if err != nil {
+				t.Fatalf("MakeRoute() = %v", err)
+			}
+			if got, want := r.Spec.Traffic[1].Percent, test.wantPercent; got != want {
+				t.Errorf("expected %d percent for candidate got %d", want, got)
+			}
+			if got, want := r.Spec.Traffic[0].Percent, 100-test.wantPercent; got != want {
+				t.Errorf("expected %d percent for current got %d", want, got)
+			}
+		})
+	}
+}
+
+func TestRouteReleaseRolloutStaleStatus(t *testing.T) {
+	s := createServiceWithRelease(2, 0)
+	s.Spec.Release.Rollout = &v1alpha1.RolloutPlan{
+		Steps: []v1alpha1.RolloutStep{{Percent: 50}},
+	}
+	// The status has not yet been advanced for this candidate.
+	s.Status.Rollout = &v1alpha1.RolloutStatus{
+		Candidate: "some-older-revision",
+	}
+
+	r, err := MakeRoute(s)
+	if err != nil {
+		t.Fatalf("MakeRoute() = %v", err)
+	}
+	if got, want := r.Spec.Traffic[1].Percent, 0; got != want {
+		t.Errorf("expected %d percent for candidate got %d", want, got)
+	}
+}
@@ -40,7 +40,7 @@ func MakeRoute(service *v1alpha1.Service) (*v1alpha1.Route, error) {
 	}
 
 	if service.Spec.Release != nil {
-		rolloutPercent := service.Spec.Release.RolloutPercent
+		rolloutPercent := candidatePercent(service)
 		numRevisions := len(service.Spec.Release.Revisions)
 
 		// Configure the 'current' route.
@@ -82,9 +82,26 @@ func MakeRoute(service *v1alpha1.Service) (*v1alpha1.Route, error) {
 			Percent:      100,
 		}
 		c.Spec.Traffic = append(c.Spec.Traffic, tt)
+	} else if service.Spec.Inline != nil {
+		for _, it := range service.Spec.Inline.Traffic {
+			tt := v1alpha1.TrafficTarget{
+				Name:         it.Name,
+				RevisionName: it.RevisionName,
+				Percent:      it.Percent,
+				BasisPoints:  it.BasisPoints,
+				Headers:      it.Headers,
+			}
+			if it.LatestRevision != nil && *it.LatestRevision {
+				// The Route tracks the latest ready revision through
+				// the Service's Configuration.
+				tt.RevisionName = ""
+				tt.ConfigurationName = names.Configuration(service)
+			}
+			c.Spec.Traffic = append(c.Spec.Traffic, tt)
+		}
 	} else {
 		// Manual does not have a route and should not reach this path.
-		return nil, errors.New("malformed Service: MakeRoute requires one of runLatest, pinned, or release must be present")
+		return nil, errors.New("malformed Service: MakeRoute requires one of runLatest, pinned, release, or inline must be present")
 	}
 
 	return c, nil
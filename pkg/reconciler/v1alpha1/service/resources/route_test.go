@@ -19,7 +19,10 @@ package resources
 import (
 	"testing"
 
+	"github.com/google/go-cmp/cmp"
+
 	"github.com/knative/serving/pkg/apis/serving"
+	"github.com/knative/serving/pkg/apis/serving/v1alpha1"
 	"github.com/knative/serving/pkg/reconciler/v1alpha1/service/resources/names"
 )
 
@@ -231,6 +234,47 @@ func TestRouteReleaseTwoRevisions(t *testing.T) {
 	}
 }
 
+func TestRouteInline(t *testing.T) {
+	s := createServiceWithInline()
+	r, err := MakeRoute(s)
+	if err != nil {
+		t.Errorf("expected nil for err got %q", err)
+	}
+	if got, want := r.Name, testServiceName; got != want {
+		t.Errorf("expected %q for service name got %q", want, got)
+	}
+	if got, want := r.Namespace, testServiceNamespace; got != want {
+		t.Errorf("expected %q for service namespace got %q", want, got)
+	}
+	// The latestRevision target should track the Service's Configuration.
+	want := []v1alpha1.TrafficTarget{{
+		Name:         "current",
+		RevisionName: testRevisionName,
+		Percent:      80,
+	}, {
+		RevisionName: testCandidateRevisionName,
+		Percent:      15,
+	}, {
+		Name:              "latest",
+		ConfigurationName: names.Configuration(s),
+		Percent:           5,
+	}}
+	if diff := cmp.Diff(want, r.Spec.Traffic); diff != "" {
+		t.Errorf("Unexpected traffic diff (-want +got): %v", diff)
+	}
+	expectOwnerReferencesSetCorrectly(t, r.OwnerReferences)
+
+	if got, want := len(r.Labels), 2; got != want {
+		t.Errorf("expected %d labels got %d", want, got)
+	}
+	if got, want := r.Labels[testLabelKey], testLabelValueInline; got != want {
+		t.Errorf("expected %q labels got %q", want, got)
+	}
+	if got, want := r.Labels[serving.ServiceLabelKey], testServiceName; got != want {
+		t.Errorf("expected %q labels got %q", want, got)
+	}
+}
+
 // MakeRoute is not called with a ManualType service, but if it is
 // called it should produce a route with no traffic targets
 func TestRouteManual(t *testing.T) {
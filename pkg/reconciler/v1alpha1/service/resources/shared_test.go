@@ -35,11 +35,13 @@ const (
 	testContainerNameRunLatest = "test-container-run-latest"
 	testContainerNamePinned    = "test-container-pinned"
 	testContainerNameRelease   = "test-container-release"
+	testContainerNameInline    = "test-container-inline"
 	testLabelKey               = "test-label-key"
 	testLabelValuePinned       = "test-label-value-pinned"
 	testLabelValueRunLatest    = "test-label-value-run-latest"
 	testLabelValueRelease      = "test-label-value-release"
 	testLabelValueManual       = "test-label-value-manual"
+	testLabelValueInline       = "test-label-value-inline"
 )
 
 func expectOwnerReferencesSetCorrectly(t *testing.T, ownerRefs []metav1.OwnerReference) {
@@ -123,6 +125,31 @@ func createServiceWithRelease(numRevision int, rolloutPercent int) *v1alpha1.Ser
 	return s
 }
 
+func createServiceWithInline() *v1alpha1.Service {
+	latestRevision := true
+	s := createServiceMeta()
+	s.Spec = v1alpha1.ServiceSpec{
+		Inline: &v1alpha1.InlineType{
+			Traffic: []v1alpha1.TrafficTarget{{
+				Name:         "current",
+				RevisionName: testRevisionName,
+				Percent:      80,
+			}, {
+				RevisionName: testCandidateRevisionName,
+				Percent:      15,
+			}, {
+				Name:           "latest",
+				LatestRevision: &latestRevision,
+				Percent:        5,
+			}},
+			Configuration: createConfiguration(testContainerNameInline),
+		},
+	}
+	s.Labels = make(map[string]string, 2)
+	s.Labels[testLabelKey] = testLabelValueInline
+	return s
+}
+
 func createServiceWithManual() *v1alpha1.Service {
 	s := createServiceMeta()
 	s.Spec = v1alpha1.ServiceSpec{
@@ -0,0 +1,106 @@
+/*
+Copyright 2018 The Knative Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package service
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/knative/pkg/logging"
+	"github.com/knative/serving/pkg/apis/serving/v1alpha1"
+	"github.com/knative/serving/pkg/metrics"
+	corev1 "k8s.io/api/core/v1"
+)
+
+// outcomesQueryTimeout bounds the time spent getting the request outcomes
+// of a Service's revisions, so that a slow metrics backend doesn't hold up
+// the reconciler. A shorter rollback window bounds it further.
+const outcomesQueryTimeout = 5 * time.Second
+
+// reconcileRollback checks the request outcomes of the Service's "candidate"
+// revision against its release's rollback policy, and sends all traffic back
+// to the "current" revision if the candidate regressed. It returns when the
+// outcomes should be checked again, or zero if no further check is needed.
+func (c *Reconciler) reconcileRollback(ctx context.Context, service *v1alpha1.Service) time.Duration {
+	logger := logging.FromContext(ctx)
+
+	rs := service.Status.Rollout
+	if rs == nil || !rs.RolledBack {
+		service.Status.ClearRolloutFailed()
+	}
+	release := service.Spec.Release
+	if release == nil || release.Rollback == nil || rs == nil || rs.RolledBack {
+		return 0
+	}
+	policy := release.Rollback
+
+	// A timed out query is an error like any other, checked again after
+	// the window.
+	timeout := outcomesQueryTimeout
+	if w := policy.Window.Duration; w > 0 && w < timeout {
+		timeout = w
+	}
+	queryCtx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	current, err := c.outcomes.RevisionOutcomes(queryCtx, service.Namespace, release.Revisions[0], policy.Window.Duration)
+	if err == metrics.ErrNoOutcomesSource {
+		logger.Debugf("Not checking Service %q for rollback: %v", service.Name, err)
+		return 0
+	} else if err != nil {
+		logger.Errorf("Failed to get request outcomes of Revision %q: %v", release.Revisions[0], err)
+		return policy.Window.Duration
+	}
+	candidate, err := c.outcomes.RevisionOutcomes(queryCtx, service.Namespace, rs.Candidate, policy.Window.Duration)
+	if err != nil {
+		logger.Errorf("Failed to get request outcomes of Revision %q: %v", rs.Candidate, err)
+		return policy.Window.Duration
+	}
+
+	reason, message, regressed := regression(policy, current, candidate)
+	if !regressed {
+		return policy.Window.Duration
+	}
+	rs.RolledBack = true
+	rs.NextTransitionTime = nil
+	service.Status.MarkRolloutFailed(reason, "Revision %q regressed: %s", rs.Candidate, message)
+	c.Recorder.Eventf(service, corev1.EventTypeWarning, "RolloutFailed",
+		"Sent all traffic back to Revision %q, Revision %q regressed: %s", release.Revisions[0], rs.Candidate, message)
+	return 0
+}
+
+// regression reports whether the candidate's request outcomes cross one of
+// the policy's thresholds while also being worse than the current revision's.
+func regression(policy *v1alpha1.RollbackPolicy, current, candidate *metrics.RequestOutcomes) (reason, message string, regressed bool) {
+	if candidate.Requests < float64(policy.MinRequests) {
+		return "", "", false
+	}
+	if policy.MaxErrorPercent > 0 {
+		if got := candidate.ErrorPercent(); got > float64(policy.MaxErrorPercent) && got > current.ErrorPercent() {
+			return "ErrorRateRegression", fmt.Sprintf("%.2f%% of requests failed, above the %d%% threshold and the current revision's %.2f%%",
+				got, policy.MaxErrorPercent, current.ErrorPercent()), true
+		}
+	}
+	if max := policy.MaxLatency.Duration; max > 0 {
+		if got := candidate.P99Latency; got > max && got > current.P99Latency {
+			return "LatencyRegression", fmt.Sprintf("99th percentile latency of %v, above the %v threshold and the current revision's %v",
+				got, max, current.P99Latency), true
+		}
+	}
+	return "", "", false
+}
@@ -0,0 +1,119 @@
+/*
+Copyright 2018 The Knative Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package service
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/knative/serving/pkg/apis/serving/v1alpha1"
+	"github.com/knative/serving/pkg/metrics"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func TestRegression(t *testing.T) {
+	policy := &v1alpha1.RollbackPolicy{
+		MaxErrorPercent: 5,
+		MaxLatency:      metav1.Duration{Duration: time.Second},
+		MinRequests:     10,
+	}
+
+	tests := []struct {
+		name       string
+		current    *metrics.RequestOutcomes
+		candidate  *metrics.RequestOutcomes
+		wantReason string
+	}{{
+		name:      "healthy",
+		current:   &metrics.RequestOutcomes{Requests: 100, P99Latency: 100 * time.Millisecond},
+		candidate: &metrics.RequestOutcomes{Requests: 100, Errors: 5, P99Latency: time.Second},
+	}, {
+		name:      "too few requests",
+		current:   &metrics.RequestOutcomes{Requests: 100},
+		candidate: &metrics.RequestOutcomes{Requests: 9, Errors: 9},
+	}, {
+		name:       "error rate",
+		current:    &metrics.RequestOutcomes{Requests: 100, Errors: 1},
+		candidate:  &metrics.RequestOutcomes{Requests: 10, Errors: 1},
+		wantReason: "ErrorRateRegression",
+	}, {
+		name:      "error rate shared with current",
+		current:   &metrics.RequestOutcomes{Requests: 100, Errors: 50},
+		candidate: &metrics.RequestOutcomes{Requests: 100, Errors: 50},
+	}, {
+		name:       "latency",
+		current:    &metrics.RequestOutcomes{Requests: 100, P99Latency: 100 * time.Millisecond},
+		candidate:  &metrics.RequestOutcomes{Requests: 100, P99Latency: 2 * time.Second},
+		wantReason: "LatencyRegression",
+	}, {
+		name:      "latency shared with current",
+		current:   &metrics.RequestOutcomes{Requests: 100, P99Latency: 3 * time.Second},
+		candidate: &metrics.RequestOutcomes{Requests: 100, P99Latency: 2 * time.Second},
+	}}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			reason, message, regressed := regression(policy, test.current, test.candidate)
+			if got, want := regressed, test.wantReason != ""; got != want {
+				t.Errorf("regression() = %v, wanted %v", got, want)
+			}
+			if got, want := reason, test.wantReason; got != want {
+				t.Errorf("reason = %q, wanted %q", got, want)
+			}
+			if regressed && message == "" {
+				t.Error("message is empty, wanted an explanation")
+			}
+		})
+	}
+}
+
+// blockingOutcomes answers queries only once their context is done.
+type blockingOutcomes struct{}
+
+func (blockingOutcomes) RevisionOutcomes(ctx context.Context, namespace, revision string, window time.Duration) (*metrics.RequestOutcomes, error) {
+	<-ctx.Done()
+	return nil, ctx.Err()
+}
+
+func TestReconcileRollbackTimeout(t *testing.T) {
+	window := 10 * time.Millisecond
+	c := &Reconciler{outcomes: blockingOutcomes{}}
+	service := &v1alpha1.Service{
+		Spec: v1alpha1.ServiceSpec{
+			Release: &v1alpha1.ReleaseType{
+				Revisions: []string{"current", "candidate"},
+				Rollback: &v1alpha1.RollbackPolicy{
+					MaxErrorPercent: 5,
+					Window:          metav1.Duration{Duration: window},
+				},
+			},
+		},
+		Status: v1alpha1.ServiceStatus{
+			Rollout: &v1alpha1.RolloutStatus{Candidate: "candidate"},
+		},
+	}
+
+	// A query that doesn't answer within the window is checked again
+	// after the window.
+	if got := c.reconcileRollback(context.Background(), service); got != window {
+		t.Errorf("reconcileRollback() = %v, want %v", got, window)
+	}
+	if service.Status.Rollout.RolledBack {
+		t.Error("RolledBack = true, want false")
+	}
+}
@@ -20,6 +20,7 @@ import (
 	"context"
 	"fmt"
 	"reflect"
+	"time"
 
 	"github.com/knative/pkg/controller"
 	"github.com/knative/pkg/kmp"
@@ -27,9 +28,11 @@ import (
 	"github.com/knative/serving/pkg/apis/serving/v1alpha1"
 	servinginformers "github.com/knative/serving/pkg/client/informers/externalversions/serving/v1alpha1"
 	listers "github.com/knative/serving/pkg/client/listers/serving/v1alpha1"
+	"github.com/knative/serving/pkg/metrics"
 	"github.com/knative/serving/pkg/reconciler"
 	"github.com/knative/serving/pkg/reconciler/v1alpha1/service/resources"
 	resourcenames "github.com/knative/serving/pkg/reconciler/v1alpha1/service/resources/names"
+	"github.com/knative/serving/pkg/system"
 	"go.uber.org/zap"
 	corev1 "k8s.io/api/core/v1"
 	"k8s.io/apimachinery/pkg/api/equality"
@@ -48,6 +51,16 @@ type Reconciler struct {
 	serviceLister       listers.ServiceLister
 	configurationLister listers.ConfigurationLister
 	routeLister         listers.RouteLister
+
+	// enqueueAfter schedules the Service to be reconciled again once
+	// the given duration has passed, e.g. to take the next rollout step.
+	enqueueAfter func(obj interface{}, after time.Duration)
+
+	// outcomes is queried for the request outcomes of release revisions
+	// to decide whether to roll a "candidate" revision back.
+	outcomes metrics.RequestOutcomesSource
+
+	clock system.Clock
 }
 
 // Check that our Reconciler implements controller.Reconciler
@@ -67,8 +80,21 @@ func NewController(
 		serviceLister:       serviceInformer.Lister(),
 		configurationLister: configurationInformer.Lister(),
 		routeLister:         routeInformer.Lister(),
+		clock:               system.RealClock{},
 	}
 	impl := controller.NewImpl(c, c.Logger, "Services", reconciler.MustNewStatsReporter("Services", c.Logger))
+	c.enqueueAfter = func(obj interface{}, after time.Duration) {
+		key, err := cache.MetaNamespaceKeyFunc(obj)
+		if err != nil {
+			c.Logger.Errorf("Failed to get key to requeue Service: %v", err)
+			return
+		}
+		impl.WorkQueue.AddAfter(key, after)
+	}
+
+	outcomes := metrics.NewDynamicOutcomesSource(c.Logger.Named("outcomes"))
+	opt.ConfigMapWatcher.Watch(metrics.ObservabilityConfigName, outcomes.Update)
+	c.outcomes = outcomes
 
 	c.Logger.Info("Setting up event handlers")
 	serviceInformer.Informer().AddEventHandler(cache.ResourceEventHandlerFuncs{
@@ -182,6 +208,13 @@ func (c *Reconciler) reconcile(ctx context.Context, service *v1alpha1.Service) e
 	// Update our Status based on the state of our underlying Configuration.
 	service.Status.PropagateConfigurationStatus(config.Status)
 
+	// Move any release rollout forward, or back if the candidate regressed,
+	// before computing the desired Route.
+	requeueAfter := resources.AdvanceRollout(service, c.clock.Now())
+	if nextCheck := c.reconcileRollback(ctx, service); nextCheck > 0 && (requeueAfter == 0 || nextCheck < requeueAfter) {
+		requeueAfter = nextCheck
+	}
+
 	routeName := resourcenames.Route(service)
 	route, err := c.routeLister.Routes(service.Namespace).Get(routeName)
 	if errors.IsNotFound(err) {
@@ -208,6 +241,10 @@ func (c *Reconciler) reconcile(ctx context.Context, service *v1alpha1.Service) e
 	// TODO(#642): Remove this.
 	service.Status.ObservedGeneration = service.Spec.Generation
 
+	if requeueAfter > 0 {
+		logger.Infof("Checking the rollout of Service %q again in %v", service.Name, requeueAfter)
+		c.enqueueAfter(service, requeueAfter)
+	}
 	return nil
 }
 
@@ -17,18 +17,24 @@ limitations under the License.
 package service
 
 import (
+	"context"
 	"fmt"
 	"testing"
+	"time"
 
+	"github.com/google/go-cmp/cmp"
 	duckv1alpha1 "github.com/knative/pkg/apis/duck/v1alpha1"
 	fakesharedclientset "github.com/knative/pkg/client/clientset/versioned/fake"
+	"github.com/knative/pkg/configmap"
 	"github.com/knative/pkg/controller"
 	"github.com/knative/serving/pkg/apis/serving/v1alpha1"
 	fakeclientset "github.com/knative/serving/pkg/client/clientset/versioned/fake"
 	informers "github.com/knative/serving/pkg/client/informers/externalversions"
+	"github.com/knative/serving/pkg/metrics"
 	"github.com/knative/serving/pkg/reconciler"
 	"github.com/knative/serving/pkg/reconciler/v1alpha1/service/resources"
 	. "github.com/knative/serving/pkg/reconciler/v1alpha1/testing"
+	"github.com/knative/serving/pkg/system"
 	corev1 "k8s.io/api/core/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/runtime"
@@ -36,6 +42,36 @@ import (
 	clientgotesting "k8s.io/client-go/testing"
 )
 
+var (
+	fakeCurTime = time.Unix(1e9, 0)
+
+	rolloutPlan = v1alpha1.RolloutPlan{
+		Steps: []v1alpha1.RolloutStep{{
+			Percent: 10,
+			Pause:   metav1.Duration{Duration: time.Minute},
+		}, {
+			Percent: 50,
+			Pause:   metav1.Duration{Duration: 10 * time.Minute},
+		}, {
+			Percent: 90,
+		}},
+	}
+
+	rollbackPolicy = v1alpha1.RollbackPolicy{
+		MaxErrorPercent: 5,
+	}
+)
+
+// fakeOutcomes serves the request outcomes of the Revisions it is keyed by.
+type fakeOutcomes map[string]*metrics.RequestOutcomes
+
+func (f fakeOutcomes) RevisionOutcomes(ctx context.Context, namespace, revision string, window time.Duration) (*metrics.RequestOutcomes, error) {
+	if o, ok := f[revision]; ok {
+		return o, nil
+	}
+	return &metrics.RequestOutcomes{}, nil
+}
+
 // This is heavily based on the way the OpenShift Ingress controller tests its reconciliation method.
 func TestReconcile(t *testing.T) {
 	table := TableTest{{
@@ -55,7 +91,7 @@ func TestReconcile(t *testing.T) {
 		WantErr: true,
 		WantEvents: []string{
 			Eventf(corev1.EventTypeWarning, "CreationFailed", "Failed to create Configuration %q: %v",
-				"incomplete", "malformed Service: MakeConfiguration requires one of runLatest, pinned, or release must be present"),
+				"incomplete", "malformed Service: MakeConfiguration requires one of runLatest, pinned, release, or inline must be present"),
 		},
 	}, {
 		Name: "runLatest - create route and service",
@@ -194,6 +230,203 @@ func TestReconcile(t *testing.T) {
 			Eventf(corev1.EventTypeNormal, "Created", "Created Route %q", "release-with-percent"),
 			Eventf(corev1.EventTypeNormal, "Updated", "Updated Service %q", "release-with-percent"),
 		},
+	}, {
+		Name: "inline - create route and service",
+		Objects: []runtime.Object{
+			svc("inline", "foo", WithInlineRollout(inlineTraffic("inline-00001")...)),
+		},
+		Key: "foo/inline",
+		WantCreates: []metav1.Object{
+			config("inline", "foo", WithInlineRollout(inlineTraffic("inline-00001")...)),
+			route("inline", "foo", WithInlineRollout(inlineTraffic("inline-00001")...)),
+		},
+		WantUpdates: []clientgotesting.UpdateActionImpl{{
+			Object: svc("inline", "foo", WithInlineRollout(inlineTraffic("inline-00001")...),
+				// The first reconciliation will initialize the status conditions.
+				WithInitSvcConditions),
+		}},
+		WantEvents: []string{
+			Eventf(corev1.EventTypeNormal, "Created", "Created Configuration %q", "inline"),
+			Eventf(corev1.EventTypeNormal, "Created", "Created Route %q", "inline"),
+			Eventf(corev1.EventTypeNormal, "Updated", "Updated Service %q", "inline"),
+		},
+	}, {
+		Name: "inline - with ready config and route",
+		Objects: []runtime.Object{
+			svc("inline2", "foo", WithInlineRollout(inlineTraffic("inline2-00001")...),
+				WithInitSvcConditions),
+			config("inline2", "foo", WithInlineRollout(inlineTraffic("inline2-00001")...),
+				WithGeneration(2), WithLatestCreated, WithObservedGen, WithLatestReady),
+			route("inline2", "foo", WithInlineRollout(inlineTraffic("inline2-00001")...),
+				WithDomain, WithDomainInternal, WithAddress, WithInitRouteConditions,
+				WithStatusTraffic(v1alpha1.TrafficTarget{
+					Name:         "current",
+					RevisionName: "inline2-00001",
+					Percent:      90,
+				}, v1alpha1.TrafficTarget{
+					Name:         "latest",
+					RevisionName: "inline2-00002",
+					Percent:      10,
+				}), MarkTrafficAssigned, MarkIngressReady),
+		},
+		Key: "foo/inline2",
+		WantUpdates: []clientgotesting.UpdateActionImpl{{
+			// Make sure that the route's traffic is reflected in the
+			// service's status.
+			Object: svc("inline2", "foo",
+				WithInlineRollout(inlineTraffic("inline2-00001")...),
+				WithReadyConfig("inline2-00002"),
+				WithReadyRoute, WithSvcStatusDomain, WithSvcStatusAddress,
+				WithSvcStatusTraffic(v1alpha1.TrafficTarget{
+					Name:         "current",
+					RevisionName: "inline2-00001",
+					Percent:      90,
+				}, v1alpha1.TrafficTarget{
+					Name:         "latest",
+					RevisionName: "inline2-00002",
+					Percent:      10,
+				})),
+		}},
+		WantEvents: []string{
+			Eventf(corev1.EventTypeNormal, "Updated", "Updated Service %q", "inline2"),
+		},
+	}, {
+		Name: "release - start rollout plan",
+		Objects: []runtime.Object{
+			svc("rollout", "foo", WithReleaseRolloutPlan(rolloutPlan, "rollout-00001", "rollout-00002")),
+		},
+		Key: "foo/rollout",
+		WantCreates: []metav1.Object{
+			config("rollout", "foo", WithReleaseRolloutPlan(rolloutPlan, "rollout-00001", "rollout-00002")),
+			// The first step of the plan sends 10% of traffic to the candidate.
+			route("rollout", "foo", withOptions(
+				WithReleaseRolloutPlan(rolloutPlan, "rollout-00001", "rollout-00002"),
+				WithSvcRolloutStatus(v1alpha1.RolloutStatus{
+					Candidate:          "rollout-00002",
+					NextTransitionTime: transitionAt(time.Minute),
+				}))),
+		},
+		WantUpdates: []clientgotesting.UpdateActionImpl{{
+			Object: svc("rollout", "foo", WithReleaseRolloutPlan(rolloutPlan, "rollout-00001", "rollout-00002"),
+				WithInitSvcConditions,
+				WithSvcRolloutStatus(v1alpha1.RolloutStatus{
+					Candidate:          "rollout-00002",
+					NextTransitionTime: transitionAt(time.Minute),
+				})),
+		}},
+		WantEvents: []string{
+			Eventf(corev1.EventTypeNormal, "Created", "Created Configuration %q", "rollout"),
+			Eventf(corev1.EventTypeNormal, "Created", "Created Route %q", "rollout"),
+			Eventf(corev1.EventTypeNormal, "Updated", "Updated Service %q", "rollout"),
+		},
+	}, {
+		Name: "release - advance rollout plan",
+		Objects: []runtime.Object{
+			svc("rollout-advance", "foo", WithReleaseRolloutPlan(rolloutPlan, "rollout-advance-00001", "rollout-advance-00002"),
+				WithInitSvcConditions,
+				WithSvcRolloutStatus(v1alpha1.RolloutStatus{
+					Candidate:          "rollout-advance-00002",
+					NextTransitionTime: transitionAt(-time.Second),
+				})),
+			config("rollout-advance", "foo", WithReleaseRolloutPlan(rolloutPlan, "rollout-advance-00001", "rollout-advance-00002")),
+			route("rollout-advance", "foo", withOptions(
+				WithReleaseRolloutPlan(rolloutPlan, "rollout-advance-00001", "rollout-advance-00002"),
+				WithSvcRolloutStatus(v1alpha1.RolloutStatus{
+					Candidate: "rollout-advance-00002",
+				}))),
+		},
+		Key: "foo/rollout-advance",
+		WantUpdates: []clientgotesting.UpdateActionImpl{{
+			// The second step of the plan sends 50% of traffic to the candidate.
+			Object: route("rollout-advance", "foo", withOptions(
+				WithReleaseRolloutPlan(rolloutPlan, "rollout-advance-00001", "rollout-advance-00002"),
+				WithSvcRolloutStatus(v1alpha1.RolloutStatus{
+					Candidate:   "rollout-advance-00002",
+					CurrentStep: 1,
+				}))),
+		}, {
+			Object: svc("rollout-advance", "foo", WithReleaseRolloutPlan(rolloutPlan, "rollout-advance-00001", "rollout-advance-00002"),
+				WithInitSvcConditions,
+				WithSvcRolloutStatus(v1alpha1.RolloutStatus{
+					Candidate:          "rollout-advance-00002",
+					CurrentStep:        1,
+					NextTransitionTime: transitionAt(10 * time.Minute),
+				})),
+		}},
+		WantEvents: []string{
+			Eventf(corev1.EventTypeNormal, "Updated", "Updated Service %q", "rollout-advance"),
+		},
+	}, {
+		Name: "release - roll back regressed candidate",
+		Objects: []runtime.Object{
+			svc("rollback", "foo", WithReleaseRolloutAndPercentage(50, "rollback-00001", "rollback-00002"),
+				WithRollbackPolicy(rollbackPolicy), WithInitSvcConditions,
+				WithSvcRolloutStatus(v1alpha1.RolloutStatus{
+					Candidate: "rollback-00002",
+				})),
+			config("rollback", "foo", WithReleaseRolloutAndPercentage(50, "rollback-00001", "rollback-00002")),
+			route("rollback", "foo", WithReleaseRolloutAndPercentage(50, "rollback-00001", "rollback-00002")),
+		},
+		Key: "foo/rollback",
+		WantUpdates: []clientgotesting.UpdateActionImpl{{
+			// All traffic is sent back to the current revision.
+			Object: route("rollback", "foo", withOptions(
+				WithReleaseRolloutAndPercentage(50, "rollback-00001", "rollback-00002"),
+				WithSvcRolloutStatus(v1alpha1.RolloutStatus{
+					Candidate:  "rollback-00002",
+					RolledBack: true,
+				}))),
+		}, {
+			Object: svc("rollback", "foo", WithReleaseRolloutAndPercentage(50, "rollback-00001", "rollback-00002"),
+				WithRollbackPolicy(rollbackPolicy), WithInitSvcConditions,
+				WithSvcRolloutStatus(v1alpha1.RolloutStatus{
+					Candidate:  "rollback-00002",
+					RolledBack: true,
+				}),
+				WithRolloutFailed("ErrorRateRegression", `Revision "rollback-00002" regressed: `+
+					"20.00% of requests failed, above the 5% threshold and the current revision's 0.00%")),
+		}},
+		WantEvents: []string{
+			Eventf(corev1.EventTypeWarning, "RolloutFailed", "Sent all traffic back to Revision %q, Revision %q regressed: %s",
+				"rollback-00001", "rollback-00002", "20.00% of requests failed, above the 5% threshold and the current revision's 0.00%"),
+			Eventf(corev1.EventTypeNormal, "Updated", "Updated Service %q", "rollback"),
+		},
+	}, {
+		Name: "release - keep healthy candidate",
+		Objects: []runtime.Object{
+			svc("rollback-healthy", "foo", WithReleaseRolloutAndPercentage(50, "rollback-healthy-00001", "rollback-healthy-00002"),
+				WithRollbackPolicy(rollbackPolicy), WithInitSvcConditions,
+				WithSvcRolloutStatus(v1alpha1.RolloutStatus{
+					Candidate: "rollback-healthy-00002",
+				})),
+			config("rollback-healthy", "foo", WithReleaseRolloutAndPercentage(50, "rollback-healthy-00001", "rollback-healthy-00002")),
+			route("rollback-healthy", "foo", WithReleaseRolloutAndPercentage(50, "rollback-healthy-00001", "rollback-healthy-00002")),
+		},
+		Key: "foo/rollback-healthy",
+	}, {
+		Name: "release - new candidate after rollback",
+		Objects: []runtime.Object{
+			svc("rollback-new", "foo", WithReleaseRolloutAndPercentage(50, "rollback-new-00001", "rollback-new-00003"),
+				WithRollbackPolicy(rollbackPolicy), WithInitSvcConditions,
+				WithSvcRolloutStatus(v1alpha1.RolloutStatus{
+					Candidate:  "rollback-new-00002",
+					RolledBack: true,
+				}),
+				WithRolloutFailed("ErrorRateRegression", "Revision regressed")),
+			config("rollback-new", "foo", WithReleaseRolloutAndPercentage(50, "rollback-new-00001", "rollback-new-00003")),
+			route("rollback-new", "foo", WithReleaseRolloutAndPercentage(50, "rollback-new-00001", "rollback-new-00003")),
+		},
+		Key: "foo/rollback-new",
+		WantUpdates: []clientgotesting.UpdateActionImpl{{
+			Object: svc("rollback-new", "foo", WithReleaseRolloutAndPercentage(50, "rollback-new-00001", "rollback-new-00003"),
+				WithRollbackPolicy(rollbackPolicy), WithInitSvcConditions,
+				WithSvcRolloutStatus(v1alpha1.RolloutStatus{
+					Candidate: "rollback-new-00003",
+				})),
+		}},
+		WantEvents: []string{
+			Eventf(corev1.EventTypeNormal, "Updated", "Updated Service %q", "rollback-new"),
+		},
 	}, {
 		Name: "manual- no creates",
 		Objects: []runtime.Object{
@@ -430,14 +663,35 @@ func TestReconcile(t *testing.T) {
 		},
 	}}
 
+	requeues := make(map[string]time.Duration)
 	table.Test(t, MakeFactory(func(listers *Listers, opt reconciler.Options) controller.Reconciler {
 		return &Reconciler{
 			Base:                reconciler.NewBase(opt, controllerAgentName),
 			serviceLister:       listers.GetServiceLister(),
 			configurationLister: listers.GetConfigurationLister(),
 			routeLister:         listers.GetRouteLister(),
+			enqueueAfter: func(obj interface{}, after time.Duration) {
+				requeues[obj.(*v1alpha1.Service).Name] = after
+			},
+			outcomes: fakeOutcomes{
+				"rollback-00001":         {Requests: 100},
+				"rollback-00002":         {Requests: 100, Errors: 20},
+				"rollback-healthy-00001": {Requests: 100, Errors: 1},
+				"rollback-healthy-00002": {Requests: 100, Errors: 1},
+			},
+			clock: FakeClock{Time: fakeCurTime},
 		}
 	}))
+
+	want := map[string]time.Duration{
+		"rollout":          time.Minute,
+		"rollout-advance":  10 * time.Minute,
+		"rollback-healthy": time.Minute,
+		"rollback-new":     time.Minute,
+	}
+	if diff := cmp.Diff(want, requeues); diff != "" {
+		t.Errorf("Unexpected requeues (-want +got): %v", diff)
+	}
 }
 
 func TestNew(t *testing.T) {
@@ -454,6 +708,7 @@ func TestNew(t *testing.T) {
 		KubeClientSet:    kubeClient,
 		SharedClientSet:  sharedClient,
 		ServingClientSet: servingClient,
+		ConfigMapWatcher: &configmap.ManualWatcher{Namespace: system.Namespace},
 		Logger:           TestLogger(t),
 	}, serviceInformer, configurationInformer, routeInformer)
 
@@ -499,6 +754,36 @@ func route(name, namespace string, so ServiceOption, ro ...RouteOption) *v1alpha
 	return route
 }
 
+// withOptions combines several ServiceOptions into one, e.g. to shape
+// the Service from which a Route is made.
+func withOptions(so ...ServiceOption) ServiceOption {
+	return func(s *v1alpha1.Service) {
+		for _, opt := range so {
+			opt(s)
+		}
+	}
+}
+
+// inlineTraffic splits traffic between the "current" revision and the
+// latest ready revision of an inline Service.
+func inlineTraffic(current string) []v1alpha1.TrafficTarget {
+	latestRevision := true
+	return []v1alpha1.TrafficTarget{{
+		Name:         "current",
+		RevisionName: current,
+		Percent:      90,
+	}, {
+		Name:           "latest",
+		LatestRevision: &latestRevision,
+		Percent:        10,
+	}}
+}
+
+func transitionAt(d time.Duration) *metav1.Time {
+	t := metav1.NewTime(fakeCurTime.Add(d))
+	return &t
+}
+
 // TODO(mattmoor): Replace these when we refactor Route's table_test.go
 func MutateRoute(rt *v1alpha1.Route) {
 	rt.Spec = v1alpha1.RouteSpec{}
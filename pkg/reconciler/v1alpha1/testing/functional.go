@@ -144,6 +144,41 @@ func WithReleaseRollout(names ...string) ServiceOption {
 	}
 }
 
+// WithReleaseRolloutPlan configures the Service to use a "release" rollout,
+// which spans the provided revisions and follows the given rollout plan.
+func WithReleaseRolloutPlan(plan v1alpha1.RolloutPlan, names ...string) ServiceOption {
+	return func(s *v1alpha1.Service) {
+		s.Spec = v1alpha1.ServiceSpec{
+			Release: &v1alpha1.ReleaseType{
+				Revisions:     names,
+				Rollout:       &plan,
+				Configuration: configSpec,
+			},
+		}
+	}
+}
+
+// WithRollbackPolicy configures the "release" rollout of the Service
+// to roll back according to the given policy.
+func WithRollbackPolicy(policy v1alpha1.RollbackPolicy) ServiceOption {
+	return func(s *v1alpha1.Service) {
+		s.Spec.Release.Rollback = &policy
+	}
+}
+
+// WithInlineRollout configures the Service to use an "inline" rollout,
+// which splits traffic over the provided targets.
+func WithInlineRollout(traffic ...v1alpha1.TrafficTarget) ServiceOption {
+	return func(s *v1alpha1.Service) {
+		s.Spec = v1alpha1.ServiceSpec{
+			Inline: &v1alpha1.InlineType{
+				Traffic:       traffic,
+				Configuration: configSpec,
+			},
+		}
+	}
+}
+
 // WithManualRollout configures the Service to use a "manual" rollout.
 func WithManualRollout(s *v1alpha1.Service) {
 	s.Spec = v1alpha1.ServiceSpec{
@@ -193,6 +228,20 @@ func WithSvcStatusTraffic(traffic ...v1alpha1.TrafficTarget) ServiceOption {
 	}
 }
 
+// WithSvcRolloutStatus sets the Service's status rollout block to the specified progress.
+func WithSvcRolloutStatus(rs v1alpha1.RolloutStatus) ServiceOption {
+	return func(s *v1alpha1.Service) {
+		s.Status.Rollout = &rs
+	}
+}
+
+// WithRolloutFailed marks the Service's rollout as failed.
+func WithRolloutFailed(reason, message string) ServiceOption {
+	return func(s *v1alpha1.Service) {
+		s.Status.MarkRolloutFailed(reason, "%s", message)
+	}
+}
+
 // WithFailedRoute reflects a Route's failure in the Service resource.
 func WithFailedRoute(reason, message string) ServiceOption {
 	return func(s *v1alpha1.Service) {
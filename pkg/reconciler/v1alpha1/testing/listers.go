@@ -133,6 +133,10 @@ func (l *Listers) GetVirtualServiceLister() istiolisters.VirtualServiceLister {
 	return istiolisters.NewVirtualServiceLister(l.indexerFor(&istiov1alpha3.VirtualService{}))
 }
 
+func (l *Listers) GetDestinationRuleLister() istiolisters.DestinationRuleLister {
+	return istiolisters.NewDestinationRuleLister(l.indexerFor(&istiov1alpha3.DestinationRule{}))
+}
+
 func (l *Listers) GetImageLister() cachinglisters.ImageLister {
 	return cachinglisters.NewImageLister(l.indexerFor(&cachingv1alpha1.Image{}))
 }
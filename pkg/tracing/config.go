@@ -0,0 +1,101 @@
+/*
+Copyright 2018 The Knative Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package tracing
+
+import (
+	"fmt"
+	"net/url"
+	"strconv"
+	"strings"
+
+	corev1 "k8s.io/api/core/v1"
+)
+
+const (
+	// ConfigName is the name of the ConfigMap holding the tracing configuration.
+	ConfigName = "config-tracing"
+
+	// EnableKey is the key of the flag enabling tracing.
+	EnableKey = "enable"
+	// ZipkinEndpointKey is the key of the Zipkin collector URL.
+	ZipkinEndpointKey = "zipkin-endpoint"
+	// DebugKey is the key of the flag that samples every trace.
+	DebugKey = "debug"
+	// SampleRateKey is the key of the fraction of sampled traces.
+	SampleRateKey = "sample-rate"
+
+	// DefaultZipkinEndpoint is the Zipkin collector installed alongside Istio.
+	DefaultZipkinEndpoint = "http://zipkin.istio-system.svc.cluster.local:9411/api/v2/spans"
+	// DefaultSampleRate is the fraction of traces sampled when not in debug mode.
+	DefaultSampleRate = 0.1
+)
+
+// Config holds the configuration for tracing.
+type Config struct {
+	// Enable turns on the creation and export of trace spans.
+	Enable bool
+	// ZipkinEndpoint is the URL spans are POSTed to. It has to accept
+	// the Zipkin v2 JSON API, e.g. a Zipkin server or a local collector.
+	ZipkinEndpoint string
+	// Debug samples every trace, ignoring SampleRate.
+	Debug bool
+	// SampleRate is the fraction of traces that are sampled, in [0, 1].
+	SampleRate float64
+}
+
+// NewTracingConfigFromMap creates a Config from the supplied map.
+func NewTracingConfigFromMap(cfgMap map[string]string) (*Config, error) {
+	tc := &Config{
+		Enable:         false,
+		ZipkinEndpoint: DefaultZipkinEndpoint,
+		Debug:          false,
+		SampleRate:     DefaultSampleRate,
+	}
+
+	if enable, ok := cfgMap[EnableKey]; ok {
+		tc.Enable = strings.ToLower(enable) == "true"
+	}
+
+	if endpoint, ok := cfgMap[ZipkinEndpointKey]; ok && endpoint != "" {
+		if _, err := url.ParseRequestURI(endpoint); err != nil {
+			return nil, fmt.Errorf("failed to parse %q: %v", ZipkinEndpointKey, err)
+		}
+		tc.ZipkinEndpoint = endpoint
+	}
+
+	if debug, ok := cfgMap[DebugKey]; ok {
+		tc.Debug = strings.ToLower(debug) == "true"
+	}
+
+	if sampleRate, ok := cfgMap[SampleRateKey]; ok && sampleRate != "" {
+		sr, err := strconv.ParseFloat(sampleRate, 64)
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse %q: %v", SampleRateKey, err)
+		}
+		if sr < 0 || sr > 1 {
+			return nil, fmt.Errorf("%q must be in [0, 1], got %v", SampleRateKey, sr)
+		}
+		tc.SampleRate = sr
+	}
+
+	return tc, nil
+}
+
+// NewTracingConfigFromConfigMap creates a Config from the supplied ConfigMap.
+func NewTracingConfigFromConfigMap(config *corev1.ConfigMap) (*Config, error) {
+	return NewTracingConfigFromMap(config.Data)
+}
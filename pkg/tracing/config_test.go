@@ -0,0 +1,101 @@
+/*
+Copyright 2018 The Knative Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package tracing
+
+import (
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/knative/serving/pkg/system"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	. "github.com/knative/serving/pkg/reconciler/testing"
+)
+
+func TestOurTracingConfig(t *testing.T) {
+	cm := ConfigMapFromTestFile(t, ConfigName)
+
+	if _, err := NewTracingConfigFromConfigMap(cm); err != nil {
+		t.Errorf("NewTracingConfigFromConfigMap() = %v", err)
+	}
+}
+
+func TestTracingConfig(t *testing.T) {
+	tests := []struct {
+		name    string
+		data    map[string]string
+		want    *Config
+		wantErr bool
+	}{{
+		name: "defaults",
+		want: &Config{
+			ZipkinEndpoint: DefaultZipkinEndpoint,
+			SampleRate:     DefaultSampleRate,
+		},
+	}, {
+		name: "all options",
+		data: map[string]string{
+			EnableKey:         "true",
+			ZipkinEndpointKey: "http://localhost:9411/api/v2/spans",
+			DebugKey:          "True",
+			SampleRateKey:     "0.5",
+		},
+		want: &Config{
+			Enable:         true,
+			ZipkinEndpoint: "http://localhost:9411/api/v2/spans",
+			Debug:          true,
+			SampleRate:     0.5,
+		},
+	}, {
+		name: "bad endpoint",
+		data: map[string]string{
+			ZipkinEndpointKey: "not a url",
+		},
+		wantErr: true,
+	}, {
+		name: "bad sample rate",
+		data: map[string]string{
+			SampleRateKey: "often",
+		},
+		wantErr: true,
+	}, {
+		name: "sample rate out of range",
+		data: map[string]string{
+			SampleRateKey: "1.5",
+		},
+		wantErr: true,
+	}}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			got, err := NewTracingConfigFromConfigMap(&corev1.ConfigMap{
+				ObjectMeta: metav1.ObjectMeta{
+					Namespace: system.Namespace,
+					Name:      ConfigName,
+				},
+				Data: test.data,
+			})
+			if (err != nil) != test.wantErr {
+				t.Fatalf("NewTracingConfigFromConfigMap() = %v, wantErr %v", err, test.wantErr)
+			}
+			if diff := cmp.Diff(test.want, got); diff != "" {
+				t.Errorf("NewTracingConfigFromConfigMap() (-want, +got) = %v", diff)
+			}
+		})
+	}
+}
@@ -0,0 +1,54 @@
+/*
+Copyright 2018 The Knative Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package tracing
+
+import (
+	"net/http"
+
+	"go.opencensus.io/plugin/ochttp/propagation/b3"
+	"go.opencensus.io/plugin/ochttp/propagation/tracecontext"
+	"go.opencensus.io/trace"
+	"go.opencensus.io/trace/propagation"
+)
+
+// HTTPFormat is the propagation.HTTPFormat used by the data-plane components.
+// It understands both W3C Trace Context headers and B3 headers (as used by
+// Istio/Envoy), preferring the former, and writes both on outgoing requests
+// so that either kind of downstream keeps the trace.
+var HTTPFormat propagation.HTTPFormat = &multiFormat{
+	formats: []propagation.HTTPFormat{&tracecontext.HTTPFormat{}, &b3.HTTPFormat{}},
+}
+
+// +k8s:deepcopy-gen=false
+type multiFormat struct {
+	formats []propagation.HTTPFormat
+}
+
+func (f *multiFormat) SpanContextFromRequest(req *http.Request) (trace.SpanContext, bool) {
+	for _, format := range f.formats {
+		if sc, ok := format.SpanContextFromRequest(req); ok {
+			return sc, true
+		}
+	}
+	return trace.SpanContext{}, false
+}
+
+func (f *multiFormat) SpanContextToRequest(sc trace.SpanContext, req *http.Request) {
+	for _, format := range f.formats {
+		format.SpanContextToRequest(sc, req)
+	}
+}
@@ -0,0 +1,138 @@
+/*
+Copyright 2018 The Knative Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package tracing
+
+import (
+	"net/http"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"go.opencensus.io/plugin/ochttp/propagation/b3"
+	"go.opencensus.io/trace"
+)
+
+// traceParentHeader is the W3C Trace Context header.
+const traceParentHeader = "traceparent"
+
+var (
+	testTraceID = trace.TraceID{0x4b, 0xf9, 0x2f, 0x35, 0x77, 0xb3, 0x4d, 0xa6, 0xa3, 0xce, 0x92, 0x9d, 0x0e, 0x0e, 0x47, 0x36}
+	testSpanID  = trace.SpanID{0x00, 0xf0, 0x67, 0xaa, 0x0b, 0xa9, 0x02, 0xb7}
+)
+
+func TestSpanContextFromRequest(t *testing.T) {
+	tests := []struct {
+		name    string
+		headers map[string]string
+		want    trace.SpanContext
+		wantOK  bool
+	}{{
+		name: "no headers",
+	}, {
+		name: "traceparent",
+		headers: map[string]string{
+			traceParentHeader: "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01",
+		},
+		want: trace.SpanContext{
+			TraceID:      testTraceID,
+			SpanID:       testSpanID,
+			TraceOptions: 1,
+		},
+		wantOK: true,
+	}, {
+		name: "traceparent not sampled",
+		headers: map[string]string{
+			traceParentHeader: "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-00",
+		},
+		want: trace.SpanContext{
+			TraceID: testTraceID,
+			SpanID:  testSpanID,
+		},
+		wantOK: true,
+	}, {
+		name: "b3",
+		headers: map[string]string{
+			b3.TraceIDHeader: "4bf92f3577b34da6a3ce929d0e0e4736",
+			b3.SpanIDHeader:  "00f067aa0ba902b7",
+			b3.SampledHeader: "1",
+		},
+		want: trace.SpanContext{
+			TraceID:      testTraceID,
+			SpanID:       testSpanID,
+			TraceOptions: 1,
+		},
+		wantOK: true,
+	}, {
+		name: "bad traceparent falls back to b3",
+		headers: map[string]string{
+			traceParentHeader: "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7",
+			b3.TraceIDHeader:  "4bf92f3577b34da6a3ce929d0e0e4736",
+			b3.SpanIDHeader:   "00f067aa0ba902b7",
+		},
+		want: trace.SpanContext{
+			TraceID: testTraceID,
+			SpanID:  testSpanID,
+		},
+		wantOK: true,
+	}, {
+		name: "zero trace id",
+		headers: map[string]string{
+			traceParentHeader: "00-00000000000000000000000000000000-00f067aa0ba902b7-01",
+		},
+	}, {
+		name: "invalid version",
+		headers: map[string]string{
+			traceParentHeader: "ff-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01",
+		},
+	}}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			req := &http.Request{Header: http.Header{}}
+			for k, v := range test.headers {
+				req.Header.Set(k, v)
+			}
+			got, ok := HTTPFormat.SpanContextFromRequest(req)
+			if ok != test.wantOK {
+				t.Errorf("SpanContextFromRequest() ok = %v, want %v", ok, test.wantOK)
+			}
+			if diff := cmp.Diff(test.want, got); diff != "" {
+				t.Errorf("SpanContextFromRequest() (-want, +got) = %v", diff)
+			}
+		})
+	}
+}
+
+func TestSpanContextToRequest(t *testing.T) {
+	req := &http.Request{Header: http.Header{}}
+	HTTPFormat.SpanContextToRequest(trace.SpanContext{
+		TraceID:      testTraceID,
+		SpanID:       testSpanID,
+		TraceOptions: 1,
+	}, req)
+
+	want := map[string]string{
+		traceParentHeader: "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01",
+		b3.TraceIDHeader:  "4bf92f3577b34da6a3ce929d0e0e4736",
+		b3.SpanIDHeader:   "00f067aa0ba902b7",
+		b3.SampledHeader:  "1",
+	}
+	for k, v := range want {
+		if got := req.Header.Get(k); got != v {
+			t.Errorf("Header %q = %q, want %q", k, got, v)
+		}
+	}
+}
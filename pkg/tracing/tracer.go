@@ -0,0 +1,137 @@
+/*
+Copyright 2018 The Knative Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package tracing
+
+import (
+	"net/http"
+	"sync"
+
+	"github.com/openzipkin/zipkin-go/model"
+	"github.com/openzipkin/zipkin-go/reporter"
+	zipkinhttp "github.com/openzipkin/zipkin-go/reporter/http"
+	"go.opencensus.io/exporter/zipkin"
+	"go.opencensus.io/plugin/ochttp"
+	"go.opencensus.io/trace"
+	"go.uber.org/zap"
+	corev1 "k8s.io/api/core/v1"
+)
+
+// Tracer configures the global OpenCensus tracer, and the exporter spans
+// are sent through, of the component it was created for.
+// +k8s:deepcopy-gen=false
+type Tracer struct {
+	serviceName string
+	logger      *zap.SugaredLogger
+
+	mu       sync.Mutex
+	config   *Config
+	reporter reporter.Reporter
+	exporter *zipkin.Exporter
+}
+
+// NewTracer creates a Tracer for the component serviceName. Tracing stays
+// disabled until a Config is applied.
+func NewTracer(serviceName string, logger *zap.SugaredLogger) *Tracer {
+	trace.ApplyConfig(trace.Config{DefaultSampler: trace.NeverSample()})
+	return &Tracer{
+		serviceName: serviceName,
+		logger:      logger,
+	}
+}
+
+// ApplyConfig (re)configures sampling and exporting according to cfg.
+func (t *Tracer) ApplyConfig(cfg *Config) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	if t.config != nil && *t.config == *cfg {
+		return
+	}
+	t.config = cfg.DeepCopy()
+
+	t.stopExporter()
+	if !cfg.Enable {
+		t.logger.Info("Tracing is disabled")
+		trace.ApplyConfig(trace.Config{DefaultSampler: trace.NeverSample()})
+		return
+	}
+
+	t.logger.Infof("Exporting traces to %s", cfg.ZipkinEndpoint)
+	t.reporter = zipkinhttp.NewReporter(cfg.ZipkinEndpoint)
+	t.exporter = zipkin.NewExporter(t.reporter, &model.Endpoint{ServiceName: t.serviceName})
+	trace.RegisterExporter(t.exporter)
+	if cfg.Debug {
+		trace.ApplyConfig(trace.Config{DefaultSampler: trace.AlwaysSample()})
+	} else {
+		trace.ApplyConfig(trace.Config{DefaultSampler: trace.ProbabilitySampler(cfg.SampleRate)})
+	}
+}
+
+// Shutdown sends the spans pending export and disables tracing.
+func (t *Tracer) Shutdown() {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	trace.ApplyConfig(trace.Config{DefaultSampler: trace.NeverSample()})
+	t.stopExporter()
+}
+
+func (t *Tracer) stopExporter() {
+	if t.exporter == nil {
+		return
+	}
+	trace.UnregisterExporter(t.exporter)
+	// Closing the reporter flushes the spans it has batched.
+	if err := t.reporter.Close(); err != nil {
+		t.logger.Errorw("Failed to flush the pending spans", zap.Error(err))
+	}
+	t.reporter = nil
+	t.exporter = nil
+}
+
+// UpdateTracerFromConfigMap returns a helper func that can be used to update
+// the tracer when the tracing config map is updated.
+func UpdateTracerFromConfigMap(t *Tracer, logger *zap.SugaredLogger) func(configMap *corev1.ConfigMap) {
+	return func(configMap *corev1.ConfigMap) {
+		cfg, err := NewTracingConfigFromConfigMap(configMap)
+		if err != nil {
+			logger.Errorw("Failed to parse the tracing config, keeping the current one", zap.Error(err))
+			return
+		}
+		t.ApplyConfig(cfg)
+	}
+}
+
+// NewHandler wraps h so that every request it serves gets a server span
+// named name, continuing the trace propagated by the caller if any.
+func NewHandler(name string, h http.Handler) http.Handler {
+	return &ochttp.Handler{
+		Handler:     h,
+		Propagation: HTTPFormat,
+		FormatSpanName: func(*http.Request) string {
+			return name
+		},
+	}
+}
+
+// NewTransport wraps rt so that every request it sends gets a client span,
+// and the trace is propagated to the server.
+func NewTransport(rt http.RoundTripper) http.RoundTripper {
+	return &ochttp.Transport{
+		Base:        rt,
+		Propagation: HTTPFormat,
+	}
+}
@@ -197,6 +197,8 @@ func ReleaseService(svc *v1alpha1.Service, revisions []string, rolloutPercent in
 		config = svc.Spec.Release.Configuration
 	} else if svc.Spec.Pinned != nil {
 		config = svc.Spec.Pinned.Configuration
+	} else if svc.Spec.Inline != nil {
+		config = svc.Spec.Inline.Configuration
 	}
 	return &v1alpha1.Service{
 		ObjectMeta: svc.ObjectMeta,
@@ -173,6 +173,8 @@ func PatchServiceImage(logger *logging.BaseLogger, clients *Clients, svc *v1alph
 		newSvc.Spec.Release.Configuration.RevisionTemplate.Spec.Container.Image = imagePath
 	} else if svc.Spec.Pinned != nil {
 		newSvc.Spec.Pinned.Configuration.RevisionTemplate.Spec.Container.Image = imagePath
+	} else if svc.Spec.Inline != nil {
+		newSvc.Spec.Inline.Configuration.RevisionTemplate.Spec.Container.Image = imagePath
 	} else {
 		return nil, fmt.Errorf("UpdateImageService(%v): unable to determine service type", svc)
 	}
@@ -192,6 +194,8 @@ func PatchServiceRevisionTemplateMetadata(logger *logging.BaseLogger, clients *C
 		newSvc.Spec.Release.Configuration.RevisionTemplate.ObjectMeta = metadata
 	} else if svc.Spec.Pinned != nil {
 		newSvc.Spec.Pinned.Configuration.RevisionTemplate.ObjectMeta = metadata
+	} else if svc.Spec.Inline != nil {
+		newSvc.Spec.Inline.Configuration.RevisionTemplate.ObjectMeta = metadata
 	} else {
 		return nil, fmt.Errorf("UpdateServiceRevisionTemplateMetadata(%v): unable to determine service type", svc)
 	}